@@ -0,0 +1,108 @@
+package rpk
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"sort"
+)
+
+// An AdminReport describes the state of a handler, as HandleAdmin serves it.
+type AdminReport struct {
+	// Funcs has the stats of every registered function, including internal ones, with
+	// the CollectStats option. Without it, the stats are zero.
+	Funcs map[string]FuncStats `json:"funcs"`
+
+	Disabled    []string               `json:"disabled"` // By Disable, sorted.
+	Connections []Connection           `json:"connections"`
+	Queue       QueueStats             `json:"queue"`
+	Bulkheads   map[string]QueueStats  `json:"bulkheads,omitempty"`
+	Tenants     map[string]TenantStats `json:"tenants,omitempty"`
+	Load        *LoadStats             `json:"load,omitempty"` // With LoadShedding.
+}
+
+// AdminReport returns the state of the handler.
+func (h *Handler) AdminReport() *AdminReport {
+	fs := h.table()
+	stats := h.FuncStats()
+	report := &AdminReport{Funcs: make(map[string]FuncStats, len(fs)), Disabled: []string{},
+		Connections: h.Connections(), Queue: h.QueueStats(),
+		Bulkheads: h.BulkheadStats(), Load: h.LoadStats()}
+	for name := range fs {
+		report.Funcs[name] = stats[name]
+	}
+	h.fmu.RLock()
+	for name := range h.disabled {
+		report.Disabled = append(report.Disabled, name)
+	}
+	h.fmu.RUnlock()
+	sort.Strings(report.Disabled)
+	if h.opts.tenants != nil {
+		report.Tenants = h.TenantStats()
+	}
+	return report
+}
+
+// HandleAdmin returns an http.Handler that serves the AdminReport of h as JSON, for
+// monitoring and debugging live servers. POST requests with a JSON object of "disable"
+// or "enable", the name of a function, disable or enable it, and get the updated
+// report:
+//
+//	curl -H 'Content-Type: application/json' -d '{"disable":"Export"}' \
+//		http://localhost:8080/admin
+//
+// Requests for which authorize returns false are forbidden. The admin handler lets its
+// users turn off functions, so the page must not be public: authorize should let only
+// administrators in, and it is best served on an internal port too. POST requests must
+// have a JSON content type, which browsers do not send across origins without asking,
+// so other sites cannot make the browsers of administrators disable functions.
+// Panics if authorize is nil.
+func HandleAdmin(h *Handler, authorize func(r *http.Request) bool) http.Handler {
+	if authorize == nil {
+		panic("rpk: HandleAdmin needs an authorize function")
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorize(r) {
+			http.Error(w, "Forbidden.", http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPost:
+			if t, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); t !=
+				"application/json" {
+				http.Error(w, "Content type must be application/json.",
+					http.StatusUnsupportedMediaType)
+				return
+			}
+			var form struct {
+				Disable string `json:"disable"`
+				Enable  string `json:"enable"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+				http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			disable, enable := form.Disable, form.Enable
+			for _, name := range []string{disable, enable} {
+				if name != "" && h.table()[name] == nil {
+					http.Error(w, "No such function '"+name+"'.", http.StatusNotFound)
+					return
+				}
+			}
+			if disable != "" {
+				h.Disable(disable)
+			}
+			if enable != "" {
+				h.Enable(enable)
+			}
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST")
+			http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		h.opts.encode.newEncoder(w).Encode(h.AdminReport())
+	})
+}
@@ -0,0 +1,86 @@
+package rpk
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleAdmin(t *testing.T) {
+	h := New(CollectStats())
+	h.Register("Half", func(i int) (int, error) {
+		if i < 0 {
+			return 0, errors.New("negative")
+		}
+		return i / 2, nil
+	})
+	h.Register("Ping", func() {})
+	for _, param := range []string{"4", "6", "-1", "8"} {
+		callAPI(h, "Half", param)
+	}
+	callAPI(h, "Nope", "")
+
+	admin := HandleAdmin(h, func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer admin"
+	})
+	send := func(method, contentType, body string) (*AdminReport, int) {
+		req := httptest.NewRequest(method, "/admin", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer admin")
+		req.Header.Set("Content-Type", contentType)
+		res := httptest.NewRecorder()
+		admin.ServeHTTP(res, req)
+		var r *AdminReport
+		json.Unmarshal(res.Body.Bytes(), &r)
+		return r, res.Code
+	}
+	report := func(method, body string) (*AdminReport, int) {
+		return send(method, "application/json", body)
+	}
+
+	r, _ := report("GET", "")
+	half := r.Funcs["Half"]
+	if half.Calls != 4 || half.Errors != 1 || half.ErrRate != 0.25 || half.InFlight != 0 {
+		t.Fatalf("Bad stats of Half: %+v, expected 4 calls and 1 error.", half)
+	}
+	if _, ok := r.Funcs["Ping"]; !ok || len(r.Funcs) != 2 {
+		t.Fatalf("Bad functions in report: %v, expected Half and Ping.", r.Funcs)
+	}
+
+	r, _ = report("POST", `{"disable":"Half"}`)
+	if len(r.Disabled) != 1 || r.Disabled[0] != "Half" {
+		t.Fatalf("Bad disabled functions: %v, expected Half.", r.Disabled)
+	}
+	want := `{"error":"Function 'Half' is disabled: unavailable","code":"unavailable"}`
+	if got := callAPI(h, "Half", "2"); got != want {
+		t.Fatalf("Bad result of a disabled function: %q, expected %q.", got, want)
+	}
+
+	r, _ = report("POST", `{"enable":"Half"}`)
+	if len(r.Disabled) != 0 {
+		t.Fatalf("Bad disabled functions: %v, expected none.", r.Disabled)
+	}
+	if _, code := report("POST", `{"disable":"Nope"}`); code != 404 {
+		t.Fatalf("Bad status for disabling a missing function: %d, expected 404.", code)
+	}
+	if _, code := report("DELETE", ""); code != 405 {
+		t.Fatalf("Bad status for DELETE: %d, expected 405.", code)
+	}
+
+	// Forms, which other sites can post, are not accepted.
+	_, code := send("POST", "application/x-www-form-urlencoded", "disable=Ping")
+	if code != 415 {
+		t.Fatalf("Bad status for a form: %d, expected 415.", code)
+	}
+	req := httptest.NewRequest("GET", "/admin", nil)
+	res := httptest.NewRecorder()
+	admin.ServeHTTP(res, req)
+	if res.Code != 403 {
+		t.Fatalf("Bad status without authorization: %d, expected 403.", res.Code)
+	}
+	if r, _ := report("GET", ""); len(r.Disabled) != 0 {
+		t.Fatalf("Bad disabled functions: %v, expected none.", r.Disabled)
+	}
+}
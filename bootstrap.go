@@ -0,0 +1,101 @@
+package rpk
+
+import (
+	"encoding/json"
+	"html"
+	"html/template"
+)
+
+// jsFeatures are the features that the Javascript client supports, as in its code.
+const jsFeatures = "events,gzip,int64string,jobs,jsnames,stream,transactions,uploads," +
+	"versions"
+
+// CSRFHeader is the request header in which the Javascript client sends the CSRF token
+// of BootstrapOptions, for servers that check it, like the middleware of
+// github.com/gorilla/csrf.
+const CSRFHeader = "X-CSRF-Token"
+
+// BootstrapOptions configure the Javascript clients of a page, for BootstrapScript.
+// Their values usually come from the server's environment, so the same frontend code
+// runs in every deployment.
+type BootstrapOptions struct {
+	ID    string // Of the element, which clients name in their bootstrap option.
+	Nonce string // The element's nonce attribute, if not empty.
+
+	// URL of the handler, for clients created with an empty URL, like
+	// "https://api.example.com/rpc".
+	URL string
+
+	CSRFToken string                 // Sent in the CSRFHeader of every call.
+	Flags     map[string]interface{} // Feature flags, in rpkObject.flags.
+	Version   string                 // Version of the deployment, in rpkObject.version.
+
+	// Handler, if not nil, adds the names of its functions and its protocol, so clients
+	// initialize without calling it, like Handler.Bootstrap.
+	Handler *Handler
+}
+
+// BootstrapScript returns a script element that configures the Javascript clients of a
+// page, that are created with the element's ID as their bootstrap option:
+//
+//	{{.Bootstrap}}  <!-- From rpk.BootstrapScript(opts). -->
+//	<script nonce="...">
+//	  var api = rpk("", {bootstrap: "rpk-bootstrap"});
+//	  if (api.flags.newEditor) { ... }
+//	</script>
+//
+// The element holds JSON data, which browsers do not run, so it is allowed under strict
+// Content Security Policies. Nonce, if not empty, is set as its nonce attribute, for
+// policies that require one on all script elements. The client itself does not use
+// eval or inline code.
+func BootstrapScript(opts BootstrapOptions) template.HTML {
+	meta := map[string]interface{}{}
+	if opts.URL != "" {
+		meta["url"] = opts.URL
+	}
+	if opts.CSRFToken != "" {
+		meta["csrfToken"] = opts.CSRFToken
+	}
+	if opts.Flags != nil {
+		meta["flags"] = opts.Flags
+	}
+	if opts.Version != "" {
+		meta["version"] = opts.Version
+	}
+	if h := opts.Handler; h != nil {
+		version, features := h.negotiated("1", jsFeatures)
+		names := h.publicNames(h.table())
+		meta["funcs"] = names
+		meta["protocol"] = version
+		meta["features"] = features
+		if h.opts.jsNames != nil {
+			meta["jsNames"] = h.jsNameMap(names)
+		}
+		if paths := h.int64Paths(names); paths != nil {
+			meta["int64Paths"] = paths
+		}
+	}
+	data, _ := json.Marshal(meta)
+	attrs := `type="application/json" id="` + html.EscapeString(opts.ID) + `"`
+	if opts.Nonce != "" {
+		attrs += ` nonce="` + html.EscapeString(opts.Nonce) + `"`
+	}
+	// Marshal escapes <, > and &, so the data cannot end the element.
+	return template.HTML("<script " + attrs + ">" + string(data) + "</script>")
+}
+
+// Bootstrap returns a script element that holds the names of the handler's functions,
+// and the protocol that it speaks with the Javascript client, for embedding in pages.
+// The client then initializes synchronously, without calling the handler, when created
+// with the element's ID as its bootstrap option:
+//
+//	{{.Bootstrap}}  <!-- From h.Bootstrap("rpk-bootstrap", nonce). -->
+//	<script nonce="...">
+//	  var api = rpk("/api", {bootstrap: "rpk-bootstrap"});
+//	  api.GetUser(7, ...);  // No need to wait for onReady.
+//	</script>
+//
+// It is like BootstrapScript with the handler, and with no other options.
+func (h *Handler) Bootstrap(id, nonce string) template.HTML {
+	return BootstrapScript(BootstrapOptions{ID: id, Nonce: nonce, Handler: h})
+}
@@ -0,0 +1,60 @@
+package rpk
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHandler_Bootstrap(t *testing.T) {
+	h := New(Async(0, "B"))
+	h.Register("A", func() {})
+	h.Register("B", func() {})
+
+	tests := []struct {
+		id, nonce, want string
+	}{
+		{"rpk", "", `<script type="application/json" id="rpk">` +
+			`{"features":["events","gzip","jobs","stream","versions"],` +
+			`"funcs":["A","B"],"protocol":1}</script>`},
+		{`a"b`, "n<1>", `<script type="application/json" id="a&#34;b" nonce="n&lt;1&gt;">` +
+			`{"features":["events","gzip","jobs","stream","versions"],` +
+			`"funcs":["A","B"],"protocol":1}</script>`},
+	}
+	for _, test := range tests {
+		if got := string(h.Bootstrap(test.id, test.nonce)); got != test.want {
+			t.Fatalf("Bad result for %q: %s, expected %s.", test.id, got, test.want)
+		}
+	}
+}
+
+func TestJSFeatures(t *testing.T) {
+	if !strings.Contains(jsCode, `var jsFeatures = "`+jsFeatures+`";`) {
+		t.Fatalf("Features in the Javascript code do not match %q.", jsFeatures)
+	}
+}
+
+func TestBootstrapScript(t *testing.T) {
+	h := New()
+	h.Register("A", func() {})
+
+	tests := []struct {
+		opts BootstrapOptions
+		want string
+	}{
+		{BootstrapOptions{ID: "cfg", URL: "https://api.example.com/rpc",
+			CSRFToken: "t</script>", Flags: map[string]interface{}{"beta": true},
+			Version: "1.2"},
+			`<script type="application/json" id="cfg">` +
+				`{"csrfToken":"t\u003c/script\u003e","flags":{"beta":true},` +
+				`"url":"https://api.example.com/rpc","version":"1.2"}</script>`},
+		{BootstrapOptions{ID: "cfg", Version: "1.2", Handler: h},
+			`<script type="application/json" id="cfg">` +
+				`{"features":["events","gzip","stream","versions"],"funcs":["A"],` +
+				`"protocol":1,"version":"1.2"}</script>`},
+	}
+	for _, test := range tests {
+		if got := string(BootstrapScript(test.opts)); got != test.want {
+			t.Fatalf("Bad result for %+v: %s, expected %s.", test.opts, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package rpk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CircuitBreaker makes calls of the named functions, or of all functions if none are
+// named, fail fast while their dependencies are down. After the given number of
+// consecutive failures of a function, its circuit opens, and its calls fail with an
+// error that wraps ErrUnavailable, without calling it, for the cooldown period. Then a
+// single trial call is let through, which closes the circuit if it succeeds, and opens
+// it again if it fails. Only failures of the functions count: errors that they return
+// with an HTTPStatus of 500 and above, and panics. Bad parameters and other errors of
+// clients, full queues of worker pools, and calls whose callers cancel them or whose
+// deadlines pass count neither as failures nor as successes.
+// Handler.CircuitStates reports the states of the circuits, and so do FuncStats with the
+// CollectStats option.
+func CircuitBreaker(failures int, cooldown time.Duration, names ...string) Option {
+	return func(o *options) {
+		o.breaker = &breakerOptions{failures, cooldown, nameSet(names)}
+	}
+}
+
+// Circuit states.
+const (
+	CircuitClosed   = "closed"    // Calls are made.
+	CircuitOpen     = "open"      // Calls fail without being made.
+	CircuitHalfOpen = "half-open" // A trial call is made.
+)
+
+// CircuitStates returns the states of the circuits of functions that have failed since
+// the handler was created, by name, with the CircuitBreaker option. A circuit whose
+// cooldown has passed is half-open, as its next call is a trial.
+func (h *Handler) CircuitStates() map[string]string {
+	if h.opts.breaker == nil {
+		return nil
+	}
+	h.circuits.mu.Lock()
+	defer h.circuits.mu.Unlock()
+	result := make(map[string]string, len(h.circuits.m))
+	now := time.Now()
+	for name, c := range h.circuits.m {
+		result[name] = c.state(now, h.opts.breaker.cooldown)
+	}
+	return result
+}
+
+// breakerOptions are the options of CircuitBreaker.
+type breakerOptions struct {
+	failures int
+	cooldown time.Duration
+	names    map[string]bool // Nil means all.
+}
+
+// circuitSet holds the circuits of a handler.
+type circuitSet struct {
+	mu sync.Mutex
+	m  map[string]*circuit // By function name.
+}
+
+// circuit is the state of the circuit of a function.
+type circuit struct {
+	failures int       // Consecutive failures.
+	opened   time.Time // When the circuit last opened, zero if it is closed.
+	trial    bool      // Whether a trial call is in progress.
+}
+
+// state returns the state of the circuit at the given time.
+func (c *circuit) state(now time.Time, cooldown time.Duration) string {
+	switch {
+	case c.opened.IsZero():
+		return CircuitClosed
+	case c.trial || now.Sub(c.opened) >= cooldown:
+		return CircuitHalfOpen
+	}
+	return CircuitOpen
+}
+
+// enterCircuit checks the circuit of the named function before a call. Returns an error
+// if the call should fail fast, and otherwise a function that reports the call's error,
+// or its panic as a PanicError.
+func (h *Handler) enterCircuit(funcName string) (func(ctx context.Context, err error),
+	error) {
+	b := h.opts.breaker
+	if b == nil || b.names != nil && !b.names[funcName] || h.table()[funcName] == nil {
+		return nil, nil
+	}
+	s := &h.circuits
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.m[funcName]
+	trial := false
+	if c != nil && !c.opened.IsZero() {
+		if c.trial || time.Since(c.opened) < b.cooldown {
+			return nil, fmt.Errorf("Circuit of function '%s' is open: %w", funcName,
+				ErrUnavailable)
+		}
+		c.trial, trial = true, true
+	}
+	return func(ctx context.Context, err error) {
+		counted, failed := circuitResult(ctx, err)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.m == nil {
+			s.m = map[string]*circuit{}
+		}
+		c := s.m[funcName]
+		if c == nil {
+			if !failed {
+				return
+			}
+			c = &circuit{}
+			s.m[funcName] = c
+		}
+		if trial {
+			c.trial = false // An uncounted trial leaves the circuit half-open.
+		}
+		if !counted {
+			return
+		}
+		if !failed {
+			c.failures, c.opened = 0, time.Time{}
+			return
+		}
+		c.failures++
+		if trial || c.failures >= b.failures {
+			c.opened = time.Now()
+		}
+	}, nil
+}
+
+// circuitResult returns whether a call with ctx that returned err counts for its circuit,
+// and whether it failed.
+func circuitResult(ctx context.Context, err error) (counted, failed bool) {
+	if err == nil {
+		return true, false
+	}
+	if errors.As(err, new(*callError)) {
+		return false, false // Bad parameters and busy pools.
+	}
+	if ctx.Err() != nil && (errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded)) {
+		return false, false // Ended by the caller.
+	}
+	failed = HTTPStatus(err) >= http.StatusInternalServerError
+	return true, failed
+}
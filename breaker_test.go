@@ -0,0 +1,136 @@
+package rpk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	down, calls := true, 0
+	h := New(CircuitBreaker(2, 50*time.Millisecond, "Fetch"), CollectStats())
+	h.Register("Fetch", func(i int) (int, error) {
+		calls++
+		if down {
+			return 0, errors.New("connection refused")
+		}
+		return i, nil
+	})
+	state := func() string {
+		return h.CircuitStates()["Fetch"]
+	}
+
+	// Bad parameters do not count as failures.
+	callAPI(h, "Fetch", `"a"`)
+	callAPI(h, "Fetch", `"a"`)
+	callAPI(h, "Fetch", "1")
+	if got := state(); got != CircuitClosed {
+		t.Fatalf("Bad state after 1 failure: %q, expected %q.", got, CircuitClosed)
+	}
+	callAPI(h, "Fetch", "1")
+	if got := state(); got != CircuitOpen {
+		t.Fatalf("Bad state after 2 failures: %q, expected %q.", got, CircuitOpen)
+	}
+	want := `{"error":"Circuit of function 'Fetch' is open: unavailable",` +
+		`"code":"unavailable"}`
+	if got := callAPI(h, "Fetch", "1"); got != want {
+		t.Fatalf("Bad result with an open circuit: %q, expected %q.", got, want)
+	}
+	if calls != 2 {
+		t.Fatalf("Bad number of calls: %d, expected 2.", calls)
+	}
+	if got := h.FuncStats()["Fetch"].Circuit; got != CircuitOpen {
+		t.Fatalf("Bad circuit in stats: %q, expected %q.", got, CircuitOpen)
+	}
+
+	// A failed trial opens the circuit again.
+	time.Sleep(60 * time.Millisecond)
+	if got := state(); got != CircuitHalfOpen {
+		t.Fatalf("Bad state after cooldown: %q, expected %q.", got, CircuitHalfOpen)
+	}
+	callAPI(h, "Fetch", "1")
+	if got := state(); got != CircuitOpen {
+		t.Fatalf("Bad state after a failed trial: %q, expected %q.", got, CircuitOpen)
+	}
+
+	// A successful trial closes it.
+	time.Sleep(60 * time.Millisecond)
+	down = false
+	if got := callAPI(h, "Fetch", "3"); got != "3" {
+		t.Fatalf("Bad result of trial: %q, expected %q.", got, "3")
+	}
+	if got := state(); got != CircuitClosed {
+		t.Fatalf("Bad state after a trial: %q, expected %q.", got, CircuitClosed)
+	}
+}
+
+func TestCircuitBreaker_panics(t *testing.T) {
+	for _, hide := range []bool{false, true} {
+		opts := []Option{CircuitBreaker(1, time.Minute)}
+		if hide {
+			opts = append(opts, HideErrors(nil))
+		}
+		h := New(opts...)
+		h.Register("Crash", func() { panic("oops") })
+		func() {
+			defer func() { recover() }()
+			callAPI(h, "Crash", "")
+		}()
+		if got := h.CircuitStates()["Crash"]; got != CircuitOpen {
+			t.Fatalf("Bad state after a panic (hidden: %v): %q, expected %q.",
+				hide, got, CircuitOpen)
+		}
+	}
+}
+
+func TestCircuitBreaker_cancel(t *testing.T) {
+	h := New(CircuitBreaker(1, time.Minute))
+	h.Register("Wait", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	h.Register("Timeout", func() error {
+		return context.DeadlineExceeded
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	serve(h, apiRequest("Wait", "").WithContext(ctx))
+	if got := h.CircuitStates()["Wait"]; got != "" {
+		t.Fatalf("Bad state after a cancelled call: %q, expected none.", got)
+	}
+
+	// Deadlines of the function's own are its failures.
+	callAPI(h, "Timeout", "")
+	if got := h.CircuitStates()["Timeout"]; got != CircuitOpen {
+		t.Fatalf("Bad state after a timeout: %q, expected %q.", got, CircuitOpen)
+	}
+}
+
+func TestCircuitResult(t *testing.T) {
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		ctx             context.Context
+		err             error
+		counted, failed bool
+	}{
+		{context.Background(), nil, true, false},
+		{context.Background(), errors.New("down"), true, true},
+		{context.Background(), &PanicError{"oops"}, true, true},
+		{context.Background(), ErrNotFound, true, false},
+		{context.Background(), ErrUnavailable, true, true},
+		{context.Background(), newCallError(errBusy, "Busy."), false, false},
+		{context.Background(), newCallError(errBadParam, "Bad."), false, false},
+		{context.Background(), context.Canceled, true, true},
+		{done, context.Canceled, false, false},
+		{done, errors.New("down"), true, true},
+	}
+	for _, test := range tests {
+		counted, failed := circuitResult(test.ctx, test.err)
+		if counted != test.counted || failed != test.failed {
+			t.Fatalf("Bad result for %v: %v %v, expected %v %v.", test.err, counted,
+				failed, test.counted, test.failed)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package rpk
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuthorizeTopics makes the handler call authorize before letting a client subscribe to
+// a topic. If it returns an error, the subscription is refused with status 403. Without
+// it, clients may subscribe to any topic.
+func AuthorizeTopics(authorize func(r *http.Request, topic string) error) Option {
+	return func(o *options) {
+		o.authorizeTopic = authorize
+	}
+}
+
+// Broadcast sends payload to the clients that subscribed to topic, which should not
+// contain commas. It does not wait for them to receive it. Clients that fall behind miss
+// messages, rather than slowing others down.
+//
+// Clients subscribe with the special function "events", which streams messages as
+// server-sent events, one JSON object per message with the fields "topic" and
+// "payload". The Javascript client does that with subscribe. Note that the server's
+// WriteTimeout, if set, also limits the length of subscriptions. To broadcast from
+// several instances of a server, see PubSubBackend.
+func (h *Handler) Broadcast(topic string, payload interface{}) error {
+	data, err := h.opts.encode.marshal(struct {
+		Topic   string      `json:"topic"`
+		Payload interface{} `json:"payload"`
+	}{topic, payload})
+	if err != nil {
+		return fmt.Errorf("rpk: error encoding payload: %v", err)
+	}
+	return h.publish(topic, data)
+}
+
+// subscriberBuffer is the number of messages that a subscriber can fall behind by.
+const subscriberBuffer = 64
+
+// A subscriber is a client connection that receives broadcast messages.
+type subscriber struct {
+	conn   Connection
+	topics map[string]bool
+	ch     chan []byte
+	done   chan struct{} // Closed to disconnect.
+	once   sync.Once
+}
+
+// disconnect makes the subscriber's stream end.
+func (sub *subscriber) disconnect() {
+	sub.once.Do(func() { close(sub.done) })
+}
+
+// subscribers holds the subscribers of a handler.
+type subscribers struct {
+	mu sync.Mutex
+	m  map[*subscriber]struct{}
+}
+
+// add adds a subscriber for the given connection and returns it.
+func (s *subscribers) add(conn Connection) *subscriber {
+	sub := &subscriber{conn: conn, topics: map[string]bool{},
+		ch: make(chan []byte, subscriberBuffer), done: make(chan struct{})}
+	for _, t := range conn.Topics {
+		sub.topics[t] = true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m == nil {
+		s.m = map[*subscriber]struct{}{}
+	}
+	s.m[sub] = struct{}{}
+	return sub
+}
+
+// remove removes a subscriber.
+func (s *subscribers) remove(sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, sub)
+}
+
+// publish sends an encoded message to the subscribers of topic that have room for it.
+func (s *subscribers) publish(topic string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.m {
+		if !sub.topics[topic] {
+			continue
+		}
+		select {
+		case sub.ch <- data:
+		default:
+		}
+	}
+}
+
+// serveEvents streams the messages of the topics in the request's "topics" value,
+// separated by commas, as server-sent events.
+func (h *Handler) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, "Streaming is not supported.")
+		return
+	}
+	var topics []string
+	for _, t := range strings.Split(r.FormValue("topics"), ",") {
+		if t == "" {
+			continue
+		}
+		if h.opts.authorizeTopic != nil {
+			if err := h.opts.authorizeTopic(r, t); err != nil {
+				w.WriteHeader(http.StatusForbidden)
+				writeError(w, "Topic '%s': %v", t, err)
+				return
+			}
+		}
+		topics = append(topics, t)
+	}
+
+	conn := Connection{ID: newID(), Topics: topics, Connected: time.Now()}
+	if h.opts.connectionUser != nil {
+		conn.User = h.opts.connectionUser(r)
+	}
+	sub := h.subs.add(conn)
+	if h.opts.onConnect != nil {
+		h.opts.onConnect(conn)
+	}
+	defer func() {
+		h.subs.remove(sub)
+		if h.opts.onDisconnect != nil {
+			h.opts.onDisconnect(conn)
+		}
+	}()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, ": subscribed\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.done:
+			return
+		case data := <-sub.ch:
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package rpk
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBroadcast(t *testing.T) {
+	h := New(AuthorizeTopics(func(r *http.Request, topic string) error {
+		if topic == "secret" {
+			return errors.New("not allowed")
+		}
+		return nil
+	}))
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET",
+		server.URL+"?func=events&topics=a,b", nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("Failed to subscribe:", err)
+	}
+	defer res.Body.Close()
+	if typ := res.Header.Get("Content-Type"); typ != "text/event-stream" {
+		t.Fatalf("Bad content type: %q, expected %q.", typ, "text/event-stream")
+	}
+	r := bufio.NewReader(res.Body)
+	if line, _ := r.ReadString('\n'); line != ": subscribed\n" {
+		t.Fatalf("Bad first line: %q, expected %q.", line, ": subscribed\n")
+	}
+	r.ReadString('\n')
+
+	h.Broadcast("c", 1)
+	h.Broadcast("b", map[string]int{"x": 2})
+	h.Broadcast("a", "hello")
+	want := []string{`data: {"topic":"b","payload":{"x":2}}`,
+		`data: {"topic":"a","payload":"hello"}`}
+	for _, w := range want {
+		line, _ := r.ReadString('\n')
+		if line = strings.TrimSpace(line); line != w {
+			t.Fatalf("Bad message: %q, expected %q.", line, w)
+		}
+		r.ReadString('\n')
+	}
+
+	res, err = http.Get(server.URL + "?func=events&topics=a,secret")
+	if err != nil {
+		t.Fatal("Failed to subscribe:", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("Bad status for unauthorized topic: %d, expected %d.", res.StatusCode,
+			http.StatusForbidden)
+	}
+}
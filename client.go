@@ -0,0 +1,205 @@
+package rpk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Client calls the functions of a remote RPK handler from Go. The rpkgen command can
+// generate typed clients that implement an API interface on top of it:
+//
+//	go run github.com/fluhus/rpk/cmd/rpkgen -type=MyAPI -client
+type Client struct {
+	url    string
+	http   *http.Client
+	errors map[string]func(data json.RawMessage) error // By code.
+	keyID  string                                      // Of SignCalls.
+	key    []byte                                      // Of SignCalls, nil without it.
+}
+
+// NewClient returns a client of the handler at the given URL. If httpClient is nil,
+// http.DefaultClient is used.
+func NewClient(url string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{url: url, http: httpClient}
+}
+
+// A RemoteError is an error returned by a remote function.
+type RemoteError struct {
+	Message string
+
+	// Conflict is set for ConflictErrors, with the current version of the resource.
+	Conflict bool
+	Version  string
+
+	// Code and Data are set for RPCErrors.
+	Code string
+	Data json.RawMessage
+
+	err error // Made of the code and data by the client's registered function.
+}
+
+func (e *RemoteError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the error that the function registered for the error's code made, or
+// the standard error with the code, like ErrNotFound, if any, so errors.As and
+// errors.Is can find typed errors of the remote function.
+func (e *RemoteError) Unwrap() error {
+	return e.err
+}
+
+// RegisterError makes RemoteErrors with the given code unwrap to the error that decode
+// returns for their data, typically an error of the same type as the remote function's
+// RPCError:
+//
+//	c.RegisterError("quota", func(data json.RawMessage) error {
+//	  e := &QuotaError{}
+//	  json.Unmarshal(data, e)
+//	  return e
+//	})
+//	...
+//	var qerr *QuotaError
+//	if errors.As(err, &qerr) { ... }
+//
+// Errors should be registered before making calls.
+func (c *Client) RegisterError(code string, decode func(data json.RawMessage) error) {
+	if c.errors == nil {
+		c.errors = map[string]func(data json.RawMessage) error{}
+	}
+	c.errors[code] = decode
+}
+
+// SignCalls makes the client sign its calls with key, for handlers with the
+// ReplayProtection option. If keyID is not empty, it is sent in KeyIDHeader, for
+// handlers that look up keys with SigningKeys. It should be called before making calls.
+func (c *Client) SignCalls(keyID string, key []byte) {
+	c.keyID, c.key = keyID, key
+}
+
+// Call calls the named remote function with param, and decodes its output into result.
+// A nil param means that the function takes no input, and a nil result means that its
+// output, if any, is ignored. Errors returned by the remote function are of type
+// *RemoteError. If ctx has a deadline, it is sent to the handler, which applies it to
+// the remote call's context. If ctx has a version set by WithVersion, it is sent as the
+// version that the call expects. If ctx is made by WithValidateOnly, the call only
+// validates param, and result is left as is. If ctx is made by WithFields, only the
+// given fields of the output are sent, and decoded into result.
+func (c *Client) Call(ctx context.Context, name string, param, result interface{}) error {
+	var body io.Reader = http.NoBody
+	var encoded []byte
+	if param != nil {
+		var err error
+		encoded, err = json.Marshal(param)
+		if err != nil {
+			return fmt.Errorf("rpk: error encoding parameter: %v", err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return fmt.Errorf("rpk: bad URL: %v", err)
+	}
+	q := u.Query()
+	q.Set("func", name)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), body)
+	if err != nil {
+		return fmt.Errorf("rpk: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if deadline, ok := ctx.Deadline(); ok {
+		ms := time.Until(deadline).Milliseconds()
+		if ms < 1 {
+			ms = 1
+		}
+		req.Header.Set(TimeoutHeader, strconv.FormatInt(ms, 10))
+	}
+	if v, ok := clientVersion(ctx); ok {
+		req.Header.Set("If-Match", `"`+v+`"`)
+	}
+	if ValidateOnly(ctx) {
+		req.Header.Set(ValidateOnlyHeader, "true")
+	}
+	if fields := clientFields(ctx); fields != nil {
+		req.Header.Set(FieldsHeader, strings.Join(fields, ","))
+	}
+	if c.key != nil {
+		nonce, ts := newID()+newID(), strconv.FormatInt(time.Now().UnixMilli(), 10)
+		req.Header.Set(NonceHeader, nonce)
+		req.Header.Set(TimestampHeader, ts)
+		req.Header.Set(SignatureHeader, signCall(c.key, name, nonce, ts, encoded))
+		if c.keyID != "" {
+			req.Header.Set(KeyIDHeader, c.keyID)
+		}
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("rpk: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("rpk: got bad response status code: %d", res.StatusCode)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("rpk: error reading response: %v", err)
+	}
+	if err := c.responseError(data); err != nil {
+		return err
+	}
+	if result == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("rpk: error decoding response: %v", err)
+	}
+	return nil
+}
+
+// responseError returns the error in a response, or nil if it is not an error.
+func (c *Client) responseError(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return nil
+	}
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(data, &obj) != nil {
+		return nil
+	}
+	for k := range obj {
+		if k != "error" && k != "conflict" && k != "version" && k != "code" && k != "data" {
+			return nil
+		}
+	}
+	var msg string
+	if raw, ok := obj["error"]; !ok || json.Unmarshal(raw, &msg) != nil {
+		return nil
+	}
+	err := &RemoteError{Message: msg}
+	json.Unmarshal(obj["conflict"], &err.Conflict)
+	json.Unmarshal(obj["version"], &err.Version)
+	json.Unmarshal(obj["code"], &err.Code)
+	err.Data = obj["data"]
+	if decode := c.errors[err.Code]; decode != nil && err.Code != "" {
+		err.err = decode(err.Data)
+	} else {
+		err.err = codeErrors[err.Code]
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package rpk
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	handler, err := HandlerFunc(testType{})
+	if err != nil {
+		t.Fatal("Failed to create handler:", err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	c := NewClient(server.URL, nil)
+	ctx := context.Background()
+
+	if err := c.Call(ctx, "Foo", nil, nil); err != nil {
+		t.Fatal("Failed to call Foo:", err)
+	}
+
+	var s string
+	if err := c.Call(ctx, "Bar", 7, &s); err != nil {
+		t.Fatal("Failed to call Bar:", err)
+	}
+	if s != "Bar 7" {
+		t.Fatalf("Bad result: %q, expected %q.", s, "Bar 7")
+	}
+
+	if err := c.Call(ctx, "Fun", &thing{7, "aaa"}, &s); err != nil {
+		t.Fatal("Failed to call Fun:", err)
+	}
+	if s != "Fun 7 aaa" {
+		t.Fatalf("Bad result: %q, expected %q.", s, "Fun 7 aaa")
+	}
+
+	err = c.Call(ctx, "BarErr", 7, &s)
+	if rerr, ok := err.(*RemoteError); !ok || rerr.Message != "Bar error 7" {
+		t.Fatalf("Expected remote error %q, got: %v", "Bar error 7", err)
+	}
+
+	if err := c.Call(ctx, "Bar", nil, &s); err == nil {
+		t.Fatal("Expected error when calling Bar without a parameter.")
+	}
+}
@@ -0,0 +1,677 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/fluhus/rpk"
+)
+
+// gen runs the gen command.
+func gen(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("gen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	lang := flags.String("lang", "", "Language of the client: ts, go, py, dart, "+
+		"swift or kotlin. Required.")
+	pkg := flags.String("pkg", ".", "Directory of the API type's package.")
+	typ := flags.String("type", "", "Name of the API type. Default is the only type "+
+		"in the package with exported methods.")
+	naming := flags.String("naming", "", "Field naming of the handler, for fields "+
+		"without a json tag: camel or snake. Default is the Go names.")
+	output := flags.String("o", "", "Output file. Default is the standard output.")
+	if err := flags.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	if flags.NArg() != 0 {
+		return usageError("gen takes no arguments")
+	}
+	write := generators[*lang]
+	if write == nil {
+		return usageError(fmt.Sprintf("bad -lang: %q", *lang))
+	}
+	convert, ok := namings[*naming]
+	if !ok {
+		return usageError(fmt.Sprintf("bad -naming: %q", *naming))
+	}
+
+	api, err := loadAPI(*pkg, *typ, *output)
+	if err != nil {
+		return err
+	}
+	api.naming = convert
+	src, err := write(api)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*output, src, 0644)
+}
+
+// generators generate client code, by language.
+var generators = map[string]func(*genAPI) ([]byte, error){
+	"ts":     genTS,
+	"go":     genGo,
+	"py":     genPy,
+	"dart":   genDart,
+	"swift":  genSwift,
+	"kotlin": genKotlin,
+}
+
+// namings are the field naming flag values, by name.
+var namings = map[string]func(string) string{
+	"":      nil,
+	"camel": rpk.CamelCase,
+	"snake": rpk.SnakeCase,
+}
+
+// genAPI describes an API type and the types that its methods use.
+type genAPI struct {
+	pkg     string                   // Package name.
+	name    string                   // Type name.
+	methods []*genMethod             // Exported methods, sorted by name.
+	types   map[string]*ast.TypeSpec // Types declared in the package, by name.
+	naming  func(string) string      // Converts untagged field names, if not nil.
+	files   []*ast.File
+	fset    *token.FileSet
+}
+
+// genMethod describes a single exported method.
+type genMethod struct {
+	name   string
+	file   *ast.File // File of the method, for resolving imports.
+	hasCtx bool      // Whether the method takes a context first.
+	in     ast.Expr  // Input type, nil if none.
+	out    ast.Expr  // Value output type, nil if none.
+	hasErr bool      // Whether the method has an error output.
+}
+
+// loadAPI parses the Go files in dir and collects the methods of the named type. If
+// typeName is empty, finds the only type with exported methods. Skips test files and
+// the output file.
+func loadAPI(dir, typeName, output string) (*genAPI, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	api := &genAPI{types: map[string]*ast.TypeSpec{}, fset: token.NewFileSet()}
+	methods := map[string][]*genMethod{}
+	errs := map[string]error{} // Errors in methods, by type.
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") ||
+			output != "" && filepath.Clean(file) == filepath.Clean(output) {
+			continue
+		}
+		f, err := parser.ParseFile(api.fset, file, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		api.pkg = f.Name.Name
+		api.files = append(api.files, f)
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					if spec, ok := spec.(*ast.TypeSpec); ok {
+						api.types[spec.Name.Name] = spec
+					}
+				}
+			case *ast.FuncDecl:
+				recv := receiverName(decl)
+				if recv == "" || !decl.Name.IsExported() ||
+					decl.Name.Name == "RPKDispatch" {
+					continue
+				}
+				m, err := parseMethod(f, decl)
+				if err != nil && errs[recv] == nil {
+					errs[recv] = fmt.Errorf("method '%s': %v", decl.Name.Name, err)
+				}
+				methods[recv] = append(methods[recv], m)
+			}
+		}
+	}
+
+	if typeName == "" {
+		for name := range methods {
+			if typeName != "" {
+				return nil, fmt.Errorf("more than one type with exported methods in "+
+					"%s, use -type", dir)
+			}
+			typeName = name
+		}
+		if typeName == "" {
+			return nil, fmt.Errorf("no type with exported methods in %s", dir)
+		}
+	}
+	if api.types[typeName] == nil {
+		return nil, fmt.Errorf("type '%s' not found in %s", typeName, dir)
+	}
+	if errs[typeName] != nil {
+		return nil, errs[typeName]
+	}
+	api.name = typeName
+	api.methods = methods[typeName]
+	sort.Slice(api.methods, func(i, j int) bool {
+		return api.methods[i].name < api.methods[j].name
+	})
+	return api, nil
+}
+
+// receiverName returns the name of a method's receiver type. Returns an empty string
+// for functions.
+func receiverName(f *ast.FuncDecl) string {
+	if f.Recv == nil || len(f.Recv.List) != 1 {
+		return ""
+	}
+	typ := f.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	if id, ok := typ.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// parseMethod checks that a method matches the requirements of rpk and describes it.
+func parseMethod(f *ast.File, decl *ast.FuncDecl) (*genMethod, error) {
+	m := &genMethod{name: decl.Name.Name, file: f}
+	params := fieldTypes(decl.Type.Params)
+	if len(params) > 0 && isPkgType(f, params[0], "context", "Context") {
+		m.hasCtx = true
+		params = params[1:]
+	}
+	if len(params) > 1 {
+		return nil, fmt.Errorf("must have 0 or 1 inputs after an optional context, "+
+			"it has %d", len(params))
+	}
+	if len(params) == 1 {
+		m.in = params[0]
+	}
+	results := fieldTypes(decl.Type.Results)
+	if len(results) > 0 {
+		if id, ok := results[len(results)-1].(*ast.Ident); ok && id.Name == "error" {
+			m.hasErr = true
+			results = results[:len(results)-1]
+		}
+	}
+	if len(results) > 1 {
+		return nil, fmt.Errorf("must have 0 or 1 outputs before an optional error, "+
+			"it has %d", len(results))
+	}
+	if len(results) == 1 {
+		m.out = results[0]
+	}
+	return m, nil
+}
+
+// fieldTypes returns the type of each field in the list, one per name.
+func fieldTypes(fl *ast.FieldList) []ast.Expr {
+	if fl == nil {
+		return nil
+	}
+	var result []ast.Expr
+	for _, field := range fl.List {
+		for range max(len(field.Names), 1) {
+			result = append(result, field.Type)
+		}
+	}
+	return result
+}
+
+// isPkgType checks if the given type expression is pkg.name, according to the imports
+// of f.
+func isPkgType(f *ast.File, e ast.Expr, pkg, name string) bool {
+	sel, ok := e.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	id, ok := sel.X.(*ast.Ident)
+	return ok && importPath(f, id.Name) == pkg
+}
+
+// importPath returns the path of the package imported by f under name, or an empty
+// string if there is none.
+func importPath(f *ast.File, name string) string {
+	for _, imp := range f.Imports {
+		path := strings.Trim(imp.Path.Value, `"`)
+		if imp.Name != nil && imp.Name.Name == name ||
+			imp.Name == nil && path[strings.LastIndex(path, "/")+1:] == name {
+			return path
+		}
+	}
+	return ""
+}
+
+// genField is a field of a struct on the wire.
+type genField struct {
+	name     string
+	typ      ast.Expr
+	optional bool // Whether the field has omitempty or omitzero.
+}
+
+// fields returns the fields of a struct type that encoding/json encodes, promoting the
+// fields of embedded structs of the package, like the handler does.
+func (api *genAPI) fields(st *ast.StructType) []genField {
+	var result []genField
+	for _, field := range st.Fields.List {
+		tag := ""
+		if field.Tag != nil {
+			tag = reflect.StructTag(strings.Trim(field.Tag.Value, "`")).Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		optional := strings.Contains(","+opts+",", ",omitempty,") ||
+			strings.Contains(","+opts+",", ",omitzero,")
+		if len(field.Names) == 0 {
+			if id, ok := field.Type.(*ast.Ident); ok && name == "" {
+				if spec := api.types[id.Name]; spec != nil {
+					if inner, ok := spec.Type.(*ast.StructType); ok {
+						result = append(result, api.fields(inner)...)
+						continue
+					}
+				}
+			}
+			if name == "" {
+				name = embeddedName(field.Type)
+			}
+			if ast.IsExported(embeddedName(field.Type)) {
+				result = append(result, genField{name, field.Type, optional})
+			}
+			continue
+		}
+		for _, id := range field.Names {
+			if !id.IsExported() {
+				continue
+			}
+			fname := name
+			if fname == "" {
+				fname = id.Name
+				if api.naming != nil {
+					fname = api.naming(fname)
+				}
+			}
+			result = append(result, genField{fname, field.Type, optional})
+		}
+	}
+	return result
+}
+
+// embeddedName returns the field name of an embedded type.
+func embeddedName(e ast.Expr) string {
+	if star, ok := e.(*ast.StarExpr); ok {
+		e = star.X
+	}
+	if sel, ok := e.(*ast.SelectorExpr); ok {
+		return sel.Sel.Name
+	}
+	if id, ok := e.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// typeMapper converts Go types to the types of another language.
+type typeMapper struct {
+	api   *genAPI
+	lang  *genLang
+	used  map[string]bool // Package types that were referenced.
+	queue []string        // Referenced package types that were not written yet.
+}
+
+// genLang holds the type names of a target language, and the literals of zero values
+// for languages that need them.
+type genLang struct {
+	boolean, str, integer, float, unknown, time string
+	array, record, nullable                     func(string) string
+
+	zeroBool, zeroStr, zeroInt, zeroFloat, zeroTime, null string
+	zeroStruct                                            func(name string) string
+}
+
+// mapType returns the type in the target language that a Go type is encoded as. f is
+// the file in which the type appears.
+func (m *typeMapper) mapType(f *ast.File, e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.Ident:
+		switch e.Name {
+		case "bool":
+			return m.lang.boolean
+		case "string":
+			return m.lang.str
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16",
+			"uint32", "uint64", "uintptr", "byte", "rune":
+			return m.lang.integer
+		case "float32", "float64":
+			return m.lang.float
+		case "any":
+			return m.lang.unknown
+		}
+		if m.api.types[e.Name] == nil {
+			return m.lang.unknown
+		}
+		if !m.used[e.Name] {
+			m.used[e.Name] = true
+			m.queue = append(m.queue, e.Name)
+		}
+		return e.Name
+	case *ast.StarExpr:
+		return m.lang.nullable(m.mapType(f, e.X))
+	case *ast.ArrayType:
+		if id, ok := e.Elt.(*ast.Ident); ok && id.Name == "byte" && e.Len == nil {
+			return m.lang.str // Base64.
+		}
+		return m.lang.nullable(m.lang.array(m.mapType(f, e.Elt)))
+	case *ast.MapType:
+		return m.lang.nullable(m.lang.record(m.mapType(f, e.Value)))
+	case *ast.SelectorExpr:
+		switch {
+		case isPkgType(f, e, "time", "Time"):
+			return m.lang.time
+		case isPkgType(f, e, "time", "Duration"):
+			return m.lang.integer
+		}
+	}
+	return m.lang.unknown
+}
+
+// zero returns the zero value of a Go type in the target language. f is the file in
+// which the type appears.
+func (m *typeMapper) zero(f *ast.File, e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.Ident:
+		switch e.Name {
+		case "bool":
+			return m.lang.zeroBool
+		case "string":
+			return m.lang.zeroStr
+		case "float32", "float64":
+			return m.lang.zeroFloat
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16",
+			"uint32", "uint64", "uintptr", "byte", "rune":
+			return m.lang.zeroInt
+		}
+		if spec := m.api.types[e.Name]; spec != nil {
+			if _, ok := spec.Type.(*ast.StructType); ok {
+				return m.lang.zeroStruct(e.Name)
+			}
+			return m.zero(m.api.fileOf(spec), spec.Type)
+		}
+	case *ast.ArrayType:
+		if id, ok := e.Elt.(*ast.Ident); ok && id.Name == "byte" && e.Len == nil {
+			return m.lang.zeroStr
+		}
+	case *ast.SelectorExpr:
+		switch {
+		case isPkgType(f, e, "time", "Time"):
+			return m.lang.zeroTime
+		case isPkgType(f, e, "time", "Duration"):
+			return m.lang.zeroInt
+		}
+	}
+	return m.lang.null
+}
+
+// next returns the next referenced package type to write, or nil if there are no
+// more.
+func (m *typeMapper) next() *ast.TypeSpec {
+	if len(m.queue) == 0 {
+		return nil
+	}
+	name := m.queue[0]
+	m.queue = m.queue[1:]
+	return m.api.types[name]
+}
+
+// fileOf returns the file in which a node appears.
+func (api *genAPI) fileOf(n ast.Node) *ast.File {
+	for _, f := range api.files {
+		if f.FileStart <= n.Pos() && n.Pos() < f.FileEnd {
+			return f
+		}
+	}
+	return nil
+}
+
+// genTS returns the source of a TypeScript client for the API.
+func genTS(api *genAPI) ([]byte, error) {
+	m := &typeMapper{api: api, used: map[string]bool{}, lang: &genLang{
+		boolean: "boolean", str: "string", integer: "number", float: "number",
+		unknown: "unknown", time: "string",
+		array: func(t string) string {
+			if strings.Contains(t, " ") {
+				t = "(" + t + ")"
+			}
+			return t + "[]"
+		},
+		record:   func(t string) string { return "Record<string, " + t + ">" },
+		nullable: func(t string) string { return t + " | null" },
+	}}
+	var methods bytes.Buffer
+	for _, meth := range api.methods {
+		param, out := "", "void"
+		if meth.in != nil {
+			param = "param: " + m.mapType(meth.file, meth.in)
+		}
+		if meth.out != nil {
+			out = m.mapType(meth.file, meth.out)
+		}
+		arg := ""
+		if meth.in != nil {
+			arg = ", [param]"
+		}
+		fmt.Fprintf(&methods, "\n  /** Calls the remote %s function. */\n", meth.name)
+		fmt.Fprintf(&methods, "  %s(%s): Promise<%s> {\n", meth.name, param, out)
+		fmt.Fprintf(&methods, "    return this.call(%q%s);\n", meth.name, arg)
+		fmt.Fprintf(&methods, "  }\n")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by rpk gen. DO NOT EDIT.\n")
+	for spec := m.next(); spec != nil; spec = m.next() {
+		f := api.fileOf(spec)
+		fmt.Fprintln(&buf)
+		if st, ok := spec.Type.(*ast.StructType); ok {
+			fmt.Fprintf(&buf, "export interface %s {\n", spec.Name.Name)
+			for _, field := range api.fields(st) {
+				opt := ""
+				if field.optional {
+					opt = "?"
+				}
+				fmt.Fprintf(&buf, "  %q%s: %s;\n", field.name, opt,
+					m.mapType(f, field.typ))
+			}
+			fmt.Fprintf(&buf, "}\n")
+		} else {
+			fmt.Fprintf(&buf, "export type %s = %s;\n", spec.Name.Name,
+				m.mapType(f, spec.Type))
+		}
+	}
+	buf.WriteString(tsClient)
+	fmt.Fprintf(&buf, "\n/** Calls the functions of %s on a remote rpk handler. */\n",
+		api.name)
+	fmt.Fprintf(&buf, "export class %sClient extends Client {", exportedName(api.name))
+	buf.Write(methods.Bytes())
+	fmt.Fprintf(&buf, "}\n")
+	return buf.Bytes(), nil
+}
+
+// tsClient is the common part of TypeScript clients.
+const tsClient = `
+/** An error returned by a remote function. */
+export class RemoteError extends Error {
+  constructor(message: string, readonly code?: string, readonly data?: unknown,
+      readonly conflict?: boolean, readonly version?: string) {
+    super(message);
+  }
+}
+
+const errorKeys = ["error", "conflict", "version", "code", "data"];
+
+/** Calls functions of a remote rpk handler. */
+export class Client {
+  /** url is the handler's URL. */
+  constructor(readonly url: string, readonly fetch = globalThis.fetch.bind(globalThis)) {}
+
+  /** Calls the named function, with the parameter if one is given. */
+  protected async call(name: string, param?: [unknown]): Promise<any> {
+    const url = new URL(this.url, globalThis.location?.href);
+    url.searchParams.set("func", name);
+    const res = await this.fetch(url, {
+      method: "POST",
+      headers: {"Content-Type": "application/json"},
+      body: param ? JSON.stringify(param[0]) : undefined,
+    });
+    if (res.status != 200) {
+      throw new Error("rpk: got bad response status code: " + res.status);
+    }
+    const text = await res.text();
+    if (text.trim() == "") {
+      return undefined;
+    }
+    const data = JSON.parse(text);
+    if (data && typeof data == "object" && !Array.isArray(data) &&
+        typeof data.error == "string" &&
+        Object.keys(data).every((k) => errorKeys.includes(k))) {
+      throw new RemoteError(data.error, data.code, data.data, data.conflict,
+          data.version);
+    }
+    return data;
+  }
+}
+`
+
+// genGo returns the source of a Go client for the API, in the API's package.
+func genGo(api *genAPI) ([]byte, error) {
+	imports := map[string]string{"context": "context"}
+	for _, m := range api.methods {
+		for _, e := range []ast.Expr{m.in, m.out} {
+			addImports(m.file, e, imports)
+		}
+	}
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by rpk gen. DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "package %s\n\n", api.pkg)
+	fmt.Fprintln(&buf, "import (")
+	paths := make([]string, 0, len(imports))
+	for path := range imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Fprintf(&buf, "%s %q\n", imports[path], path)
+	}
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "\"github.com/fluhus/rpk\"")
+	fmt.Fprintln(&buf, ")")
+	fmt.Fprintln(&buf)
+
+	name := api.name + "Client"
+	ctor := "New" + exportedName(api.name) + "Client"
+	if !ast.IsExported(api.name) {
+		ctor = "new" + exportedName(api.name) + "Client"
+	}
+	fmt.Fprintf(&buf, "// %s calls the functions of %s on a remote rpk handler.\n",
+		name, api.name)
+	fmt.Fprintf(&buf, "type %s struct {\nc *rpk.Client\n}\n\n", name)
+	fmt.Fprintf(&buf, "// %s returns a %s that calls functions through c.\n", ctor, name)
+	fmt.Fprintf(&buf, "func %s(c *rpk.Client) *%s {\nreturn &%s{c}\n}\n", ctor, name,
+		name)
+
+	for _, m := range api.methods {
+		params, in := "ctx context.Context", "nil"
+		if m.in != nil {
+			typ, err := formatType(api.fset, m.in)
+			if err != nil {
+				return nil, err
+			}
+			params += ", in " + typ
+			in = "in"
+		}
+		fmt.Fprintf(&buf, "\n// %s calls the remote %s function.\n", m.name, m.name)
+		if m.out == nil {
+			fmt.Fprintf(&buf, "func (c *%s) %s(%s) error {\n", name, m.name, params)
+			fmt.Fprintf(&buf, "return c.c.Call(ctx, %q, %s, nil)\n}\n", m.name, in)
+			continue
+		}
+		typ, err := formatType(api.fset, m.out)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&buf, "func (c *%s) %s(%s) (%s, error) {\n", name, m.name, params,
+			typ)
+		fmt.Fprintf(&buf, "var out %s\n", typ)
+		fmt.Fprintf(&buf, "err := c.c.Call(ctx, %q, %s, &out)\n", m.name, in)
+		fmt.Fprintln(&buf, "return out, err\n}")
+	}
+	return format.Source(buf.Bytes())
+}
+
+// formatType returns the source of a type expression.
+func formatType(fset *token.FileSet, e ast.Expr) (string, error) {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, e); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// addImports adds the imports of f that are referenced in e to imports.
+func addImports(f *ast.File, e ast.Expr, imports map[string]string) {
+	if e == nil {
+		return
+	}
+	ast.Inspect(e, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				if path := importPath(f, id.Name); path != "" {
+					imports[path] = id.Name
+				}
+			}
+		}
+		return true
+	})
+}
+
+// camelName returns a camel case name for a field or method with the given name on the
+// wire, that is not one of the keywords.
+func camelName(name string, keywords map[string]bool) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	if len(parts) == 0 {
+		return "field"
+	}
+	name = rpk.CamelCase(parts[0])
+	for _, part := range parts[1:] {
+		name += exportedName(part)
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "v" + name
+	}
+	if keywords[name] {
+		name += "_"
+	}
+	return name
+}
+
+// exportedName returns name with its first letter in upper case.
+func exportedName(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSrc = `package api
+
+import (
+	"context"
+	"time"
+)
+
+type API struct{}
+
+func (API) Ping() {}
+
+func (API) Half(i int) int {
+	return i / 2
+}
+
+func (*API) Wait(ctx context.Context, d time.Duration) error {
+	return nil
+}
+
+func (API) Find(q *Query) ([]*User, error) {
+	return nil, nil
+}
+
+func (API) private(i int) {}
+
+type Query struct {
+	Name  string ` + "`json:\"name\"`" + `
+	Since time.Time
+	Tags  []string ` + "`json:\",omitempty\"`" + `
+	Paging
+	secret string
+}
+
+type Paging struct {
+	PageSize int
+}
+
+type User struct {
+	UserID int
+	Role   Role
+	Ignore bool ` + "`json:\"-\"`" + `
+}
+
+type Role string
+`
+
+func TestGen(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "api.go"), []byte(testSrc), 0644); err != nil {
+		t.Fatal("Failed to write source:", err)
+	}
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"-lang=ts"}, []string{
+			"export interface Query {\n  \"name\": string;\n  \"Since\": string;\n" +
+				"  \"Tags\"?: string[] | null;\n  \"PageSize\": number;\n}",
+			"export interface User {\n  \"UserID\": number;\n  \"Role\": Role;\n}",
+			"export type Role = string;",
+			"export class APIClient extends Client {",
+			"Find(param: Query | null): Promise<(User | null)[] | null> {",
+			"return this.call(\"Half\", [param]);",
+			"Ping(): Promise<void> {\n    return this.call(\"Ping\");",
+			"Wait(param: number): Promise<void> {",
+		}},
+		{[]string{"-lang=py", "-naming=snake"}, []string{
+			"@dataclass\nclass Query:\n" +
+				"    name: str = field(default=\"\", metadata={\"json\": \"name\"})\n" +
+				"    since: str = field(default=\"0001-01-01T00:00:00Z\", " +
+				"metadata={\"json\": \"since\"})\n" +
+				"    tags: list[str] | None = field(default=None, " +
+				"metadata={\"json\": \"tags\"})\n" +
+				"    page_size: int = field(default=0, metadata={\"json\": \"page_size\"})\n",
+			"    role: Role = field(default=\"\", metadata={\"json\": \"role\"})\n",
+			"\nRole = str\n",
+			"class APIClient(Client):",
+			"def Find(self, param: Query | None) -> list[User | None] | None:",
+			"def Ping(self) -> None:",
+			"return self._call(\"Half\", int, param)",
+			"return self._call(\"Ping\", None)",
+		}},
+		{[]string{"-lang=dart", "-naming=camel"}, []string{
+			"class Query {\n  final String name;\n  final String since;\n" +
+				"  final List<String>? tags;\n  final int pageSize;\n",
+			"Query({this.name = '', this.since = '0001-01-01T00:00:00Z', this.tags, " +
+				"this.pageSize = 0});",
+			"tags: (m['tags'] as List?)?.map((e0) => (e0 as String?) ?? '').toList(),",
+			"'pageSize': pageSize,",
+			"typedef Role = String;",
+			"class APIClient extends Client {",
+			"Future<List<User?>?> find(Query? param) async {\n" +
+				"    final data = await _call('Find', param?.toJson());\n" +
+				"    return (data as List?)?.map((e0) => e0 == null ? null : " +
+				"User.fromJson((e0 as Map<String, dynamic>?) ?? const {})).toList();",
+			"Future<void> ping() async {\n    await _call('Ping');",
+			"Future<void> wait(int param) async {",
+		}},
+		{[]string{"-lang=swift"}, []string{
+			"struct Query: Codable {\n    var name: String = \"\"\n" +
+				"    var since: String = \"0001-01-01T00:00:00Z\"\n" +
+				"    var tags: [String]? = nil\n    var pageSize: Int = 0\n",
+			"        case tags = \"Tags\"\n        case pageSize = \"PageSize\"\n",
+			"typealias Role = String",
+			"final class APIClient: Client {",
+			"func find(_ param: Query?) async throws -> [User?]? {\n" +
+				"        return try decode(await call(\"Find\", encode(param)))",
+			"func ping() async throws {\n        _ = try await call(\"Ping\", nil)",
+		}},
+		{[]string{"-lang=kotlin"}, []string{
+			"package api",
+			"@Serializable\ndata class Query(\n" +
+				"    @SerialName(\"name\") val name: String = \"\",\n" +
+				"    @SerialName(\"Since\") val since: String = \"0001-01-01T00:00:00Z\",\n" +
+				"    @SerialName(\"Tags\") val tags: List<String>? = null,\n" +
+				"    @SerialName(\"PageSize\") val pageSize: Long = 0,\n)",
+			"typealias Role = String",
+			"@SerialName(\"Role\") val role: Role = \"\",",
+			"class APIClient(url: String, http: OkHttpClient = OkHttpClient()) :",
+			"fun find(param: Query?): List<User?>? =\n        json.decodeFromJsonElement(" +
+				"call(\"Find\", json.encodeToString(param)) ?: JsonNull)",
+			"fun ping() {\n        call(\"Ping\", null)\n    }",
+			"fun wait_(param: Long) {",
+		}},
+		{[]string{"-lang=go", "-type=API"}, []string{
+			"package api",
+			"func NewAPIClient(c *rpk.Client) *APIClient {",
+			"func (c *APIClient) Find(ctx context.Context, in *Query) ([]*User, error) {",
+			"func (c *APIClient) Ping(ctx context.Context) error {",
+			"func (c *APIClient) Wait(ctx context.Context, in time.Duration) error {",
+			"return c.c.Call(ctx, \"Wait\", in, nil)",
+		}},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		args := append([]string{"gen", "-pkg=" + dir}, test.args...)
+		if status := run(args, nil, &out, &out); status != 0 {
+			t.Fatalf("Bad status for %v: %d. Output: %s", test.args, status, out.String())
+		}
+		src := out.String()
+		for _, want := range test.want {
+			if !strings.Contains(src, want) {
+				t.Fatalf("Generated code for %v does not contain %q:\n%s", test.args,
+					want, src)
+			}
+		}
+		if strings.Contains(src, "private(") || strings.Contains(src, "secret") ||
+			strings.Contains(src, "Ignore") {
+			t.Fatalf("Generated code for %v contains unexported names:\n%s",
+				test.args, src)
+		}
+		if test.args[0] == "-lang=go" {
+			if _, err := parser.ParseFile(token.NewFileSet(), "", src, 0); err != nil {
+				t.Fatalf("Failed to parse generated code: %v\n%s", err, src)
+			}
+		}
+	}
+}
+
+func TestGen_bad(t *testing.T) {
+	dir := t.TempDir()
+	src := "package api\ntype A struct{}\nfunc (A) Foo() {}\ntype B struct{}\n" +
+		"func (B) Bar(a, b int) {}\n"
+	if err := os.WriteFile(filepath.Join(dir, "api.go"), []byte(src), 0644); err != nil {
+		t.Fatal("Failed to write source:", err)
+	}
+	for _, args := range [][]string{
+		{"gen", "-pkg=" + dir, "-lang=ts"},
+		{"gen", "-pkg=" + dir, "-lang=ts", "-type=B"},
+		{"gen", "-pkg=" + dir, "-lang=ts", "-type=C"},
+		{"gen", "-pkg=" + dir, "-lang=rust", "-type=A"},
+	} {
+		var out bytes.Buffer
+		if status := run(args, nil, &out, &out); status == 0 {
+			t.Fatalf("Expected failure for %v, got output:\n%s", args, out.String())
+		}
+	}
+	var out bytes.Buffer
+	if status := run([]string{"gen", "-pkg=" + dir, "-lang=ts", "-type=A"}, nil, &out,
+		&out); status != 0 {
+		t.Fatalf("Bad status for type A: %d. Output: %s", status, out.String())
+	}
+}
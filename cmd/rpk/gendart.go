@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// genDart returns the source of a Dart client for the API. Structs are generated as
+// model classes with fromJson and toJson, and methods and fields have camel case names.
+func genDart(api *genAPI) ([]byte, error) {
+	d := &dartGen{api: api, m: &typeMapper{api: api, used: map[string]bool{},
+		lang: &genLang{
+			boolean: "bool", str: "String", integer: "int", float: "double",
+			unknown: "dynamic", time: "String",
+			array:  func(t string) string { return "List<" + t + ">" },
+			record: func(t string) string { return "Map<String, " + t + ">" },
+			nullable: func(t string) string {
+				if t == "dynamic" || strings.HasSuffix(t, "?") {
+					return t
+				}
+				return t + "?"
+			},
+			zeroBool: "false", zeroStr: "''", zeroInt: "0", zeroFloat: "0.0",
+			zeroTime: "'0001-01-01T00:00:00Z'", null: "null",
+			zeroStruct: func(string) string { return "" },
+		}}}
+	var methods bytes.Buffer
+	for _, meth := range api.methods {
+		param, arg, out := "", "", "void"
+		if meth.in != nil {
+			param = d.m.mapType(meth.file, meth.in) + " param"
+			arg = ", " + d.encode(meth.file, meth.in, "param", 0)
+		}
+		if meth.out != nil {
+			out = d.m.mapType(meth.file, meth.out)
+		}
+		fmt.Fprintf(&methods, "\n  /// Calls the remote %s function.\n", meth.name)
+		fmt.Fprintf(&methods, "  Future<%s> %s(%s) async {\n", out, dartName(meth.name),
+			param)
+		if meth.out == nil {
+			fmt.Fprintf(&methods, "    await _call(%s%s);\n", dartString(meth.name), arg)
+		} else {
+			fmt.Fprintf(&methods, "    final data = await _call(%s%s);\n",
+				dartString(meth.name), arg)
+			fmt.Fprintf(&methods, "    return %s;\n", d.decode(meth.file, meth.out,
+				"data", 0))
+		}
+		fmt.Fprintf(&methods, "  }\n")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(dartHeader)
+	for spec := d.m.next(); spec != nil; spec = d.m.next() {
+		f := api.fileOf(spec)
+		st, ok := spec.Type.(*ast.StructType)
+		if !ok {
+			fmt.Fprintf(&buf, "\ntypedef %s = %s;\n", spec.Name.Name,
+				d.m.mapType(f, spec.Type))
+			continue
+		}
+		d.writeModel(&buf, f, spec.Name.Name, api.fields(st))
+	}
+	buf.WriteString(dartClient)
+	fmt.Fprintf(&buf, "\n/// Calls the functions of %s on a remote rpk handler.\n", api.name)
+	fmt.Fprintf(&buf, "class %sClient extends Client {\n", exportedName(api.name))
+	fmt.Fprintf(&buf, "  %sClient(super.url, {super.client});\n",
+		exportedName(api.name))
+	buf.Write(methods.Bytes())
+	fmt.Fprintf(&buf, "}\n")
+	return buf.Bytes(), nil
+}
+
+// dartGen generates the type conversions of a Dart client.
+type dartGen struct {
+	api *genAPI
+	m   *typeMapper
+}
+
+// writeModel writes the model class of a struct.
+func (d *dartGen) writeModel(buf *bytes.Buffer, f *ast.File, name string,
+	fields []genField) {
+	fmt.Fprintf(buf, "\nclass %s {\n", name)
+	var params []string
+	for _, field := range fields {
+		fname := dartName(field.name)
+		fmt.Fprintf(buf, "  final %s %s;\n", d.m.mapType(f, field.typ), fname)
+		switch zero := d.m.zero(f, field.typ); zero {
+		case "":
+			params = append(params, "required this."+fname)
+		case "null":
+			params = append(params, "this."+fname)
+		default:
+			params = append(params, "this."+fname+" = "+zero)
+		}
+	}
+	if len(fields) > 0 {
+		fmt.Fprintf(buf, "\n")
+	}
+	if len(params) == 0 {
+		fmt.Fprintf(buf, "  const %s();\n", name)
+	} else {
+		fmt.Fprintf(buf, "  %s({%s});\n", name, strings.Join(params, ", "))
+	}
+
+	fmt.Fprintf(buf, "\n  factory %s.fromJson(Map<String, dynamic> m) => %s(", name,
+		name)
+	for _, field := range fields {
+		fmt.Fprintf(buf, "\n        %s: %s,", dartName(field.name),
+			d.decode(f, field.typ, "m["+dartString(field.name)+"]", 0))
+	}
+	fmt.Fprintf(buf, "\n      );\n")
+
+	fmt.Fprintf(buf, "\n  Map<String, dynamic> toJson() => {")
+	for _, field := range fields {
+		fmt.Fprintf(buf, "\n        %s: %s,", dartString(field.name),
+			d.encode(f, field.typ, dartName(field.name), 0))
+	}
+	fmt.Fprintf(buf, "\n      };\n}\n")
+}
+
+// decode returns a Dart expression that converts x, a decoded JSON value that may be
+// null, to the Dart type of e. Null values of non-nullable types become zero values.
+// depth is used for naming the parameters of nested closures.
+func (d *dartGen) decode(f *ast.File, e ast.Expr, x string, depth int) string {
+	v := fmt.Sprint("e", depth)
+	switch e := e.(type) {
+	case *ast.Ident:
+		switch e.Name {
+		case "bool":
+			return "(" + x + " as bool?) ?? false"
+		case "string":
+			return "(" + x + " as String?) ?? ''"
+		case "float32", "float64":
+			return "(" + x + " as num?)?.toDouble() ?? 0.0"
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16",
+			"uint32", "uint64", "uintptr", "byte", "rune":
+			return "(" + x + " as num?)?.toInt() ?? 0"
+		}
+		if spec := d.api.types[e.Name]; spec != nil {
+			if _, ok := spec.Type.(*ast.StructType); ok {
+				return e.Name + ".fromJson((" + x + " as Map<String, dynamic>?) ?? " +
+					"const {})"
+			}
+			return d.decode(d.api.fileOf(spec), spec.Type, x, depth)
+		}
+	case *ast.StarExpr:
+		return x + " == null ? null : " + d.decode(f, e.X, x, depth)
+	case *ast.ArrayType:
+		if id, ok := e.Elt.(*ast.Ident); ok && id.Name == "byte" && e.Len == nil {
+			return "(" + x + " as String?) ?? ''"
+		}
+		return "(" + x + " as List?)?.map((" + v + ") => " +
+			d.decode(f, e.Elt, v, depth+1) + ").toList()"
+	case *ast.MapType:
+		return "(" + x + " as Map<String, dynamic>?)?.map((k" + v[1:] + ", " + v +
+			") => MapEntry(k" + v[1:] + ", " + d.decode(f, e.Value, v, depth+1) + "))"
+	case *ast.SelectorExpr:
+		switch {
+		case isPkgType(f, e, "time", "Time"):
+			return "(" + x + " as String?) ?? '0001-01-01T00:00:00Z'"
+		case isPkgType(f, e, "time", "Duration"):
+			return "(" + x + " as num?)?.toInt() ?? 0"
+		}
+	}
+	return x
+}
+
+// encode returns a Dart expression that converts x, of the Dart type of e, to a value
+// that jsonEncode accepts.
+func (d *dartGen) encode(f *ast.File, e ast.Expr, x string, depth int) string {
+	v := fmt.Sprint("e", depth)
+	switch e := e.(type) {
+	case *ast.Ident:
+		if spec := d.api.types[e.Name]; spec != nil {
+			if _, ok := spec.Type.(*ast.StructType); ok {
+				return x + ".toJson()"
+			}
+			return d.encode(d.api.fileOf(spec), spec.Type, x, depth)
+		}
+	case *ast.StarExpr:
+		switch inner := d.encode(f, e.X, x, depth); {
+		case inner == x || strings.HasPrefix(inner, x+"?."):
+			return inner
+		case strings.HasPrefix(inner, x+"."):
+			return x + "?" + inner[len(x):]
+		default:
+			return x + " == null ? null : " + inner
+		}
+	case *ast.ArrayType:
+		if inner := d.encode(f, e.Elt, v, depth+1); inner != v {
+			return x + "?.map((" + v + ") => " + inner + ").toList()"
+		}
+	case *ast.MapType:
+		if inner := d.encode(f, e.Value, v, depth+1); inner != v {
+			return x + "?.map((k" + v[1:] + ", " + v + ") => MapEntry(k" + v[1:] + ", " +
+				inner + "))"
+		}
+	}
+	return x
+}
+
+// dartName returns the Dart name of a field or method with the given name on the wire.
+func dartName(name string) string {
+	return camelName(name, dartKeywords)
+}
+
+// dartString returns a single-quoted Dart string literal of s.
+func dartString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`, `$`, `\$`, "\n", `\n`, "\r", `\r`)
+	return "'" + r.Replace(s) + "'"
+}
+
+// dartKeywords are the Dart reserved words that field names may clash with.
+var dartKeywords = map[string]bool{
+	"assert": true, "break": true, "case": true, "catch": true, "class": true,
+	"const": true, "continue": true, "default": true, "do": true, "else": true,
+	"enum": true, "extends": true, "false": true, "final": true, "finally": true,
+	"for": true, "if": true, "in": true, "is": true, "new": true, "null": true,
+	"rethrow": true, "return": true, "super": true, "switch": true, "this": true,
+	"throw": true, "true": true, "try": true, "var": true, "void": true, "while": true,
+	"with": true,
+}
+
+// dartHeader is the beginning of Dart clients.
+const dartHeader = `// Code generated by rpk gen. DO NOT EDIT.
+
+import 'dart:convert';
+
+import 'package:http/http.dart' as http;
+`
+
+// dartClient is the common part of Dart clients.
+const dartClient = `
+/// An error returned by a remote function.
+class RemoteError implements Exception {
+  final String message;
+  final String? code;
+  final dynamic data;
+  final bool conflict;
+  final String? version;
+
+  RemoteError(this.message,
+      {this.code, this.data, this.conflict = false, this.version});
+
+  @override
+  String toString() => message;
+}
+
+const _errorKeys = {'error', 'conflict', 'version', 'code', 'data'};
+const _noParam = Object();
+
+/// Calls functions of a remote rpk handler.
+class Client {
+  final Uri url;
+  final http.Client httpClient;
+
+  /// Creates a client of the handler at url, that sends requests with client.
+  Client(String url, {http.Client? client})
+      : url = Uri.parse(url),
+        httpClient = client ?? http.Client();
+
+  /// Calls the named function, with the parameter if one is given.
+  Future<dynamic> _call(String name, [Object? param = _noParam]) async {
+    final url = this.url.replace(
+        queryParameters: {...this.url.queryParameters, 'func': name});
+    final response = await httpClient.post(url,
+        headers: {'Content-Type': 'application/json'},
+        body: identical(param, _noParam) ? '' : jsonEncode(param));
+    if (response.statusCode != 200) {
+      throw http.ClientException(
+          'rpk: got bad response status code: ${response.statusCode}', url);
+    }
+    final text = utf8.decode(response.bodyBytes);
+    if (text.trim().isEmpty) {
+      return null;
+    }
+    final data = jsonDecode(text);
+    if (data is Map<String, dynamic> &&
+        data['error'] is String &&
+        data.keys.every(_errorKeys.contains)) {
+      throw RemoteError(data['error'] as String,
+          code: data['code'] as String?,
+          data: data['data'],
+          conflict: data['conflict'] == true,
+          version: data['version'] as String?);
+    }
+    return data;
+  }
+}
+`
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+)
+
+// genKotlin returns the source of a Kotlin client for the API, with serializable data
+// classes and an OkHttp client, in a package named after the API's package. Methods and
+// fields have camel case names.
+func genKotlin(api *genAPI) ([]byte, error) {
+	m := &typeMapper{api: api, used: map[string]bool{}, lang: &genLang{
+		boolean: "Boolean", str: "String", integer: "Long", float: "Double",
+		unknown: "JsonElement?", time: "String",
+		array:    func(t string) string { return "List<" + t + ">" },
+		record:   func(t string) string { return "Map<String, " + t + ">" },
+		nullable: optionalType,
+		zeroBool: "false", zeroStr: `""`, zeroInt: "0", zeroFloat: "0.0",
+		zeroTime: `"0001-01-01T00:00:00Z"`, null: "null",
+		zeroStruct: func(name string) string { return name + "()" },
+	}}
+	var methods bytes.Buffer
+	for _, meth := range api.methods {
+		param, arg, out := "", "null", ""
+		if meth.in != nil {
+			param = "param: " + m.mapType(meth.file, meth.in)
+			arg = "json.encodeToString(param)"
+		}
+		name := camelName(meth.name, kotlinKeywords)
+		fmt.Fprintf(&methods, "\n    /** Calls the remote %s function. */\n", meth.name)
+		if meth.out == nil {
+			fmt.Fprintf(&methods, "    fun %s(%s) {\n", name, param)
+			fmt.Fprintf(&methods, "        call(%q, %s)\n    }\n", meth.name, arg)
+			continue
+		}
+		out = m.mapType(meth.file, meth.out)
+		fmt.Fprintf(&methods, "    fun %s(%s): %s =\n", name, param, out)
+		fmt.Fprintf(&methods, "        json.decodeFromJsonElement(call(%q, %s) ?: JsonNull)\n",
+			meth.name, arg)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by rpk gen. DO NOT EDIT.\n\npackage %s\n", api.pkg)
+	buf.WriteString(kotlinImports)
+	for spec := m.next(); spec != nil; spec = m.next() {
+		f := api.fileOf(spec)
+		st, ok := spec.Type.(*ast.StructType)
+		if !ok {
+			fmt.Fprintf(&buf, "\ntypealias %s = %s\n", spec.Name.Name,
+				m.mapType(f, spec.Type))
+			continue
+		}
+		fields := api.fields(st)
+		if len(fields) == 0 {
+			fmt.Fprintf(&buf, "\n@Serializable\nclass %s\n", spec.Name.Name)
+			continue
+		}
+		fmt.Fprintf(&buf, "\n@Serializable\ndata class %s(\n", spec.Name.Name)
+		for _, field := range fields {
+			fmt.Fprintf(&buf, "    @SerialName(%q) val %s: %s = %s,\n", field.name,
+				camelName(field.name, kotlinKeywords), m.mapType(f, field.typ),
+				m.zero(f, field.typ))
+		}
+		fmt.Fprintf(&buf, ")\n")
+	}
+	buf.WriteString(kotlinClient)
+	fmt.Fprintf(&buf, "\n/**\n * Calls the functions of %s on a remote rpk handler. Calls "+
+		"block, so they should\n * not run on the main thread.\n */\n", api.name)
+	fmt.Fprintf(&buf, "class %sClient(url: String, http: OkHttpClient = OkHttpClient()) :\n",
+		exportedName(api.name))
+	fmt.Fprintf(&buf, "    Client(url, http) {")
+	buf.Write(methods.Bytes())
+	fmt.Fprintf(&buf, "}\n")
+	return buf.Bytes(), nil
+}
+
+// kotlinKeywords are the Kotlin hard keywords that names may clash with, and the
+// methods of Java objects that client methods would clash with.
+var kotlinKeywords = map[string]bool{
+	"wait": true, "notify": true, "notifyAll": true, "getClass": true,
+	"hashCode": true, "toString": true, "equals": true,
+	"as": true, "break": true, "class": true, "continue": true, "do": true,
+	"else": true, "false": true, "for": true, "fun": true, "if": true, "in": true,
+	"interface": true, "is": true, "null": true, "object": true, "package": true,
+	"return": true, "super": true, "this": true, "throw": true, "true": true,
+	"try": true, "typealias": true, "typeof": true, "val": true, "var": true,
+	"when": true, "while": true,
+}
+
+// kotlinImports are the imports of Kotlin clients.
+const kotlinImports = `
+import java.io.IOException
+import kotlinx.serialization.SerialName
+import kotlinx.serialization.Serializable
+import kotlinx.serialization.encodeToString
+import kotlinx.serialization.json.Json
+import kotlinx.serialization.json.JsonElement
+import kotlinx.serialization.json.JsonNull
+import kotlinx.serialization.json.JsonObject
+import kotlinx.serialization.json.JsonPrimitive
+import kotlinx.serialization.json.booleanOrNull
+import kotlinx.serialization.json.contentOrNull
+import kotlinx.serialization.json.decodeFromJsonElement
+import okhttp3.HttpUrl.Companion.toHttpUrl
+import okhttp3.MediaType.Companion.toMediaType
+import okhttp3.OkHttpClient
+import okhttp3.Request
+import okhttp3.RequestBody.Companion.toRequestBody
+`
+
+// kotlinClient is the common part of Kotlin clients.
+const kotlinClient = `
+/** An error returned by a remote function. */
+class RemoteException(
+    message: String,
+    val code: String? = null,
+    val data: JsonElement? = null,
+    val conflict: Boolean = false,
+    val version: String? = null,
+) : Exception(message)
+
+/** Calls functions of a remote rpk handler. */
+open class Client(url: String, private val http: OkHttpClient = OkHttpClient()) {
+    private val url = url.toHttpUrl()
+    protected val json = Json { ignoreUnknownKeys = true; encodeDefaults = true }
+
+    /**
+     * Calls the named function with a JSON parameter, if one is given, and returns its
+     * output, or null if it has none.
+     */
+    protected fun call(name: String, param: String?): JsonElement? {
+        val request = Request.Builder()
+            .url(url.newBuilder().setQueryParameter("func", name).build())
+            .post((param ?: "").toRequestBody("application/json".toMediaType()))
+            .build()
+        http.newCall(request).execute().use { response ->
+            if (response.code != 200) {
+                throw IOException("rpk: got bad response status code: ${response.code}")
+            }
+            val text = response.body?.string().orEmpty()
+            if (text.isBlank()) {
+                return null
+            }
+            val data = json.parseToJsonElement(text)
+            val message = ((data as? JsonObject)?.get("error") as? JsonPrimitive)
+            if (data is JsonObject && message != null && message.isString &&
+                data.keys.all { it in errorKeys }) {
+                throw RemoteException(
+                    message.content,
+                    (data["code"] as? JsonPrimitive)?.contentOrNull,
+                    data["data"],
+                    (data["conflict"] as? JsonPrimitive)?.booleanOrNull ?: false,
+                    (data["version"] as? JsonPrimitive)?.contentOrNull,
+                )
+            }
+            return data
+        }
+    }
+
+    private companion object {
+        val errorKeys = setOf("error", "conflict", "version", "code", "data")
+    }
+}
+`
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"slices"
+	"strings"
+
+	"github.com/fluhus/rpk"
+)
+
+// genPy returns the source of a Python client for the API. Structs are generated as
+// dataclasses, with Python names for their fields.
+func genPy(api *genAPI) ([]byte, error) {
+	m := &typeMapper{api: api, used: map[string]bool{}, lang: &genLang{
+		boolean: "bool", str: "str", integer: "int", float: "float",
+		unknown: "Any", time: "str",
+		array:    func(t string) string { return "list[" + t + "]" },
+		record:   func(t string) string { return "dict[str, " + t + "]" },
+		nullable: func(t string) string { return t + " | None" },
+		zeroBool: "False", zeroStr: `""`, zeroInt: "0", zeroFloat: "0.0",
+		zeroTime: `"0001-01-01T00:00:00Z"`, null: "None",
+		zeroStruct: func(name string) string { return "lambda: " + name + "()" },
+	}}
+	var methods bytes.Buffer
+	for _, meth := range api.methods {
+		param, arg, out := "", "", "None"
+		if meth.in != nil {
+			param = ", param: " + m.mapType(meth.file, meth.in)
+			arg = ", param"
+		}
+		if meth.out != nil {
+			out = m.mapType(meth.file, meth.out)
+		}
+		fmt.Fprintf(&methods, "\n    def %s(self%s) -> %s:\n", meth.name, param, out)
+		fmt.Fprintf(&methods, "        \"\"\"Calls the remote %s function.\"\"\"\n",
+			meth.name)
+		fmt.Fprintf(&methods, "        return self._call(%q, %s%s)\n", meth.name, out,
+			arg)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(pyHeader)
+	// Aliases are assignments, so they come after the classes that they may refer
+	// to, and before the aliases that refer to them.
+	var aliases []string
+	for spec := m.next(); spec != nil; spec = m.next() {
+		f := api.fileOf(spec)
+		st, ok := spec.Type.(*ast.StructType)
+		if !ok {
+			aliases = append(aliases, fmt.Sprintf("%s = %s\n", spec.Name.Name,
+				m.mapType(f, spec.Type)))
+			continue
+		}
+		fmt.Fprintf(&buf, "\n\n@dataclass\nclass %s:\n", spec.Name.Name)
+		fields := api.fields(st)
+		if len(fields) == 0 {
+			fmt.Fprintf(&buf, "    pass\n")
+		}
+		for _, field := range fields {
+			zero := m.zero(f, field.typ)
+			if strings.HasPrefix(zero, "lambda") {
+				zero = "default_factory=" + zero
+			} else {
+				zero = "default=" + zero
+			}
+			fmt.Fprintf(&buf, "    %s: %s = field(%s, metadata={\"json\": %q})\n",
+				pyName(field.name), m.mapType(f, field.typ), zero, field.name)
+		}
+	}
+	if len(aliases) > 0 {
+		buf.WriteString("\n\n")
+	}
+	for _, alias := range slices.Backward(aliases) {
+		buf.WriteString(alias)
+	}
+	buf.WriteString(pyClient)
+	fmt.Fprintf(&buf, "\n\nclass %sClient(Client):\n", exportedName(api.name))
+	fmt.Fprintf(&buf, "    \"\"\"Calls the functions of %s on a remote rpk handler.\"\"\"\n",
+		api.name)
+	buf.Write(methods.Bytes())
+	return buf.Bytes(), nil
+}
+
+// pyName returns the Python name of a field with the given name on the wire.
+func pyName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' ||
+			r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, rpk.SnakeCase(name))
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	if pyKeywords[name] {
+		name += "_"
+	}
+	return name
+}
+
+// pyKeywords are the Python keywords that field names may clash with.
+var pyKeywords = map[string]bool{
+	"and": true, "as": true, "assert": true, "async": true, "await": true,
+	"break": true, "class": true, "continue": true, "def": true, "del": true,
+	"elif": true, "else": true, "except": true, "finally": true, "for": true,
+	"from": true, "global": true, "if": true, "import": true, "in": true, "is": true,
+	"lambda": true, "nonlocal": true, "not": true, "or": true, "pass": true,
+	"raise": true, "return": true, "try": true, "while": true, "with": true,
+	"yield": true,
+}
+
+// pyHeader is the beginning of Python clients.
+const pyHeader = `# Code generated by rpk gen. DO NOT EDIT.
+
+from __future__ import annotations
+
+import dataclasses
+import json
+import types
+import typing
+from dataclasses import dataclass, field
+from typing import Any
+
+import requests
+`
+
+// pyClient is the common part of Python clients.
+const pyClient = `
+
+class RemoteError(Exception):
+    """An error returned by a remote function."""
+
+    def __init__(self, message: str, code: str | None = None, data: Any = None,
+                 conflict: bool = False, version: str | None = None):
+        super().__init__(message)
+        self.code = code
+        self.data = data
+        self.conflict = conflict
+        self.version = version
+
+
+_ERROR_KEYS = {"error", "conflict", "version", "code", "data"}
+
+
+def _encode(value: Any) -> Any:
+    """Converts dataclasses in value to their JSON objects."""
+    if dataclasses.is_dataclass(value) and not isinstance(value, type):
+        return {f.metadata["json"]: _encode(getattr(value, f.name))
+                for f in dataclasses.fields(value)}
+    if isinstance(value, (list, tuple)):
+        return [_encode(v) for v in value]
+    if isinstance(value, dict):
+        return {k: _encode(v) for k, v in value.items()}
+    return value
+
+
+def _decode(tp: Any, data: Any) -> Any:
+    """Converts the JSON objects in data to the dataclasses of type tp."""
+    if data is None:
+        return None
+    origin, args = typing.get_origin(tp), typing.get_args(tp)
+    if origin in (typing.Union, types.UnionType):
+        return _decode(next(a for a in args if a is not type(None)), data)
+    if origin is list:
+        return [_decode(args[0], v) for v in data]
+    if origin is dict:
+        return {k: _decode(args[1], v) for k, v in data.items()}
+    if dataclasses.is_dataclass(tp):
+        hints = typing.get_type_hints(tp)
+        return tp(**{f.name: _decode(hints[f.name], data[f.metadata["json"]])
+                     for f in dataclasses.fields(tp) if f.metadata["json"] in data})
+    return data
+
+
+class Client:
+    """Calls functions of a remote rpk handler at url."""
+
+    def __init__(self, url: str, session: requests.Session | None = None,
+                 timeout: float = 10):
+        self.url = url
+        self.session = session or requests.Session()
+        self.timeout = timeout
+
+    def _call(self, name: str, result: Any, *param: Any) -> Any:
+        """Calls the named function, with the parameter if one is given."""
+        response = self.session.post(
+            self.url, params={"func": name}, timeout=self.timeout,
+            data=json.dumps(_encode(param[0])) if param else b"",
+            headers={"Content-Type": "application/json"})
+        response.raise_for_status()
+        if not response.text.strip():
+            return None
+        data = response.json()
+        if (isinstance(data, dict) and isinstance(data.get("error"), str)
+                and data.keys() <= _ERROR_KEYS):
+            raise RemoteError(data["error"], data.get("code"), data.get("data"),
+                              data.get("conflict", False), data.get("version"))
+        return _decode(result, data)
+`
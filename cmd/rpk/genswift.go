@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// genSwift returns the source of a Swift client for the API, with Codable structs and
+// async methods over URLSession. Methods and fields have camel case names.
+func genSwift(api *genAPI) ([]byte, error) {
+	m := &typeMapper{api: api, used: map[string]bool{}, lang: &genLang{
+		boolean: "Bool", str: "String", integer: "Int", float: "Double",
+		unknown: "JSONValue", time: "String",
+		array:    func(t string) string { return "[" + t + "]" },
+		record:   func(t string) string { return "[String: " + t + "]" },
+		nullable: optionalType,
+		zeroBool: "false", zeroStr: `""`, zeroInt: "0", zeroFloat: "0.0",
+		zeroTime: `"0001-01-01T00:00:00Z"`, null: "nil",
+		zeroStruct: func(name string) string { return name + "()" },
+	}}
+	var methods bytes.Buffer
+	for _, meth := range api.methods {
+		param, arg, out := "", "nil", ""
+		if meth.in != nil {
+			param = "_ param: " + m.mapType(meth.file, meth.in)
+			arg = "encode(param)"
+		}
+		if meth.out != nil {
+			out = " -> " + m.mapType(meth.file, meth.out)
+		}
+		fmt.Fprintf(&methods, "\n    /// Calls the remote %s function.\n", meth.name)
+		fmt.Fprintf(&methods, "    func %s(%s) async throws%s {\n",
+			camelName(meth.name, swiftKeywords), param, out)
+		if meth.out == nil {
+			fmt.Fprintf(&methods, "        _ = try await call(%q, %s)\n", meth.name, arg)
+		} else {
+			fmt.Fprintf(&methods, "        return try decode(await call(%q, %s))\n",
+				meth.name, arg)
+		}
+		fmt.Fprintf(&methods, "    }\n")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by rpk gen. DO NOT EDIT.\n\nimport Foundation\n")
+	for spec := m.next(); spec != nil; spec = m.next() {
+		f := api.fileOf(spec)
+		st, ok := spec.Type.(*ast.StructType)
+		if !ok {
+			fmt.Fprintf(&buf, "\ntypealias %s = %s\n", spec.Name.Name,
+				m.mapType(f, spec.Type))
+			continue
+		}
+		fields := api.fields(st)
+		fmt.Fprintf(&buf, "\nstruct %s: Codable {\n", spec.Name.Name)
+		for _, field := range fields {
+			typ, zero := m.mapType(f, field.typ), m.zero(f, field.typ)
+			if field.optional || zero == "nil" {
+				// Optional fields may be missing, and are decoded as nil.
+				typ, zero = optionalType(typ), "nil"
+			}
+			fmt.Fprintf(&buf, "    var %s: %s = %s\n",
+				camelName(field.name, swiftKeywords), typ, zero)
+		}
+		if len(fields) > 0 {
+			fmt.Fprintf(&buf, "\n    enum CodingKeys: String, CodingKey {\n")
+			for _, field := range fields {
+				fmt.Fprintf(&buf, "        case %s = %q\n",
+					camelName(field.name, swiftKeywords), field.name)
+			}
+			fmt.Fprintf(&buf, "    }\n")
+		}
+		fmt.Fprintf(&buf, "}\n")
+	}
+	buf.WriteString(swiftClient)
+	fmt.Fprintf(&buf, "\n/// Calls the functions of %s on a remote rpk handler.\n", api.name)
+	fmt.Fprintf(&buf, "final class %sClient: Client {", exportedName(api.name))
+	buf.Write(methods.Bytes())
+	fmt.Fprintf(&buf, "}\n")
+	return buf.Bytes(), nil
+}
+
+// optionalType returns the optional type of t, in Swift or Kotlin.
+func optionalType(t string) string {
+	if strings.HasSuffix(t, "?") {
+		return t
+	}
+	return t + "?"
+}
+
+// swiftKeywords are the Swift keywords that names may clash with.
+var swiftKeywords = map[string]bool{
+	"associatedtype": true, "class": true, "deinit": true, "enum": true,
+	"extension": true, "fileprivate": true, "func": true, "import": true, "init": true,
+	"inout": true, "internal": true, "let": true, "open": true, "operator": true,
+	"private": true, "protocol": true, "public": true, "rethrows": true, "static": true,
+	"struct": true, "subscript": true, "typealias": true, "var": true, "break": true,
+	"case": true, "continue": true, "default": true, "defer": true, "do": true,
+	"else": true, "fallthrough": true, "for": true, "guard": true, "if": true,
+	"in": true, "repeat": true, "return": true, "switch": true, "where": true,
+	"while": true, "as": true, "catch": true, "false": true, "is": true, "nil": true,
+	"super": true, "self": true, "throw": true, "throws": true, "true": true,
+	"try": true,
+}
+
+// swiftClient is the common part of Swift clients.
+const swiftClient = `
+/// Any JSON value.
+enum JSONValue: Codable {
+    case null
+    case bool(Bool)
+    case number(Double)
+    case string(String)
+    case array([JSONValue])
+    case object([String: JSONValue])
+
+    init(from decoder: Decoder) throws {
+        let c = try decoder.singleValueContainer()
+        if c.decodeNil() {
+            self = .null
+        } else if let v = try? c.decode(Bool.self) {
+            self = .bool(v)
+        } else if let v = try? c.decode(Double.self) {
+            self = .number(v)
+        } else if let v = try? c.decode(String.self) {
+            self = .string(v)
+        } else if let v = try? c.decode([JSONValue].self) {
+            self = .array(v)
+        } else {
+            self = .object(try c.decode([String: JSONValue].self))
+        }
+    }
+
+    func encode(to encoder: Encoder) throws {
+        var c = encoder.singleValueContainer()
+        switch self {
+        case .null: try c.encodeNil()
+        case .bool(let v): try c.encode(v)
+        case .number(let v): try c.encode(v)
+        case .string(let v): try c.encode(v)
+        case .array(let v): try c.encode(v)
+        case .object(let v): try c.encode(v)
+        }
+    }
+
+    /// The value if it is a string, otherwise nil.
+    var stringValue: String? {
+        if case .string(let v) = self {
+            return v
+        }
+        return nil
+    }
+}
+
+/// An error returned by a remote function.
+struct RemoteError: Error {
+    var message: String
+    var code: String?
+    var data: JSONValue?
+    var conflict = false
+    var version: String?
+}
+
+/// Calls functions of a remote rpk handler.
+class Client {
+    let url: URL
+    let session: URLSession
+
+    init(url: URL, session: URLSession = .shared) {
+        self.url = url
+        self.session = session
+    }
+
+    /// Calls the named function with a JSON parameter, if one is given, and returns
+    /// its JSON output.
+    func call(_ name: String, _ param: Data?) async throws -> Data {
+        var components = URLComponents(url: url, resolvingAgainstBaseURL: true)!
+        components.queryItems = (components.queryItems ?? []) +
+            [URLQueryItem(name: "func", value: name)]
+        var request = URLRequest(url: components.url!)
+        request.httpMethod = "POST"
+        request.setValue("application/json", forHTTPHeaderField: "Content-Type")
+        request.httpBody = param ?? Data()
+        let (data, response) = try await session.data(for: request)
+        if let response = response as? HTTPURLResponse, response.statusCode != 200 {
+            throw URLError(.badServerResponse)
+        }
+        let keys: Set = ["error", "conflict", "version", "code", "data"]
+        if let obj = try? JSONDecoder().decode([String: JSONValue].self, from: data),
+           let message = obj["error"]?.stringValue,
+           obj.keys.allSatisfy(keys.contains) {
+            var conflict = false
+            if case .bool(let v)? = obj["conflict"] {
+                conflict = v
+            }
+            throw RemoteError(message: message, code: obj["code"]?.stringValue,
+                              data: obj["data"], conflict: conflict,
+                              version: obj["version"]?.stringValue)
+        }
+        return data
+    }
+
+    func encode<T: Encodable>(_ value: T) throws -> Data {
+        try JSONEncoder().encode(value)
+    }
+
+    func decode<T: Decodable>(_ data: Data) throws -> T {
+        try JSONDecoder().decode(T.self, from: data)
+    }
+}
+`
@@ -0,0 +1,197 @@
+// Command rpk calls the functions of rpk handlers from the terminal, and generates
+// clients for them.
+//
+// Usage
+//
+//	rpk call [-timeout=10s] [-version=v] [-key-file=file [-key-id=id]]
+//		<url> <function> [<json parameter> | -]
+//	rpk funcs <url>
+//	rpk gen -lang=ts|go|py|dart|swift|kotlin [-pkg=.] [-type=API]
+//		[-naming=camel|snake] [-o=file]
+//
+// Call prints the function's output as indented JSON. The parameter is read from the
+// standard input if it is "-", and omitted if it is not given, for functions that take
+// no input. With -version, the call expects that version of the resource that it
+// changes, like the If-Match header. With -key-file, the call is signed with the key in
+// the file, without surrounding whitespace, for handlers with the ReplayProtection
+// option, and -key-id is sent as the ID of the key. Errors of the function are printed
+// with their code and data, if any, and make the command exit with status 1.
+//
+// Funcs prints the names of the handler's functions, one per line.
+//
+// Gen parses the Go package in the -pkg directory, finds the API type, and generates
+// a typed client for its methods in TypeScript, Go, Python, Dart, Swift or Kotlin. The
+// type is the only one in the package with exported methods, unless -type is given.
+// The Go client is in the API's package, and the others also declare the package's
+// types that the methods use. TypeScript interfaces have the field names of the wire,
+// while Python dataclasses have snake case names and the models of the other languages
+// camel case names, converted to and from the wire names. The Python client depends on
+// the requests package, the Dart client on the http package, and the Kotlin client on
+// OkHttp and kotlinx.serialization. The Swift client uses URLSession. Use -naming if
+// the handler has the FieldNaming option.
+//
+// For example:
+//
+//	rpk call http://localhost:8080/api Half 7
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/fluhus/rpk"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// usage is printed for bad command lines.
+const usage = `Usage:
+  rpk call [-timeout=10s] [-version=v] [-key-file=file [-key-id=id]]
+      <url> <function> [<json parameter> | -]
+  rpk funcs <url>
+  rpk gen -lang=ts|go|py|dart|swift|kotlin [-pkg=.] [-type=API]
+      [-naming=camel|snake] [-o=file]
+`
+
+// run runs the command with the given arguments, and returns its exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	var err error
+	switch args[0] {
+	case "call":
+		err = call(args[1:], stdin, stdout, stderr)
+	case "funcs":
+		err = funcs(args[1:], stdout, stderr)
+	case "gen":
+		err = gen(args[1:], stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "rpk: unknown command %q\n%s", args[0], usage)
+		return 2
+	}
+	var uerr usageError
+	switch {
+	case errors.As(err, &uerr):
+		fmt.Fprintf(stderr, "rpk: %v\n%s", err, usage)
+		return 2
+	case err != nil:
+		fmt.Fprintln(stderr, "rpk:", err)
+		return 1
+	}
+	return 0
+}
+
+// usageError is an error in the command line.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// call runs the call command.
+func call(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("call", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	timeout := flags.Duration("timeout", 10*time.Second, "Timeout of the call.")
+	version := flags.String("version", "", "Version that the call expects.")
+	keyFile := flags.String("key-file", "", "File of the key to sign the call with.")
+	keyID := flags.String("key-id", "", "ID of the signing key.")
+	if err := flags.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	if flags.NArg() < 2 || flags.NArg() > 3 {
+		return usageError("call takes a URL, a function and an optional parameter")
+	}
+	url, name := flags.Arg(0), flags.Arg(1)
+
+	var param interface{}
+	if flags.NArg() == 3 {
+		data := []byte(flags.Arg(2))
+		if flags.Arg(2) == "-" {
+			var err error
+			if data, err = io.ReadAll(stdin); err != nil {
+				return err
+			}
+		}
+		if !json.Valid(data) {
+			return fmt.Errorf("parameter is not valid JSON: %s", data)
+		}
+		param = json.RawMessage(data)
+	}
+
+	c := rpk.NewClient(url, nil)
+	if *keyFile != "" {
+		key, err := os.ReadFile(*keyFile)
+		if err != nil {
+			return err
+		}
+		c.SignCalls(*keyID, bytes.TrimSpace(key))
+	} else if *keyID != "" {
+		return usageError("-key-id needs -key-file")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if *version != "" {
+		ctx = rpk.WithVersion(ctx, *version)
+	}
+	var result json.RawMessage
+	err := c.Call(ctx, name, param, &result)
+	var rerr *rpk.RemoteError
+	if errors.As(err, &rerr) {
+		return remoteError(rerr)
+	}
+	if err != nil {
+		return err
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	json.Indent(&buf, result, "", "  ")
+	buf.WriteByte('\n')
+	_, err = stdout.Write(buf.Bytes())
+	return err
+}
+
+// remoteError returns an error that describes an error of a remote function.
+func remoteError(e *rpk.RemoteError) error {
+	msg := e.Message
+	switch {
+	case e.Conflict:
+		msg += fmt.Sprintf(" (conflict, current version %q)", e.Version)
+	case e.Code != "" && len(e.Data) > 0:
+		msg += fmt.Sprintf(" (code %s, data %s)", e.Code, e.Data)
+	case e.Code != "":
+		msg += fmt.Sprintf(" (code %s)", e.Code)
+	}
+	return errors.New(msg)
+}
+
+// funcs runs the funcs command.
+func funcs(args []string, stdout, stderr io.Writer) error {
+	if len(args) != 1 {
+		return usageError("funcs takes a URL")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var names []string
+	if err := rpk.NewClient(args[0], nil).Call(ctx, "funcs", nil, &names); err != nil {
+		return err
+	}
+	for _, name := range names {
+		fmt.Fprintln(stdout, name)
+	}
+	return nil
+}
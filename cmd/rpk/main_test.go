@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fluhus/rpk"
+)
+
+func TestRun(t *testing.T) {
+	h := rpk.New()
+	h.Register("Half", func(i int) int { return i / 2 })
+	h.Register("Echo", func(m map[string]string) map[string]string { return m })
+	h.Register("Ping", func() {})
+	h.Register("Find", func(ctx context.Context, id int) error {
+		if v, _ := rpk.ExpectedVersion(ctx); v != "" {
+			return &rpk.ConflictError{Current: "2"}
+		}
+		return errors.Join(errors.New("no such user"), rpk.ErrNotFound)
+	})
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	tests := []struct {
+		args   []string
+		stdin  string
+		status int
+		out    string
+	}{
+		{[]string{"call", server.URL, "Half", "7"}, "", 0, "3\n"},
+		{[]string{"call", server.URL, "Echo", "-"}, `{"a": "&+#"}`, 0,
+			"{\n  \"a\": \"\\u0026+#\"\n}\n"},
+		{[]string{"call", server.URL, "Ping"}, "", 0, ""},
+		{[]string{"call", server.URL, "Find", "1"}, "", 1,
+			"rpk: no such user\nnot found (code not_found)\n"},
+		{[]string{"call", "-version=1", server.URL, "Find", "1"}, "", 1,
+			"rpk: Version conflict, the current version is \"2\". " +
+				"(conflict, current version \"2\")\n"},
+		{[]string{"call", server.URL, "Half", "{"}, "", 1,
+			"rpk: parameter is not valid JSON: {\n"},
+		{[]string{"funcs", server.URL}, "", 0, "Echo\nFind\nHalf\nPing\n"},
+		{[]string{"call", server.URL}, "", 2, "rpk: call takes a URL"},
+		{[]string{"bla"}, "", 2, `rpk: unknown command "bla"`},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		status := run(test.args, strings.NewReader(test.stdin), &out, &out)
+		if status != test.status {
+			t.Fatalf("Bad status for %v: %d, expected %d. Output: %s", test.args,
+				status, test.status, out.String())
+		}
+		if test.status == 2 && !strings.HasPrefix(out.String(), test.out) ||
+			test.status != 2 && out.String() != test.out {
+			t.Fatalf("Bad output for %v: %q, expected %q.", test.args, out.String(),
+				test.out)
+		}
+	}
+}
+
+func TestRun_signed(t *testing.T) {
+	keys := map[string][]byte{"billing": []byte("secret")}
+	h := rpk.New(rpk.ReplayProtection(rpk.SigningKeys(func(id string) ([]byte, error) {
+		if keys[id] == nil {
+			return nil, rpk.ErrPermissionDenied
+		}
+		return keys[id], nil
+	}), time.Minute))
+	h.Register("Whoami", func(ctx context.Context) string { return rpk.SignerKeyID(ctx) })
+	server := httptest.NewServer(h)
+	defer server.Close()
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args   []string
+		status int
+		out    string
+	}{
+		{[]string{"call", "-key-file=" + keyFile, "-key-id=billing", server.URL,
+			"Whoami"}, 0, "\"billing\"\n"},
+		{[]string{"call", "-key-file=" + keyFile, "-key-id=other", server.URL,
+			"Whoami"}, 1, "rpk: permission denied (code permission_denied)\n"},
+		{[]string{"call", server.URL, "Whoami"}, 1,
+			"rpk: Call is not signed: permission denied (code permission_denied)\n"},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		status := run(test.args, nil, &out, &out)
+		if status != test.status || out.String() != test.out {
+			t.Fatalf("Bad result for %v: %d %q, expected %d %q.", test.args, status,
+				out.String(), test.status, test.out)
+		}
+	}
+}
@@ -0,0 +1,500 @@
+// Command rpkgen generates static dispatch tables and Go clients for rpk API types.
+//
+// By default, the generated code implements rpk.Dispatcher for the given type, with
+// typed stubs that decode each method's input and call it directly, instead of going
+// through reflection.
+//
+// With -client, the given type should be an interface, and the generated code is a
+// client type that implements it by calling a remote handler through an rpk.Client.
+// Methods of the interface without an error output panic if the call fails.
+//
+// Usage
+//
+//	rpkgen -type=myAPI [-client] [-dir=.] [-o=myapi_rpk.go]
+//
+// Typically used with a go:generate directive next to the API type:
+//
+//	//go:generate go run github.com/fluhus/rpk/cmd/rpkgen -type=myAPI
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	typeName = flag.String("type", "", "Name of the API type. Required.")
+	dir      = flag.String("dir", ".", "Directory of the API type's package.")
+	output   = flag.String("o", "", "Output file. Default is <type>_rpk.go in dir, "+
+		"or <type>_rpkclient.go with -client.")
+	client = flag.Bool("client", false, "Generate a client for an interface type.")
+)
+
+func main() {
+	flag.Parse()
+	if *typeName == "" {
+		fmt.Fprintln(os.Stderr, "rpkgen: -type is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *output == "" {
+		suffix := "_rpk.go"
+		if *client {
+			suffix = "_rpkclient.go"
+		}
+		*output = filepath.Join(*dir, strings.ToLower(*typeName)+suffix)
+	}
+
+	api, err := parseAPI(*dir, *typeName, *output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rpkgen:", err)
+		os.Exit(1)
+	}
+	var src []byte
+	if *client {
+		src, err = generateClient(api)
+	} else {
+		src, err = generate(api)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rpkgen:", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "rpkgen:", err)
+		os.Exit(1)
+	}
+}
+
+// apiType describes an API type's exported methods.
+type apiType struct {
+	pkg     string            // Package name.
+	name    string            // Type name.
+	ptr     bool              // Whether methods should be called on a pointer.
+	iface   bool              // Whether the type is an interface.
+	methods []*apiMethod      // Exported methods, sorted by name.
+	imports map[string]string // Imports needed by the methods' input types, by path.
+	outImps map[string]string // Imports needed by the methods' output types, by path.
+}
+
+// apiMethod describes a single exported method.
+type apiMethod struct {
+	name   string
+	hasCtx bool   // Whether the method takes a context first.
+	in     string // Input type, empty if none.
+	out    string // Value output type, empty if none.
+	hasOut bool   // Whether the method has a value output.
+	hasErr bool   // Whether the method has an error output.
+}
+
+// parseAPI parses the Go files in dir and collects the methods of the named type.
+// Skips test files and the output file.
+func parseAPI(dir, typeName, output string) (*apiType, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	api := &apiType{name: typeName, imports: map[string]string{},
+		outImps: map[string]string{}}
+	found := false
+
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") || filepath.Clean(file) == filepath.Clean(output) {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		api.pkg = f.Name.Name
+
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					spec, ok := spec.(*ast.TypeSpec)
+					if !ok || spec.Name.Name != typeName {
+						continue
+					}
+					found = true
+					if iface, ok := spec.Type.(*ast.InterfaceType); ok {
+						api.iface = true
+						if err := parseInterface(fset, f, iface, api); err != nil {
+							return nil, err
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				recv, ptr := receiverName(decl)
+				if recv != typeName || !decl.Name.IsExported() ||
+					decl.Name.Name == "RPKDispatch" {
+					continue
+				}
+				m, err := parseMethod(fset, f, decl.Name.Name, decl.Type, api)
+				if err != nil {
+					return nil, fmt.Errorf("method '%s': %v", decl.Name.Name, err)
+				}
+				api.ptr = api.ptr || ptr
+				api.methods = append(api.methods, m)
+			}
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("type '%s' not found in %s", typeName, dir)
+	}
+	sort.Slice(api.methods, func(i, j int) bool {
+		return api.methods[i].name < api.methods[j].name
+	})
+	return api, nil
+}
+
+// receiverName returns the name of a method's receiver type, and whether it is a
+// pointer. Returns an empty string for functions.
+func receiverName(f *ast.FuncDecl) (string, bool) {
+	if f.Recv == nil || len(f.Recv.List) != 1 {
+		return "", false
+	}
+	typ := f.Recv.List[0].Type
+	ptr := false
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ, ptr = star.X, true
+	}
+	if id, ok := typ.(*ast.Ident); ok {
+		return id.Name, ptr
+	}
+	return "", false
+}
+
+// parseInterface adds the methods of an interface type to api.
+func parseInterface(fset *token.FileSet, f *ast.File, iface *ast.InterfaceType,
+	api *apiType) error {
+	for _, field := range iface.Methods.List {
+		ft, ok := field.Type.(*ast.FuncType)
+		if !ok {
+			return fmt.Errorf("embedded interfaces are not supported")
+		}
+		for _, name := range field.Names {
+			if !name.IsExported() {
+				continue
+			}
+			m, err := parseMethod(fset, f, name.Name, ft, api)
+			if err != nil {
+				return fmt.Errorf("method '%s': %v", name.Name, err)
+			}
+			api.methods = append(api.methods, m)
+		}
+	}
+	return nil
+}
+
+// parseMethod checks that a method matches the requirements of rpk and describes it.
+// Adds the imports that the method's types use to api.
+func parseMethod(fset *token.FileSet, f *ast.File, name string, ft *ast.FuncType,
+	api *apiType) (*apiMethod, error) {
+	m := &apiMethod{name: name}
+
+	params := fieldTypes(ft.Params)
+	if len(params) > 0 && isContextType(f, params[0]) {
+		m.hasCtx = true
+		params = params[1:]
+	}
+	if len(params) > 1 {
+		return nil, fmt.Errorf("must have 0 or 1 inputs after an optional context, "+
+			"it has %d", len(params))
+	}
+	if len(params) == 1 {
+		if isContextType(f, params[0]) {
+			return nil, fmt.Errorf("context.Context should come first")
+		}
+		in, err := formatType(fset, params[0])
+		if err != nil {
+			return nil, err
+		}
+		m.in = in
+		addImports(f, params[0], api.imports)
+	}
+
+	results := fieldTypes(ft.Results)
+	if len(results) > 2 {
+		return nil, fmt.Errorf("more than 2 outputs: %d", len(results))
+	}
+	if len(results) > 0 && isErrorType(results[len(results)-1]) {
+		m.hasErr = true
+		results = results[:len(results)-1]
+	}
+	if len(results) == 2 {
+		return nil, fmt.Errorf("second output should be an error")
+	}
+	m.hasOut = len(results) == 1
+	if m.hasOut {
+		out, err := formatType(fset, results[0])
+		if err != nil {
+			return nil, err
+		}
+		m.out = out
+		addImports(f, results[0], api.outImps)
+	}
+	return m, nil
+}
+
+// formatType returns the source of a type expression.
+func formatType(fset *token.FileSet, e ast.Expr) (string, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := format.Node(buf, fset, e); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// fieldTypes returns the type of each field in the list, one per name.
+func fieldTypes(fl *ast.FieldList) []ast.Expr {
+	if fl == nil {
+		return nil
+	}
+	var result []ast.Expr
+	for _, field := range fl.List {
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			result = append(result, field.Type)
+		}
+	}
+	return result
+}
+
+// isErrorType checks if the given type expression is the builtin error.
+func isErrorType(e ast.Expr) bool {
+	id, ok := e.(*ast.Ident)
+	return ok && id.Name == "error"
+}
+
+// isContextType checks if the given type expression is context.Context, according to
+// the imports of f.
+func isContextType(f *ast.File, e ast.Expr) bool {
+	sel, ok := e.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Context" {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	imports := map[string]string{}
+	addImports(f, e, imports)
+	return imports["context"] == pkg.Name
+}
+
+// addImports adds the imports of f that are referenced in e to imports.
+func addImports(f *ast.File, e ast.Expr, imports map[string]string) {
+	ast.Inspect(e, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		pkg, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		for _, imp := range f.Imports {
+			path := strings.Trim(imp.Path.Value, "\"")
+			name := path[strings.LastIndex(path, "/")+1:]
+			if imp.Name != nil {
+				name = imp.Name.Name
+			}
+			if name == pkg.Name {
+				imports[path] = name
+			}
+		}
+		return true
+	})
+}
+
+// generate returns the formatted source of the dispatch table of the given type.
+func generate(api *apiType) ([]byte, error) {
+	if api.iface {
+		return nil, fmt.Errorf("type '%s' is an interface, dispatch tables are "+
+			"generated for concrete types", api.name)
+	}
+	buf := bytes.NewBuffer(nil)
+	writeHeader(buf, api.pkg, api.imports)
+
+	recv := api.name
+	if api.ptr {
+		recv = "*" + recv
+	}
+	fmt.Fprintln(buf, "// RPKDispatch implements rpk.Dispatcher.")
+	fmt.Fprintf(buf, "func (a %s) RPKDispatch() map[string]rpk.StaticFunc {\n", recv)
+	fmt.Fprintln(buf, "return map[string]rpk.StaticFunc{")
+	for _, m := range api.methods {
+		writeMethod(buf, m)
+	}
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "}")
+
+	return format.Source(buf.Bytes())
+}
+
+// writeHeader writes the header of a generated file, importing context, rpk and the
+// given imports.
+func writeHeader(buf *bytes.Buffer, pkg string, imports map[string]string) {
+	fmt.Fprintln(buf, "// Code generated by rpkgen. DO NOT EDIT.")
+	fmt.Fprintln(buf)
+	fmt.Fprintf(buf, "package %s\n\n", pkg)
+
+	imports["context"] = "context"
+	fmt.Fprintln(buf, "import (")
+	paths := make([]string, 0, len(imports))
+	for path := range imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if name := imports[path]; name != path[strings.LastIndex(path, "/")+1:] {
+			fmt.Fprintf(buf, "\t%s %q\n", name, path)
+		} else {
+			fmt.Fprintf(buf, "\t%q\n", path)
+		}
+	}
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "\t\"github.com/fluhus/rpk\"")
+	fmt.Fprintln(buf, ")")
+	fmt.Fprintln(buf)
+}
+
+// writeMethod writes the dispatch table entry of a single method.
+func writeMethod(buf *bytes.Buffer, m *apiMethod) {
+	fmt.Fprintf(buf, "%q: {\n", m.name)
+	fmt.Fprintf(buf, "HasInput: %v,\n", m.in != "")
+	fmt.Fprintf(buf, "HasOutput: %v,\n", m.hasOut)
+	fmt.Fprintln(buf, "Call: func(ctx context.Context, dec rpk.Decoder) (interface{}, error) {")
+
+	var args []string
+	if m.hasCtx {
+		args = append(args, "ctx")
+	}
+	if m.in != "" {
+		// Pointers are decoded through their address too, so that null gives nil.
+		fmt.Fprintf(buf, "var in %s\n", m.in)
+		fmt.Fprintln(buf, "if err := dec.Decode(&in); err != nil {")
+		fmt.Fprintln(buf, "return nil, err")
+		fmt.Fprintln(buf, "}")
+		args = append(args, "in")
+	}
+
+	call := fmt.Sprintf("a.%s(%s)", m.name, strings.Join(args, ", "))
+	switch {
+	case m.hasOut && m.hasErr:
+		fmt.Fprintf(buf, "return %s\n", call)
+	case m.hasOut:
+		fmt.Fprintf(buf, "return %s, nil\n", call)
+	case m.hasErr:
+		fmt.Fprintf(buf, "return nil, %s\n", call)
+	default:
+		fmt.Fprintln(buf, call)
+		fmt.Fprintln(buf, "return nil, nil")
+	}
+	fmt.Fprintln(buf, "},")
+	fmt.Fprintln(buf, "},")
+}
+
+// generateClient returns the formatted source of a client that implements the given
+// interface type.
+func generateClient(api *apiType) ([]byte, error) {
+	if !api.iface {
+		return nil, fmt.Errorf("type '%s' is not an interface, clients are generated "+
+			"for interfaces", api.name)
+	}
+	imports := map[string]string{}
+	for path, name := range api.imports {
+		imports[path] = name
+	}
+	for path, name := range api.outImps {
+		imports[path] = name
+	}
+	buf := bytes.NewBuffer(nil)
+	writeHeader(buf, api.pkg, imports)
+
+	name := api.name + "Client"
+	ctor := "New" + strings.ToUpper(api.name[:1]) + api.name[1:] + "Client"
+	if !ast.IsExported(api.name) {
+		ctor = "new" + strings.ToUpper(api.name[:1]) + api.name[1:] + "Client"
+	}
+	fmt.Fprintf(buf, "// %s implements %s by calling a remote rpk handler.\n", name, api.name)
+	fmt.Fprintf(buf, "type %s struct {\n", name)
+	fmt.Fprintln(buf, "c *rpk.Client")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+	fmt.Fprintf(buf, "var _ %s = (*%s)(nil)\n\n", api.name, name)
+	fmt.Fprintf(buf, "// %s returns a %s that calls functions through c.\n", ctor, name)
+	fmt.Fprintf(buf, "func %s(c *rpk.Client) *%s {\n", ctor, name)
+	fmt.Fprintf(buf, "return &%s{c}\n", name)
+	fmt.Fprintln(buf, "}")
+
+	for _, m := range api.methods {
+		fmt.Fprintln(buf)
+		writeClientMethod(buf, name, m)
+	}
+	return format.Source(buf.Bytes())
+}
+
+// writeClientMethod writes a client method that calls the remote function of m.
+func writeClientMethod(buf *bytes.Buffer, client string, m *apiMethod) {
+	var params, outs []string
+	ctx, in, out := "context.Background()", "nil", "nil"
+	if m.hasCtx {
+		params = append(params, "ctx context.Context")
+		ctx = "ctx"
+	}
+	if m.in != "" {
+		params = append(params, "in "+m.in)
+		in = "in"
+	}
+	if m.hasOut {
+		outs = append(outs, m.out)
+		out = "&out"
+	}
+	if m.hasErr {
+		outs = append(outs, "error")
+	}
+	result := strings.Join(outs, ", ")
+	if len(outs) > 1 {
+		result = "(" + result + ")"
+	}
+
+	fmt.Fprintf(buf, "// %s calls the remote %s function.\n", m.name, m.name)
+	fmt.Fprintf(buf, "func (c *%s) %s(%s) %s {\n", client, m.name,
+		strings.Join(params, ", "), result)
+	if m.hasOut {
+		fmt.Fprintf(buf, "var out %s\n", m.out)
+	}
+	call := fmt.Sprintf("c.c.Call(%s, %q, %s, %s)", ctx, m.name, in, out)
+	switch {
+	case m.hasOut && m.hasErr:
+		fmt.Fprintf(buf, "err := %s\n", call)
+		fmt.Fprintln(buf, "return out, err")
+	case m.hasErr:
+		fmt.Fprintf(buf, "return %s\n", call)
+	default:
+		// No way to report the error.
+		fmt.Fprintf(buf, "if err := %s; err != nil {\n", call)
+		fmt.Fprintln(buf, "panic(err)")
+		fmt.Fprintln(buf, "}")
+		if m.hasOut {
+			fmt.Fprintln(buf, "return out")
+		}
+	}
+	fmt.Fprintln(buf, "}")
+}
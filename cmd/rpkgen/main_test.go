@@ -0,0 +1,183 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSrc = `package api
+
+import (
+	"context"
+	"time"
+)
+
+type myAPI struct{}
+
+func (myAPI) Foo() {}
+
+func (myAPI) Half(i int) int {
+	return i / 2
+}
+
+func (*myAPI) Wait(d time.Duration) error {
+	return nil
+}
+
+func (myAPI) Ctx(ctx context.Context) string {
+	return ""
+}
+
+func (myAPI) Fun(th *thing) (string, error) {
+	return "", nil
+}
+
+func (myAPI) private(i int) {}
+
+type thing struct{}
+`
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "api.go"), []byte(testSrc), 0644); err != nil {
+		t.Fatal("Failed to write source:", err)
+	}
+
+	api, err := parseAPI(dir, "myAPI", filepath.Join(dir, "myapi_rpk.go"))
+	if err != nil {
+		t.Fatal("Failed to parse API:", err)
+	}
+	if len(api.methods) != 5 {
+		t.Fatalf("Expected 5 methods, got %d.", len(api.methods))
+	}
+
+	src, err := generate(api)
+	if err != nil {
+		t.Fatal("Failed to generate:", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "", src, 0); err != nil {
+		t.Fatalf("Failed to parse generated code: %v\n%s", err, src)
+	}
+
+	for _, want := range []string{
+		"func (a *myAPI) RPKDispatch() map[string]rpk.StaticFunc",
+		"\"time\"",
+		"var in time.Duration",
+		"var in *thing",
+		"return a.Half(in), nil",
+		"return nil, a.Wait(in)",
+		"return a.Fun(in)",
+		"return a.Ctx(ctx), nil",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Fatalf("Generated code does not contain %q:\n%s", want, src)
+		}
+	}
+	if strings.Contains(string(src), "private") {
+		t.Fatalf("Generated code contains unexported method:\n%s", src)
+	}
+}
+
+func TestGenerate_badMethod(t *testing.T) {
+	dir := t.TempDir()
+	src := "package api\ntype myAPI struct{}\nfunc (myAPI) Bad(a, b int) {}\n"
+	if err := os.WriteFile(filepath.Join(dir, "api.go"), []byte(src), 0644); err != nil {
+		t.Fatal("Failed to write source:", err)
+	}
+	if _, err := parseAPI(dir, "myAPI", ""); err == nil {
+		t.Fatal("Expected error for method with 2 inputs.")
+	}
+}
+
+const testIfaceSrc = `package api
+
+import (
+	"context"
+	"time"
+)
+
+type Remote interface {
+	Foo()
+	Half(i int) int
+	Wait(ctx context.Context, d time.Duration) error
+	Now() (time.Time, error)
+}
+`
+
+func TestGenerateClient(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "api.go"), []byte(testIfaceSrc), 0644)
+	if err != nil {
+		t.Fatal("Failed to write source:", err)
+	}
+
+	api, err := parseAPI(dir, "Remote", "")
+	if err != nil {
+		t.Fatal("Failed to parse API:", err)
+	}
+	if _, err := generate(api); err == nil {
+		t.Fatal("Expected error when generating a dispatch table for an interface.")
+	}
+
+	src, err := generateClient(api)
+	if err != nil {
+		t.Fatal("Failed to generate:", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "", src, 0); err != nil {
+		t.Fatalf("Failed to parse generated code: %v\n%s", err, src)
+	}
+
+	for _, want := range []string{
+		"func NewRemoteClient(c *rpk.Client) *RemoteClient",
+		"func (c *RemoteClient) Foo() {",
+		"func (c *RemoteClient) Half(in int) int {",
+		"c.c.Call(context.Background(), \"Half\", in, &out)",
+		"func (c *RemoteClient) Wait(ctx context.Context, in time.Duration) error {",
+		"return c.c.Call(ctx, \"Wait\", in, nil)",
+		"func (c *RemoteClient) Now() (time.Time, error) {",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Fatalf("Generated code does not contain %q:\n%s", want, src)
+		}
+	}
+}
+
+const testPtrSrc = `package rpk
+
+type staticPtrType struct{}
+
+func (staticPtrType) IsNil(th *thing) bool {
+	return th == nil
+}
+`
+
+// Checks that the dispatcher with which rpk tests null pointer inputs is what rpkgen
+// generates.
+func TestGenerate_pointer(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "api.go"), []byte(testPtrSrc), 0644)
+	if err != nil {
+		t.Fatal("Failed to write source:", err)
+	}
+	api, err := parseAPI(dir, "staticPtrType", "")
+	if err != nil {
+		t.Fatal("Failed to parse API:", err)
+	}
+	src, err := generate(api)
+	if err != nil {
+		t.Fatal("Failed to generate:", err)
+	}
+	want := string(src[strings.Index(string(src), "func (a staticPtrType)"):])
+	want = strings.ReplaceAll(want, "rpk.", "")
+	test, err := os.ReadFile(filepath.Join("..", "..", "dispatch_test.go"))
+	if err != nil {
+		t.Fatal("Failed to read rpk's tests:", err)
+	}
+	if !strings.Contains(string(test), want) {
+		t.Fatalf("rpk's dispatch_test.go does not contain the generated code:\n%s", want)
+	}
+}
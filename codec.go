@@ -0,0 +1,701 @@
+package rpk
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A codec encodes and decodes values of one type in its own way, replacing their
+// default JSON encoding.
+type codec struct {
+	// encode returns a value to encode to JSON instead of v.
+	encode func(v reflect.Value) (interface{}, error)
+
+	// decode decodes data into v, which is settable.
+	decode func(data []byte, v reflect.Value) error
+}
+
+// codecSet holds the codecs of a handler.
+type codecSet struct {
+	m     map[reflect.Type]*codec
+	kinds map[reflect.Kind]*codec // For all types of a kind, without custom marshaling.
+
+	// Converts names of fields without a name in their json tag, nil for none.
+	naming func(name string) string
+
+	strict bool // Whether to reject unknown fields when decoding.
+
+	// Whether types have values with codecs, by type.
+	cache sync.Map
+}
+
+// codecsOf returns the codecs of the options, creating them if needed.
+func (o *options) codecsOf() *codecSet {
+	if o.codecs == nil {
+		o.codecs = &codecSet{
+			m:     map[reflect.Type]*codec{},
+			kinds: map[reflect.Kind]*codec{},
+		}
+	}
+	return o.codecs
+}
+
+// get returns the codec of type t, or nil if it has none.
+func (cs *codecSet) get(t reflect.Type) *codec {
+	if c := cs.m[t]; c != nil {
+		return c
+	}
+	if c := cs.kinds[t.Kind()]; c != nil && !isMarshaler(t) {
+		return c
+	}
+	return nil
+}
+
+// has returns whether values of type t may contain values with codecs. Fields of
+// interface types are not looked into.
+func (cs *codecSet) has(t reflect.Type) bool {
+	if cs == nil {
+		return false
+	}
+	if r, ok := cs.cache.Load(t); ok {
+		return r.(bool)
+	}
+	r := cs.find(t, map[reflect.Type]bool{})
+	cs.cache.Store(t, r)
+	return r
+}
+
+// find implements has. Seen holds the types that are already being looked into, for
+// recursive types. Types with custom marshaling are not looked into, except Optionals,
+// whose values are encoded and decoded by the codecs, and whose decoding is strict.
+func (cs *codecSet) find(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if cs.get(t) != nil {
+		return true
+	}
+	if seen[t] || t.Kind() != reflect.Ptr && isMarshaler(t) && !isOptional(t) {
+		return false
+	}
+	seen[t] = true
+	if isOptional(t) {
+		return cs.strict || cs.find(t.Field(0).Type, seen)
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return cs.find(t.Elem(), seen)
+	case reflect.Struct:
+		if cs.naming != nil && len(jsonFields(t)) > 0 {
+			return true
+		}
+		for _, f := range jsonFields(t) {
+			if cs.find(t.FieldByIndex(f.index).Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// forType returns cs if values of type t may contain values with codecs, or nil if
+// they can be encoded and decoded as usual.
+func (cs *codecSet) forType(t reflect.Type) *codecSet {
+	if !cs.has(t) {
+		return nil
+	}
+	return cs
+}
+
+// encode returns a value to encode to JSON instead of v, where values with codecs are
+// replaced by their encoded forms.
+func (cs *codecSet) encode(v reflect.Value) (interface{}, error) {
+	t := v.Type()
+	if c := cs.get(t); c != nil {
+		return c.encode(v)
+	}
+	if t.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		return cs.encode(v.Elem())
+	}
+	if !cs.has(t) {
+		return v.Interface(), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return cs.encode(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		result := make([]interface{}, v.Len())
+		for i := range result {
+			e, err := cs.encode(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			result[i] = e
+		}
+		return result, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		result := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := encodeMapKey(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			e, err := cs.encode(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			result[key] = e
+		}
+		return result, nil
+	case reflect.Struct:
+		if isOptional(t) {
+			if !v.Field(1).Bool() {
+				return nil, nil
+			}
+			return cs.encode(v.Field(0))
+		}
+		var result jsonObject
+		for _, f := range jsonFields(t) {
+			fv, ok := fieldValue(v, f.index)
+			if !ok || f.omitEmpty && isEmptyValue(fv) || f.omitZero && isZeroValue(fv) {
+				continue
+			}
+			e, err := cs.encode(fv)
+			if err != nil {
+				return nil, err
+			}
+			if f.quoted && !(fv.Kind() == reflect.Ptr && fv.IsNil()) &&
+				cs.get(fv.Type()) == nil {
+				data, err := json.Marshal(e)
+				if err != nil {
+					return nil, err
+				}
+				e = string(data)
+			}
+			result = append(result, jsonMember{cs.fieldName(f), e})
+		}
+		return result, nil
+	}
+	return v.Interface(), nil
+}
+
+// decode decodes data into v, which is settable, using codecs where they apply.
+func (cs *codecSet) decode(data []byte, v reflect.Value) error {
+	t := v.Type()
+	if c := cs.get(t); c != nil {
+		return c.decode(data, v)
+	}
+	if !cs.has(t) {
+		return cs.unmarshal(data, v)
+	}
+	if isOptional(t) {
+		// Set even if null, like Optional.UnmarshalJSON.
+		v.Field(1).SetBool(true)
+		return cs.decode(data, v.Field(0))
+	}
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map:
+			v.Set(reflect.Zero(t))
+		}
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return cs.decode(data, v.Elem())
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		if t.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(t, len(items), len(items)))
+		}
+		for i := 0; i < len(items) && i < v.Len(); i++ {
+			if err := cs.decode(items[i], v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		var items map[string]json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(t, len(items)))
+		}
+		for k, item := range items {
+			key := reflect.New(t.Key()).Elem()
+			if err := decodeMapKey(k, key); err != nil {
+				return err
+			}
+			e := reflect.New(t.Elem()).Elem()
+			if err := cs.decode(item, e); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, e)
+		}
+	case reflect.Struct:
+		var items map[string]json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		fields := jsonFields(t)
+		if cs.strict {
+			if err := cs.checkFields(items, fields); err != nil {
+				return err
+			}
+		}
+		// Like encoding/json, each member goes to the field with its exact name, or else
+		// to the first field with its name in any case.
+		byField := make([]json.RawMessage, len(fields))
+		for k, item := range items {
+			match := -1
+			for i, f := range fields {
+				name := cs.fieldName(f)
+				if name == k {
+					match = i
+					break
+				}
+				if match == -1 && strings.EqualFold(name, k) {
+					match = i
+				}
+			}
+			if match != -1 {
+				byField[match] = item
+			}
+		}
+		// Also like encoding/json, fields that cannot be set fail the decoding after the
+		// others are set.
+		var embedErr error
+		for i, f := range fields {
+			item := byField[i]
+			if item == nil {
+				continue
+			}
+			name := cs.fieldName(f)
+			fv, err := settableField(v, f.index)
+			if err != nil {
+				if embedErr == nil {
+					embedErr = err
+				}
+				continue
+			}
+			if f.quoted && cs.get(fv.Type()) == nil {
+				if item, err = unquoteField(item); err != nil {
+					return fmt.Errorf("field %s: %v", name, err)
+				}
+			}
+			if err := cs.decode(item, fv); err != nil {
+				return fmt.Errorf("field %s: %v", name, err)
+			}
+		}
+		return embedErr
+	default:
+		return cs.unmarshal(data, v)
+	}
+	return nil
+}
+
+// unquoteField returns the value in the JSON string of a field with the string option.
+// Null is returned as is.
+func unquoteField(data []byte) ([]byte, error) {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return data, nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid use of ,string struct tag: %v", err)
+	}
+	return []byte(s), nil
+}
+
+// unmarshal decodes data into v, which is settable, like json.Unmarshal.
+func (cs *codecSet) unmarshal(data []byte, v reflect.Value) error {
+	if !cs.strict {
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	return d.Decode(v.Addr().Interface())
+}
+
+// checkFields returns an error if an object has a member that matches none of the given
+// fields, in any case.
+func (cs *codecSet) checkFields(items map[string]json.RawMessage, fields []jsonField) error {
+	for k := range items {
+		known := false
+		for _, f := range fields {
+			if strings.EqualFold(k, cs.fieldName(f)) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("json: unknown field %q", k)
+		}
+	}
+	return nil
+}
+
+// codecValue is a value that is encoded with codecs.
+type codecValue struct {
+	v  interface{}
+	cs *codecSet
+}
+
+func (v codecValue) MarshalJSON() ([]byte, error) {
+	if v.v == nil {
+		return []byte("null"), nil
+	}
+	e, err := v.cs.encode(reflect.ValueOf(v.v))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(e)
+}
+
+// codecDecoder decodes parameters with codecs, reading them with its underlying decoder.
+type codecDecoder struct {
+	Decoder
+	cs *codecSet
+}
+
+func (d codecDecoder) Decode(v interface{}) error {
+	var raw Raw
+	if err := d.Decoder.Decode(&raw); err != nil {
+		return err
+	}
+	return d.cs.decode(raw, reflect.ValueOf(v).Elem())
+}
+
+// jsonMember is a member of a jsonObject.
+type jsonMember struct {
+	name  string
+	value interface{}
+}
+
+// jsonObject is a JSON object that keeps the order of its members.
+type jsonObject []jsonMember
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(m.name)
+		value, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonField is a struct field as encoding/json sees it.
+type jsonField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	omitZero  bool
+	quoted    bool // Whether the value is encoded in a string, by the string option.
+	tagged    bool // Whether the name is from the json tag.
+}
+
+// fieldName returns the name of a field on the wire. A nil cs keeps the default names.
+func (cs *codecSet) fieldName(f jsonField) string {
+	if cs == nil || cs.naming == nil || f.tagged {
+		return f.name
+	}
+	return cs.naming(f.name)
+}
+
+// jsonFieldsCache caches the results of jsonFields.
+var jsonFieldsCache sync.Map // reflect.Type -> []jsonField
+
+// jsonFields returns the fields of struct type t that encoding/json encodes, by the
+// same rules: fields of embedded structs and struct pointers are promoted, and of
+// fields with the same name, the shallowest wins, then the tagged one, and if that
+// still leaves several, none is encoded.
+func jsonFields(t reflect.Type) []jsonField {
+	if fs, ok := jsonFieldsCache.Load(t); ok {
+		return fs.([]jsonField)
+	}
+
+	// Embedded structs are scanned breadth first, one depth at a time.
+	type embedded struct {
+		t     reflect.Type
+		index []int
+	}
+	var fields []jsonField
+	next := []embedded{{t: t}}
+	count := map[reflect.Type]int{}
+	visited := map[reflect.Type]bool{}
+	for len(next) > 0 {
+		current := next
+		next = nil
+		currentCount := count
+		count = map[reflect.Type]int{}
+		for _, e := range current {
+			if visited[e.t] {
+				continue
+			}
+			visited[e.t] = true
+			for i := 0; i < e.t.NumField(); i++ {
+				f := e.t.Field(i)
+				ft := f.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if !f.IsExported() && !(f.Anonymous && ft.Kind() == reflect.Struct) {
+					continue
+				}
+				tag := f.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				index := append(append([]int{}, e.index...), i)
+				if !f.IsExported() && name != "" {
+					continue
+				}
+				if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+					count[ft]++
+					if count[ft] == 1 {
+						next = append(next, embedded{ft, index})
+					}
+					continue
+				}
+				tagged := name != ""
+				if !tagged {
+					name = f.Name
+				}
+				quoted := false
+				if strings.Contains(","+opts+",", ",string,") {
+					switch ft.Kind() {
+					case reflect.Bool, reflect.String,
+						reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+						reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+						reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+						reflect.Float32, reflect.Float64:
+						quoted = true
+					}
+				}
+				field := jsonField{
+					name:      name,
+					index:     index,
+					omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+					omitZero:  strings.Contains(","+opts+",", ",omitzero,"),
+					quoted:    quoted,
+					tagged:    tagged,
+				}
+				fields = append(fields, field)
+				if currentCount[e.t] > 1 {
+					// The struct is embedded several times at this depth, so its fields
+					// conflict with themselves.
+					fields = append(fields, field)
+				}
+			}
+		}
+	}
+
+	// Keeps the dominant field of each name.
+	sort.SliceStable(fields, func(i, j int) bool {
+		a, b := fields[i], fields[j]
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		if len(a.index) != len(b.index) {
+			return len(a.index) < len(b.index)
+		}
+		return a.tagged && !b.tagged
+	})
+	var result []jsonField
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].name == fields[i].name {
+			j++
+		}
+		if j == i+1 || len(fields[i+1].index) > len(fields[i].index) ||
+			fields[i].tagged != fields[i+1].tagged {
+			result = append(result, fields[i])
+		}
+		i = j
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].index, result[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	jsonFieldsCache.Store(t, result)
+	return result
+}
+
+// fieldValue returns the field of struct value v at index, or false if it is in a nil
+// embedded pointer.
+func fieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	f, err := v.FieldByIndexErr(index)
+	return f, err == nil
+}
+
+// settableField returns the field of struct value v, which is settable, at index,
+// allocating the nil embedded pointers that it is in. Like encoding/json, fails for
+// pointers to unexported struct types, which cannot be set.
+func settableField(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf(
+						"cannot set embedded pointer to unexported struct: %v",
+						v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+// isEmptyValue reports whether v is empty by the rules of omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// isZeroValue reports whether v is zero by the rules of omitzero: by its IsZero method
+// if it has one, or else if it is its type's zero value.
+func isZeroValue(v reflect.Value) bool {
+	if z, ok := v.Interface().(interface{ IsZero() bool }); ok {
+		return (v.Kind() != reflect.Ptr || !v.IsNil()) && z.IsZero()
+	}
+	return v.IsZero()
+}
+
+// encodeMapKey returns the JSON object key of a map key.
+func encodeMapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		text, err := tm.MarshalText()
+		return string(text), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported map key type: %v", k.Type())
+}
+
+// decodeMapKey decodes a JSON object key into k, which is settable.
+func decodeMapKey(s string, k reflect.Value) error {
+	if k.Kind() == reflect.String {
+		k.SetString(s)
+		return nil
+	}
+	if tu, ok := k.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return tu.UnmarshalText([]byte(s))
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, k.Type().Bits())
+		k.SetInt(n)
+		return err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, k.Type().Bits())
+		k.SetUint(n)
+		return err
+	}
+	return fmt.Errorf("unsupported map key type: %v", k.Type())
+}
+
+// TypeCodec makes the handler encode and decode values of type T with the given
+// functions, wherever they appear in parameters and results. Encode returns a value to
+// encode to JSON instead of the given one, and decode returns a value decoded from its
+// encoded form. This lets types such as UUIDs and decimals be sent consistently,
+// without wrapping every struct that holds them. For example:
+//
+//	rpk.TypeCodec(
+//		func(id uuid.UUID) (interface{}, error) { return id.String(), nil },
+//		func(data rpk.Raw) (uuid.UUID, error) {
+//			var s string
+//			if err := json.Unmarshal(data, &s); err != nil {
+//				return uuid.UUID{}, err
+//			}
+//			return uuid.Parse(s)
+//		},
+//	)
+//
+// Values inside fields of interface types are not affected.
+func TypeCodec[T any](encode func(T) (interface{}, error),
+	decode func(data Raw) (T, error)) Option {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	return func(o *options) {
+		o.codecsOf().m[t] = &codec{
+			encode: func(v reflect.Value) (interface{}, error) {
+				return encode(v.Interface().(T))
+			},
+			decode: func(data []byte, v reflect.Value) error {
+				val, err := decode(data)
+				if err != nil {
+					return err
+				}
+				v.Set(reflect.ValueOf(&val).Elem())
+				return nil
+			},
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package rpk
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type codecID [2]byte
+
+type codecItem struct {
+	ID     codecID
+	Parent *codecID `json:"parent,omitempty"`
+	Tags   map[codecID]string
+	Name   string `json:"-"`
+	codecEmbedded
+}
+
+type codecEmbedded struct {
+	Others []codecID `json:"others"`
+}
+
+func (id codecID) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%02x%02x", id[0], id[1])), nil
+}
+
+func (id *codecID) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "%02x%02x", &id[0], &id[1])
+	return err
+}
+
+func TestTypeCodec(t *testing.T) {
+	h := New(TypeCodec(
+		func(id codecID) (interface{}, error) {
+			return fmt.Sprintf("id-%02x%02x", id[0], id[1]), nil
+		},
+		func(data Raw) (codecID, error) {
+			var s string
+			var id codecID
+			if err := json.Unmarshal(data, &s); err != nil {
+				return id, err
+			}
+			if !strings.HasPrefix(s, "id-") {
+				return id, fmt.Errorf("bad id %q", s)
+			}
+			err := id.UnmarshalText([]byte(s[3:]))
+			return id, err
+		},
+	))
+	err := h.Register("Echo", func(i *codecItem) *codecItem { return i })
+	if err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+
+	tests := []struct {
+		param  string
+		result string
+	}{
+		{`{"ID":"id-0102","parent":"id-0304","Tags":{"0506":"a"},"others":["id-0708"]}`,
+			`{"ID":"id-0102","parent":"id-0304","Tags":{"0506":"a"},"others":["id-0708"]}`},
+		{`{"ID":"id-0102"}`, `{"ID":"id-0102","Tags":null,"others":null}`},
+		{`{"ID":"0102"}`,
+			`{"error":"Error decoding JSON: field ID: bad id \"0102\""}`},
+	}
+	for _, test := range tests {
+		if result := callAPI(h, "Echo", test.param); result != test.result {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.param, result,
+				test.result)
+		}
+	}
+}
+
+type fieldsBase struct {
+	ID   int64
+	Name string
+}
+
+type fieldsOuter struct {
+	*fieldsBase
+	Name  string
+	Count int `json:",string"`
+}
+
+type fieldsLeft struct {
+	X int
+	Y int `json:"y"`
+}
+
+type fieldsRight struct {
+	X int
+	Y int
+	Z string `json:"z,string"`
+}
+
+type fieldsConflict struct {
+	fieldsLeft
+	*fieldsRight
+	On *bool `json:",string"`
+}
+
+type fieldsTwice struct {
+	A fieldsLeft
+	B struct{ fieldsLeft }
+}
+
+func TestJSONFields(t *testing.T) {
+	// Encoded like encoding/json, with codecs applied to all structs.
+	cs := &codecSet{naming: func(name string) string { return name }}
+	on := true
+	tests := []interface{}{
+		fieldsOuter{&fieldsBase{5, "inner"}, "outer", 3},
+		fieldsOuter{Name: "outer"},
+		fieldsConflict{fieldsLeft{1, 2}, &fieldsRight{3, 4, "z"}, &on},
+		fieldsConflict{},
+		fieldsTwice{},
+	}
+	for _, test := range tests {
+		want, err := json.Marshal(test)
+		if err != nil {
+			t.Fatalf("Failed to marshal %+v: %v", test, err)
+		}
+		got, err := json.Marshal(codecValue{test, cs})
+		if err != nil {
+			t.Fatalf("Failed to encode %+v: %v", test, err)
+		}
+		if string(got) != string(want) {
+			t.Fatalf("Bad result for %+v: %s, expected %s.", test, got, want)
+		}
+
+		// Decodes like encoding/json too.
+		v := reflect.New(reflect.TypeOf(test))
+		jv := reflect.New(reflect.TypeOf(test))
+		err = cs.decode(got, v.Elem())
+		jerr := json.Unmarshal(want, jv.Interface())
+		if (err == nil) != (jerr == nil) {
+			t.Fatalf("Bad error for decoding %s: %v, expected %v.", got, err, jerr)
+		}
+		if !reflect.DeepEqual(v.Interface(), jv.Interface()) {
+			t.Fatalf("Bad result for decoding %s: %+v, expected %+v.", got, v.Elem(),
+				jv.Elem())
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package rpk
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Standard errors, for failures that handlers and clients should agree on. Functions
+// return them, typically wrapped with details, like:
+//
+//	return fmt.Errorf("user %d: %w", id, rpk.ErrNotFound)
+//
+// They are RPCErrors, with the codes "not_found", "permission_denied",
+// "invalid_argument" and "unavailable", and the Go client turns remote errors with these
+// codes back into them, so errors.Is finds them on both sides. HTTPStatus maps them to
+// HTTP status codes.
+var (
+	ErrNotFound         = newCodeError("not_found", "not found", http.StatusNotFound)
+	ErrPermissionDenied = newCodeError("permission_denied", "permission denied",
+		http.StatusForbidden)
+	ErrInvalidArgument = newCodeError("invalid_argument", "invalid argument",
+		http.StatusBadRequest)
+	ErrUnavailable = newCodeError("unavailable", "unavailable",
+		http.StatusServiceUnavailable)
+)
+
+// codeErrors are the standard errors, by code.
+var codeErrors = map[string]error{}
+
+// newCodeError returns a standard error, and adds it to codeErrors.
+func newCodeError(code, msg string, status int) error {
+	err := &codeError{code, msg, status}
+	codeErrors[code] = err
+	return err
+}
+
+// codeError is a standard error.
+type codeError struct {
+	code   string
+	msg    string
+	status int
+}
+
+func (e *codeError) Error() string {
+	return e.msg
+}
+
+func (e *codeError) RPCError() (string, interface{}) {
+	return e.code, nil
+}
+
+// HTTPStatus returns the HTTP status code that corresponds to an error, for servers
+// that report errors of functions by status, like REST gateways. Standard errors map to
+// their statuses, errors of calling functions that do not exist to 404, errors of
+// decoding parameters to 400, calls rejected by Workers to 503, and other errors to
+// 500. Returns 200 for nil. The handler itself reports errors in the body, with status
+// 200.
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var cerr *codeError
+	if errors.As(err, &cerr) {
+		return cerr.status
+	}
+	var callErr *callError
+	if errors.As(err, &callErr) {
+		switch callErr.kind {
+		case errNoSuchFunc:
+			return http.StatusNotFound
+		case errBadParam:
+			return http.StatusBadRequest
+		case errBusy:
+			return http.StatusServiceUnavailable
+		}
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,59 @@
+package rpk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStandardErrors(t *testing.T) {
+	h := New()
+	h.Register("Get", func(id int) error {
+		switch id {
+		case 1:
+			return fmt.Errorf("user %d: %w", id, ErrNotFound)
+		case 2:
+			return ErrPermissionDenied
+		case 3:
+			return ErrInvalidArgument
+		case 4:
+			return ErrUnavailable
+		}
+		return errors.New("other")
+	})
+	server := httptest.NewServer(h)
+	defer server.Close()
+	c := NewClient(server.URL, nil)
+
+	tests := []struct {
+		id     int
+		want   error
+		status int
+	}{
+		{1, ErrNotFound, 404},
+		{2, ErrPermissionDenied, 403},
+		{3, ErrInvalidArgument, 400},
+		{4, ErrUnavailable, 503},
+		{5, nil, 500},
+	}
+	for _, test := range tests {
+		err := c.Call(context.Background(), "Get", test.id, nil)
+		if test.want != nil && !errors.Is(err, test.want) {
+			t.Fatalf("Bad error for %d: %v, expected %v.", test.id, err, test.want)
+		}
+		if test.want == nil && errors.Unwrap(err) != nil {
+			t.Fatalf("Bad error for %d: %#v, expected no standard error.", test.id, err)
+		}
+		if got := HTTPStatus(errors.Unwrap(err)); test.want != nil && got != test.status {
+			t.Fatalf("Bad status for %d: %d, expected %d.", test.id, got, test.status)
+		}
+	}
+	if got := HTTPStatus(errors.New("other")); got != 500 {
+		t.Fatalf("Bad status: %d, expected 500.", got)
+	}
+	if got := HTTPStatus(newCallError(errNoSuchFunc, "no")); got != 404 {
+		t.Fatalf("Bad status: %d, expected 404.", got)
+	}
+}
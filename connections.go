@@ -0,0 +1,69 @@
+package rpk
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// A Connection is a client subscription to broadcast messages, which stays open until
+// the client leaves. See Broadcast.
+type Connection struct {
+	ID        string    `json:"id"`
+	User      string    `json:"user,omitempty"` // As identified by ConnectionUser.
+	Topics    []string  `json:"topics"`
+	Connected time.Time `json:"connected"`
+}
+
+// ConnectionUser makes the handler identify the user of each connection with user, for
+// example by a session cookie, for presence features like showing who else is viewing
+// a page.
+func ConnectionUser(user func(r *http.Request) string) Option {
+	return func(o *options) {
+		o.connectionUser = user
+	}
+}
+
+// OnConnect makes the handler call f when a client connects, for example to notify
+// others with Broadcast.
+func OnConnect(f func(c Connection)) Option {
+	return func(o *options) {
+		o.onConnect = f
+	}
+}
+
+// OnDisconnect makes the handler call f when a client disconnects. See OnConnect.
+func OnDisconnect(f func(c Connection)) Option {
+	return func(o *options) {
+		o.onDisconnect = f
+	}
+}
+
+// Connections returns the open connections, by connection time.
+func (h *Handler) Connections() []Connection {
+	h.subs.mu.Lock()
+	result := make([]Connection, 0, len(h.subs.m))
+	for sub := range h.subs.m {
+		result = append(result, sub.conn)
+	}
+	h.subs.mu.Unlock()
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Connected.Before(result[j].Connected)
+	})
+	return result
+}
+
+// Disconnect closes the connection with the given ID. Returns false if there is no such
+// connection. Note that the Javascript client reconnects, unless the handler refuses it,
+// for example with AuthorizeTopics.
+func (h *Handler) Disconnect(id string) bool {
+	h.subs.mu.Lock()
+	defer h.subs.mu.Unlock()
+	for sub := range h.subs.m {
+		if sub.conn.ID == id {
+			sub.disconnect()
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package rpk
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestConnections(t *testing.T) {
+	connected := make(chan Connection, 1)
+	disconnected := make(chan Connection, 1)
+	h := New(ConnectionUser(func(r *http.Request) string {
+		return r.Header.Get("User")
+	}), OnConnect(func(c Connection) {
+		connected <- c
+	}), OnDisconnect(func(c Connection) {
+		disconnected <- c
+	}))
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+"?func=events&topics=a,b", nil)
+	req.Header.Set("User", "bob")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("Failed to subscribe:", err)
+	}
+	defer res.Body.Close()
+
+	c := <-connected
+	if c.User != "bob" || !reflect.DeepEqual(c.Topics, []string{"a", "b"}) {
+		t.Fatalf("Bad connection: %+v, expected user %q and topics [a b].", c, "bob")
+	}
+	if conns := h.Connections(); len(conns) != 1 || conns[0].ID != c.ID {
+		t.Fatalf("Bad connections: %+v, expected [%+v].", conns, c)
+	}
+
+	if h.Disconnect("nope") {
+		t.Fatal("Disconnect(nope) succeeded, expected false.")
+	}
+	if !h.Disconnect(c.ID) {
+		t.Fatalf("Disconnect(%q) failed, expected true.", c.ID)
+	}
+	if _, err := io.ReadAll(res.Body); err != nil {
+		t.Fatal("Failed to read until disconnected:", err)
+	}
+	if d := <-disconnected; d.ID != c.ID {
+		t.Fatalf("Bad disconnected ID: %q, expected %q.", d.ID, c.ID)
+	}
+	if conns := h.Connections(); len(conns) != 0 {
+		t.Fatalf("Bad connections: %+v, expected none.", conns)
+	}
+}
@@ -0,0 +1,194 @@
+package rpk
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// A Snapshot describes the functions of a handler, for checking that changes to an API
+// do not break its deployed clients. Snapshots encode to JSON, so they can be kept in
+// files, typically committed along with the API and checked by a test with
+// VerifyCompatibility.
+type Snapshot struct {
+	Funcs []FuncSnapshot `json:"funcs"` // By name.
+}
+
+// A FuncSnapshot describes a function of a handler.
+type FuncSnapshot struct {
+	Name   string      `json:"name"`
+	Param  *JSONSchema `json:"param,omitempty"`  // Nil if the function takes no input.
+	Result *JSONSchema `json:"result,omitempty"` // Nil if it has no value output.
+
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+}
+
+// Snapshot returns a snapshot of the handler's functions, without internal ones.
+func (h *Handler) Snapshot() *Snapshot {
+	fs := h.table()
+	result := &Snapshot{Funcs: []FuncSnapshot{}}
+	for _, name := range h.publicNames(fs) {
+		m := fs[name]
+		f := FuncSnapshot{Name: name, Deprecated: h.opts.deprecated[name]}
+		if m.hasIn {
+			f.Param = h.schemaFor(m.in)
+		}
+		if m.hasOut {
+			var out reflect.Type
+			if m.value.IsValid() {
+				out = m.value.Type().Out(m.valOut)
+			}
+			f.Result = h.schemaFor(out)
+		}
+		result.Funcs = append(result.Funcs, f)
+	}
+	return result
+}
+
+// schemaFor returns the schema of type t with the handler's field naming, or a schema of
+// any value if t is nil.
+func (h *Handler) schemaFor(t reflect.Type) *JSONSchema {
+	if t == nil {
+		return &JSONSchema{}
+	}
+	return schemaFor(t, h.opts.codecs)
+}
+
+// VerifyCompatibility checks that clients of an API whose functions are described by
+// old can call the API described by new. Returns a MethodErrors with the breaking
+// changes of each function, or nil if there are none. Breaking changes are removing
+// functions, adding or removing parameters, changing types, removing fields of
+// results, removing allowed values of parameters and adding values to results. Adding
+// functions, adding fields, and removing fields of parameters, which the handler
+// ignores, are allowed.
+func VerifyCompatibility(old, new *Snapshot) error {
+	newFuncs := map[string]FuncSnapshot{}
+	for _, f := range new.Funcs {
+		newFuncs[f.Name] = f
+	}
+	var errs MethodErrors
+	for _, of := range old.Funcs {
+		nf, ok := newFuncs[of.Name]
+		if !ok {
+			errs = append(errs, &MethodError{of.Name, fmt.Errorf("removed")})
+			continue
+		}
+		var problems []string
+		switch {
+		case of.Param == nil && nf.Param != nil:
+			problems = append(problems, "added a parameter")
+		case of.Param != nil && nf.Param == nil:
+			problems = append(problems, "removed the parameter")
+		case of.Param != nil:
+			c := &schemaComparer{old: of.Param, new: nf.Param, param: true,
+				seen: map[[2]*JSONSchema]bool{}}
+			c.compare(of.Param, nf.Param, "parameter")
+			problems = append(problems, c.problems...)
+		}
+		switch {
+		case of.Result != nil && nf.Result == nil:
+			problems = append(problems, "removed the result")
+		case of.Result != nil:
+			c := &schemaComparer{old: of.Result, new: nf.Result,
+				seen: map[[2]*JSONSchema]bool{}}
+			c.compare(of.Result, nf.Result, "result")
+			problems = append(problems, c.problems...)
+		}
+		if problems != nil {
+			errs = append(errs, &MethodError{of.Name,
+				fmt.Errorf("%s", strings.Join(problems, "; "))})
+		}
+	}
+	if errs == nil {
+		return nil
+	}
+	return errs
+}
+
+// schemaComparer finds the breaking changes between an old and a new schema.
+type schemaComparer struct {
+	old, new *JSONSchema // Roots, with the definitions.
+	param    bool        // Whether the schemas are of a parameter, rather than a result.
+	seen     map[[2]*JSONSchema]bool
+	problems []string
+}
+
+// resolve returns the definition that s refers to, or s if it is not a reference.
+func resolve(s, root *JSONSchema) *JSONSchema {
+	for s != nil && s.Ref != "" {
+		s = root.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")]
+	}
+	return s
+}
+
+// compare adds the breaking changes between schemas o and n, at the given path.
+func (c *schemaComparer) compare(o, n *JSONSchema, path string) {
+	o, n = resolve(o, c.old), resolve(n, c.new)
+	if o == nil || n == nil || c.seen[[2]*JSONSchema{o, n}] {
+		return
+	}
+	c.seen[[2]*JSONSchema{o, n}] = true
+	if o.Type != n.Type && n.Type != "" {
+		c.problems = append(c.problems, fmt.Sprintf("%s: changed type from %s to %s",
+			path, typeName(o), typeName(n)))
+		return
+	}
+	if len(n.Enum) > 0 {
+		if c.param {
+			if v := missing(o.Enum, n.Enum); v != nil {
+				c.problems = append(c.problems, fmt.Sprintf("%s: removed allowed value %v",
+					path, v))
+			}
+		} else if v := missing(n.Enum, o.Enum); v != nil {
+			c.problems = append(c.problems, fmt.Sprintf("%s: added value %v", path, v))
+		}
+	}
+	if o.Items != nil && n.Items != nil {
+		c.compare(o.Items, n.Items, path+"[]")
+	}
+	if o.AdditionalProperties != nil && n.AdditionalProperties != nil {
+		c.compare(o.AdditionalProperties, n.AdditionalProperties, path+"[]")
+	}
+	names := make([]string, 0, len(o.Properties))
+	for name := range o.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		np, ok := n.Properties[name]
+		if !ok {
+			if !c.param {
+				c.problems = append(c.problems, fmt.Sprintf("%s: removed field %s", path,
+					name))
+			}
+			continue
+		}
+		c.compare(o.Properties[name], np, path+"."+name)
+	}
+}
+
+// typeName returns the type of a schema, for error messages.
+func typeName(s *JSONSchema) string {
+	if s.Type == "" {
+		return "any"
+	}
+	return s.Type
+}
+
+// missing returns the first of the given values that are not in set, or nil if all are.
+func missing(values, set []interface{}) interface{} {
+	for _, v := range values {
+		found := false
+		for _, s := range set {
+			if reflect.DeepEqual(v, s) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package rpk
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestHandler_Snapshot(t *testing.T) {
+	type user struct {
+		UserID int
+		Color  testColor
+	}
+	h := New(FieldNaming(SnakeCase))
+	h.Register("Get", func(id int) user { return user{} })
+	h.Register("Ping", func() {})
+
+	data, err := json.Marshal(h.Snapshot())
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	want := `{"funcs":[{"name":"Get","param":{"type":"integer"},"result":{"$ref":` +
+		`"#/$defs/user","$defs":{"user":{"type":"object","properties":{"color":` +
+		`{"type":"string","enum":["red","green"]},"user_id":{"type":"integer"}}}}}},` +
+		`{"name":"Ping"}]}`
+	if string(data) != want {
+		t.Fatalf("Bad result: %s, expected %s.", data, want)
+	}
+}
+
+func TestVerifyCompatibility(t *testing.T) {
+	type userV1 struct {
+		Name  string
+		Age   int
+		Color testColor
+	}
+	type userV2 struct {
+		Name  string
+		Color testColor
+		Email string
+	}
+	type userV3 struct {
+		Name  string
+		Age   string
+		Color testColor
+	}
+	snapshot := func(funcs map[string]interface{}) *Snapshot {
+		h := New()
+		for name, f := range funcs {
+			if err := h.Register(name, f); err != nil {
+				t.Fatalf("Register(%q) failed: %v", name, err)
+			}
+		}
+		// Round trip, like snapshots read from files.
+		data, err := json.Marshal(h.Snapshot())
+		if err != nil {
+			t.Fatalf("Marshal() failed: %v", err)
+		}
+		result := &Snapshot{}
+		if err := json.Unmarshal(data, result); err != nil {
+			t.Fatalf("Unmarshal() failed: %v", err)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		old, new map[string]interface{}
+		want     []string // Substrings of the error, none for no error.
+	}{
+		{"same", map[string]interface{}{"A": func(userV1) userV1 { return userV1{} }},
+			map[string]interface{}{"A": func(userV1) userV1 { return userV1{} }}, nil},
+		{"added func", map[string]interface{}{"A": func() {}},
+			map[string]interface{}{"A": func() {}, "B": func() {}}, nil},
+		{"removed func", map[string]interface{}{"A": func() {}, "B": func() {}},
+			map[string]interface{}{"A": func() {}}, []string{"'B': removed"}},
+		{"param fields", map[string]interface{}{"A": func(userV1) {}},
+			map[string]interface{}{"A": func(userV2) {}}, nil},
+		{"result fields", map[string]interface{}{"A": func() userV1 { return userV1{} }},
+			map[string]interface{}{"A": func() userV2 { return userV2{} }},
+			[]string{"result: removed field Age"}},
+		{"type", map[string]interface{}{"A": func(userV1) {}},
+			map[string]interface{}{"A": func(userV3) {}},
+			[]string{"parameter.Age: changed type from integer to string"}},
+		{"added param", map[string]interface{}{"A": func() {}},
+			map[string]interface{}{"A": func(int) {}}, []string{"added a parameter"}},
+		{"removed result", map[string]interface{}{"A": func() int { return 0 }},
+			map[string]interface{}{"A": func() {}}, []string{"removed the result"}},
+		{"list", map[string]interface{}{"A": func([]int) {}},
+			map[string]interface{}{"A": func([]string) {}},
+			[]string{"parameter[]: changed type"}},
+		{"recursive", map[string]interface{}{"A": func(testNode) testNode {
+			return testNode{}
+		}}, map[string]interface{}{"A": func(testNode) testNode {
+			return testNode{}
+		}}, nil},
+	}
+	for _, test := range tests {
+		err := VerifyCompatibility(snapshot(test.old), snapshot(test.new))
+		if test.want == nil {
+			if err != nil {
+				t.Fatalf("VerifyCompatibility(%s) failed: %v", test.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Fatalf("VerifyCompatibility(%s) succeeded, expected error.", test.name)
+		}
+		for _, want := range test.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Fatalf("Bad error for %s: %q, expected %q.", test.name, err, want)
+			}
+		}
+	}
+}
+
+func TestVerifyCompatibility_enums(t *testing.T) {
+	colors := func(values ...interface{}) *Snapshot {
+		return &Snapshot{Funcs: []FuncSnapshot{{Name: "A",
+			Param:  &JSONSchema{Type: "string", Enum: values},
+			Result: &JSONSchema{Type: "string", Enum: values}}}}
+	}
+	err := VerifyCompatibility(colors("red", "green"), colors("red"))
+	want := "parameter: removed allowed value green"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("Bad error for removed value: %v.", err)
+	}
+	err = VerifyCompatibility(colors("red"), colors("red", "green"))
+	want = "result: added value green"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("Bad error for added value: %v.", err)
+	}
+}
@@ -0,0 +1,40 @@
+package rpk
+
+import (
+	"net/http"
+	"time"
+)
+
+// A Deprecation describes why a function is deprecated, and when it will be removed.
+type Deprecation struct {
+	Message string    `json:"message,omitempty"`
+	Sunset  time.Time `json:"sunset,omitzero"` // Zero if no date is set.
+}
+
+// DeprecationHeader is the response header that marks calls to deprecated functions,
+// with the deprecation message. The Javascript client logs a warning the first time it
+// calls each deprecated function.
+const DeprecationHeader = "Rpk-Deprecated"
+
+// Deprecated marks the named function as deprecated, with a message for its callers,
+// such as which function to call instead, and the date after which it may be removed,
+// or a zero time for none. The message is sent in a response header, so it should be
+// short plain ASCII text. Responses of deprecated functions have the DeprecationHeader,
+// and a Sunset header with the date, and snapshots of the handler include the
+// deprecation.
+func Deprecated(name, message string, sunset time.Time) Option {
+	return func(o *options) {
+		if o.deprecated == nil {
+			o.deprecated = map[string]*Deprecation{}
+		}
+		o.deprecated[name] = &Deprecation{message, sunset}
+	}
+}
+
+// setHeaders marks a response as a response of a deprecated function.
+func (d *Deprecation) setHeaders(w http.ResponseWriter) {
+	w.Header().Set(DeprecationHeader, d.Message)
+	if !d.Sunset.IsZero() {
+		w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+}
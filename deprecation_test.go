@@ -0,0 +1,40 @@
+package rpk
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeprecated(t *testing.T) {
+	sunset := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := New(Deprecated("Old", "Call New instead.", sunset))
+	h.Register("Old", func() int { return 1 })
+	h.Register("New", func() int { return 2 })
+
+	tests := []struct {
+		name, message, sunset string
+	}{
+		{"Old", "Call New instead.", "Fri, 01 Jan 2027 00:00:00 GMT"},
+		{"New", "", ""},
+	}
+	for _, test := range tests {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, apiRequest(test.name, ""))
+		if got := res.Header().Get(DeprecationHeader); got != test.message {
+			t.Fatalf("Bad message for %s: %q, expected %q.", test.name, got, test.message)
+		}
+		if got := res.Header().Get("Sunset"); got != test.sunset {
+			t.Fatalf("Bad sunset for %s: %q, expected %q.", test.name, got, test.sunset)
+		}
+	}
+
+	funcs := h.Snapshot().Funcs
+	d := funcs[1].Deprecated
+	if funcs[1].Name != "Old" || d == nil || !d.Sunset.Equal(sunset) {
+		t.Fatalf("Bad snapshot of Old: %+v.", funcs[1])
+	}
+	if funcs[0].Deprecated != nil {
+		t.Fatalf("Bad snapshot of New: %+v.", funcs[0])
+	}
+}
@@ -0,0 +1,32 @@
+package rpk
+
+import "context"
+
+// A Dispatcher provides a static dispatch table of its RPC functions, which replaces
+// reflection when calling them. The rpkgen command generates implementations of this
+// interface for API types:
+//
+//	go run github.com/fluhus/rpk/cmd/rpkgen -type=myAPI
+//
+// When a Dispatcher is given to HandlerFunc, only the functions in its table are
+// exposed, filtered by Include, Exclude and NoPromoted. With the Nested option, the
+// same goes for Dispatchers in the fields of registered objects, and the methods of the
+// fields of Dispatchers are found by reflection. Functions whose values the options
+// convert, like with unions, codecs and enums, are still called by reflection.
+type Dispatcher interface {
+	RPKDispatch() map[string]StaticFunc
+}
+
+// A StaticFunc is a statically dispatched RPC function.
+type StaticFunc struct {
+	// HasInput tells whether the function takes an input parameter.
+	HasInput bool
+
+	// HasOutput tells whether the function has a value output.
+	HasOutput bool
+
+	// Call decodes the function's input parameter using dec if it takes one, calls the
+	// function with ctx if it takes one and returns its value output (nil if none) and
+	// error output. Decoding errors should be returned as is.
+	Call func(ctx context.Context, dec Decoder) (interface{}, error)
+}
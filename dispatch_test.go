@@ -0,0 +1,175 @@
+package rpk
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// staticType is a hand written equivalent of rpkgen's output.
+type staticType struct{ testType }
+
+func (a staticType) RPKDispatch() map[string]StaticFunc {
+	return map[string]StaticFunc{
+		"FooStr": {
+			HasOutput: true,
+			Call: func(ctx context.Context, dec Decoder) (interface{}, error) {
+				return a.FooStr(), nil
+			},
+		},
+		"Bar": {
+			HasInput:  true,
+			HasOutput: true,
+			Call: func(ctx context.Context, dec Decoder) (interface{}, error) {
+				var in int
+				if err := dec.Decode(&in); err != nil {
+					return nil, err
+				}
+				return a.Bar(in)
+			},
+		},
+		"FooErr": {
+			Call: func(ctx context.Context, dec Decoder) (interface{}, error) {
+				return nil, a.FooErr()
+			},
+		},
+	}
+}
+
+// staticPtrType has a method with a pointer input, dispatched like rpkgen writes it.
+type staticPtrType struct{}
+
+func (staticPtrType) IsNil(th *thing) bool {
+	return th == nil
+}
+
+func (a staticPtrType) RPKDispatch() map[string]StaticFunc {
+	return map[string]StaticFunc{
+		"IsNil": {
+			HasInput:  true,
+			HasOutput: true,
+			Call: func(ctx context.Context, dec Decoder) (interface{}, error) {
+				var in *thing
+				if err := dec.Decode(&in); err != nil {
+					return nil, err
+				}
+				return a.IsNil(in), nil
+			},
+		},
+	}
+}
+
+func TestFuncs_staticNull(t *testing.T) {
+	h := New()
+	if err := h.RegisterObject(staticPtrType{}); err != nil {
+		t.Fatal("Failed to register object:", err)
+	}
+	if h.table()["IsNil"].static == nil {
+		t.Fatal("Expected IsNil to be called statically.")
+	}
+	tests := []struct {
+		param  string
+		result string
+	}{
+		{`null`, "true"},
+		{`{"I":1}`, "false"},
+	}
+	for _, test := range tests {
+		if got := callAPI(h, "IsNil", test.param); got != test.result {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.param, got, test.result)
+		}
+	}
+}
+
+func TestFuncs_static(t *testing.T) {
+	f, err := newFuncs(staticType{}, newOptions(nil))
+	if err != nil {
+		t.Fatal("Failed to create funcs:", err)
+	}
+	if len(f) != 3 {
+		t.Fatalf("Expected funcs to be of length 3, instead got %d.", len(f))
+	}
+
+	for _, test := range tests {
+		if f[test.f] == nil {
+			continue
+		}
+		buf := bytes.NewBuffer(nil)
+		f.call(context.Background(), buf, test.f, strings.NewReader(test.arg), newJSONDecoder)
+		result := buf.String()
+
+		if test.shouldErr && !isJSONError(result) {
+			t.Fatal("Expected error but got nil in test:", test)
+		}
+		if !test.shouldErr && isJSONError(result) {
+			t.Fatal("Expected success but got error in test:", test, result)
+		}
+		if !test.shouldErr && result != test.result {
+			t.Fatalf("Bad result for test: %v Got: %s", test, result)
+		}
+	}
+}
+
+// staticNested has a field with a dispatch table.
+type staticNested struct {
+	testType
+	Static staticType
+}
+
+func TestFuncs_staticNested(t *testing.T) {
+	h := New(Nested())
+	if err := h.RegisterObject(staticType{}); err != nil {
+		t.Fatal("Failed to register object:", err)
+	}
+	if got := h.table().names(); len(got) != 3 || h.table()["Bar"].static == nil {
+		t.Fatalf("Bad functions: %v, expected Bar, FooErr and FooStr, statically.", got)
+	}
+
+	h = New(Nested())
+	if err := h.RegisterObject(&staticNested{}); err != nil {
+		t.Fatal("Failed to register object:", err)
+	}
+	if m := h.table()["Static.Bar"]; m == nil || m.static == nil {
+		t.Fatalf("Bad functions: %v, expected Static.Bar, statically.", h.table().names())
+	}
+	if h.table()["Static.Foo"] != nil || h.table()["Foo"] == nil {
+		t.Fatalf("Bad functions: %v, expected Foo but not Static.Foo.",
+			h.table().names())
+	}
+	if got := callAPI(h, "Static.Bar", "3"); got != `"Bar 3"` {
+		t.Fatalf("Bad result: %q, expected %q.", got, `"Bar 3"`)
+	}
+}
+
+func TestFuncs_staticOptions(t *testing.T) {
+	var skipped []string
+	f, err := newFuncs(staticType{}, newOptions([]Option{Exclude("FooErr"),
+		OnSkip(func(err *MethodError) { skipped = append(skipped, err.Method) })}))
+	if err != nil {
+		t.Fatal("Failed to create funcs:", err)
+	}
+	if len(f) != 2 || f["FooErr"] != nil {
+		t.Fatalf("Bad functions: %v, expected FooStr and Bar.", f.names())
+	}
+	if len(skipped) != 1 || skipped[0] != "FooErr" {
+		t.Fatalf("Bad skipped functions: %v, expected FooErr.", skipped)
+	}
+	if f, _ := newFuncs(staticType{}, newOptions([]Option{NoPromoted()})); len(f) != 0 {
+		t.Fatalf("Bad functions: %v, expected none.", f.names())
+	}
+
+	// Functions whose values have codecs are called by reflection.
+	h := New(TypeCodec(func(s string) (interface{}, error) {
+		return strings.ToUpper(s), nil
+	}, func(data Raw) (string, error) { return string(data), nil }))
+	if err := h.RegisterObject(staticType{}); err != nil {
+		t.Fatal("Failed to register object:", err)
+	}
+	if h.table()["FooStr"].static != nil || h.table()["FooErr"].static == nil {
+		t.Fatal("Expected FooStr to be called by reflection, and FooErr statically.")
+	}
+	if got := callAPI(h, "FooStr", ""); got != `"FOO!"` {
+		t.Fatalf("Bad result: %q, expected %q.", got, `"FOO!"`)
+	}
+}
@@ -0,0 +1,154 @@
+package rpk
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"io"
+	"reflect"
+)
+
+// encodeOptions configures how the handler encodes results. Nil means the defaults of
+// encoding/json.
+type encodeOptions struct {
+	noEscapeHTML bool
+	prefix       string
+	indent       string
+	omitNull     bool
+}
+
+// newEncoder returns a JSON encoder that writes to w, configured by enc.
+func (enc *encodeOptions) newEncoder(w io.Writer) *json.Encoder {
+	e := json.NewEncoder(w)
+	if enc != nil {
+		e.SetEscapeHTML(!enc.noEscapeHTML)
+		e.SetIndent(enc.prefix, enc.indent)
+	}
+	return e
+}
+
+// marshal encodes v like json.Marshal, but with the escaping of enc. Indentation is
+// left to the encoder of the enclosing value.
+func (enc *encodeOptions) marshal(v interface{}) ([]byte, error) {
+	if enc == nil || !enc.noEscapeHTML {
+		return json.Marshal(v)
+	}
+	buf := bytes.NewBuffer(nil)
+	e := json.NewEncoder(buf)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// omits returns whether a result value should be left out of the response.
+func (enc *encodeOptions) omits(v interface{}) bool {
+	if enc == nil || !enc.omitNull {
+		return false
+	}
+	if v == nil {
+		return true
+	}
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return val.IsNil()
+	}
+	return false
+}
+
+// encodeOf returns the encoding options, creating them if needed.
+func (o *options) encodeOf() *encodeOptions {
+	if o.encode == nil {
+		o.encode = &encodeOptions{}
+	}
+	return o.encode
+}
+
+// NoEscapeHTML makes the handler leave the characters <, > and & as they are in results,
+// instead of escaping them for safe embedding in HTML.
+func NoEscapeHTML() Option {
+	return func(o *options) {
+		o.encodeOf().noEscapeHTML = true
+	}
+}
+
+// Indent makes the handler indent its responses like json.MarshalIndent, which makes them
+// easier to read while debugging.
+func Indent(prefix, indent string) Option {
+	return func(o *options) {
+		o.encodeOf().prefix = prefix
+		o.encodeOf().indent = indent
+	}
+}
+
+// OmitNull makes the handler send an empty response instead of null, when a function
+// returns a nil pointer, slice, map or interface. Clients treat an empty response like
+// the response of a function with no output.
+func OmitNull() Option {
+	return func(o *options) {
+		o.encodeOf().omitNull = true
+	}
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// isEncodable checks if values of the given type can be encoded to JSON, looking into
+// struct fields and elements. Types that implement json.Marshaler or
+// encoding.TextMarshaler are assumed to handle it, and so are interface types, whose
+// values are only known when encoding.
+func isEncodable(t reflect.Type) bool {
+	return findEncodable(t, map[reflect.Type]bool{})
+}
+
+// findEncodable implements isEncodable. Seen holds the types that are already being
+// looked into, for recursive types.
+func findEncodable(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] || isMarshaler(t) {
+		return true
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128,
+		reflect.UnsafePointer:
+		return false
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return findEncodable(t.Elem(), seen)
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+			reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+			reflect.Uint64, reflect.Uintptr:
+		default:
+			if !t.Key().Implements(textMarshalerType) {
+				return false
+			}
+		}
+		return findEncodable(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if (f.PkgPath != "" && !f.Anonymous) || f.Tag.Get("json") == "-" {
+				continue
+			}
+			if !findEncodable(f.Type, seen) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isMarshaler checks if t, or a pointer to it, has custom JSON marshaling.
+func isMarshaler(t reflect.Type) bool {
+	for _, t := range []reflect.Type{t, reflect.PtrTo(t)} {
+		if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package rpk
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeOptions(t *testing.T) {
+	type point struct{ X, Y int }
+	tests := []struct {
+		opts   []Option
+		f      interface{}
+		result string
+	}{
+		{nil, func() string { return "<a>" }, "\"\\u003ca\\u003e\"\n"},
+		{[]Option{NoEscapeHTML()}, func() string { return "<a>" }, "\"<a>\"\n"},
+		{[]Option{Indent("", " ")}, func() point { return point{1, 2} },
+			"{\n \"X\": 1,\n \"Y\": 2\n}\n"},
+		{nil, func() *point { return nil }, "null\n"},
+		{[]Option{OmitNull()}, func() *point { return nil }, ""},
+		{[]Option{OmitNull()}, func() []int { return nil }, ""},
+		{[]Option{OmitNull()}, func() []int { return []int{} }, "[]\n"},
+	}
+	for i, test := range tests {
+		h := New(test.opts...)
+		if err := h.Register("F", test.f); err != nil {
+			t.Fatalf("#%d: Failed to register function: %v", i, err)
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, apiRequest("F", ""))
+		if result := res.Body.String(); result != test.result {
+			t.Fatalf("#%d: Bad result: %q, expected %q.", i, result, test.result)
+		}
+	}
+}
+
+func TestEncodeOptions_jsonrpc(t *testing.T) {
+	h := New(JSONRPC(), NoEscapeHTML())
+	if err := h.Register("F", func() string { return "<a>" }); err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest("POST", "/api",
+		strings.NewReader(`{"jsonrpc":"2.0","method":"F","id":1}`)))
+	want := `{"jsonrpc":"2.0","result":"<a>","id":1}` + "\n"
+	if result := res.Body.String(); result != want {
+		t.Fatalf("Bad result: %q, expected %q.", result, want)
+	}
+}
+
+type encodeRecursive struct {
+	Next *encodeRecursive
+}
+
+type encodeMarshaler struct {
+	f func()
+	C chan int
+}
+
+func (encodeMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+func TestIsEncodable(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want bool
+	}{
+		{0, true},
+		{struct{ A []map[string]int }{}, true},
+		{struct{ A chan int }{}, false},
+		{struct{ A []func() }{}, false},
+		{struct {
+			A chan int `json:"-"`
+			b func()
+		}{}, true},
+		{map[encodeKey]int{}, false},
+		{map[time.Time]int{}, true},
+		{encodeRecursive{}, true},
+		{encodeMarshaler{}, true},
+		{struct{ A interface{} }{}, true},
+	}
+	for _, test := range tests {
+		if got := isEncodable(reflect.TypeOf(test.v)); got != test.want {
+			t.Fatalf("isEncodable(%T)=%v, want %v", test.v, got, test.want)
+		}
+	}
+}
+
+type encodeKey struct{ X, Y int }
@@ -0,0 +1,141 @@
+package rpk
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// enumSets caches the allowed values of enum types.
+var enumSets sync.Map // reflect.Type -> map[interface{}]bool
+
+// enumSet returns the allowed values of enum type t.
+func enumSet(t reflect.Type) map[interface{}]bool {
+	if set, ok := enumSets.Load(t); ok {
+		return set.(map[interface{}]bool)
+	}
+	set := map[interface{}]bool{}
+	for _, v := range reflect.New(t).Interface().(Enum).RPKEnum() {
+		set[v] = true
+	}
+	enumSets.Store(t, set)
+	return set
+}
+
+// isEnum returns whether t is an enum type.
+func isEnum(t reflect.Type) bool {
+	return t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface &&
+		t.Comparable() && (t.Implements(enumType) || reflect.PtrTo(t).Implements(enumType))
+}
+
+// hasEnumsCache caches the results of hasEnums.
+var hasEnumsCache sync.Map // reflect.Type -> bool
+
+// hasEnums returns whether values of type t may contain values of enum types. Fields of
+// interface types, and types with custom JSON marshaling, are not looked into.
+func hasEnums(t reflect.Type) bool {
+	if r, ok := hasEnumsCache.Load(t); ok {
+		return r.(bool)
+	}
+	r := findEnums(t, map[reflect.Type]bool{})
+	hasEnumsCache.Store(t, r)
+	return r
+}
+
+// findEnums implements hasEnums. Seen holds the types that are already being looked
+// into, for recursive types.
+func findEnums(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if isEnum(t) {
+		return true
+	}
+	if seen[t] || t.Kind() != reflect.Ptr && isMarshaler(t) && !isOptional(t) {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return findEnums(t.Elem(), seen)
+	case reflect.Struct:
+		for _, f := range jsonFields(t) {
+			if findEnums(t.FieldByIndex(f.index).Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkEnums returns an error if v contains a value of an enum type that is not one of
+// its allowed values. Zero values are allowed, since they stand for absent fields. Path
+// is where v is in the parameter, for error messages.
+func checkEnums(v reflect.Value, path string) error {
+	t := v.Type()
+	if isEnum(t) {
+		if v.IsZero() || enumSet(t)[v.Interface()] {
+			return nil
+		}
+		var allowed []string
+		for _, a := range reflect.New(t).Interface().(Enum).RPKEnum() {
+			allowed = append(allowed, fmt.Sprintf("%#v", a))
+		}
+		if path != "" {
+			path = " at " + path
+		}
+		return fmt.Errorf("invalid value %#v%s, expected one of: %s", v.Interface(), path,
+			strings.Join(allowed, ", "))
+	}
+	if !hasEnums(t) {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return checkEnums(v.Elem(), path)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := checkEnums(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			p := fmt.Sprintf("%s[%v]", path, iter.Key())
+			if err := checkEnums(iter.Value(), p); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		if isOptional(t) {
+			return checkEnums(v.Field(0), path)
+		}
+		for _, f := range jsonFields(t) {
+			p := f.name
+			if path != "" {
+				p = path + "." + f.name
+			}
+			fv, ok := fieldValue(v, f.index)
+			if !ok {
+				continue
+			}
+			if err := checkEnums(fv, p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// enumDecoder checks that decoded parameters have only allowed values of enum types.
+type enumDecoder struct {
+	Decoder
+}
+
+func (d enumDecoder) Decode(v interface{}) error {
+	if err := d.Decoder.Decode(v); err != nil {
+		return err
+	}
+	return checkEnums(reflect.ValueOf(v).Elem(), "")
+}
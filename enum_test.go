@@ -0,0 +1,40 @@
+package rpk
+
+import (
+	"testing"
+)
+
+func TestEnum(t *testing.T) {
+	type paint struct {
+		Colors []testColor           `json:"colors"`
+		ByName map[string]*testColor `json:"byName"`
+		Main   Optional[testColor]   `json:"main"`
+	}
+	h := New()
+	h.Register("Color", func(c testColor) string { return string(c) })
+	h.Register("Paint", func(p paint) int { return len(p.Colors) })
+
+	tests := []struct {
+		funcName string
+		param    string
+		result   string
+	}{
+		{"Color", `"red"`, `"red"`},
+		{"Color", `""`, `""`},
+		{"Color", `"blue"`, `{"error":"Error decoding JSON: invalid value \"blue\", ` +
+			`expected one of: \"red\", \"green\""}`},
+		{"Paint", `{"colors":["red","green"],"byName":{"a":"red"},"main":"green"}`, `2`},
+		{"Paint", `{"colors":["red","pink"]}`, `{"error":"Error decoding JSON: invalid ` +
+			`value \"pink\" at colors[1], expected one of: \"red\", \"green\""}`},
+		{"Paint", `{"byName":{"a":"pink"}}`, `{"error":"Error decoding JSON: invalid ` +
+			`value \"pink\" at byName[a], expected one of: \"red\", \"green\""}`},
+		{"Paint", `{"main":"pink"}`, `{"error":"Error decoding JSON: invalid value ` +
+			`\"pink\" at main, expected one of: \"red\", \"green\""}`},
+	}
+	for _, test := range tests {
+		if result := callAPI(h, test.funcName, test.param); result != test.result {
+			t.Fatalf("Bad result for %s(%s): %q, expected %q.", test.funcName,
+				test.param, result, test.result)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package rpk
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HideErrors puts the handler in production mode, where the details of errors are not
+// sent to clients, to avoid leaking internals to browsers. Errors of decoding
+// parameters, errors returned by functions and hooks, and panics in functions are
+// replaced by a generic message with a random error ID. If logError is not nil, it is
+// called with the ID and the original error, so that the details can be found in the
+// server log. Errors wrapped by Public, ConflictErrors, LocalizedErrors, RPCErrors, and
+// errors of calling functions that do not exist are sent as they are.
+func HideErrors(logError func(id string, err error)) Option {
+	return func(o *options) {
+		o.hideErrors = true
+		o.logError = logError
+	}
+}
+
+// Public marks an error as safe to send to clients in production mode, for example for
+// validation errors that users should see. Returns nil if err is nil.
+func Public(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &publicError{err}
+}
+
+// publicError is an error that is sent to clients in production mode.
+type publicError struct {
+	err error
+}
+
+func (e *publicError) Error() string {
+	return e.err.Error()
+}
+
+func (e *publicError) Unwrap() error {
+	return e.err
+}
+
+// An RPCError is an error with a code and data, which clients can act on without
+// parsing its message. The handler sends them along with the message, and sends
+// RPCErrors in production mode like errors wrapped by Public. Clients can turn them
+// back into typed errors with Client.RegisterError.
+type RPCError interface {
+	error
+	RPCError() (code string, data interface{})
+}
+
+// A PanicError is an error made of a panic in a function, in production mode or in an
+// async job.
+type PanicError struct {
+	Value interface{} // The value passed to panic.
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// runHidden calls a function like runPooled, and in production mode, hides the details
+// of its error. Also collects the function's stats.
+func (h *Handler) runHidden(r *http.Request, funcName string, param io.Reader) (
+	res callResult) {
+	// Only registered functions are counted, so the stats cannot grow without bound.
+	if h.stats != nil && h.table()[funcName] != nil {
+		done := h.stats.start(funcName)
+		defer func() { done(res.err) }()
+	}
+	unload, err := h.enterLoad(funcName)
+	if err != nil {
+		return callResult{err: err}
+	}
+	if unload != nil {
+		defer unload()
+	}
+	leave, err := h.enterCircuit(funcName)
+	if err != nil {
+		return callResult{err: err}
+	}
+	if h.opts.hideErrors {
+		defer func() {
+			if p := recover(); p != nil {
+				res = callResult{err: &PanicError{p}}
+			}
+			res.err = h.hideError(res.err)
+		}()
+	}
+	if leave != nil {
+		// Deferred after hiding errors, so it sees the function's errors and panics.
+		defer func() {
+			if p := recover(); p != nil {
+				leave(r.Context(), &PanicError{p})
+				panic(p)
+			}
+			leave(r.Context(), res.err)
+		}()
+	}
+	return h.runPooled(r, funcName, param)
+}
+
+// hideError returns an error with a generic message to be sent instead of err, and logs
+// err.
+func (h *Handler) hideError(err error) error {
+	if err == nil || errors.As(err, new(*publicError)) ||
+		errors.As(err, new(*ConflictError)) || errors.As(err, new(*LocalizedError)) ||
+		errors.As(err, new(RPCError)) {
+		return err
+	}
+	if cerr, ok := err.(*callError); ok && cerr.kind == errNoSuchFunc {
+		return err
+	}
+	kind := errHidden
+	if cerr, ok := err.(*callError); ok {
+		kind = cerr.kind
+	}
+	id := newID()
+	if h.opts.logError != nil {
+		h.opts.logError(id, err)
+	}
+	return newCallError(kind, "Internal error, ID %s.", id)
+}
+
+// newID returns a random ID, for hidden errors and jobs.
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,123 @@
+package rpk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestHideErrors(t *testing.T) {
+	logged := map[string]string{}
+	h := New(HideErrors(func(id string, err error) {
+		logged[id] = err.Error()
+	}))
+	funcs := map[string]interface{}{
+		"Fail":   func() error { return errors.New("db password is 1234") },
+		"Public": func() error { return Public(errors.New("name is taken")) },
+		"Panic":  func() { panic("oops") },
+		"Int":    func(a int) {},
+	}
+	for name, f := range funcs {
+		if err := h.Register(name, f); err != nil {
+			t.Fatal("Failed to register function:", err)
+		}
+	}
+
+	hidden := regexp.MustCompile(`^{"error":"Internal error, ID (\w+)\."}$`)
+	tests := []struct {
+		funcName string
+		param    string
+		logged   string // Empty if the error is not hidden.
+		result   string // For errors that are not hidden.
+	}{
+		{"Fail", "", "db password is 1234", ""},
+		{"Panic", "", "panic: oops", ""},
+		{"Int", `"a"`, "json: cannot unmarshal string into Go value of type int", ""},
+		{"Public", "", "", `{"error":"name is taken"}`},
+		{"Nope", "", "", `{"error":"No such function 'Nope'."}`},
+	}
+	for _, test := range tests {
+		result := callAPI(h, test.funcName, test.param)
+
+		if test.logged == "" {
+			if result != test.result {
+				t.Fatalf("Bad result for %s: %q, expected %q.", test.funcName, result,
+					test.result)
+			}
+			continue
+		}
+		match := hidden.FindStringSubmatch(result)
+		if match == nil {
+			t.Fatalf("Bad result for %s: %q, expected a hidden error.", test.funcName,
+				result)
+		}
+		if got := logged[match[1]]; !strings.Contains(got, test.logged) {
+			t.Fatalf("Bad logged error for %s: %q, expected %q.", test.funcName, got,
+				test.logged)
+		}
+	}
+}
+
+func TestPublic(t *testing.T) {
+	if Public(nil) != nil {
+		t.Fatal("Public(nil) is not nil.")
+	}
+	err := errors.New("a")
+	if got := fmt.Errorf("b: %w", Public(err)); !errors.Is(got, err) {
+		t.Fatalf("Public(%v) does not unwrap.", err)
+	}
+}
+
+// testQuotaError is an RPCError.
+type testQuotaError struct {
+	Limit int `json:"limit"`
+}
+
+func (e *testQuotaError) Error() string {
+	return fmt.Sprintf("quota of %d exceeded", e.Limit)
+}
+
+func (e *testQuotaError) RPCError() (string, interface{}) {
+	return "quota", e
+}
+
+func TestRPCError(t *testing.T) {
+	h := New(HideErrors(nil))
+	h.Register("Use", func() error {
+		return fmt.Errorf("using: %w", &testQuotaError{10})
+	})
+	h.Register("Fail", func() error { return errors.New("secret") })
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	want := `{"error":"using: quota of 10 exceeded","code":"quota","data":{"limit":10}}`
+	if got := callAPI(h, "Use", ""); got != want {
+		t.Fatalf("Bad result: %s, expected %s.", got, want)
+	}
+
+	c := NewClient(server.URL, nil)
+	c.RegisterError("quota", func(data json.RawMessage) error {
+		e := &testQuotaError{}
+		json.Unmarshal(data, e)
+		return e
+	})
+	err := c.Call(context.Background(), "Use", nil, nil)
+	var qerr *testQuotaError
+	if !errors.As(err, &qerr) || qerr.Limit != 10 {
+		t.Fatalf("Bad error: %#v, expected a quota error.", err)
+	}
+	var rerr *RemoteError
+	if !errors.As(err, &rerr) || rerr.Code != "quota" {
+		t.Fatalf("Bad error: %#v, expected a remote error with code quota.", err)
+	}
+
+	err = c.Call(context.Background(), "Fail", nil, nil)
+	if !errors.As(err, &rerr) || rerr.Code != "" || errors.Unwrap(err) != nil {
+		t.Fatalf("Bad error: %#v, expected a remote error without code.", err)
+	}
+}
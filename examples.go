@@ -0,0 +1,92 @@
+package rpk
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// An Example is a recorded call of a function, for documentation.
+type Example struct {
+	Param  json.RawMessage `json:"param,omitempty"`  // Nil if the function takes no input.
+	Result json.RawMessage `json:"result,omitempty"` // Nil if it has no value output.
+}
+
+// CaptureExamples makes the handler record the last n successful calls of each function
+// as examples, which Examples returns and the playground shows. Fields tagged with
+// `rpk:"redact"` are zeroed in the recorded parameters and outputs, like in hooks, so
+// tag any field that should not be kept. A call with the same parameter as a recorded
+// one replaces it. Streamed outputs and validate-only calls are not recorded.
+func CaptureExamples(n int) Option {
+	return func(o *options) {
+		o.examples = n
+	}
+}
+
+// Examples returns the recorded examples of calls of the named function, from oldest to
+// newest, with the CaptureExamples option.
+func (h *Handler) Examples(name string) []Example {
+	if h.examples == nil {
+		return nil
+	}
+	h.examples.mu.Lock()
+	defer h.examples.mu.Unlock()
+	return append([]Example(nil), h.examples.m[name]...)
+}
+
+// exampleSet holds the examples of the CaptureExamples option.
+type exampleSet struct {
+	mu sync.Mutex
+	m  map[string][]Example // By function name.
+}
+
+// recordExample records the call of the named function that had result res, if the
+// handler captures examples.
+func (h *Handler) recordExample(funcName string, res *callResult) {
+	if h.examples == nil || res.err != nil || res.stream || res.validated {
+		return
+	}
+	m := h.table()[funcName]
+	if m == nil {
+		return
+	}
+	var e Example
+	if res.param != nil {
+		var v interface{} = Redact(reflect.ValueOf(res.param).Elem().Interface())
+		switch {
+		case m.inU != nil:
+			v = m.inU.wrap(v)
+		case m.inC != nil:
+			v = codecValue{v, m.inC}
+		}
+		data, err := h.opts.encode.marshal(v)
+		if err != nil {
+			return
+		}
+		e.Param = data
+	}
+	if res.hasOut {
+		out := *res
+		out.val = Redact(out.val)
+		data, err := h.opts.encode.marshal(out.encoded())
+		if err != nil {
+			return
+		}
+		e.Result = data
+	}
+
+	h.examples.mu.Lock()
+	defer h.examples.mu.Unlock()
+	list := h.examples.m[funcName]
+	for i := range list {
+		if bytes.Equal(list[i].Param, e.Param) {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) >= h.opts.examples {
+		list = list[len(list)-h.opts.examples+1:]
+	}
+	h.examples.m[funcName] = append(list, e)
+}
@@ -0,0 +1,61 @@
+package rpk
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCaptureExamples(t *testing.T) {
+	type login struct {
+		User     string
+		Password string `rpk:"redact"`
+	}
+	h := New(CaptureExamples(2))
+	h.Register("Login", func(l login) (login, error) {
+		if l.User == "" {
+			return login{}, errors.New("no user")
+		}
+		return l, nil
+	})
+	h.Register("Ping", func() string { return "pong" })
+
+	for _, param := range []string{
+		`{"User":"a","Password":"x"}`,
+		`{"User":"b","Password":"y"}`,
+		`{"User":""}`,
+		`{"User":"a","Password":"z"}`,
+	} {
+		callAPI(h, "Login", param)
+	}
+	callAPI(h, "Ping", "")
+
+	got := h.Examples("Login")
+	want := []string{`{"User":"b","Password":""}`, `{"User":"a","Password":""}`}
+	if len(got) != len(want) {
+		t.Fatalf("Bad number of examples: %d, expected %d.", len(got), len(want))
+	}
+	for i := range want {
+		if string(got[i].Param) != want[i] || string(got[i].Result) != want[i] {
+			t.Fatalf("Bad example %d: %s -> %s, expected %s.", i, got[i].Param,
+				got[i].Result, want[i])
+		}
+	}
+
+	got = h.Examples("Ping")
+	if len(got) != 1 || got[0].Param != nil || string(got[0].Result) != `"pong"` {
+		t.Fatalf("Bad examples of Ping: %+v, expected one without a parameter.", got)
+	}
+
+	req := httptest.NewRequest("GET", "/playground", nil)
+	res := httptest.NewRecorder()
+	HandlePlayground(h).ServeHTTP(res, req)
+	if want := `"Ping":[{"result":"pong"}]`; !strings.Contains(res.Body.String(), want) {
+		t.Fatalf("Playground does not contain %q.", want)
+	}
+
+	if got := New().Examples("Login"); got != nil {
+		t.Fatalf("Bad examples without the option: %+v, expected nil.", got)
+	}
+}
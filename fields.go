@@ -0,0 +1,354 @@
+package rpk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// FieldsHeader is the request header with which clients ask for only some fields of a
+// call's result, as a comma-separated list of field paths, like "id,name,owner.name".
+// Clients that cannot set headers send the list as a "_fields" array in a parameter
+// object instead, like {"id": 7, "_fields": ["name"]}, which is removed from the
+// parameter before it is decoded. Paths are of JSON object keys, with dots between
+// nested keys, and apply to each element of arrays, so results of list functions are
+// filtered element by element, and so are streamed outputs, item by item, except for
+// readers. Other fields are left out of the result, which reduces the payloads of
+// list-heavy pages without new functions.
+const FieldsHeader = "Rpk-Fields"
+
+// Fields returns the fields of the result that the call with ctx asks for, as sent in
+// the FieldsHeader, or nil if it asks for all fields. Functions may use it to skip
+// computing fields that are left out. Calls of the Go client with ctx do not ask for
+// them too, unless WithFields says so.
+func Fields(ctx context.Context) []string {
+	f, _ := ctx.Value(requestFieldsKey{}).(*requestedFields)
+	if f == nil {
+		return nil
+	}
+	return f.get()
+}
+
+// WithFields returns a copy of ctx with which calls of the Go client ask for only the
+// given fields of their results.
+func WithFields(ctx context.Context, fields ...string) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// fieldsKey is the context key of the fields that calls of the Go client ask for.
+type fieldsKey struct{}
+
+// clientFields returns the fields that calls of the Go client with ctx ask for.
+func clientFields(ctx context.Context) []string {
+	f, _ := ctx.Value(fieldsKey{}).([]string)
+	return f
+}
+
+// requestFieldsKey is the context key of the *requestedFields of a call.
+type requestFieldsKey struct{}
+
+// requestedFields are the fields that a call asks for. Those in the parameter are only
+// known once it is read.
+type requestedFields struct {
+	header []string
+	param  *fieldsReader // Nil if the parameter is not read through one.
+}
+
+// get returns the fields, from the parameter if it has them, or else from the header.
+func (f *requestedFields) get() []string {
+	if f.param != nil && f.param.found {
+		return f.param.fields
+	}
+	return f.header
+}
+
+// requestFields returns the fields that a request asks for, and a reader of its
+// parameter without the "_fields" member, which fills them in as it is read.
+func requestFields(r *http.Request, param io.Reader) (*requestedFields, io.Reader) {
+	f := &requestedFields{}
+	if h := r.Header.Get(FieldsHeader); h != "" {
+		for _, name := range strings.Split(h, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				f.header = append(f.header, name)
+			}
+		}
+	}
+	f.param = &fieldsReader{r: param}
+	return f, f.param
+}
+
+// States of a fieldsReader.
+const (
+	fieldsAtStart  = iota // Before the parameter, in leading whitespace.
+	fieldsInKey           // Before a key of the top-level object, or in it.
+	fieldsInValue         // In the value of the "_fields" member.
+	fieldsInMember        // In another member of the top-level object.
+	fieldsPassing         // Not in the top-level object.
+)
+
+// fieldsReader reads a parameter without the "_fields" member of its top-level object,
+// if it has one, whose value it decodes as it goes. Other bytes are read as they are,
+// so the parameter is still decoded as it is read.
+type fieldsReader struct {
+	r       io.Reader
+	fields  []string
+	found   bool // Whether the "_fields" member was read.
+	err     error
+	state   int
+	first   bool   // Whether the current member is the first of the object.
+	depth   int    // Of arrays and objects in the current member or value.
+	inStr   bool   // Whether in a string.
+	escaped bool   // Whether the last character in a string was a backslash.
+	held    []byte // Bytes of the current key, held until it is known to be another.
+	keyLen  int    // Length of the key in held, including its opening quote.
+	value   []byte // Of the "_fields" member.
+	out     []byte // Ready to be read.
+}
+
+// fieldsKeyJSON is the key of the "_fields" member as it is read.
+const fieldsKeyJSON = `"_fields"`
+
+func (r *fieldsReader) Read(b []byte) (int, error) {
+	if r.state == fieldsPassing && len(r.out) == 0 && r.err == nil {
+		return r.r.Read(b)
+	}
+	for len(r.out) == 0 && r.err == nil && len(b) > 0 {
+		// b holds what is read until it is scanned.
+		n, err := r.r.Read(b)
+		r.out = r.out[:0]
+		for _, c := range b[:n] {
+			if r.scan(c); r.err != nil {
+				// A bad "_fields" fails the parameter, before the rest of it is read.
+				r.out = nil
+				return 0, r.err
+			}
+		}
+		if err != nil {
+			r.out = append(r.out, r.held...) // Left for the decoder to report.
+			r.held = nil
+			r.err = err
+		}
+	}
+	n := copy(b, r.out)
+	r.out = r.out[n:]
+	if len(r.out) == 0 && r.err != nil {
+		return n, r.err
+	}
+	return n, nil
+}
+
+// scan handles the next byte of the parameter.
+func (r *fieldsReader) scan(c byte) {
+	switch r.state {
+	case fieldsAtStart:
+		switch c {
+		case ' ', '\t', '\n', '\r':
+		case '{':
+			r.state, r.first = fieldsInKey, true
+		default:
+			r.state = fieldsPassing
+		}
+		r.out = append(r.out, c)
+
+	case fieldsInKey:
+		if r.keyLen == 0 {
+			switch c {
+			case ' ', '\t', '\n', '\r', ',':
+				r.held = append(r.held, c)
+			case '"':
+				r.held = append(r.held, c)
+				r.keyLen = 1
+			default: // Like the end of the object.
+				r.release()
+				r.member(c)
+			}
+			return
+		}
+		if c != fieldsKeyJSON[r.keyLen] {
+			// Another key, which the member goes on from.
+			r.release()
+			r.inStr = true
+			r.member(c)
+			return
+		}
+		r.held = append(r.held, c)
+		r.keyLen++
+		if r.keyLen == len(fieldsKeyJSON) {
+			// The comma before the member is dropped with it, unless it is the first,
+			// whose following comma is dropped instead.
+			r.held, r.keyLen, r.value = nil, 0, nil
+			r.state = fieldsInValue
+		}
+
+	case fieldsInValue:
+		if r.inStr {
+			r.str(c)
+			r.value = append(r.value, c)
+			return
+		}
+		switch c {
+		case '"':
+			r.inStr = true
+		case '[', '{':
+			r.depth++
+		case ']':
+			r.depth--
+		case '}':
+			if r.depth == 0 {
+				r.endValue()
+				r.member(c)
+				return
+			}
+			r.depth--
+		case ',':
+			if r.depth == 0 {
+				r.endValue()
+				r.state = fieldsInKey
+				if !r.first {
+					r.held = append(r.held, c)
+				}
+				return
+			}
+		}
+		r.value = append(r.value, c)
+
+	case fieldsInMember:
+		r.member(c)
+
+	case fieldsPassing:
+		r.out = append(r.out, c)
+	}
+}
+
+// release makes the held bytes ready to be read, as part of another member.
+func (r *fieldsReader) release() {
+	r.out = append(r.out, r.held...)
+	r.held, r.keyLen = r.held[:0], 0
+	r.first = false
+	r.state = fieldsInMember
+}
+
+// member handles the next byte of a member other than "_fields".
+func (r *fieldsReader) member(c byte) {
+	r.out = append(r.out, c)
+	if r.inStr {
+		r.str(c)
+		return
+	}
+	switch c {
+	case '"':
+		r.inStr = true
+	case '[', '{':
+		r.depth++
+	case ']':
+		r.depth--
+	case '}':
+		if r.depth == 0 {
+			r.state = fieldsPassing
+			return
+		}
+		r.depth--
+	case ',':
+		if r.depth == 0 {
+			r.state = fieldsInKey
+			r.out = r.out[:len(r.out)-1]
+			r.held = append(r.held, c)
+		}
+	}
+}
+
+// str handles the next byte of a string.
+func (r *fieldsReader) str(c byte) {
+	switch {
+	case r.escaped:
+		r.escaped = false
+	case c == '\\':
+		r.escaped = true
+	case c == '"':
+		r.inStr = false
+	}
+}
+
+// endValue decodes the value of the "_fields" member once it is read.
+func (r *fieldsReader) endValue() {
+	r.found = true
+	r.depth = 0
+	r.fields = nil
+	if err := json.Unmarshal(bytes.TrimPrefix(bytes.TrimSpace(r.value), []byte(":")),
+		&r.fields); err != nil {
+		r.err = newCallError(errBadParam, "Bad _fields: %v", err)
+	}
+	r.value = nil
+}
+
+// fieldMask is a tree of the fields to keep in a result. A nil mask keeps all fields.
+type fieldMask map[string]fieldMask
+
+// newFieldMask returns a mask of the given field paths, or nil if there are none.
+func newFieldMask(fields []string) fieldMask {
+	if len(fields) == 0 {
+		return nil
+	}
+	m := fieldMask{}
+	for _, f := range fields {
+		parts := strings.Split(f, ".")
+		node := m
+		for i, p := range parts {
+			sub, ok := node[p]
+			if ok && sub == nil {
+				break // The whole field is kept.
+			}
+			if i == len(parts)-1 {
+				node[p] = nil
+				break
+			}
+			if sub == nil {
+				sub = fieldMask{}
+				node[p] = sub
+			}
+			node = sub
+		}
+	}
+	return m
+}
+
+// prune returns v, decoded from JSON, without the fields that are not in the mask.
+func (m fieldMask) prune(v interface{}) interface{} {
+	if m == nil {
+		return v
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			sub, ok := m[k]
+			if !ok {
+				delete(v, k)
+				continue
+			}
+			v[k] = sub.prune(val)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = m.prune(v[i])
+		}
+	}
+	return v
+}
+
+// pruned returns v without the fields that are not in the mask, for encoding.
+func (m fieldMask) pruned(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v // The encoder reports the error.
+	}
+	var result interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if dec.Decode(&result) != nil {
+		return v
+	}
+	return m.prune(result)
+}
@@ -0,0 +1,151 @@
+package rpk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+type testOwner struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type testRepo struct {
+	ID    int       `json:"id"`
+	Name  string    `json:"name"`
+	Owner testOwner `json:"owner"`
+}
+
+func TestFields(t *testing.T) {
+	var asked []string
+	h := New()
+	h.Register("List", func(ctx context.Context, q struct{ Prefix string }) []testRepo {
+		asked = Fields(ctx)
+		return []testRepo{
+			{1, q.Prefix + "a", testOwner{"amy", "amy@x.com"}},
+			{2, q.Prefix + "b", testOwner{"bob", "bob@x.com"}},
+		}
+	})
+	call := func(param, header string) string {
+		req := apiRequest("List", param)
+		if header != "" {
+			req.Header.Set(FieldsHeader, header)
+		}
+		return serve(h, req)
+	}
+
+	tests := []struct {
+		param  string
+		header string
+		want   string
+	}{
+		{`{"Prefix":"r"}`, "", `[{"id":1,"name":"ra","owner":{"name":"amy",` +
+			`"email":"amy@x.com"}},{"id":2,"name":"rb","owner":{"name":"bob",` +
+			`"email":"bob@x.com"}}]`},
+		{`{"Prefix":"r"}`, "id, owner.name", `[{"id":1,"owner":{"name":"amy"}},` +
+			`{"id":2,"owner":{"name":"bob"}}]`},
+		{` {"_fields":["name","owner"],"Prefix":"r"}`, "", `[{"name":"ra",` +
+			`"owner":{"email":"amy@x.com","name":"amy"}},{"name":"rb",` +
+			`"owner":{"email":"bob@x.com","name":"bob"}}]`},
+		{`{"_fields":["owner.email","owner"]}`, "", `[{"owner":{"email":"amy@x.com",` +
+			`"name":"amy"}},{"owner":{"email":"bob@x.com","name":"bob"}}]`},
+		{`{"_fields":"id"}`, "", `{"error":"Bad _fields: json: cannot unmarshal ` +
+			`string into Go value of type []string"}`},
+		{`{"Prefix":"r", "_fields":["name"] }`, "id", `[{"name":"ra"},{"name":"rb"}]`},
+		{`{"_field":["id"],"Prefix":"r"}`, "id", `[{"id":1},{"id":2}]`},
+		{`{"_fields":["id"]} {}`, "", `{"error":"Error decoding JSON: unexpected data ` +
+			`after the parameter"}`},
+	}
+	for _, test := range tests {
+		if got := call(test.param, test.header); got != test.want {
+			t.Fatalf("Bad result for %s %q: %q, expected %q.", test.param, test.header,
+				got, test.want)
+		}
+	}
+	call(`{"_fields":["id"]}`, "")
+	if len(asked) != 1 || asked[0] != "id" {
+		t.Fatalf("Bad fields in context: %q, expected %q.", asked, []string{"id"})
+	}
+}
+
+func TestClient_fields(t *testing.T) {
+	h := New()
+	h.Register("Get", func(id int) testRepo {
+		return testRepo{id, "rpk", testOwner{"amy", "amy@x.com"}}
+	})
+	server := httptest.NewServer(h)
+	defer server.Close()
+	c := NewClient(server.URL, nil)
+
+	var repo testRepo
+	ctx := WithFields(context.Background(), "name", "owner.name")
+	if err := c.Call(ctx, "Get", 7, &repo); err != nil {
+		t.Fatal("Failed to call Get:", err)
+	}
+	want := testRepo{Name: "rpk", Owner: testOwner{Name: "amy"}}
+	if repo != want {
+		t.Fatalf("Bad result: %+v, expected %+v.", repo, want)
+	}
+
+	// The fields that a call asks for are not asked of the calls that it makes.
+	var asked []string
+	h.Register("Asked", func(ctx context.Context) { asked = Fields(ctx) })
+	front := New()
+	front.Register("Call", func(ctx context.Context) error {
+		return c.Call(ctx, "Asked", nil, nil)
+	})
+	req := apiRequest("Call", "")
+	req.Header.Set(FieldsHeader, "name")
+	if got := serve(front, req); got != "" || asked != nil {
+		t.Fatalf("Bad result: %q with fields %q, expected none.", got, asked)
+	}
+}
+
+func TestFields_raw(t *testing.T) {
+	h := New()
+	h.Register("Raw", func(p json.RawMessage) string { return string(p) })
+	tests := []struct {
+		param string
+		want  string
+	}{
+		{`{"b": 1,  "_fields":["x"], "a":2}`, `{"b": 1, "a":2}`},
+		{`{ "_fields" : ["x"] , "a" : [1, "_fields"]}`, `{ "a" : [1, "_fields"]}`},
+		{`{"_fields":["x"]}`, `{}`},
+		{`{"_fieldsX":["x"]}`, `{"_fieldsX":["x"]}`},
+		{`{"a":"_fields\"","_fields":["x"]}`, `{"a":"_fields\""}`},
+		{`[1, "_fields"]`, `[1, "_fields"]`},
+	}
+	for _, test := range tests {
+		result := callAPI(h, "Raw", test.param)
+		var got string
+		if err := json.Unmarshal([]byte(result), &got); err != nil {
+			t.Fatalf("Bad result for %s: %q", test.param, result)
+		}
+		if got != test.want {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.param, got, test.want)
+		}
+	}
+}
+
+func TestFields_stream(t *testing.T) {
+	h := New()
+	h.Register("List", func() []testRepo {
+		return []testRepo{{1, "a", testOwner{}}, {2, "b", testOwner{}}}
+	})
+	h.Register("Each", func() func(yield func(testRepo) bool) {
+		return func(yield func(testRepo) bool) {
+			_ = yield(testRepo{1, "a", testOwner{}}) && yield(testRepo{2, "b", testOwner{}})
+		}
+	})
+	for _, name := range []string{"List", "Each"} {
+		req := apiRequest(name, "")
+		req.Header.Set("Accept", NDJSONType)
+		req.Header.Set(FieldsHeader, "name")
+		want := "{\"name\":\"a\"}\n{\"name\":\"b\"}"
+		if got := serve(h, req); got != want {
+			t.Fatalf("Bad result for %s: %q, expected %q.", name, got, want)
+		}
+	}
+}
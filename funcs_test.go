@@ -1,13 +1,21 @@
 package rpk
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 func TestFuncs(t *testing.T) {
-	f, err := newFuncs(testType{})
+	f, err := newFuncs(testType{}, newOptions(nil))
 	if err != nil {
 		t.Fatal("Failed to create funcs:", err)
 	}
@@ -21,7 +29,9 @@ func TestFuncs(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		result := f.call(test.f, test.arg)
+		buf := bytes.NewBuffer(nil)
+		f.call(context.Background(), buf, test.f, strings.NewReader(test.arg), newJSONDecoder)
+		result := buf.String()
 		if test.shouldErr && !isJSONError(result) {
 			t.Fatal("Expected error but got nil in test:", test)
 		}
@@ -34,6 +44,116 @@ func TestFuncs(t *testing.T) {
 	}
 }
 
+func TestFuncs_decoder(t *testing.T) {
+	f, err := newFuncs(testType{}, newOptions(nil))
+	if err != nil {
+		t.Fatal("Failed to create funcs:", err)
+	}
+
+	// Decodes every parameter as 7.
+	seven := func(r io.Reader) Decoder {
+		return json.NewDecoder(strings.NewReader("7"))
+	}
+	buf := bytes.NewBuffer(nil)
+	f.call(context.Background(), buf, "Bar", strings.NewReader("whatever"), seven)
+	if result := buf.String(); result != "\"Bar 7\"\n" {
+		t.Fatalf("Bad result: %q, expected %q.", result, "\"Bar 7\"\n")
+	}
+}
+
+func TestNewFuncs_bad(t *testing.T) {
+	badTests := []struct {
+		a   interface{}
+		err string
+	}{
+		{badTooManyIns{}, "input 2 (int)"},
+		{badCtxSecond{}, "input 2 (context.Context)"},
+		{badChanIn{}, "input 1 (chan int)"},
+		{badTooManyOuts{}, "found 3"},
+		{badNoErr{}, "output 2 (int)"},
+		{badErrFirst{}, "output 2 (int)"},
+		{badFuncOut{}, "output 1 (func())"},
+	}
+	for _, test := range badTests {
+		_, err := newFuncs(test.a, newOptions(nil))
+		if err == nil {
+			t.Fatalf("Expected error for %T but got nil.", test.a)
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("Error for %T should contain %q, got: %v", test.a, test.err, err)
+		}
+	}
+}
+
+func TestNewFuncs_allErrors(t *testing.T) {
+	_, err := newFuncs(badMany{}, newOptions(nil))
+	errs, ok := err.(MethodErrors)
+	if !ok {
+		t.Fatalf("Expected MethodErrors, got %T: %v", err, err)
+	}
+	if len(errs) != 2 || errs[0].Method != "A" || errs[1].Method != "B" {
+		t.Fatalf("Expected errors for A and B, got: %v", errs)
+	}
+}
+
+func TestNewFuncs_skipInvalid(t *testing.T) {
+	var skipped []string
+	o := newOptions([]Option{SkipInvalid(func(err *MethodError) {
+		skipped = append(skipped, err.Method)
+	})})
+	f, err := newFuncs(badMany{}, o)
+	if err != nil {
+		t.Fatal("Failed to create funcs:", err)
+	}
+	if len(f) != 1 || f["C"] == nil {
+		t.Fatalf("Expected only C to be registered, got: %v", f)
+	}
+	if len(skipped) != 2 || skipped[0] != "A" || skipped[1] != "B" {
+		t.Fatalf("Expected A and B to be skipped, got: %v", skipped)
+	}
+}
+
+func TestMethodInfo(t *testing.T) {
+	f, err := newFuncs(testType{}, newOptions(nil))
+	if err != nil {
+		t.Fatal("Failed to create funcs:", err)
+	}
+	infoTests := []struct {
+		f      string
+		inPtr  bool
+		numOut int
+		valOut int
+		errOut int
+	}{
+		{"Foo", false, 0, -1, -1},
+		{"FooStr", false, 1, 0, -1},
+		{"FooErr", false, 1, -1, 0},
+		{"Bar", false, 2, 0, 1},
+		{"Fun", true, 2, 0, 1},
+	}
+	for _, test := range infoTests {
+		m := f[test.f]
+		if m.inPtr != test.inPtr || m.numOut != test.numOut ||
+			m.valOut != test.valOut || m.errOut != test.errOut {
+			t.Fatalf("Bad method info for %s: %+v", test.f, m)
+		}
+	}
+}
+
+func TestFuncs_nullPointer(t *testing.T) {
+	h := New()
+	h.Register("IsNil", func(th *thing) bool { return th == nil })
+	for _, test := range []struct{ param, want string }{
+		{"null", "true"},
+		{`{"I":1}`, "false"},
+		{"{}", "false"},
+	} {
+		if got := callAPI(h, "IsNil", test.param); got != test.want {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.param, got, test.want)
+		}
+	}
+}
+
 // ----- HELPERS --------------------------------------------------------------
 
 // isJSONError checks if the given string looks like a JSON error.
@@ -84,8 +204,51 @@ func (t testType) FunErr(th *thing) (string, error) {
 	return "", fmt.Errorf("Fun error")
 }
 
+func (t testType) Ctx(ctx context.Context, i int) (string, error) {
+	if ctx == nil {
+		return "", fmt.Errorf("Ctx got nil context")
+	}
+	return fmt.Sprint("Ctx ", i), nil
+}
+
 var funcNames = []string{"Foo", "FooStr", "FooErr", "Bar", "BarErr", "Baz", "BazErr",
-	"Fun", "FunErr"}
+	"Fun", "FunErr", "Ctx"}
+
+// Types with methods that do not match the requirements.
+
+type badTooManyIns struct{}
+
+func (badTooManyIns) F(a, b int) {}
+
+type badCtxSecond struct{}
+
+func (badCtxSecond) F(i int, ctx context.Context) {}
+
+type badChanIn struct{}
+
+func (badChanIn) F(c chan int) {}
+
+type badTooManyOuts struct{}
+
+func (badTooManyOuts) F() (int, int, error) { return 0, 0, nil }
+
+type badNoErr struct{}
+
+func (badNoErr) F() (int, int) { return 0, 0 }
+
+type badErrFirst struct{}
+
+func (badErrFirst) F() (error, int) { return nil, 0 }
+
+type badFuncOut struct{}
+
+func (badFuncOut) F() func() { return nil }
+
+type badMany struct{}
+
+func (badMany) A(a, b int)     {}
+func (badMany) B() (int, int)  { return 0, 0 }
+func (badMany) C(i int) string { return "" }
 
 // ----- TESTS -----------------------------------------------------------------
 
@@ -97,17 +260,265 @@ var tests = []struct {
 }{
 	{"Foo", "", "", false},
 	{"Foo", "a", "", true},
-	{"FooStr", "", "\"Foo!\"", false},
+	{"FooStr", "", "\"Foo!\"\n", false},
 	{"FooStr", "1", "", true},
 	{"FooErr", "", "", true},
-	{"Bar", "7", "\"Bar 7\"", false},
+	{"Bar", "7", "\"Bar 7\"\n", false},
 	{"Bar", "", "", true},
 	{"BarErr", "7", "", true},
-	{"Baz", "[\"x\",\"y\"]", "\"Baz x\"", false},
+	{"Baz", "[\"x\",\"y\"]", "\"Baz x\"\n", false},
 	{"Baz", "", "", true},
 	{"BazErr", "[\"x\",\"y\"]", "", true},
-	{"Fun", "{\"i\":7,\"s\":\"aaa\"}", "\"Fun 7 aaa\"", false},
+	{"Fun", "{\"i\":7,\"s\":\"aaa\"}", "\"Fun 7 aaa\"\n", false},
 	{"Fun", "{\"i\":7,\"s\":\"aaa\"{", "", true},
 	{"Fun", "", "", true},
 	{"FunErr", "{\"i\":7,\"s\":\"aaa\"}", "", true},
+	{"Ctx", "7", "\"Ctx 7\"\n", false},
+	{"Ctx", "", "", true},
+}
+
+func TestCall_allocs(t *testing.T) {
+	f, err := newFuncs(testType{}, newOptions(nil))
+	if err != nil {
+		t.Fatal("Failed to create funcs:", err)
+	}
+	r := strings.NewReader("")
+	allocs := testing.AllocsPerRun(100, func() {
+		r.Reset("")
+		f.call(context.Background(), io.Discard, "Foo", r, newJSONDecoder)
+	})
+	if allocs != 0 {
+		t.Fatalf("Calling a function with no input and output took %v allocations, "+
+			"expected 0.", allocs)
+	}
+}
+
+func BenchmarkCall(b *testing.B) {
+	f, err := newFuncs(testType{}, newOptions(nil))
+	if err != nil {
+		b.Fatal("Failed to create funcs:", err)
+	}
+	b.Run("Foo", func(b *testing.B) {
+		b.ReportAllocs()
+		r := strings.NewReader("")
+		for i := 0; i < b.N; i++ {
+			r.Reset("")
+			f.call(context.Background(), io.Discard, "Foo", r, newJSONDecoder)
+		}
+	})
+	b.Run("Bar", func(b *testing.B) {
+		b.ReportAllocs()
+		r := strings.NewReader("")
+		for i := 0; i < b.N; i++ {
+			r.Reset("7")
+			f.call(context.Background(), io.Discard, "Bar", r, newJSONDecoder)
+		}
+	})
+}
+
+func TestCall_raw(t *testing.T) {
+	h := New()
+	err := h.Register("Raw", func(r Raw) string { return "raw:" + string(r) })
+	if err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+	err = h.Register("RawPtr", func(r *json.RawMessage) int { return len(*r) })
+	if err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+
+	// A decoder that fails, to check that raw parameters are not decoded.
+	failing := func(r io.Reader) Decoder {
+		return json.NewDecoder(iotest.ErrReader(errors.New("decoded")))
+	}
+	tests := []struct {
+		funcName string
+		param    string
+		result   string
+	}{
+		{"Raw", `{"a": [1, 2]}`, "\"raw:{\\\"a\\\": [1, 2]}\"\n"},
+		{"Raw", ``, "\"raw:\"\n"},
+		{"RawPtr", `"abc"`, "5\n"},
+	}
+	for _, test := range tests {
+		w := &bytes.Buffer{}
+		h.funcs.call(context.Background(), w, test.funcName,
+			strings.NewReader(test.param), failing)
+		if result := w.String(); result != test.result {
+			t.Fatalf("Bad result for %s(%s): %q, expected %q.", test.funcName,
+				test.param, result, test.result)
+		}
+	}
+}
+
+type nestedAPI struct {
+	Users  *nestedUsers
+	Admin  nestedAdmin
+	Nil    *nestedUsers
+	Hidden *nestedUsers `rpk:"-"`
+	When   time.Time
+	Self   *nestedAPI
+}
+
+func (a *nestedAPI) Ping() string { return "pong" }
+
+type nestedUsers struct{ n int }
+
+func (u *nestedUsers) Create(name string) int {
+	u.n++
+	return u.n
+}
+
+type nestedAdmin struct{}
+
+func (a *nestedAdmin) Reset() {}
+
+func TestNewFuncs_nested(t *testing.T) {
+	a := &nestedAPI{Users: &nestedUsers{}, Hidden: &nestedUsers{}}
+	a.Self = a
+	fs, err := newFuncs(a, newOptions([]Option{Nested()}))
+	if err != nil {
+		t.Fatal("Failed to create funcs:", err)
+	}
+	want := []string{"Admin.Reset", "Ping", "Users.Create"}
+	if got := fs.names(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("names()=%v, want %v", got, want)
+	}
+
+	w := &bytes.Buffer{}
+	fs.call(context.Background(), w, "Users.Create", strings.NewReader(`"a"`),
+		newJSONDecoder)
+	if result := w.String(); result != "1\n" {
+		t.Fatalf("Bad result: %q, expected %q.", result, "1\n")
+	}
+}
+
+type filterBase struct{}
+
+func (filterBase) Close() error { return nil }
+
+type filterAPI struct {
+	filterBase
+	Users *nestedUsers
+}
+
+func (filterAPI) String() string { return "api" }
+func (filterAPI) Get() int       { return 1 }
+func (filterAPI) Put(int)        {}
+
+func TestNewFuncs_filters(t *testing.T) {
+	tests := []struct {
+		opts []Option
+		want []string
+	}{
+		{nil, []string{"Close", "Get", "Put", "String"}},
+		{[]Option{Exclude("String", "Close")}, []string{"Get", "Put"}},
+		{[]Option{Include("Get")}, []string{"Get"}},
+		{[]Option{Include("Get"), Include("Put"), Exclude("Put")}, []string{"Get"}},
+		{[]Option{NoPromoted()}, []string{"Get", "Put", "String"}},
+		{[]Option{Nested(), Exclude("Users.Create")},
+			[]string{"Close", "Get", "Put", "String"}},
+		{[]Option{Nested(), Include("Users.Create")}, []string{"Users.Create"}},
+	}
+	for _, test := range tests {
+		fs, err := newFuncs(filterAPI{Users: &nestedUsers{}}, newOptions(test.opts))
+		if err != nil {
+			t.Fatal("Failed to create funcs:", err)
+		}
+		if got := fs.names(); !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("names()=%v, want %v", got, test.want)
+		}
+	}
+}
+
+func TestNewFuncs_onSkip(t *testing.T) {
+	skipped := map[string]error{}
+	o := newOptions([]Option{
+		NoPromoted(),
+		Exclude("String"),
+		OnSkip(func(err *MethodError) {
+			skipped[err.Method] = err.Err
+		}),
+	})
+	if _, err := newFuncs(filterAPI{}, o); err != nil {
+		t.Fatal("Failed to create funcs:", err)
+	}
+	want := map[string]error{"Close": ErrPromoted, "String": ErrExcluded}
+	if !reflect.DeepEqual(skipped, want) {
+		t.Fatalf("Skipped %v, want %v", skipped, want)
+	}
+
+	skipped = map[string]error{}
+	o = newOptions([]Option{SkipInvalid(nil), Include("A"), OnSkip(func(err *MethodError) {
+		skipped[err.Method] = err.Err
+	})})
+	if _, err := newFuncs(badMany{}, o); err != nil {
+		t.Fatal("Failed to create funcs:", err)
+	}
+	if len(skipped) != 3 || skipped["B"] != ErrNotIncluded ||
+		skipped["C"] != ErrNotIncluded || skipped["A"] == nil {
+		t.Fatalf("Bad skipped methods: %v", skipped)
+	}
+
+	// OnSkip replaces the warn callback of SkipInvalid.
+	skipped = map[string]error{}
+	warned := 0
+	o = newOptions([]Option{SkipInvalid(func(err *MethodError) { warned++ }),
+		OnSkip(func(err *MethodError) { skipped[err.Method] = err.Err })})
+	if _, err := newFuncs(badMany{}, o); err != nil {
+		t.Fatal("Failed to create funcs:", err)
+	}
+	if warned != 0 || len(skipped) != 2 || skipped["A"] == nil || skipped["B"] == nil {
+		t.Fatalf("Bad skipped methods: %v, warned %d times, expected A and B once.",
+			skipped, warned)
+	}
+}
+
+type embedA struct{}
+
+func (embedA) Get() int    { return 1 }
+func (embedA) OnlyA() int  { return 1 }
+func (*embedA) PtrA() int  { return 1 }
+func (embedA) Shared() int { return 1 }
+
+type embedB struct{}
+
+func (embedB) Get() int    { return 2 }
+func (embedB) Shared() int { return 2 }
+
+type embedBoth struct {
+	embedA
+	embedB
+}
+
+func (embedBoth) Get() int { return 3 }
+
+type embedTagged struct {
+	embedA
+	embedB `rpk:"-"`
+}
+
+func TestNewFuncs_embedded(t *testing.T) {
+	_, err := newFuncs(&embedBoth{}, newOptions(nil))
+	errs, ok := err.(MethodErrors)
+	if !ok || len(errs) != 1 || errs[0].Method != "Shared" ||
+		!strings.Contains(errs[0].Error(), "embedA and embedB") {
+		t.Fatalf("Expected an ambiguity error for Shared, got: %v", err)
+	}
+
+	fs, err := newFuncs(&embedBoth{}, newOptions([]Option{Exclude("Shared")}))
+	if err != nil {
+		t.Fatal("Failed to create funcs:", err)
+	}
+	if want := []string{"Get", "OnlyA", "PtrA"}; !reflect.DeepEqual(fs.names(), want) {
+		t.Fatalf("names()=%v, want %v", fs.names(), want)
+	}
+
+	fs, err = newFuncs(&embedTagged{}, newOptions(nil))
+	if err != nil {
+		t.Fatal("Failed to create funcs:", err)
+	}
+	if want := []string{"OnlyA", "PtrA"}; !reflect.DeepEqual(fs.names(), want) {
+		t.Fatalf("names()=%v, want %v", fs.names(), want)
+	}
 }
@@ -0,0 +1,391 @@
+package rpk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Handler is an http.Handler that calls the functions registered on it. Access it
+// using the Javascript code served by HandleJS.
+//
+// Functions may be registered and replaced while the handler is serving.
+type Handler struct {
+	opts *options
+
+	// Registered functions. The map is never modified once set, so that calls can
+	// use it without holding fmu. Registration replaces it with a modified copy.
+	fmu      sync.RWMutex
+	funcs    funcs
+	disabled map[string]bool // By Disable, guarded by fmu.
+
+	hooks map[string]*hooks // By function name.
+
+	// Of SetTxWrapper, nil without it.
+	tx func(ctx context.Context, fn func(ctx context.Context) error) error
+
+	mu       sync.Mutex
+	inflight map[string]chan struct{} // Idempotent calls in progress, by key.
+
+	jobs jobStore    // Background jobs of async functions.
+	subs subscribers // Clients that receive broadcast messages.
+
+	tenants  tenantSet   // Of the Tenants option.
+	examples *exampleSet // Of the CaptureExamples option, nil without it.
+	nonces   nonceSet    // Of the ReplayProtection option.
+	stats    *statSet    // Of the CollectStats option, nil without it.
+	circuits circuitSet  // Of the CircuitBreaker option.
+	load     loadMeter   // Of the LoadShedding option.
+}
+
+// New returns a handler with no registered functions.
+func New(opts ...Option) *Handler {
+	h := &Handler{opts: newOptions(opts), funcs: funcs{},
+		inflight: map[string]chan struct{}{}, jobs: jobStore{m: map[string]*asyncJob{}}}
+	if h.opts.pubsub != nil {
+		h.opts.pubsub.Subscribe(h.subs.publish)
+	}
+	if h.opts.examples > 0 {
+		h.examples = &exampleSet{m: map[string][]Example{}}
+	}
+	if h.opts.stats {
+		h.stats = &statSet{}
+	}
+	return h
+}
+
+// Register exposes f under the given name. f should be a function that matches the
+// requirements of RPC methods - see package description. Returns an error if it does
+// not, or if the name is already taken.
+func (h *Handler) Register(name string, f interface{}) error {
+	value := reflect.ValueOf(f)
+	if value.Kind() != reflect.Func {
+		return fmt.Errorf("Function '%s': expected a function, got %T", name, f)
+	}
+	typ := value.Type()
+	err := checkName(name)
+	if err == nil {
+		err = checkInputs(typ, h.opts)
+	}
+	if err == nil {
+		err = checkOutputs(typ)
+	}
+	if err != nil {
+		return &MethodError{name, err}
+	}
+	m := newMethodInfo(typ, value, nil, h.opts)
+	m.source = "function " + fmt.Sprint(typ)
+	return h.add(funcs{name: m})
+}
+
+// RegisterObject exposes a's exported methods, under their names. Returns an error if
+// a's methods do not match the requirements - see package description, or if one of
+// their names is already taken. On error, none of a's methods are registered.
+func (h *Handler) RegisterObject(a interface{}) error {
+	f, err := newFuncs(a, h.opts)
+	if err != nil {
+		return err
+	}
+	return h.add(f)
+}
+
+// RegisterObjects exposes the exported methods of all the given objects, so a large API
+// can be split across several types. Returns an error like RegisterObject, or if two of
+// them have a method with the same name. Conflicts are reported in a deterministic
+// order: by argument position, then by method name. On error, none of the objects'
+// methods are registered.
+func (h *Handler) RegisterObjects(objs ...interface{}) error {
+	all := funcs{}
+	for _, a := range objs {
+		f, err := newFuncs(a, h.opts)
+		if err != nil {
+			return err
+		}
+		if errs := conflicts(f, all); errs != nil {
+			return errs
+		}
+		for name, m := range f {
+			all[name] = m
+		}
+	}
+	return h.add(all)
+}
+
+// add registers the given functions, unless one of their names is already taken.
+// Returns a MethodErrors listing all conflicting names, sorted.
+func (h *Handler) add(f funcs) error {
+	h.fmu.Lock()
+	defer h.fmu.Unlock()
+	if errs := conflicts(f, h.funcs); errs != nil {
+		return errs
+	}
+	result := make(funcs, len(h.funcs)+len(f))
+	for name, m := range h.funcs {
+		result[name] = m
+	}
+	for name, m := range f {
+		result[name] = m
+	}
+	h.funcs = result
+	return nil
+}
+
+// conflicts returns a MethodErrors listing the names of f that old already has, sorted,
+// or nil if there are none.
+func conflicts(f, old funcs) MethodErrors {
+	var errs MethodErrors
+	for _, name := range f.names() {
+		if o, ok := old[name]; ok {
+			errs = append(errs, &MethodError{name, fmt.Errorf(
+				"defined by %s, but already registered by %s", f[name].source, o.source)})
+		}
+	}
+	return errs
+}
+
+// Replace atomically replaces all registered functions with a's exported methods, like
+// RegisterObject does on a new handler. Calls in progress finish with the old
+// functions, and new calls get the new ones. This lets long-running servers enable and
+// disable features, or swap implementations, without restarting. Returns an error if
+// a's methods do not match the requirements, in which case the registered functions
+// are left as they are.
+func (h *Handler) Replace(a interface{}) error {
+	f, err := newFuncs(a, h.opts)
+	if err != nil {
+		return err
+	}
+	h.fmu.Lock()
+	h.funcs = f
+	h.fmu.Unlock()
+	return nil
+}
+
+// table returns the registered functions.
+func (h *Handler) table() funcs {
+	h.fmu.RLock()
+	defer h.fmu.RUnlock()
+	return h.funcs
+}
+
+// reservedNames are names of special functions that the handler provides.
+var reservedNames = map[string]bool{"funcs": true, "health": true, "jobStatus": true,
+	"jobResult": true, "events": true, "transaction": true, "upload": true}
+
+// checkName checks that a function name can be used for registration.
+func checkName(name string) error {
+	if name == "" {
+		return fmt.Errorf("name is empty")
+	}
+	if reservedNames[name] {
+		return fmt.Errorf("name is reserved")
+	}
+	return nil
+}
+
+// ServeHTTP calls the function that the request names.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The "Content-Type" header field should read "application/x-www-form-urlencoded".
+	// The content should be "func=FunctionName&param=JsonEncodedParam".
+	// Alternatively, the "Content-Type" header field can read "application/json", with
+	// "func=FunctionName" in the URL query and the encoded parameter as the body.
+	// In path routing mode, the function name is the URL path.
+	w.Header().Set("Content-Type", "application/json")
+
+	// Abandon the call when the client gives up.
+	if timeout, ok := requestTimeout(r); ok {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
+	h.negotiate(w, r)
+	r = h.withClientIP(r)
+	if h.opts.jsonrpc {
+		h.serveJSONRPC(w, r)
+		return
+	}
+	funcName, param, ok := h.route(w, r)
+	if !ok {
+		return
+	}
+	// Special value - "upload" - receives chunks of uploads, as calls of the functions
+	// that they are for.
+	if funcName == "upload" {
+		if r, funcName, param, ok = h.routeUpload(w, r); !ok {
+			return
+		}
+	}
+	if err := h.checkIP(r.Context(), funcName); err != nil {
+		writeCallError(w, err)
+		return
+	}
+
+	// Special value - "funcs" - returns the names of registered functions.
+	if funcName == "funcs" {
+		h.serveFuncs(w, r)
+		return
+	}
+	// Special value - "health" - returns the health report.
+	if funcName == "health" {
+		h.serveHealth(w, r)
+		return
+	}
+	// Special value - "events" - streams broadcast messages.
+	if funcName == "events" {
+		h.serveEvents(w, r)
+		return
+	}
+	if d := h.opts.deprecated[funcName]; d != nil {
+		d.setHeaders(w)
+	}
+	r, param, err := h.checkReplay(r, funcName, param)
+	if err != nil {
+		if h.opts.hideErrors {
+			err = h.hideError(err)
+		}
+		writeCallError(w, err)
+		return
+	}
+
+	if key := r.Header.Get(IdempotencyHeader); key != "" && h.opts.idempotency != nil &&
+		!h.opts.safe[funcName] && !validateOnly(r) && uploadWriter(r) == nil {
+		h.callIdempotent(w, r, key, funcName, param)
+		return
+	}
+
+	res := h.run(r, funcName, param)
+	if res.streamed(r) {
+		h.serveStream(w, r, funcName, &res)
+		return
+	}
+	setETag(w, &res)
+	res.write(w, h.opts.encode)
+}
+
+// serveStream writes a streamed result, counting the stream in the handler's stats.
+func (h *Handler) serveStream(w http.ResponseWriter, r *http.Request, funcName string,
+	res *callResult) {
+	if h.stats != nil {
+		defer h.stats.stream(funcName)()
+	}
+	h.writeStream(w, r, res)
+}
+
+// route returns the name of the function that the request calls and a reader of its
+// encoded parameter. Returns false if the request is bad, after writing an error.
+func (h *Handler) route(w http.ResponseWriter, r *http.Request) (string, io.Reader, bool) {
+	var name string
+	var param io.Reader
+	if h.opts.pathRouting {
+		name = strings.TrimPrefix(r.URL.Path, h.opts.pathPrefix)
+		name = strings.TrimPrefix(name, "/")
+		if r.Method == "GET" {
+			param = strings.NewReader(r.URL.Query().Get("param"))
+		} else {
+			param = r.Body
+		}
+	} else {
+		name = r.FormValue("func")
+		param = paramReader(r)
+	}
+
+	// Only safe functions may be called with GET. Special functions are all safe, except
+	// for transactions and uploads, which modify data.
+	safe := h.opts.safe[name] || reservedNames[name] && name != "transaction" &&
+		name != "upload"
+	if r.Method != "POST" && !(r.Method == "GET" && safe) {
+		allow := "POST"
+		if safe {
+			allow = "GET, POST"
+		}
+		w.Header().Set("Allow", allow)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeError(w, "Method %s is not allowed for function '%s'.", r.Method, name)
+		return "", nil, false
+	}
+	return name, param, true
+}
+
+// TimeoutHeader is the request header in which clients send their timeout for a call, in
+// milliseconds. The handler turns it into a deadline on the call's context, so functions
+// that take a context can abandon work that the client no longer waits for.
+const TimeoutHeader = "Rpk-Timeout"
+
+// requestTimeout returns the timeout in the request's TimeoutHeader, and whether there
+// is a valid one.
+func requestTimeout(r *http.Request) (time.Duration, bool) {
+	h := r.Header.Get(TimeoutHeader)
+	if h == "" {
+		return 0, false
+	}
+	ms, err := strconv.ParseInt(h, 10, 64)
+	if err != nil || ms <= 0 {
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// HandlerFunc returns a handler function that calls a's exported methods. Access this handler
+// using the Javascript code served by HandleJS. Returns an error if a's methods do not match
+// the requirements - see package description.
+func HandlerFunc(a interface{}, opts ...Option) (http.HandlerFunc, error) {
+	h := New(opts...)
+	if err := h.RegisterObject(a); err != nil {
+		return nil, err
+	}
+	return h.ServeHTTP, nil
+}
+
+// NewHandlerFunc returns a handler function that calls the exported methods of all the
+// given objects, like RegisterObjects, with the default options. New followed by
+// RegisterObject or RegisterObjects is the general form, which takes options:
+//
+//	h := rpk.New(rpk.Strict())
+//	err := h.RegisterObjects(users{}, orders{})
+func NewHandlerFunc(objs ...interface{}) (http.HandlerFunc, error) {
+	h := New()
+	if err := h.RegisterObjects(objs...); err != nil {
+		return nil, err
+	}
+	return h.ServeHTTP, nil
+}
+
+// MustHandler returns a handler that exposes the methods of interface T, as implemented
+// by impl. Methods of impl that are not in T are not exposed, so T serves as the
+// contract of the API, which a Go client can share with the server. Panics if T is not
+// an interface, if impl is nil or if T's methods do not match the requirements - see
+// package description.
+func MustHandler[T any](impl T, opts ...Option) *Handler {
+	value := reflect.ValueOf(&impl).Elem()
+	if value.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("rpk: type parameter %v is not an interface", value.Type()))
+	}
+	if value.IsNil() {
+		panic(fmt.Sprintf("rpk: implementation of %v is nil", value.Type()))
+	}
+	h := New(opts...)
+	f, err := methodFuncs(value, h.opts, "")
+	if err != nil {
+		panic("rpk: " + err.Error())
+	}
+	h.add(f)
+	return h
+}
+
+// paramReader returns a reader of the request's encoded input parameter. JSON requests
+// carry it in their body, so it is decoded as it streams in. Other requests carry it in
+// the "param" form value.
+func paramReader(r *http.Request) io.Reader {
+	typ, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if typ == "application/json" {
+		return r.Body
+	}
+	return strings.NewReader(r.FormValue("param"))
+}
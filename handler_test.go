@@ -2,10 +2,14 @@ package rpk
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestHandler(t *testing.T) {
@@ -40,6 +44,52 @@ func TestHandler(t *testing.T) {
 	}
 }
 
+func TestHandler_jsonBody(t *testing.T) {
+	handler, err := HandlerFunc(testType{})
+	if err != nil {
+		t.Fatal("Failed to create handler:", err)
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest("POST", "/api?func="+test.f,
+			strings.NewReader(test.arg))
+		if err != nil {
+			t.Fatal("Failed to create HTTP request:", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		res := &mockResponseWriter{bytes.NewBuffer(nil)}
+
+		handler(res, req)
+		result := res.buf.String()
+
+		if test.shouldErr && !isJSONError(result) {
+			t.Fatal("Expected error but got nil in test:", test)
+		}
+		if !test.shouldErr && isJSONError(result) {
+			t.Fatal("Expected success but got error in test:", test, result)
+		}
+		if !test.shouldErr && result != test.result {
+			t.Fatalf("Bad result for test: %v Got: %s", test, result)
+		}
+	}
+}
+
+func TestHandler_trailingData(t *testing.T) {
+	h := New()
+	h.Register("Half", func(i int) int { return i / 2 })
+	want := `{"error":"Error decoding JSON: unexpected data after the parameter"}`
+	for _, param := range []string{"10 garbage", "10}", "10 6", `10{"a":1}`} {
+		if got := callAPI(h, "Half", param); got != want {
+			t.Fatalf("Bad result for %s: %q, expected %q.", param, got, want)
+		}
+	}
+	for _, param := range []string{"10", " 10\n", "10\r\n\t"} {
+		if got := callAPI(h, "Half", param); got != "5" {
+			t.Fatalf("Bad result for %q: %q, expected %q.", param, got, "5")
+		}
+	}
+}
+
 func TestHandler_funcs(t *testing.T) {
 	handler, err := HandlerFunc(testType{})
 	if err != nil {
@@ -75,6 +125,269 @@ func TestHandler_funcs(t *testing.T) {
 	}
 }
 
+func TestHandler_register(t *testing.T) {
+	h := New()
+	err := h.Register("Half", func(ctx context.Context, i int) int {
+		return i / 2
+	})
+	if err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+	if err := h.RegisterObject(testType{}); err != nil {
+		t.Fatal("Failed to register object:", err)
+	}
+
+	badRegs := []struct {
+		name string
+		f    interface{}
+	}{
+		{"Half", func() {}},
+		{"Foo", func() {}},
+		{"funcs", func() {}},
+		{"", func() {}},
+		{"NotFunc", 5},
+		{"TwoInputs", func(a, b int) {}},
+		{"Variadic", func(a ...int) {}},
+	}
+	for _, reg := range badRegs {
+		if err := h.Register(reg.name, reg.f); err == nil {
+			t.Fatalf("Expected error when registering '%s'.", reg.name)
+		}
+	}
+	if err := h.RegisterObject(testType{}); err == nil {
+		t.Fatal("Expected error when registering the same object twice.")
+	}
+
+	for _, test := range []struct {
+		f      string
+		arg    string
+		result string
+	}{
+		{"Half", "10", "5\n"},
+		{"Bar", "7", "\"Bar 7\"\n"},
+	} {
+		req, err := http.NewRequest("POST", "", nil)
+		if err != nil {
+			t.Fatal("Failed to create HTTP request:", err)
+		}
+		req.PostForm = map[string][]string{
+			"func":  {test.f},
+			"param": {test.arg},
+		}
+		res := &mockResponseWriter{bytes.NewBuffer(nil)}
+		h.ServeHTTP(res, req)
+		if result := res.buf.String(); result != test.result {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.f, result, test.result)
+		}
+	}
+}
+
+func TestNewHandlerFunc(t *testing.T) {
+	handler, err := NewHandlerFunc(testType{}, otherType{})
+	if err != nil {
+		t.Fatal("Failed to create handler:", err)
+	}
+	req, err := http.NewRequest("POST", "", nil)
+	if err != nil {
+		t.Fatal("Failed to create HTTP request:", err)
+	}
+	req.PostForm = map[string][]string{"func": {"Other"}}
+	res := &mockResponseWriter{bytes.NewBuffer(nil)}
+	handler(res, req)
+	if result := res.buf.String(); result != "\"Other!\"\n" {
+		t.Fatalf("Bad result: %q, expected %q.", result, "\"Other!\"\n")
+	}
+
+	_, err = NewHandlerFunc(testType{}, otherType{}, conflictType{})
+	errs, ok := err.(MethodErrors)
+	if !ok {
+		t.Fatalf("Expected MethodErrors, got %T: %v", err, err)
+	}
+	if len(errs) != 2 || errs[0].Method != "Foo" || errs[1].Method != "Other" {
+		t.Fatalf("Expected conflicts on Foo and Other, got: %v", errs)
+	}
+}
+
+func TestRegisterObjects(t *testing.T) {
+	h := New(Exclude("Foo"))
+	if err := h.RegisterObjects(testType{}, otherType{}); err != nil {
+		t.Fatal("Failed to register objects:", err)
+	}
+	if h.funcs["Foo"] != nil || h.funcs["Other"] == nil {
+		t.Fatalf("Bad functions: %v, expected Other without Foo.", h.funcs.names())
+	}
+
+	h = New()
+	if err := h.RegisterObjects(otherType{}, conflictType{}); err == nil {
+		t.Fatal("Registered conflicting objects, expected an error.")
+	}
+	if len(h.funcs) != 0 {
+		t.Fatalf("Bad functions after a conflict: %v, expected none.", h.funcs.names())
+	}
+}
+
+func TestMustHandler(t *testing.T) {
+	h := MustHandler[barer](testType{})
+	if len(h.funcs) != 2 || h.funcs["Bar"] == nil || h.funcs["Ctx"] == nil {
+		t.Fatalf("Expected only Bar and Ctx to be exposed, got: %v", h.funcs.names())
+	}
+
+	req, err := http.NewRequest("POST", "", nil)
+	if err != nil {
+		t.Fatal("Failed to create HTTP request:", err)
+	}
+	req.PostForm = map[string][]string{"func": {"Bar"}, "param": {"7"}}
+	res := &mockResponseWriter{bytes.NewBuffer(nil)}
+	h.ServeHTTP(res, req)
+	if result := res.buf.String(); result != "\"Bar 7\"\n" {
+		t.Fatalf("Bad result: %q, expected %q.", result, "\"Bar 7\"\n")
+	}
+}
+
+func TestMustHandler_panic(t *testing.T) {
+	for name, f := range map[string]func(){
+		"not interface": func() { MustHandler(testType{}) },
+		"nil":           func() { MustHandler[barer](nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Expected panic for %s.", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestHandler_pathRouting(t *testing.T) {
+	handler, err := HandlerFunc(testType{}, PathRouting("/api/"))
+	if err != nil {
+		t.Fatal("Failed to create handler:", err)
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest("POST", "/api/"+test.f, strings.NewReader(test.arg))
+		if err != nil {
+			t.Fatal("Failed to create HTTP request:", err)
+		}
+		res := &mockResponseWriter{bytes.NewBuffer(nil)}
+
+		handler(res, req)
+		result := res.buf.String()
+
+		if test.shouldErr && !isJSONError(result) {
+			t.Fatal("Expected error but got nil in test:", test)
+		}
+		if !test.shouldErr && isJSONError(result) {
+			t.Fatal("Expected success but got error in test:", test, result)
+		}
+		if !test.shouldErr && result != test.result {
+			t.Fatalf("Bad result for test: %v Got: %s", test, result)
+		}
+	}
+
+	req, err := http.NewRequest("GET", "/api/Foo", nil)
+	if err != nil {
+		t.Fatal("Failed to create HTTP request:", err)
+	}
+	res := &mockResponseWriter{bytes.NewBuffer(nil)}
+	handler(res, req)
+	if !isJSONError(res.buf.String()) {
+		t.Fatal("Expected error for GET request, got:", res.buf.String())
+	}
+}
+
+func TestHandler_safe(t *testing.T) {
+	handler, err := HandlerFunc(testType{}, Safe("Bar"))
+	if err != nil {
+		t.Fatal("Failed to create handler:", err)
+	}
+
+	for _, test := range []struct {
+		method string
+		url    string
+		result string
+	}{
+		{"GET", "/api?func=Bar&param=7", "\"Bar 7\"\n"},
+		{"POST", "/api?func=Bar&param=7", "\"Bar 7\"\n"},
+		{"GET", "/api?func=FooStr", ""},
+		{"PUT", "/api?func=Bar&param=7", ""},
+	} {
+		req, err := http.NewRequest(test.method, test.url, nil)
+		if err != nil {
+			t.Fatal("Failed to create HTTP request:", err)
+		}
+		res := &mockResponseWriter{bytes.NewBuffer(nil)}
+		handler(res, req)
+		result := res.buf.String()
+
+		if test.result == "" && !isJSONError(result) {
+			t.Fatalf("Expected error for %s %s, got: %s", test.method, test.url, result)
+		}
+		if test.result != "" && result != test.result {
+			t.Fatalf("Bad result for %s %s: %q, expected %q.",
+				test.method, test.url, result, test.result)
+		}
+	}
+}
+
+func TestHandler_timeout(t *testing.T) {
+	h := New()
+	err := h.Register("Deadline", func(ctx context.Context) bool {
+		_, ok := ctx.Deadline()
+		return ok
+	})
+	if err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+
+	for _, test := range []struct {
+		timeout string
+		result  string
+	}{
+		{"", "false\n"},
+		{"1000", "true\n"},
+		{"abc", "false\n"},
+	} {
+		req, err := http.NewRequest("POST", "/api?func=Deadline", nil)
+		if err != nil {
+			t.Fatal("Failed to create HTTP request:", err)
+		}
+		if test.timeout != "" {
+			req.Header.Set(TimeoutHeader, test.timeout)
+		}
+		res := &mockResponseWriter{bytes.NewBuffer(nil)}
+		h.ServeHTTP(res, req)
+		if result := res.buf.String(); result != test.result {
+			t.Fatalf("Bad result for timeout %q: %q, expected %q.",
+				test.timeout, result, test.result)
+		}
+	}
+}
+
+func BenchmarkHandler(b *testing.B) {
+	handler, err := HandlerFunc(testType{})
+	if err != nil {
+		b.Fatal("Failed to create handler:", err)
+	}
+	req, err := http.NewRequest("POST", "", nil)
+	if err != nil {
+		b.Fatal("Failed to create HTTP request:", err)
+	}
+	req.PostForm = map[string][]string{
+		"func":  {"Bar"},
+		"param": {"7"},
+	}
+	res := &mockResponseWriter{bytes.NewBuffer(nil)}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		res.buf.Reset()
+		handler(res, req)
+	}
+}
+
 // ----- HELPERS ---------------------------------------------------------------
 
 func sliceToMap(a []string) map[string]bool {
@@ -96,3 +409,166 @@ func (m *mockResponseWriter) Header() http.Header {
 	return map[string][]string{}
 }
 func (m *mockResponseWriter) WriteHeader(i int) {}
+
+type otherType struct{}
+
+func (otherType) Other() string {
+	return "Other!"
+}
+
+type conflictType struct{}
+
+func (conflictType) Other()      {}
+func (conflictType) Foo()        {}
+func (conflictType) NoConflict() {}
+
+type barer interface {
+	Bar(i int) (string, error)
+	Ctx(ctx context.Context, i int) (string, error)
+}
+
+type replaceV1 struct{}
+
+func (replaceV1) Version() int { return 1 }
+
+type replaceV2 struct{}
+
+func (replaceV2) Version() int { return 2 }
+func (replaceV2) New() string  { return "new" }
+
+func TestHandler_replace(t *testing.T) {
+	h := New()
+	if err := h.RegisterObject(replaceV1{}); err != nil {
+		t.Fatal("Failed to register object:", err)
+	}
+	// Replace while calling.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if result := callAPI(h, "Version", ""); result != "1" && result != "2" {
+				t.Errorf("Bad result while replacing: %q", result)
+				return
+			}
+		}
+	}()
+	if err := h.Replace(replaceV2{}); err != nil {
+		t.Fatal("Failed to replace:", err)
+	}
+	<-done
+
+	if result := callAPI(h, "Version", ""); result != "2" {
+		t.Fatalf("Bad result after replacing: %q, expected %q.", result, "2")
+	}
+	if result := callAPI(h, "New", ""); result != `"new"` {
+		t.Fatalf("Bad result after replacing: %q, expected %q.", result, `"new"`)
+	}
+	if err := h.Replace(badMany{}); err == nil {
+		t.Fatal("Replace with bad methods succeeded.")
+	}
+	if result := callAPI(h, "New", ""); result != `"new"` {
+		t.Fatalf("Bad result after failed replace: %q, expected %q.", result, `"new"`)
+	}
+}
+
+func TestHandler_strict(t *testing.T) {
+	type point struct {
+		X, Y int
+		When time.Time
+	}
+	for _, opts := range [][]Option{{Strict()}, {Strict(), TimeFormat(time.DateOnly)}} {
+		h := New(opts...)
+		h.Register("Sum", func(p point) int { return p.X + p.Y })
+
+		for _, test := range []struct {
+			param  string
+			result string
+		}{
+			{`{"x":1,"Y":2}`, "3"},
+			{`{"X":1,"Z":2}`, `{"error":"Error decoding JSON: json: unknown field \"Z\""}`},
+		} {
+			if result := callAPI(h, "Sum", test.param); result != test.result {
+				t.Fatalf("Bad result for %s: %q, expected %q.", test.param, result,
+					test.result)
+			}
+		}
+	}
+}
+
+func TestHandler_specialCharacters(t *testing.T) {
+	h := New(Safe("Echo"))
+	h.Register("Echo", func(s string) string { return s })
+	ph := New(Safe("Echo"), PathRouting("/api/"))
+	ph.Register("Echo", func(s string) string { return s })
+
+	for _, s := range []string{"a&b", "a+b", "a#b", "a%20b", "a=b?c/d", "a b\n", "ü\"'"} {
+		data, _ := json.Marshal(s)
+		// Like the Javascript client's encodeURIComponent.
+		param := strings.ReplaceAll(url.QueryEscape(string(data)), "+", "%20")
+		form := func(target string) *http.Request {
+			req := httptest.NewRequest("POST", target, strings.NewReader("param="+param))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			return req
+		}
+		tests := []struct {
+			h   *Handler
+			req *http.Request
+		}{
+			{h, form("/api?func=Echo")},
+			{h, httptest.NewRequest("GET", "/api?func=Echo&param="+param, nil)},
+			{ph, httptest.NewRequest("GET", "/api/Echo?param="+param, nil)},
+			{ph, httptest.NewRequest("POST", "/api/Echo", bytes.NewReader(data))},
+		}
+		for _, test := range tests {
+			res := httptest.NewRecorder()
+			test.h.ServeHTTP(res, test.req)
+			if got := strings.TrimSpace(res.Body.String()); got != string(data) {
+				t.Fatalf("Bad result for %s %s: %s, expected %s.", test.req.Method,
+					test.req.URL, got, data)
+			}
+		}
+	}
+}
+
+func TestHandler_unicode(t *testing.T) {
+	type message struct {
+		Text string
+		Data []byte
+	}
+	h := New()
+	h.Register("Echo", func(m message) message { return m })
+
+	tests := []struct {
+		param string
+		want  message
+	}{
+		{`{"Text":"שלום, ü, 日本"}`, message{Text: "שלום, ü, 日本"}},
+		{`{"Text":"😀"}`, message{Text: "😀"}},
+		// Surrogate pairs, as Javascript may escape characters beyond the BMP.
+		{`{"Text":"\ud83d\ude00"}`, message{Text: "😀"}},
+		{`{"Text":"a\u0000b\u001f\t\n "}`, message{Text: "a\x00b\x1f\t\n "}},
+		{`{"Data":"AAH6/w=="}`, message{Data: []byte{0, 1, 250, 255}}},
+	}
+	for _, test := range tests {
+		for _, form := range []bool{false, true} {
+			var req *http.Request
+			if form {
+				req = httptest.NewRequest("POST", "/api?func=Echo",
+					strings.NewReader("param="+url.QueryEscape(test.param)))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				req = apiRequest("Echo", test.param)
+			}
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+			var got message
+			if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+				t.Fatalf("Bad result for %s: %s", test.param, res.Body)
+			}
+			if got.Text != test.want.Text || !bytes.Equal(got.Data, test.want.Data) {
+				t.Fatalf("Bad result for %s: %+v, expected %+v.", test.param, got,
+					test.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package rpk
+
+import (
+	"context"
+	"net/http"
+)
+
+// A HealthReport describes the status of a handler, as served by its health endpoint.
+type HealthReport struct {
+	// Status is "ok" if all checks passed, or "error" otherwise.
+	Status string `json:"status"`
+
+	// Funcs is the number of registered functions.
+	Funcs int `json:"funcs"`
+
+	// Checks maps each check's name to "ok", or to its error message.
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// healthCheck is a named check added with the HealthCheck option.
+type healthCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// HealthCheck adds a check to the handler's health report, for example pinging a
+// database. The check should report an error if the handler cannot serve calls.
+func HealthCheck(name string, check func(ctx context.Context) error) Option {
+	return func(o *options) {
+		o.healthChecks = append(o.healthChecks, healthCheck{name, check})
+	}
+}
+
+// Health runs the handler's health checks and reports its status.
+func (h *Handler) Health(ctx context.Context) *HealthReport {
+	report := &HealthReport{Status: "ok", Funcs: len(h.table())}
+	if len(h.opts.healthChecks) > 0 {
+		report.Checks = map[string]string{}
+	}
+	for _, c := range h.opts.healthChecks {
+		if err := c.check(ctx); err != nil {
+			report.Status = "error"
+			report.Checks[c.name] = err.Error()
+		} else {
+			report.Checks[c.name] = "ok"
+		}
+	}
+	return report
+}
+
+// HealthHandler returns a handler that serves the handler's health report, for load
+// balancers and readiness probes, for example at "/healthz". Responds with status 503 if
+// a check fails. The same report is served by the RPK handler itself, as the special
+// function "health".
+func (h *Handler) HealthHandler() http.Handler {
+	return http.HandlerFunc(h.serveHealth)
+}
+
+// serveHealth writes the handler's health report.
+func (h *Handler) serveHealth(w http.ResponseWriter, r *http.Request) {
+	report := h.Health(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	h.opts.encode.newEncoder(w).Encode(report)
+}
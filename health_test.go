@@ -0,0 +1,51 @@
+package rpk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealth(t *testing.T) {
+	dbErr := fmt.Errorf("db is down")
+	h := New(
+		HealthCheck("cache", func(ctx context.Context) error { return nil }),
+		HealthCheck("db", func(ctx context.Context) error { return dbErr }),
+	)
+	if err := h.RegisterObject(testType{}); err != nil {
+		t.Fatal("Failed to register object:", err)
+	}
+
+	req := httptest.NewRequest("GET", "/healthz", nil)
+	res := httptest.NewRecorder()
+	h.HealthHandler().ServeHTTP(res, req)
+
+	if res.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Bad status code: %d, expected %d.", res.Code, http.StatusServiceUnavailable)
+	}
+	var report HealthReport
+	if err := json.Unmarshal(res.Body.Bytes(), &report); err != nil {
+		t.Fatal("Failed to parse JSON response:", err)
+	}
+	if report.Status != "error" || report.Funcs != len(funcNames) ||
+		report.Checks["cache"] != "ok" || report.Checks["db"] != "db is down" {
+		t.Fatalf("Bad health report: %+v", report)
+	}
+
+	// Through the RPK handler.
+	dbErr = nil
+	req = httptest.NewRequest("GET", "/api?func=health", nil)
+	res = httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+
+	if res.Code != http.StatusOK {
+		t.Fatalf("Bad status code: %d, expected %d.", res.Code, http.StatusOK)
+	}
+	if !bytes.Contains(res.Body.Bytes(), []byte(`"status":"ok"`)) {
+		t.Fatalf("Bad health report: %s", res.Body.String())
+	}
+}
@@ -0,0 +1,32 @@
+package rpk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// apiRequest returns a request that calls the named function, with param as its JSON
+// parameter, or without a parameter if it is empty.
+func apiRequest(funcName, param string) *http.Request {
+	if param == "" {
+		return httptest.NewRequest("POST", "/api?func="+funcName, nil)
+	}
+	req := httptest.NewRequest("POST", "/api?func="+funcName, strings.NewReader(param))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// serve serves req with h, and returns the body of the response without surrounding
+// whitespace.
+func serve(h http.Handler, req *http.Request) string {
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	return strings.TrimSpace(res.Body.String())
+}
+
+// callAPI calls the named function of h, with param as its JSON parameter like in
+// apiRequest, and returns the body of the response like serve.
+func callAPI(h http.Handler, funcName, param string) string {
+	return serve(h, apiRequest(funcName, param))
+}
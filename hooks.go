@@ -0,0 +1,140 @@
+package rpk
+
+import (
+	"io"
+	"net/http"
+)
+
+// A Call describes a function call, as seen by hooks.
+type Call struct {
+	// Func is the name of the called function.
+	Func string
+
+	// Request is the HTTP request of the call.
+	Request *http.Request
+
+	// Tenant is the ID of the call's tenant, with the Tenants option.
+	Tenant string
+
+	// Param points to the decoded input parameter. It is nil in before hooks, or if the
+	// function takes no input. Fields tagged with `rpk:"redact"` are zeroed.
+	Param interface{}
+
+	// Result is the function's value output, if any. Set for after hooks.
+	Result interface{}
+
+	// Err is the error of the call. Set for error hooks.
+	Err error
+
+	// ValidateOnly tells whether the call only validates its parameter, as asked in the
+	// ValidateOnlyHeader, so the function is not called even if the hooks pass.
+	ValidateOnly bool
+}
+
+// hooks holds the hooks of a function.
+type hooks struct {
+	before  []func(c *Call) error
+	after   []func(c *Call)
+	onError []func(c *Call)
+}
+
+// hooksOf returns the hooks of the named function, creating them if needed.
+func (h *Handler) hooksOf(name string) *hooks {
+	if h.hooks == nil {
+		h.hooks = map[string]*hooks{}
+	}
+	if h.hooks[name] == nil {
+		h.hooks[name] = &hooks{}
+	}
+	return h.hooks[name]
+}
+
+// Before adds a hook that runs before the named function is called, for example to
+// check permissions. If the hook returns an error, the function is not called, and the
+// error is returned to the client. The name "*" matches all functions, and its hooks run
+// before the function's own hooks. Hooks should be added before the handler starts
+// serving.
+func (h *Handler) Before(name string, hook func(c *Call) error) {
+	h.hooksOf(name).before = append(h.hooksOf(name).before, hook)
+}
+
+// After adds a hook that runs after the named function returns successfully, for example
+// for audit logging. The name "*" matches all functions.
+func (h *Handler) After(name string, hook func(c *Call)) {
+	h.hooksOf(name).after = append(h.hooksOf(name).after, hook)
+}
+
+// OnError adds a hook that runs when a call to the named function fails, including
+// failures of decoding its parameter and errors returned by before hooks. The name "*"
+// matches all functions.
+func (h *Handler) OnError(name string, hook func(c *Call)) {
+	h.hooksOf(name).onError = append(h.hooksOf(name).onError, hook)
+}
+
+// runHooked calls a function like funcs.run, with the request's context and the
+// handler's decoder, running the function's hooks around it.
+func (h *Handler) runHooked(r *http.Request, funcName string,
+	param io.Reader) callResult {
+	all, own := h.hooks["*"], h.hooks[funcName]
+	if all == nil && own == nil {
+		res := h.call(r, funcName, param)
+		if uploadWriter(r) == nil {
+			h.recordExample(funcName, &res)
+		}
+		return res
+	}
+	c := &Call{Func: funcName, Request: r, Tenant: TenantOf(r.Context()),
+		ValidateOnly: validateOnly(r)}
+	res := h.runBefore(c, param, all, own)
+	if uploadWriter(r) == nil {
+		h.recordExample(funcName, &res)
+	}
+	c.Param, c.Result, c.Err = Redact(res.param), res.val, res.err
+	if c.Err != nil {
+		for _, hk := range []*hooks{all, own} {
+			if hk != nil {
+				for _, f := range hk.onError {
+					f(c)
+				}
+			}
+		}
+		return res
+	}
+	for _, hk := range []*hooks{all, own} {
+		if hk != nil {
+			for _, f := range hk.after {
+				f(c)
+			}
+		}
+	}
+	return res
+}
+
+// runBefore runs the before hooks of a call, and if they pass, calls the function.
+func (h *Handler) runBefore(c *Call, param io.Reader, hks ...*hooks) callResult {
+	for _, hk := range hks {
+		if hk == nil {
+			continue
+		}
+		for _, f := range hk.before {
+			if err := f(c); err != nil {
+				return callResult{err: err}
+			}
+		}
+	}
+	return h.call(c.Request, c.Func, param)
+}
+
+// call calls a function like funcs.run, with the request's context and the handler's
+// decoder, or receives a chunk of an upload for it if the request is of the "upload"
+// function.
+func (h *Handler) call(r *http.Request, funcName string, param io.Reader) callResult {
+	if w := uploadWriter(r); w != nil {
+		info, err := h.upload(w, r, param)
+		if err != nil {
+			return callResult{err: err}
+		}
+		return callResult{val: info, hasOut: true}
+	}
+	return h.table().run(h.callContext(r), funcName, param, h.opts.decoder)
+}
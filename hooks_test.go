@@ -0,0 +1,68 @@
+package rpk
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestHandler_hooks(t *testing.T) {
+	h := New()
+	err := h.Register("Double", func(a int) (int, error) {
+		if a < 0 {
+			return 0, errors.New("negative")
+		}
+		return a * 2, nil
+	})
+	if err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+
+	var log []string
+	h.Before("*", func(c *Call) error {
+		log = append(log, "before * "+c.Func)
+		return nil
+	})
+	h.Before("Double", func(c *Call) error {
+		log = append(log, "before "+c.Func)
+		if c.Request.Header.Get("X-Deny") != "" {
+			return errors.New("denied")
+		}
+		return nil
+	})
+	h.After("Double", func(c *Call) {
+		log = append(log, "after", fmt.Sprint(*c.Param.(*int)), fmt.Sprint(c.Result))
+	})
+	h.OnError("*", func(c *Call) {
+		log = append(log, "error "+c.Err.Error())
+	})
+
+	tests := []struct {
+		param  string
+		deny   bool
+		result string
+		log    []string
+	}{
+		{"3", false, "6", []string{"before * Double", "before Double", "after", "3",
+			"6"}},
+		{"-1", false, `{"error":"negative"}`, []string{"before * Double",
+			"before Double", "error negative"}},
+		{"3", true, `{"error":"denied"}`, []string{"before * Double", "before Double",
+			"error denied"}},
+	}
+	for _, test := range tests {
+		log = nil
+		req := apiRequest("Double", test.param)
+		if test.deny {
+			req.Header.Set("X-Deny", "1")
+		}
+		if result := serve(h, req); result != test.result {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.param, result,
+				test.result)
+		}
+		if !reflect.DeepEqual(log, test.log) {
+			t.Fatalf("Bad hook log for %s: %q, expected %q.", test.param, log, test.log)
+		}
+	}
+}
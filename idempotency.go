@@ -0,0 +1,169 @@
+package rpk
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IdempotencyHeader is the request header in which clients send an idempotency key. If
+// the handler has an idempotency store, repeated calls to the same function with the
+// same key get the recorded response of the first call, instead of calling the function
+// again. This protects unsafe functions against double submits from flaky networks
+// and impatient users. Functions marked with the Safe option are not affected, and
+// streamed results are not recorded.
+const IdempotencyHeader = "Idempotency-Key"
+
+// An IdempotencyStore records responses of calls by their idempotency keys. It should be
+// safe for concurrent use.
+type IdempotencyStore interface {
+	// Get returns the response recorded under key, and whether there is one.
+	Get(key string) ([]byte, bool)
+
+	// Put records a response under key.
+	Put(key string, response []byte)
+}
+
+// Idempotency makes the handler record responses in store, by the idempotency keys that
+// clients send in IdempotencyHeader. Keys are scoped by function, by tenant with the
+// Tenants option, by the ID of the signing key with ReplayProtection, and by the caller
+// with IdempotencyScope, so that different callers cannot get each other's responses.
+// Errors that a retry may not get, like those that wrap ErrUnavailable or of calls
+// whose clients gave up, are not recorded.
+func Idempotency(store IdempotencyStore) Option {
+	return func(o *options) {
+		o.idempotency = store
+	}
+}
+
+// IdempotencyScope makes the handler scope idempotency keys by the caller that scope
+// returns for a request, like the ID of its user, for the Idempotency option.
+func IdempotencyScope(scope func(r *http.Request) string) Option {
+	return func(o *options) {
+		o.idemScope = scope
+	}
+}
+
+// callIdempotent calls a function and writes its result, unless a call with the same
+// function and idempotency key was already made, in which case its recorded response is
+// written instead.
+// Concurrent calls with the same key wait for the first one to finish.
+func (h *Handler) callIdempotent(w http.ResponseWriter, r *http.Request, key,
+	funcName string, param io.Reader) {
+	key = h.idempotencyScope(r) + "\n" + funcName + "\n" + key
+	store := h.opts.idempotency
+
+	for {
+		h.mu.Lock()
+		if done, ok := h.inflight[key]; ok {
+			h.mu.Unlock()
+			select {
+			case <-done:
+				continue
+			case <-r.Context().Done():
+				writeCallError(w, r.Context().Err())
+				return
+			}
+		}
+		if response, ok := store.Get(key); ok {
+			h.mu.Unlock()
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.Write(response)
+			return
+		}
+		h.inflight[key] = make(chan struct{})
+		h.mu.Unlock()
+		break
+	}
+
+	// Released even if the function panics, so that later calls with the key do not
+	// wait for it forever.
+	defer func() {
+		h.mu.Lock()
+		close(h.inflight[key])
+		delete(h.inflight, key)
+		h.mu.Unlock()
+	}()
+
+	res := h.run(r, funcName, param)
+	if res.streamed(r) {
+		// Streams are written as they are made, so they cannot be recorded.
+		h.serveStream(w, r, funcName, &res)
+		return
+	}
+	buf := bytes.NewBuffer(nil)
+	setETag(w, &res)
+	res.write(buf, h.opts.encode)
+	if !transient(r, res.err) {
+		store.Put(key, buf.Bytes())
+	}
+	w.Write(buf.Bytes())
+}
+
+// idempotencyScope returns the caller of a request, that its idempotency key is scoped
+// by.
+func (h *Handler) idempotencyScope(r *http.Request) string {
+	var tenant, caller string
+	if h.opts.tenants != nil {
+		// A tenant that cannot be resolved fails the call, so its response is not
+		// shared.
+		tenant, _ = h.opts.tenants(r)
+	}
+	if h.opts.idemScope != nil {
+		caller = h.opts.idemScope(r)
+	}
+	return strings.Join([]string{tenant, SignerKeyID(r.Context()), caller}, "\n")
+}
+
+// transient returns whether err is of a failure that a retry of the call may not get,
+// which is not recorded for its idempotency key.
+func transient(r *http.Request, err error) bool {
+	return err != nil && (r.Context().Err() != nil ||
+		HTTPStatus(err) == http.StatusServiceUnavailable)
+}
+
+// NewMemoryIdempotencyStore returns an in-memory store that keeps responses for the
+// given duration.
+func NewMemoryIdempotencyStore(ttl time.Duration) IdempotencyStore {
+	return &memoryIdempotencyStore{ttl: ttl, m: map[string]*storedResponse{}}
+}
+
+// memoryIdempotencyStore is an IdempotencyStore that keeps responses in memory.
+type memoryIdempotencyStore struct {
+	ttl time.Duration
+	mu  sync.Mutex
+	m   map[string]*storedResponse
+}
+
+// storedResponse is a response kept in a memoryIdempotencyStore.
+type storedResponse struct {
+	response []byte
+	expires  time.Time
+}
+
+func (s *memoryIdempotencyStore) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.m[key]
+	if !ok || time.Now().After(r.expires) {
+		return nil, false
+	}
+	return r.response, true
+}
+
+func (s *memoryIdempotencyStore) Put(key string, response []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop expired responses.
+	now := time.Now()
+	for k, r := range s.m {
+		if now.After(r.expires) {
+			delete(s.m, k)
+		}
+	}
+	s.m[key] = &storedResponse{response, now.Add(s.ttl)}
+}
@@ -0,0 +1,228 @@
+package rpk
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIdempotency(t *testing.T) {
+	calls := 0
+	h := New(Idempotency(NewMemoryIdempotencyStore(time.Minute)))
+	err := h.Register("Inc", func() int {
+		calls++
+		return calls
+	})
+	if err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+
+	for _, test := range []struct {
+		key    string
+		result string
+	}{
+		{"a", "1"},
+		{"a", "1"},
+		{"b", "2"},
+		{"", "3"},
+		{"b", "2"},
+		{"", "4"},
+	} {
+		req := apiRequest("Inc", "")
+		if test.key != "" {
+			req.Header.Set(IdempotencyHeader, test.key)
+		}
+		if result := serve(h, req); result != test.result {
+			t.Fatalf("Bad result for key %q: %q, expected %q.", test.key, result, test.result)
+		}
+	}
+}
+
+func TestIdempotency_concurrent(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	h := New(Idempotency(NewMemoryIdempotencyStore(time.Minute)))
+	err := h.Register("Slow", func() int {
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return calls
+	})
+	if err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := apiRequest("Slow", "")
+			req.Header.Set(IdempotencyHeader, "key")
+			results[i] = serve(h, req)
+		}(i)
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("Function was called %d times, expected 1.", calls)
+	}
+	for _, result := range results {
+		if result != "1" {
+			t.Fatalf("Bad result: %q, expected %q.", result, "1")
+		}
+	}
+}
+
+func TestMemoryIdempotencyStore_expiry(t *testing.T) {
+	s := NewMemoryIdempotencyStore(time.Millisecond)
+	s.Put("a", []byte("x"))
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := s.Get("a"); ok {
+		t.Fatal("Expected response to expire.")
+	}
+}
+
+func TestIdempotency_transient(t *testing.T) {
+	calls := 0
+	h := New(Idempotency(NewMemoryIdempotencyStore(time.Minute)))
+	h.Register("Inc", func() (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, fmt.Errorf("database is down: %w", ErrUnavailable)
+		}
+		if calls == 2 {
+			panic("oops")
+		}
+		return calls, nil
+	})
+	call := func() (result string, panicked bool) {
+		defer func() {
+			if recover() != nil {
+				panicked = true
+			}
+		}()
+		req := apiRequest("Inc", "")
+		req.Header.Set(IdempotencyHeader, "a")
+		return serve(h, req), false
+	}
+
+	want := `{"error":"database is down: unavailable","code":"unavailable"}`
+	if result, _ := call(); result != want {
+		t.Fatalf("Bad result: %q, expected %q.", result, want)
+	}
+	if _, panicked := call(); !panicked {
+		t.Fatal("Expected the call to panic.")
+	}
+	for range 2 {
+		done := make(chan string)
+		go func() {
+			result, _ := call()
+			done <- result
+		}()
+		select {
+		case result := <-done:
+			if result != "3" {
+				t.Fatalf("Bad result: %q, expected %q.", result, "3")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Call after a panic did not return.")
+		}
+	}
+}
+
+func TestIdempotency_canceled(t *testing.T) {
+	release := make(chan struct{})
+	h := New(Idempotency(NewMemoryIdempotencyStore(time.Minute)))
+	h.Register("Slow", func() int {
+		<-release
+		return 1
+	})
+	call := func(ctx context.Context) string {
+		req := apiRequest("Slow", "").WithContext(ctx)
+		req.Header.Set(IdempotencyHeader, "a")
+		return serve(h, req)
+	}
+	first := make(chan string)
+	go func() { first <- call(context.Background()) }()
+	for {
+		h.mu.Lock()
+		n := len(h.inflight)
+		h.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if result := call(ctx); !strings.Contains(result, "deadline exceeded") {
+		t.Fatalf("Bad result of a waiting call that timed out: %q", result)
+	}
+	close(release)
+	if result := <-first; result != "1" {
+		t.Fatalf("Bad result: %q, expected %q.", result, "1")
+	}
+}
+
+func TestIdempotency_scope(t *testing.T) {
+	calls := 0
+	h := New(Idempotency(NewMemoryIdempotencyStore(time.Minute)),
+		Tenants(func(r *http.Request) (string, error) {
+			return r.Header.Get("Tenant"), nil
+		}),
+		IdempotencyScope(func(r *http.Request) string {
+			return r.Header.Get("User")
+		}))
+	h.Register("Inc", func() int {
+		calls++
+		return calls
+	})
+
+	for _, test := range []struct {
+		tenant, user string
+		result       string
+	}{
+		{"a", "x", "1"},
+		{"a", "x", "1"},
+		{"b", "x", "2"},
+		{"a", "y", "3"},
+		{"b", "x", "2"},
+		{"a", "y", "3"},
+	} {
+		req := apiRequest("Inc", "")
+		req.Header.Set(IdempotencyHeader, "key")
+		req.Header.Set("Tenant", test.tenant)
+		req.Header.Set("User", test.user)
+		if result := serve(h, req); result != test.result {
+			t.Fatalf("Bad result for %s/%s: %q, expected %q.", test.tenant, test.user,
+				result, test.result)
+		}
+	}
+}
+
+func TestIdempotency_stream(t *testing.T) {
+	calls := 0
+	h := New(Idempotency(NewMemoryIdempotencyStore(time.Minute)))
+	h.Register("Count", func() iter.Seq[int] {
+		calls++
+		return func(yield func(int) bool) {
+			_ = yield(calls) && yield(calls+1)
+		}
+	})
+	for _, want := range []string{"1\n2", "2\n3"} {
+		req := apiRequest("Count", "")
+		req.Header.Set(IdempotencyHeader, "a")
+		if result := serve(h, req); result != want {
+			t.Fatalf("Bad result: %q, expected %q.", result, want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package rpk
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Int64AsString makes the handler encode int64 and uint64 values in parameters and
+// results as strings, like "9007199254740993". Javascript numbers cannot hold integers
+// beyond 2^53 exactly, so large IDs would otherwise change silently on the way. Decoding
+// accepts both strings and numbers. Types with custom marshaling are not affected.
+func Int64AsString() Option {
+	return func(o *options) {
+		kinds := o.codecsOf().kinds
+		kinds[reflect.Int64] = &codec{
+			encode: func(v reflect.Value) (interface{}, error) {
+				return strconv.FormatInt(v.Int(), 10), nil
+			},
+			decode: func(data []byte, v reflect.Value) error {
+				s, err := intString(data)
+				if err != nil {
+					return err
+				}
+				n, err := strconv.ParseInt(s, 10, 64)
+				if err != nil {
+					return fmt.Errorf("bad int64 %q", s)
+				}
+				v.SetInt(n)
+				return nil
+			},
+		}
+		kinds[reflect.Uint64] = &codec{
+			encode: func(v reflect.Value) (interface{}, error) {
+				return strconv.FormatUint(v.Uint(), 10), nil
+			},
+			decode: func(data []byte, v reflect.Value) error {
+				s, err := intString(data)
+				if err != nil {
+					return err
+				}
+				n, err := strconv.ParseUint(s, 10, 64)
+				if err != nil {
+					return fmt.Errorf("bad uint64 %q", s)
+				}
+				v.SetUint(n)
+				return nil
+			},
+		}
+	}
+}
+
+// intString returns the digits of an integer encoded as a JSON string or number.
+func intString(data []byte) (string, error) {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		return s, nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return "", fmt.Errorf("expected an integer, got %s", data)
+	}
+	return n.String(), nil
+}
+
+// int64Paths returns the paths in the results of the named functions of the values that
+// Int64AsString encodes as strings, for the bigints option of the Javascript client. A
+// path lists the names of object members, with "*" for all the items of an array or
+// the members of an object. Returns nil if the handler does not have the option.
+func (h *Handler) int64Paths(names []string) map[string][][]string {
+	cs := h.opts.codecs
+	if cs == nil || cs.kinds[reflect.Int64] == nil {
+		return nil
+	}
+	result := map[string][][]string{}
+	for _, name := range names {
+		m := h.table()[name]
+		if m == nil || !m.value.IsValid() || m.valOut == -1 {
+			continue
+		}
+		var paths [][]string
+		cs.int64Paths(m.value.Type().Out(m.valOut), nil, map[reflect.Type]bool{},
+			&paths)
+		if paths != nil {
+			result[name] = paths
+		}
+	}
+	return result
+}
+
+// int64Paths adds to paths the paths of the int64 and uint64 values in values of type
+// t, which is at path. Seen holds the types that are already being looked into, whose
+// recursive values are not looked into again.
+func (cs *codecSet) int64Paths(t reflect.Type, path []string, seen map[reflect.Type]bool,
+	paths *[][]string) {
+	if c := cs.get(t); c != nil {
+		if c == cs.kinds[reflect.Int64] || c == cs.kinds[reflect.Uint64] {
+			*paths = append(*paths, append([]string{}, path...))
+		}
+		return
+	}
+	if seen[t] || t.Kind() != reflect.Ptr && isMarshaler(t) && !isOptional(t) {
+		return
+	}
+	seen[t] = true
+	defer delete(seen, t)
+	if isOptional(t) {
+		cs.int64Paths(t.Field(0).Type, path, seen, paths)
+		return
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		cs.int64Paths(t.Elem(), path, seen, paths)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		cs.int64Paths(t.Elem(), append(path, "*"), seen, paths)
+	case reflect.Struct:
+		for _, f := range jsonFields(t) {
+			cs.int64Paths(t.FieldByIndex(f.index).Type, append(path, cs.fieldName(f)),
+				seen, paths)
+		}
+	}
+}
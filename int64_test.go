@@ -0,0 +1,70 @@
+package rpk
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type int64Item struct {
+	ID    int64
+	Count int
+	Flags uint64
+	Took  time.Duration
+	Refs  map[string]int64
+}
+
+func TestInt64AsString(t *testing.T) {
+	h := New(Int64AsString())
+	err := h.Register("Echo", func(i int64Item) int64Item { return i })
+	if err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+	tests := []struct {
+		param  string
+		result string
+	}{
+		{`{"ID":"9007199254740993","Count":1,"Flags":"18446744073709551615",` +
+			`"Took":"5","Refs":{"a":"-9007199254740993"}}`,
+			`{"ID":"9007199254740993","Count":1,"Flags":"18446744073709551615",` +
+				`"Took":"5","Refs":{"a":"-9007199254740993"}}`},
+		{`{"ID":9007199254740993,"Flags":3,"Took":5}`,
+			`{"ID":"9007199254740993","Count":0,"Flags":"3","Took":"5","Refs":null}`},
+		{`{"ID":"1.5"}`, `{"error":"Error decoding JSON: field ID: bad int64 \"1.5\""}`},
+	}
+	for _, test := range tests {
+		if result := callAPI(h, "Echo", test.param); result != test.result {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.param, result,
+				test.result)
+		}
+	}
+}
+
+func TestInt64AsString_paths(t *testing.T) {
+	h := New(Int64AsString(), FieldNaming(CamelCase))
+	h.Register("Item", func() int64Item { return int64Item{} })
+	h.Register("Items", func() []*int64Item { return nil })
+	h.Register("Count", func() int { return 0 })
+	h.Register("Name", func() string { return "" })
+
+	tests := []struct {
+		features string
+		result   string
+	}{
+		{"", `["Count","Item","Items","Name"]`},
+		{"jsnames", `["Count","Item","Items","Name"]`},
+		{"int64string", `{"funcs":["Count","Item","Items","Name"],"int64Paths":{` +
+			`"Item":[["id"],["flags"],["took"],["refs","*"]],` +
+			`"Items":[["*","id"],["*","flags"],["*","took"],["*","refs","*"]]}}`},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/api?func=funcs", nil)
+		req.Header.Set(FeaturesHeader, test.features)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		if result := res.Body.String(); result != test.result+"\n" {
+			t.Fatalf("Bad result for %q: %q, expected %q.", test.features, result,
+				test.result)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package rpk
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+)
+
+// Internal marks the named functions as internal, like debugging and admin functions.
+// They are left out of the funcs list, Bootstrap, Snapshot and the playground, and can
+// only be called by requests for which allow returns true. Other requests get the error
+// of calling a function that does not exist, so browsers cannot tell that they are
+// there. For example:
+//
+//	rpk.Internal(rpk.LocalRequest, "DumpCache", "SetLogLevel")
+func Internal(allow func(r *http.Request) bool, names ...string) Option {
+	return func(o *options) {
+		if o.internal == nil {
+			o.internal = map[string]func(r *http.Request) bool{}
+		}
+		for _, name := range names {
+			o.internal[name] = allow
+		}
+	}
+}
+
+// LocalRequest returns whether a request comes from the local host, by its client IP
+// with the TrustedProxies option, or otherwise by its remote address. It can be given
+// to Internal.
+func LocalRequest(r *http.Request) bool {
+	if ip := ClientIP(r.Context()); ip != "" {
+		addr, err := netip.ParseAddr(ip)
+		return err == nil && addr.IsLoopback()
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	return err == nil && addr.IsLoopback()
+}
+
+// publicNames returns the sorted names of the functions that are not internal.
+func (h *Handler) publicNames(fs funcs) []string {
+	names := fs.names()
+	if h.opts.internal == nil {
+		return names
+	}
+	result := names[:0]
+	for _, name := range names {
+		if h.opts.internal[name] == nil {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// admit returns an error if the request may not call the named function, because it is
+// internal or disabled.
+func (h *Handler) admit(r *http.Request, funcName string) error {
+	if allow := h.opts.internal[funcName]; allow != nil && !allow(r) {
+		return newCallError(errNoSuchFunc, "No such function '%s'.", funcName)
+	}
+	return h.checkDisabled(funcName)
+}
@@ -0,0 +1,48 @@
+package rpk
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInternal(t *testing.T) {
+	h := New(Internal(LocalRequest, "Debug"))
+	h.Register("Debug", func() string { return "stats" })
+	h.Register("Hello", func() string { return "hi" })
+
+	tests := []struct{ funcName, remote, want string }{
+		{"funcs", "1.2.3.4:5", `["Hello"]`},
+		{"Hello", "1.2.3.4:5", `"hi"`},
+		{"Debug", "1.2.3.4:5", `{"error":"No such function 'Debug'."}`},
+		{"Debug", "127.0.0.1:5", `"stats"`},
+		{"Debug", "[::1]:5", `"stats"`},
+	}
+	for _, test := range tests {
+		req := apiRequest(test.funcName, "")
+		req.RemoteAddr = test.remote
+		if got := serve(h, req); got != test.want {
+			t.Fatalf("Bad result for %s from %s: %q, expected %q.", test.funcName,
+				test.remote, got, test.want)
+		}
+	}
+
+	s := h.Snapshot()
+	if len(s.Funcs) != 1 || s.Funcs[0].Name != "Hello" {
+		t.Fatalf("Bad snapshot: %+v, expected only Hello.", s.Funcs)
+	}
+	if b := string(h.Bootstrap("api", "")); strings.Contains(b, "Debug") {
+		t.Fatalf("Bootstrap contains an internal function: %s", b)
+	}
+}
+
+func TestLocalRequest_proxied(t *testing.T) {
+	h := New(TrustedProxies("127.0.0.1"), Internal(LocalRequest, "Debug"))
+	h.Register("Debug", func() string { return "stats" })
+	req := apiRequest("Debug", "")
+	req.RemoteAddr = "127.0.0.1:5"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	want := `{"error":"No such function 'Debug'."}`
+	if got := serve(h, req); got != want {
+		t.Fatalf("Bad result of a proxied call: %q, expected %q.", got, want)
+	}
+}
@@ -0,0 +1,174 @@
+package rpk
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// TrustedProxies makes the handler trust the X-Forwarded-For and X-Real-IP headers of
+// requests that come from the given addresses or networks, like "10.0.0.0/8", so that
+// the client IPs of requests behind load balancers and reverse proxies are those of the
+// original clients. X-Forwarded-For is read from right to left, and the client is the
+// first address that is not of a trusted proxy, so clients cannot forge it. Headers of
+// other requests are ignored. Panics if an address is invalid.
+func TrustedProxies(addrs ...string) Option {
+	return func(o *options) {
+		o.trustedProxies = append(o.trustedProxies, parsePrefixes(addrs)...)
+	}
+}
+
+// AllowIPs makes the handler reject calls of the named functions, or of all functions
+// if none are named, from clients whose IPs are not in the given addresses or networks,
+// like "10.0.0.0/8", so that internal functions can only be called from inside. Rejected
+// calls get an ErrPermissionDenied. With several AllowIPs options that apply to a
+// function, clients must be allowed by all of them. Use TrustedProxies if the handler
+// is behind proxies. Panics if an address is invalid.
+func AllowIPs(addrs []string, names ...string) Option {
+	return func(o *options) {
+		o.ipRules = append(o.ipRules, ipRule{parsePrefixes(addrs), nameSet(names), true})
+	}
+}
+
+// DenyIPs makes the handler reject calls of the named functions, or of all functions if
+// none are named, from clients whose IPs are in the given addresses or networks. Denied
+// addresses take precedence over allowed ones. Rejected calls get an
+// ErrPermissionDenied. Panics if an address is invalid.
+func DenyIPs(addrs []string, names ...string) Option {
+	return func(o *options) {
+		o.ipRules = append(o.ipRules, ipRule{parsePrefixes(addrs), nameSet(names), false})
+	}
+}
+
+// ClientIP returns the IP address of the client of the call with ctx, as the
+// TrustedProxies option finds it. Returns an empty string if the handler has none of
+// the TrustedProxies, AllowIPs and DenyIPs options.
+func ClientIP(ctx context.Context) string {
+	addr, _ := ctx.Value(clientIPKey{}).(netip.Addr)
+	if !addr.IsValid() {
+		return ""
+	}
+	return addr.String()
+}
+
+// clientIPKey is the context key of the client IP of a call.
+type clientIPKey struct{}
+
+// ipRule allows or denies the calls of functions from a set of networks.
+type ipRule struct {
+	prefixes []netip.Prefix
+	names    map[string]bool // Nil means all.
+	allow    bool
+}
+
+// nameSet returns a set of the given names, or nil if there are none.
+func nameSet(names []string) map[string]bool {
+	if len(names) == 0 {
+		return nil
+	}
+	result := map[string]bool{}
+	for _, name := range names {
+		result[name] = true
+	}
+	return result
+}
+
+// parsePrefixes parses addresses and networks, and panics if one is invalid.
+func parsePrefixes(addrs []string) []netip.Prefix {
+	var result []netip.Prefix
+	for _, a := range addrs {
+		if strings.Contains(a, "/") {
+			p, err := netip.ParsePrefix(a)
+			if err != nil {
+				panic("rpk: " + err.Error())
+			}
+			result = append(result, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(a)
+		if err != nil {
+			panic("rpk: " + err.Error())
+		}
+		addr = addr.Unmap()
+		result = append(result, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return result
+}
+
+// containsAddr returns whether one of the prefixes contains addr.
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// withClientIP returns the request with its client IP in its context, if the handler
+// uses client IPs.
+func (h *Handler) withClientIP(r *http.Request) *http.Request {
+	if h.opts.trustedProxies == nil && h.opts.ipRules == nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), clientIPKey{}, h.clientIP(r)))
+}
+
+// clientIP returns the IP address of the client of a request, which is invalid if it
+// cannot be parsed.
+func (h *Handler) clientIP(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, _ := netip.ParseAddr(host)
+	addr = addr.Unmap()
+	if !containsAddr(h.opts.trustedProxies, addr) {
+		return addr
+	}
+
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	if len(hops) == 0 {
+		if ip, err := netip.ParseAddr(r.Header.Get("X-Real-IP")); err == nil {
+			return ip.Unmap()
+		}
+		return addr
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// The proxy that added it cannot be trusted to report the client.
+			return addr
+		}
+		addr = hop.Unmap()
+		if !containsAddr(h.opts.trustedProxies, addr) {
+			break
+		}
+	}
+	return addr
+}
+
+// checkIP returns an error if the client of the call with ctx may not call the named
+// function.
+func (h *Handler) checkIP(ctx context.Context, funcName string) error {
+	if h.opts.ipRules == nil {
+		return nil
+	}
+	addr, _ := ctx.Value(clientIPKey{}).(netip.Addr)
+	for _, rule := range h.opts.ipRules {
+		if rule.names != nil && !rule.names[funcName] {
+			continue
+		}
+		if containsAddr(rule.prefixes, addr) != rule.allow {
+			return fmt.Errorf("Address %v may not call '%s': %w", addr, funcName,
+				ErrPermissionDenied)
+		}
+	}
+	return nil
+}
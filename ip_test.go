@@ -0,0 +1,77 @@
+package rpk
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	h := New(TrustedProxies("10.0.0.0/8", "::1"))
+	tests := []struct {
+		remote, forwarded, real, want string
+	}{
+		{"1.2.3.4:5", "", "", "1.2.3.4"},
+		{"1.2.3.4:5", "6.6.6.6", "", "1.2.3.4"},
+		{"10.0.0.1:5", "", "", "10.0.0.1"},
+		{"10.0.0.1:5", "6.6.6.6, 1.2.3.4", "", "1.2.3.4"},
+		{"10.0.0.1:5", "6.6.6.6, 1.2.3.4, 10.1.1.1", "", "1.2.3.4"},
+		{"10.0.0.1:5", "10.2.2.2, 10.1.1.1", "", "10.2.2.2"},
+		{"10.0.0.1:5", "1.2.3.4, bla, 10.1.1.1", "", "10.1.1.1"},
+		{"10.0.0.1:5", "", "1.2.3.4", "1.2.3.4"},
+		{"[::1]:5", "::ffff:1.2.3.4", "", "1.2.3.4"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/api", nil)
+		req.RemoteAddr = test.remote
+		if test.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", test.forwarded)
+		}
+		if test.real != "" {
+			req.Header.Set("X-Real-IP", test.real)
+		}
+		got := ClientIP(h.withClientIP(req).Context())
+		if got != test.want {
+			t.Fatalf("Bad client IP for %s %q %q: %q, expected %q.", test.remote,
+				test.forwarded, test.real, got, test.want)
+		}
+	}
+	if got := ClientIP(context.Background()); got != "" {
+		t.Fatalf("Bad client IP without a request: %q, expected none.", got)
+	}
+}
+
+func TestAllowIPs(t *testing.T) {
+	h := New(TrustedProxies("10.0.0.1"), AllowIPs([]string{"10.0.0.0/8"}, "Admin"),
+		DenyIPs([]string{"6.6.6.0/24", "10.6.6.6"}))
+	h.Register("Admin", func(ctx context.Context) string { return ClientIP(ctx) })
+	h.Register("Public", func() string { return "hi" })
+
+	tests := []struct {
+		funcName, remote, forwarded, want string
+	}{
+		{"Admin", "10.1.2.3:5", "", `"10.1.2.3"`},
+		{"Admin", "1.2.3.4:5", "", `{"error":"Address 1.2.3.4 may not call 'Admin': ` +
+			`permission denied","code":"permission_denied"}`},
+		{"Admin", "10.0.0.1:5", "1.2.3.4", `{"error":"Address 1.2.3.4 may not call ` +
+			`'Admin': permission denied","code":"permission_denied"}`},
+		{"Admin", "10.0.0.1:5", "10.6.6.6", `{"error":"Address 10.6.6.6 may not call ` +
+			`'Admin': permission denied","code":"permission_denied"}`},
+		{"Public", "1.2.3.4:5", "", `"hi"`},
+		{"Public", "6.6.6.1:5", "", `{"error":"Address 6.6.6.1 may not call ` +
+			`'Public': permission denied","code":"permission_denied"}`},
+		{"funcs", "6.6.6.1:5", "", `{"error":"Address 6.6.6.1 may not call ` +
+			`'funcs': permission denied","code":"permission_denied"}`},
+	}
+	for _, test := range tests {
+		req := apiRequest(test.funcName, "")
+		req.RemoteAddr = test.remote
+		if test.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", test.forwarded)
+		}
+		if got := serve(h, req); got != test.want {
+			t.Fatalf("Bad result for %s from %s %q: %q, expected %q.", test.funcName,
+				test.remote, test.forwarded, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package rpk
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Async makes the named functions run as background jobs. A call to such a function
+// returns a job ID right away, instead of the function's result. The built-in function
+// "jobStatus" takes a job ID and returns its JobStatus, and "jobResult" takes a job ID
+// and returns the result of its function, or its error, once it is done. Jobs are kept
+// for the given duration after they finish. This is useful for long-running operations
+// that would otherwise time out.
+//
+// Jobs run with the context of their request, but are not canceled when the request
+// ends. Jobs whose functions panic fail with a PanicError. The Javascript client waits
+// for jobs with rpkObject.wait.
+func Async(keep time.Duration, names ...string) Option {
+	return func(o *options) {
+		if o.async == nil {
+			o.async = map[string]bool{}
+		}
+		for _, name := range names {
+			o.async[name] = true
+		}
+		o.jobTTL = keep
+	}
+}
+
+// Job statuses.
+const (
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// JobStatus describes the state of a background job.
+type JobStatus struct {
+	Status   string  `json:"status"`             // JobRunning, JobDone or JobFailed.
+	Progress float64 `json:"progress,omitempty"` // Percent done, as last reported.
+	Message  string  `json:"message,omitempty"`  // What the job is doing, as last reported.
+}
+
+// asyncJob is a background job.
+type asyncJob struct {
+	JobStatus
+	res     callResult
+	expires time.Time // Zero while running.
+}
+
+// Progress reports the progress of the background job that runs with ctx, as a
+// percent and a message that describes what the job is doing, such as
+// Progress(ctx, 42, "indexing"). The Javascript client passes it to the onProgress
+// callback of rpkObject.wait. Does nothing if ctx is not of a background job, so
+// functions can report progress whether or not they are async.
+func Progress(ctx context.Context, percent float64, message string) {
+	p, _ := ctx.Value(progressKey{}).(*jobProgress)
+	if p == nil {
+		return
+	}
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	p.job.Progress = percent
+	p.job.Message = message
+}
+
+// progressKey is the context key of a background job's progress reporter.
+type progressKey struct{}
+
+// jobProgress reports the progress of a job.
+type jobProgress struct {
+	store *jobStore
+	job   *asyncJob
+}
+
+// jobStore holds the background jobs of a handler.
+type jobStore struct {
+	mu sync.Mutex
+	m  map[string]*asyncJob
+}
+
+// run calls a function like runHidden, and runs it as a background job if it is async.
+// Also serves the built-in job and transaction functions. Parameters are read within the
+// handler's limits, errors are translated, calls are counted by tenant, and results are
+// filtered by the fields that the request asks for.
+func (h *Handler) run(r *http.Request, funcName string, param io.Reader) (
+	res callResult) {
+	if h.opts.translate != nil {
+		defer h.translate(r, &res)
+	}
+	if err := h.admit(r, funcName); err != nil {
+		return callResult{err: err}
+	}
+	if h.opts.tenants != nil {
+		var tenant string
+		var err error
+		if r, tenant, err = h.tenant(r); err != nil {
+			return callResult{err: err}
+		}
+		defer func() {
+			if res.err != nil {
+				h.countError(tenant)
+			}
+		}()
+	}
+	if uploadWriter(r) != nil {
+		// Chunks are not JSON, and are not received in background jobs.
+		return h.runHidden(r, funcName, param)
+	}
+	param = h.opts.limits.reader(param)
+	fields, param := requestFields(r, param)
+	r = r.WithContext(context.WithValue(r.Context(), requestFieldsKey{}, fields))
+	defer func() {
+		if res.err == nil {
+			res.fields = newFieldMask(fields.get())
+		}
+	}()
+	if h.opts.uploads != nil {
+		r = r.WithContext(context.WithValue(r.Context(), uploadsKey{},
+			h.opts.uploads.store))
+	}
+	if funcName == "transaction" && h.tx != nil {
+		return h.runTx(r, param)
+	}
+	if h.opts.async == nil || validateOnly(r) {
+		return h.runHidden(r, funcName, param)
+	}
+	switch {
+	case funcName == "jobStatus" || funcName == "jobResult":
+		var id string
+		if err := newJSONDecoder(param).Decode(&id); err != nil {
+			return callResult{err: newCallError(errBadParam,
+				"Error decoding JSON: %v", err)}
+		}
+		return h.jobs.get(id, funcName == "jobResult")
+	case h.opts.async[funcName]:
+		return h.startJob(r, funcName, param)
+	}
+	return h.runHidden(r, funcName, param)
+}
+
+// startJob starts a background job that calls a function, and returns its ID.
+func (h *Handler) startJob(r *http.Request, funcName string, param io.Reader) callResult {
+	// The parameter is read now, since the request's body is closed when it ends.
+	data, err := io.ReadAll(param)
+	if err != nil {
+		return callResult{err: newCallError(errBadParam, "Error reading parameter: %v",
+			err)}
+	}
+	id := newID()
+	j := &asyncJob{JobStatus: JobStatus{Status: JobRunning}}
+	h.jobs.put(id, j)
+
+	ctx := context.WithoutCancel(r.Context())
+	r = r.WithContext(context.WithValue(ctx, progressKey{}, &jobProgress{&h.jobs, j}))
+	go func() {
+		var res callResult
+		// There is no request to fail with a panic, so the job fails instead.
+		defer func() {
+			if p := recover(); p != nil {
+				res = callResult{err: &PanicError{p}}
+			}
+			h.jobs.finish(j, res, h.opts.jobTTL)
+		}()
+		res = h.runHidden(r, funcName, bytes.NewReader(data))
+	}()
+	return callResult{val: id, hasOut: true}
+}
+
+// put adds a job, and drops expired ones.
+func (s *jobStore) put(id string, j *asyncJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for id, j := range s.m {
+		if !j.expires.IsZero() && now.After(j.expires) {
+			delete(s.m, id)
+		}
+	}
+	s.m[id] = j
+}
+
+// finish records the result of a job.
+func (s *jobStore) finish(j *asyncJob, res callResult, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.res = res
+	j.Status = JobDone
+	if res.err != nil {
+		j.Status = JobFailed
+	}
+	j.expires = time.Now().Add(ttl)
+}
+
+// get returns the status of a job, or its result if result is true.
+func (s *jobStore) get(id string, result bool) callResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.m[id]
+	if !ok || !j.expires.IsZero() && time.Now().After(j.expires) {
+		return callResult{err: newCallError(errBadParam, "No such job '%s'.", id)}
+	}
+	if !result {
+		status := j.JobStatus
+		return callResult{val: &status, hasOut: true}
+	}
+	if j.Status == JobRunning {
+		return callResult{err: newCallError(errBadParam, "Job '%s' is still running.",
+			id)}
+	}
+	return j.res
+}
@@ -0,0 +1,98 @@
+package rpk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAsync(t *testing.T) {
+	release := make(chan struct{})
+	h := New(Async(time.Minute, "Slow", "Fail", "Panic"))
+	err := h.Register("Slow", func(a int) int {
+		<-release
+		return a * 2
+	})
+	if err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+	if err := h.Register("Fail", func() error { return errors.New("oops") }); err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+	if err := h.Register("Panic", func() { panic("oops") }); err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+	wait := func(job string) {
+		for callAPI(h, "jobStatus", job) == `{"status":"running"}` {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	job := callAPI(h, "Slow", "21")
+	var id string
+	if err := json.Unmarshal([]byte(job), &id); err != nil || id == "" {
+		t.Fatalf("Bad job ID: %q", job)
+	}
+	if result := callAPI(h, "jobStatus", job); result != `{"status":"running"}` {
+		t.Fatalf("Bad status: %q, expected running.", result)
+	}
+	if result := callAPI(h, "jobResult", job); !strings.Contains(result, "still running") {
+		t.Fatalf("Bad result: %q, expected a still running error.", result)
+	}
+	close(release)
+	wait(job)
+	if result := callAPI(h, "jobStatus", job); result != `{"status":"done"}` {
+		t.Fatalf("Bad status: %q, expected done.", result)
+	}
+	if result := callAPI(h, "jobResult", job); result != "42" {
+		t.Fatalf("Bad result: %q, expected %q.", result, "42")
+	}
+
+	job = callAPI(h, "Fail", "")
+	wait(job)
+	if result := callAPI(h, "jobStatus", job); result != `{"status":"failed"}` {
+		t.Fatalf("Bad status: %q, expected failed.", result)
+	}
+	if result := callAPI(h, "jobResult", job); result != `{"error":"oops"}` {
+		t.Fatalf("Bad result: %q, expected %q.", result, `{"error":"oops"}`)
+	}
+
+	job = callAPI(h, "Panic", "")
+	wait(job)
+	if result := callAPI(h, "jobResult", job); result != `{"error":"panic: oops"}` {
+		t.Fatalf("Bad result: %q, expected %q.", result, `{"error":"panic: oops"}`)
+	}
+
+	result := callAPI(h, "jobStatus", `"nope"`)
+	if !strings.Contains(result, "No such job") {
+		t.Fatalf("Bad status of a missing job: %q", result)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	reported := make(chan struct{})
+	release := make(chan struct{})
+	h := New(Async(time.Minute, "Index"))
+	err := h.Register("Index", func(ctx context.Context) {
+		Progress(ctx, 42, "indexing")
+		close(reported)
+		<-release
+	})
+	if err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+
+	job := callAPI(h, "Index", "")
+	<-reported
+	want := `{"status":"running","progress":42,"message":"indexing"}`
+	if result := callAPI(h, "jobStatus", job); result != want {
+		t.Fatalf("Bad status: %q, expected %q.", result, want)
+	}
+	close(release)
+
+	// Outside of jobs, progress is ignored.
+	Progress(context.Background(), 1, "nothing")
+}
@@ -1,9 +1,30 @@
 package rpk
 
-var jsCode = `function rpk(url) {
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+var jsCode = `function rpk(url, options) {
+	options = options || {};
 	var result = {
 		ready : false
 	};
+
+	// The configuration in the element of the bootstrap option, from BootstrapScript or
+	// the handler's Bootstrap method, if any.
+	var bootstrap = options.bootstrap && typeof document != "undefined" &&
+		document.getElementById(options.bootstrap);
+	var meta = bootstrap ? JSON.parse(bootstrap.textContent) : {};
+	url = url || meta.url || "";
+	result.flags = meta.flags || {};
+	result.version = meta.version;
 	
 	// Calls callback with the parameters, or throws an exception if no callback.
 	var callOrThrow = function(callback, data, error) {
@@ -15,69 +36,1009 @@ var jsCode = `function rpk(url) {
 		}
 	}
 	
-	// Calls an RPK function.
-	var callRpk = function(name, param, callback) {
+	// Last JSON-RPC request ID.
+	var lastId = 0;
+
+	// Returns a handler's URL, resolved against the page's URL, with https on https
+	// pages, and without its query, which is added to every call.
+	var resolveURL = function(url) {
+		var endpoint = {base: url, query: ""};
+		if (typeof location != "undefined" && typeof URL == "function") {
+			var resolved = new URL(url, location.href);
+			if (location.protocol == "https:" && resolved.protocol == "http:") {
+				resolved.protocol = "https:";
+			}
+			endpoint.query = resolved.search.replace(/^\?/, "");
+			resolved.search = "";
+			resolved.hash = "";
+			endpoint.base = resolved.href;
+		} else if (url.indexOf("?") != -1) {
+			endpoint.query = url.slice(url.indexOf("?") + 1);
+			endpoint.base = url.slice(0, url.indexOf("?"));
+		}
+		return endpoint;
+	};
+
+	// The endpoints of the handler, and the index of the one that calls go to.
+	var endpoints = (Array.isArray(url) ? url : [url]).map(resolveURL);
+	var current = 0;
+
+	// Returns the URL of a call to the endpoint at index i, with path added to its URL
+	// if given, for path routing, and with query.
+	var endpointURL = function(i, path, query) {
+		var endpoint = endpoints[i];
+		var u = path ? endpoint.base.replace(/\/*$/, "/") + path : endpoint.base;
+		query = [endpoint.query, query || ""].filter(Boolean).join("&");
+		return query ? u + "?" + query : u;
+	};
+
+	// Returns the URL of a call to the current endpoint.
+	var callURL = function(path, query) {
+		return endpointURL(current, path, query);
+	};
+
+	// Matches RFC 3339 times, as the server encodes them by default.
+	var timePattern = /^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(\.\d+)?(Z|[+-]\d\d:\d\d)$/;
+
+	// Parses a response, converting times to Date objects if the dates option is set.
+	var parse = function(text) {
+		if (!options.dates) {
+			return JSON.parse(text);
+		}
+		return JSON.parse(text, function(key, value) {
+			if (typeof value == "string" && timePattern.test(value)) {
+				return new Date(value);
+			}
+			return value;
+		});
+	};
+
+	// Whether to convert 64-bit integers in results to BigInt values.
+	var bigints = options.bigints && typeof BigInt != "undefined";
+
+	// The paths of the values that the handler encodes from 64-bit integers as strings,
+	// in the results of each function, as the handler lists them. "*" stands for all the
+	// items of an array or the members of an object.
+	var int64Paths = {};
+
+	// Converts the integer strings at path, from its ith name on, in value to BigInt
+	// values. Returns the converted value.
+	var convertPath = function(value, path, i) {
+		if (i == path.length) {
+			return typeof value == "string" && /^-?\d+$/.test(value) ? BigInt(value) :
+				value;
+		}
+		if (value === null || typeof value != "object") {
+			return value;
+		}
+		var keys = path[i] == "*" ? Object.keys(value) : [path[i]];
+		for (var j = 0; j < keys.length; j++) {
+			if (Object.prototype.hasOwnProperty.call(value, keys[j])) {
+				value[keys[j]] = convertPath(value[keys[j]], path, i + 1);
+			}
+		}
+		return value;
+	};
+
+	// Converts the 64-bit integers in a result of the named function to BigInt values if
+	// the bigints option is set, or in an item of a streamed list if item is true.
+	// Returns the converted result.
+	var toBigInts = function(value, name, item) {
+		var paths = bigints && int64Paths[name] || [];
+		for (var i = 0; i < paths.length; i++) {
+			if (!item) {
+				value = convertPath(value, paths[i], 0);
+			} else if (paths[i][0] == "*") {
+				value = convertPath(value, paths[i], 1);
+			}
+		}
+		return value;
+	};
+
+	// Encodes a parameter. BigInt values are encoded as strings, and binary data, like
+	// ArrayBuffer and Uint8Array values, as base64 strings, like Go encodes []byte.
+	var stringify = function(value) {
+		return JSON.stringify(value, function(key, value) {
+			if (typeof value == "bigint") {
+				return value.toString();
+			}
+			if (typeof ArrayBuffer != "undefined" &&
+				(value instanceof ArrayBuffer || ArrayBuffer.isView(value))) {
+				return rpk.toBase64(value);
+			}
+			return value;
+		});
+	};
+
+	// The error of calls that fail to reach the server.
+	var networkError = "Network error";
+
+	// The features that this client supports.
+	var jsFeatures = "events,gzip,int64string,jobs,jsnames,stream,transactions,uploads,versions";
+
+	// The protocol version and the features that the handler and this client both
+	// support, as the handler answers the first call.
+	result.protocol = 1;
+	result.features = [];
+
+	// Reads the negotiated protocol from a response, if it has one.
+	var readProtocol = function(xhr) {
+		var version = xhr.getResponseHeader("Rpk-Protocol");
+		if (version === null) {
+			return;  // Handlers that predate negotiation.
+		}
+		result.protocol = parseInt(version, 10) || 1;
+		var features = xhr.getResponseHeader("Rpk-Features");
+		result.features = features ? features.split(",") : [];
+	};
+
+	// Names of the deprecated functions that were warned about.
+	var warned = {};
+
+	// Logs a warning the first time that a function responds that it is deprecated.
+	var warnDeprecated = function(name, xhr) {
+		var message = xhr.getResponseHeader("Rpk-Deprecated");
+		if (message === null || warned[name] || typeof console == "undefined") {
+			return;
+		}
+		warned[name] = true;
+		var sunset = xhr.getResponseHeader("Sunset");
+		console.warn("rpk: function '" + name + "' is deprecated" +
+			(message ? ": " + message : ".") +
+			(sunset ? " It may be removed after " + sunset + "." : ""));
+	};
+
+	// Sends a call of an RPK function, and calls back with its result and the status
+	// of the response, or 0 if there is none.
+	var callXhr = function(name, param, callback, headers, onItem) {
 		var xhr = new XMLHttpRequest();
+		var respond = function(data, error) {
+			callback(data, error, xhr.status);
+		};
+		// Streamed values, the length of the response that was read, and the error that
+		// ended the stream, if any.
+		var items = [];
+		var read = 0;
+		var streamError = null;
+		// Reads the complete lines of a streamed response, or all of it if final.
+		var readStream = function(final) {
+			var type = xhr.getResponseHeader("Content-Type") || "";
+			if (type.indexOf("application/x-ndjson") != 0) {
+				return false;
+			}
+			var text = xhr.responseText;
+			var end = final ? text.length : text.lastIndexOf("\n") + 1;
+			var lines = text.substring(read, end).split("\n");
+			read = Math.max(read, end);
+			for (var i = 0; i < lines.length && !streamError; i++) {
+				if (!lines[i]) {
+					continue;
+				}
+				var item = parse(lines[i]);
+				if (item && item.error) {
+					streamError = item.error;
+				} else if (onItem) {
+					onItem(toBigInts(item, name, true));
+				} else {
+					items.push(toBigInts(item, name, true));
+				}
+			}
+			return true;
+		};
+		xhr.onprogress = function() {
+			try {
+				readStream(false);
+			} catch (error) {}  // Reported when done.
+		};
 		xhr.onreadystatechange = function() {
 			if (xhr.readyState == 4) {
+				if (xhr.status == 0) {
+					return;  // Handled by onerror or ontimeout.
+				}
+				warnDeprecated(name, xhr);
+				if (name == "funcs") {
+					readProtocol(xhr);
+				}
 				if (xhr.status != 200) {
-					callOrThrow(callback, null, "Got bad response status code: " + xhr.status);
+					respond(null, "Got bad response status code: " + xhr.status);
 					return;
 				}
 				try {
-					var response = JSON.parse(xhr.responseText);
+					if (readStream(true)) {
+						respond(onItem || streamError ? null : items,
+							streamError);
+						return;
+					}
 				} catch (error) {
-					callOrThrow(callback, null, "Error parsing response: " + error);
+					respond(null, "Error parsing response: " + error);
+					return;
+				}
+				try {
+					// Functions with no output, or with a null output under OmitNull,
+					// send an empty response.
+					var response = xhr.responseText ? parse(xhr.responseText) : null;
+				} catch (error) {
+					respond(null, "Error parsing response: " + error);
+					return;
+				}
+				if (options.jsonrpc) {
+					if (response.error) {
+						respond(null, response.error.message);
+						return;
+					}
+					respond(toBigInts(response.result, name), null);
+					return;
+				}
+				if (response && response.error && response.conflict) {
+					// A version conflict, which callers can tell apart from other errors.
+					var conflict = new String(response.error);
+					conflict.conflict = true;
+					conflict.version = response.version;
+					respond(null, conflict);
+					return;
+				}
+				if (response && response.error && response.code) {
+					// An error with a code and data, for callers to act on.
+					var coded = new String(response.error);
+					coded.code = response.code;
+					coded.data = response.data;
+					respond(null, coded);
 					return;
 				}
-				if (response.error) {
-					callOrThrow(callback, null, response.error);
+				if (response && response.error) {
+					respond(null, response.error);
 					return;
 				}
-				callOrThrow(callback, response, null);
+				respond(toBigInts(response, name), null);
 			}
 		};
+		xhr.onerror = function() {
+			respond(null, networkError);
+		};
+		xhr.ontimeout = function() {
+			respond(null, "Timed out after " + options.timeout + "ms");
+		};
+		// Sends the request, with the configured timeout.
+		var send = function(method, target, contentType, body) {
+			xhr.open(method, target, true);
+			if (contentType) {
+				xhr.setRequestHeader("Content-Type", contentType);
+			}
+			if (options.timeout) {
+				// Let the server abandon the call once it times out.
+				xhr.timeout = options.timeout;
+				xhr.setRequestHeader("Rpk-Timeout", options.timeout);
+			}
+			for (var header in headers || {}) {
+				xhr.setRequestHeader(header, headers[header]);
+			}
+			xhr.send(body);
+		};
+		if (options.jsonrpc) {
+			var request = {jsonrpc: "2.0", method: name, id: ++lastId};
+			if (typeof param != "undefined") {
+				request.params = [param];
+			}
+			send("POST", callURL(), "application/json", stringify(request));
+			return;
+		}
+		// Chunks of uploads are sent as they are.
+		if (typeof Blob != "undefined" && param instanceof Blob) {
+			send("POST", options.pathRouting ? callURL(encodeURIComponent(name)) :
+				callURL(null, "func=" + encodeURIComponent(name)),
+				"application/octet-stream", param);
+			return;
+		}
+		var get = options.get && options.get.indexOf(name) != -1;
+		if (options.pathRouting && !get) {
+			send("POST", callURL(encodeURIComponent(name)), "application/json",
+				typeof param == "undefined" ? "" : stringify(param));
+			return;
+		}
 		if (typeof param == "undefined") {
 			param = "";
 		} else {
-			param = encodeURI(JSON.stringify(param));
+			// Characters like &, + and # have meanings in URLs and forms.
+			param = encodeURIComponent(stringify(param));
+		}
+		if (get) {
+			if (options.pathRouting) {
+				send("GET", callURL(encodeURIComponent(name), "param=" + param));
+			} else {
+				send("GET", callURL(null, "func=" + encodeURIComponent(name) +
+					"&param=" + param));
+			}
+			return;
 		}
-		xhr.open("POST", url+"?func=" + name + "&param=" + param, true);
-		xhr.setRequestHeader("Content-Type", "application/x-www-form-urlencoded");
-		xhr.send();
+		// The parameter goes in the body, where its length is not limited.
+		send("POST", callURL(null, "func=" + encodeURIComponent(name)),
+			"application/x-www-form-urlencoded", "param=" + param);
 	};
 	
-	// Returns a function that calls a specific RPK function.
+	// Calls fn with value, or with what it resolves to if it is a promise, or calls
+	// onError with the promise's error.
+	var resolve = function(value, fn, onError) {
+		if (value && typeof value.then == "function") {
+			value.then(fn, onError);
+		} else {
+			fn(value);
+		}
+	};
+
+	// The functions that give the tokens of calls, set by setAuth, and the functions
+	// that wait for the refresh of the token in progress, if any.
+	var auth = null;
+	var refreshWaiters = null;
+
+	// Sets the functions that give the tokens of calls.
+	result.setAuth = function(a) {
+		auth = a;
+	};
+
+	// Refreshes the token, once for all the calls that ask for it meanwhile, and then
+	// calls done with whether it succeeded.
+	var refreshToken = function(done) {
+		if (refreshWaiters) {
+			refreshWaiters.push(done);
+			return;
+		}
+		refreshWaiters = [done];
+		var finish = function(ok) {
+			var waiters = refreshWaiters;
+			refreshWaiters = null;
+			for (var i = 0; i < waiters.length; i++) {
+				waiters[i](ok);
+			}
+		};
+		try {
+			resolve(auth.refreshToken(), function() {
+				finish(true);
+			}, function() {
+				finish(false);
+			});
+		} catch (error) {
+			finish(false);
+		}
+	};
+
+	// Attaches tokens to calls, and when the server rejects a token, refreshes it and
+	// retries the call once. Calls made during a refresh wait for it.
+	var authorize = function(call, next) {
+		if (!auth) {
+			next(call);
+			return;
+		}
+		if (refreshWaiters) {
+			refreshWaiters.push(function() {
+				authorize(call, next);
+			});
+			return;
+		}
+		var callback = call.callback;
+		var send = function(retry) {
+			var token;
+			try {
+				token = auth.getToken();
+			} catch (error) {
+				callback(null, "Error getting token: " + error, 0);
+				return;
+			}
+			resolve(token, function(token) {
+				if (token) {
+					call.headers["Authorization"] = "Bearer " + token;
+				}
+				call.callback = function(data, error, status) {
+					if (status == 401 && retry && auth && auth.refreshToken) {
+						refreshToken(function(ok) {
+							if (ok) {
+								send(false);
+							} else {
+								callback(data, error, status);
+							}
+						});
+						return;
+					}
+					callback(data, error, status);
+				};
+				next(call);
+			}, function(error) {
+				callback(null, "Error getting token: " + error, 0);
+			});
+		};
+		send(true);
+	};
+
+	// Interceptors of calls, in the order that they were added.
+	var interceptors = [authorize];
+
+	// Adds an interceptor of calls.
+	result.use = function(interceptor) {
+		interceptors.push(interceptor);
+	};
+
+	// Whether the first endpoint is being probed.
+	var probing = false;
+
+	// Probes the health of the first endpoint while calls go to another, until it is
+	// healthy, and then makes calls go to it again.
+	var probe = function() {
+		if (probing || current == 0) {
+			return;
+		}
+		probing = true;
+		setTimeout(function() {
+			var xhr = new XMLHttpRequest();
+			xhr.onreadystatechange = function() {
+				if (xhr.readyState != 4) {
+					return;
+				}
+				probing = false;
+				if (xhr.status == 200) {
+					current = 0;
+					listen();
+				} else {
+					probe();
+				}
+			};
+			xhr.open("GET", options.pathRouting ? endpointURL(0, "health") :
+				endpointURL(0, null, "func=health"), true);
+			xhr.send();
+		}, options.failback || 30000);
+	};
+
+	// Sends a call like callXhr, to the current endpoint. Calls that fail to reach it,
+	// or that a proxy answers with 502, 503 or 504, as in deploys, make later calls go
+	// to the next endpoint. Of these, calls that got 503 were not passed on, and are sent
+	// to the next endpoint too, until each was tried once. The others may have run, so
+	// they are only sent again if they have an idempotency key.
+	var callFailover = function(name, param, callback, headers, onItem, tried) {
+		var at = current;
+		tried = tried || 1;
+		callXhr(name, param, function(data, error, status) {
+			var down = error === networkError || status == 502 || status == 503 ||
+				status == 504;
+			if (!down || tried >= endpoints.length) {
+				callback(data, error, status);
+				return;
+			}
+			if (current == at) {
+				current = (at + 1) % endpoints.length;
+				listen();
+				probe();
+			}
+			if (status != 503 && !(headers || {})["Idempotency-Key"]) {
+				callback(data, error, status);
+				return;
+			}
+			callFailover(name, param, callback, headers, onItem, tried + 1);
+		}, headers, onItem);
+	};
+
+	// Calls waiting to be sent, by descending priority, and the number of calls in
+	// flight, with the concurrency option.
+	var waiting = [];
+	var running = 0;
+
+	// Sends waiting calls while fewer calls than the concurrency option are in flight.
+	var dequeue = function() {
+		while (running < options.concurrency && waiting.length) {
+			waiting.shift().send();
+		}
+	};
+
+	// Sends a call like callFailover, once fewer calls than the concurrency option are
+	// in flight, if it is set. Calls of functions with higher numbers in the priorities
+	// option are sent first.
+	var callLimited = function(name, param, callback, headers, onItem) {
+		if (!options.concurrency) {
+			callFailover(name, param, callback, headers, onItem);
+			return;
+		}
+		var priority = (options.priorities || {})[name] || 0;
+		var i = waiting.length;
+		while (i > 0 && waiting[i - 1].priority < priority) {
+			i--;
+		}
+		waiting.splice(i, 0, {priority: priority, send: function() {
+			running++;
+			callFailover(name, param, function(data, error, status) {
+				running--;
+				dequeue();
+				callback(data, error, status);
+			}, headers, onItem);
+		}});
+		dequeue();
+	};
+
+	// Calls an RPK function through the interceptors. Headers are optional. Functions
+	// that stream their output call back with an array of its values, or call onItem
+	// with each value as it arrives, if given, and then call back with null.
+	var callRpk = function(name, param, callback, headers, onItem) {
+		var call = {
+			name: name,
+			param: param,
+			headers: {},
+			callback: function(data, error, status) {
+				callOrThrow(callback, data, error);
+			}
+		};
+		if (meta.csrfToken) {
+			call.headers["X-CSRF-Token"] = meta.csrfToken;
+		}
+		for (var header in headers || {}) {
+			call.headers[header] = headers[header];
+		}
+		// Returns a function that passes a call to the interceptor at index i, or sends
+		// it after the last one.
+		var next = function(i) {
+			return function(call) {
+				if (i == interceptors.length) {
+					callLimited(call.name, call.param, call.callback, call.headers,
+						onItem);
+				} else {
+					interceptors[i](call, next(i + 1));
+				}
+			};
+		};
+		next(0)(call);
+	};
+
+	// Callbacks of calls in progress, by function name and encoded parameter.
+	var inflight = {};
+
+	// Calls an RPK function. If the dedupe option is set, and an identical call is
+	// already in progress, waits for its result instead of calling the server again.
+	// Calls with headers are not deduplicated.
+	var callShared = function(name, param, callback, headers) {
+		if (!options.dedupe || headers) {
+			callRpk(name, param, callback, headers);
+			return;
+		}
+		var key = name + "\n" + (typeof param == "undefined" ? "" : stringify(param));
+		if (inflight[key]) {
+			inflight[key].push(callback);
+			return;
+		}
+		var callbacks = inflight[key] = [callback];
+		callRpk(name, param, function(data, error) {
+			delete inflight[key];
+			for (var i = 0; i < callbacks.length; i++) {
+				callOrThrow(callbacks[i], data, error);
+			}
+		});
+	};
+
+	// Cached results, by function name and then by encoded parameter. Each entry has
+	// data and an expiration time.
+	var cache = {};
+
+	// Calls an RPK function, or calls back with its cached result if the cache option
+	// has a TTL for it. Calls with headers are not cached.
+	var callCached = function(name, param, callback, headers) {
+		var ttl = options.cache && options.cache[name];
+		if (!ttl || headers) {
+			callShared(name, param, callback, headers);
+			return;
+		}
+		var key = typeof param == "undefined" ? "" : stringify(param);
+		var entries = cache[name] = cache[name] || {};
+		var entry = entries[key];
+		if (entry && entry.expires > Date.now()) {
+			setTimeout(function() {
+				callOrThrow(callback, entry.data, null);
+			}, 0);
+			return;
+		}
+		callShared(name, param, function(data, error) {
+			if (!error) {
+				entries[key] = {data: data, expires: Date.now() + ttl};
+			}
+			callOrThrow(callback, data, error);
+		});
+	};
+
+	// Calls that failed to reach the server, to replay when it is reachable again. Each
+	// call has a function name, a parameter and headers. The queue is kept in local
+	// storage, where available, so it survives page reloads.
+	var queue = [];
+	var queueKey = "rpk-offline:" + url;
+	if (options.offline && typeof localStorage != "undefined") {
+		try {
+			queue = JSON.parse(localStorage.getItem(queueKey)) || [];
+		} catch (error) {}
+	}
+	var saveQueue = function() {
+		if (typeof localStorage != "undefined") {
+			try {
+				localStorage.setItem(queueKey, stringify(queue));
+			} catch (error) {}
+		}
+	};
+
+	// Calls an RPK function. If the offline option has its name, and the call fails to
+	// reach the server, it is queued for replay, and the callback gets an error with a
+	// true queued property. Calls made while others are queued are queued after them, to
+	// keep their order.
+	var callOffline = function(name, param, callback, headers) {
+		if (!options.offline || options.offline.indexOf(name) == -1) {
+			callCached(name, param, callback, headers);
+			return;
+		}
+		var enqueue = function() {
+			queue.push({name: name, param: param, headers: headers});
+			saveQueue();
+			var error = new String("Queued until the server is reachable");
+			error.queued = true;
+			callOrThrow(callback, null, error);
+		};
+		if (queue.length) {
+			setTimeout(enqueue, 0);
+			return;
+		}
+		callCached(name, param, function(data, error) {
+			if (error === networkError) {
+				enqueue();
+				return;
+			}
+			callOrThrow(callback, data, error);
+		}, headers);
+	};
+
+	// Whether queued calls are being replayed.
+	var replaying = false;
+
+	// Replays the queued calls, in order, until one fails to reach the server. Calls
+	// the onReplay option with the result of each.
+	result.replay = function() {
+		if (replaying || !queue.length) {
+			return;
+		}
+		replaying = true;
+		var call = queue[0];
+		callRpk(call.name, call.param, function(data, error) {
+			replaying = false;
+			if (error === networkError) {
+				return;
+			}
+			queue.shift();
+			saveQueue();
+			if (options.onReplay) {
+				options.onReplay(call.name, call.param, data, error);
+			}
+			result.replay();
+		}, call.headers);
+	};
+	if (options.offline && typeof window != "undefined" && window.addEventListener) {
+		window.addEventListener("online", function() {
+			result.replay();
+		});
+	}
+
+	// Returns a function that calls a specific RPK function. The optional call options
+	// may have ifMatch, the version of the resource that the call expects, and
+	// validateOnly, to only validate the parameter.
 	var rpkCaller = function(name) {
-		return function(param, callback) {
-			if (arguments.length != 1 && arguments.length != 2) {
+		var caller = function(param, callback, callOptions) {
+			if (arguments.length < 1 || arguments.length > 3) {
 				throw "Bad number of arguments: " + arguments.length 
-					+ ", expected 1 or 2.";
+					+ ", expected 1 to 3.";
 			}
 			if (arguments.length == 1) {
 				callback = param;
 				param = undefined;
 			}
-			callRpk(name, param, callback);
+			var headers = undefined;
+			if (callOptions && callOptions.ifMatch !== undefined) {
+				headers = {"If-Match": '"' + callOptions.ifMatch + '"'};
+			}
+			if (callOptions && callOptions.validateOnly) {
+				headers = headers || {};
+				headers["Rpk-Validate-Only"] = "true";
+				// Validations are not worth replaying once the server is reachable.
+				callRpk(name, param, callback, headers);
+				return;
+			}
+			callOffline(name, param, callback, headers);
+		};
+		caller.pages = function(param, pageOptions) {
+			return pages(name, param, pageOptions && pageOptions.prefetch);
+		};
+		caller.stream = function(param, onItem, callback) {
+			if (arguments.length == 2) {
+				callback = onItem;
+				onItem = param;
+				param = undefined;
+			}
+			// Lists are streamed too.
+			callRpk(name, param, callback, {"Accept": "application/x-ndjson"}, onItem);
 		};
+		return caller;
+	};
+
+	// Returns an async iterator over the pages of a function that returns a Page,
+	// starting with param. With prefetch, each page is fetched as soon as the previous
+	// one is returned.
+	var pages = function(name, param, prefetch) {
+		var cursor = undefined;
+		var done = false;
+		var prefetched = null;  // Promise of the next page.
+		// Returns a promise of the page at cursor.
+		var fetch = function(cursor) {
+			var request = {};
+			for (var key in param || {}) {
+				request[key] = param[key];
+			}
+			if (cursor) {
+				request.cursor = cursor;
+			}
+			return new Promise(function(resolve, reject) {
+				callCached(name, request, function(page, error) {
+					if (error) {
+						reject(error);
+					} else {
+						resolve(page);
+					}
+				});
+			});
+		};
+		var iterator = {
+			next: function() {
+				if (done) {
+					return Promise.resolve({done: true, value: undefined});
+				}
+				var page = prefetched || fetch(cursor);
+				prefetched = null;
+				return page.then(function(page) {
+					cursor = page.next;
+					done = !cursor;
+					if (prefetch && !done) {
+						prefetched = fetch(cursor);
+						prefetched["catch"](function() {});  // Rejected by next.
+					}
+					return {done: false, value: page};
+				}, function(error) {
+					done = true;
+					throw error;
+				});
+			},
+			return: function() {
+				done = true;
+				prefetched = null;
+				return Promise.resolve({done: true, value: undefined});
+			}
+		};
+		if (typeof Symbol != "undefined" && Symbol.asyncIterator) {
+			iterator[Symbol.asyncIterator] = function() {
+				return iterator;
+			};
+		}
+		return iterator;
+	};
+
+	// Drops the cached results of the named function, or of all functions if no name is
+	// given.
+	result.invalidate = function(name) {
+		if (typeof name == "undefined") {
+			cache = {};
+		} else {
+			delete cache[name];
+		}
 	};
 
 	// Prepare RPK functions for result.
 	var initError = null;
 	var initCallbacks = [];
-	callRpk("funcs", "", function(funcs, error) {
+	// Adds callers of the named functions to result, and calls the listeners. Funcs is
+	// either an array of names, or an object with the names, and their Javascript names
+	// with the jsnames feature, or the paths of 64-bit integers in their results with the
+	// int64string feature.
+	var init = function(funcs, error) {
 		if (error) {
 			initError = error;
 		} else {
+			var jsNames = {};
+			if (!Array.isArray(funcs)) {
+				jsNames = funcs.jsNames || {};
+				int64Paths = funcs.int64Paths || {};
+				funcs = funcs.funcs;
+			}
 			for (var i = 0; i < funcs.length; i++) {
-				result[funcs[i]] = rpkCaller(funcs[i]);
+				// Functions of nested objects, like "Users.Create", go in nested
+				// objects.
+				var path = (jsNames[funcs[i]] || funcs[i]).split(".");
+				var obj = result;
+				for (var j = 0; j < path.length - 1; j++) {
+					obj[path[j]] = obj[path[j]] || {};
+					obj = obj[path[j]];
+				}
+				obj[path[path.length - 1]] = rpkCaller(funcs[i]);
 			}
 			result.ready = true;
+			result.replay();  // Calls queued before the page was reloaded.
 		}
 		for (var i = 0; i < initCallbacks.length; i++) {
 			initCallbacks[i](initError);
 		}
-	});
+	};
+	// Functions in the bootstrap element need no call.
+	if (meta.funcs) {
+		result.protocol = meta.protocol;
+		result.features = meta.features;
+		init(meta, null);
+	} else {
+		// Asks for the protocol that this client speaks. Waits for interceptors that are
+		// added right after the object is created.
+		setTimeout(function() {
+			callRpk("funcs", undefined, init,
+				{"Rpk-Protocol": "1", "Rpk-Features": jsFeatures});
+		}, 0);
+	}
+
+	// Polls a background job until it finishes, then calls back with its result.
+	result.wait = function(job, callback, interval, onProgress) {
+		callRpk("jobStatus", job, function(status, error) {
+			if (error) {
+				callOrThrow(callback, null, error);
+				return;
+			}
+			if (status.status == "running") {
+				if (onProgress && status.progress !== undefined) {
+					onProgress(status.progress, status.message || "");
+				}
+				setTimeout(function() {
+					result.wait(job, callback, interval, onProgress);
+				}, interval || 1000);
+				return;
+			}
+			callRpk("jobResult", job, callback);
+		});
+	};
+
+	// Uploads a file or blob in chunks for the named function, for handlers with the
+	// Uploads option, then calls back with the upload's ID. Returns an object with which
+	// the upload is paused and resumed.
+	result.upload = function(name, file, callback, uploadOptions) {
+		uploadOptions = uploadOptions || {};
+		var chunkSize = uploadOptions.chunkSize || 1 << 20;
+		var retries = typeof uploadOptions.retries == "number" ? uploadOptions.retries : 5;
+		var failures = 0;
+		var paused = false;
+		var sending = false;
+		var upload = {id: uploadOptions.id || null};
+
+		// Calls back with an error, or retries after calls that failed to reach the
+		// server, waiting longer after each.
+		var fail = function(error) {
+			if (error === networkError && failures < retries) {
+				failures++;
+				setTimeout(start, 1000 * Math.pow(2, failures - 1));
+				return;
+			}
+			sending = false;
+			callOrThrow(callback, null, error);
+		};
+		// Sends the chunks from offset on.
+		var sendFrom = function(offset) {
+			if (offset >= file.size) {
+				sending = false;
+				callOrThrow(callback, upload.id, null);
+				return;
+			}
+			if (paused) {
+				sending = false;
+				return;
+			}
+			var headers = {"Rpk-Upload-Func": name, "Rpk-Upload-Id": upload.id,
+				"Rpk-Upload-Offset": "" + offset};
+			callRpk("upload", file.slice(offset, offset + chunkSize), function(info,
+				error) {
+				if (error) {
+					fail(error);
+					return;
+				}
+				failures = 0;
+				if (uploadOptions.onProgress) {
+					uploadOptions.onProgress(info.offset, info.size);
+				}
+				sendFrom(info.offset);
+			}, headers);
+		};
+		// Creates the upload, or gets the offset of an existing one, and sends the rest.
+		var start = function() {
+			sending = true;
+			var headers = {"Rpk-Upload-Func": name};
+			if (upload.id) {
+				headers["Rpk-Upload-Id"] = upload.id;
+			} else {
+				headers["Rpk-Upload-Length"] = "" + file.size;
+			}
+			callRpk("upload", undefined, function(info, error) {
+				if (error) {
+					fail(error);
+					return;
+				}
+				upload.id = info.id;
+				sendFrom(info.offset);
+			}, headers);
+		};
+
+		upload.pause = function() {
+			paused = true;
+		};
+		upload.resume = function() {
+			paused = false;
+			if (!sending) {
+				start();
+			}
+		};
+		start();
+		return upload;
+	};
+
+	// Calls a group of functions in a transaction, then calls back with their results.
+	result.transaction = function(calls, callback) {
+		callRpk("transaction", calls, callback);
+	};
+
+	// Callbacks of broadcast messages, by topic.
+	var subscriptions = {};
+
+	// The event stream of subscribed topics, and whether it is about to be reopened.
+	var events = null;
+	var reopening = false;
+
+	// Reopens the event stream with the subscribed topics, on the current endpoint, once
+	// the current calls to subscribe and unsubscribe are done.
+	var listen = function() {
+		if (reopening) {
+			return;
+		}
+		reopening = true;
+		setTimeout(function() {
+			reopening = false;
+			if (events) {
+				events.close();
+				events = null;
+			}
+			var topics = Object.keys(subscriptions);
+			if (!topics.length) {
+				return;
+			}
+			var query = "topics=" + encodeURIComponent(topics.join(","));
+			if (options.pathRouting) {
+				events = new EventSource(callURL("events", query));
+			} else {
+				events = new EventSource(callURL(null, "func=events&" + query));
+			}
+			events.onmessage = function(event) {
+				var message = parse(event.data);
+				var callbacks = (subscriptions[message.topic] || []).slice();
+				for (var i = 0; i < callbacks.length; i++) {
+					callbacks[i](message.payload);
+				}
+			};
+		}, 0);
+	};
+
+	// Calls callback with the payload of each message broadcast to topic. Returns a
+	// function that cancels the subscription.
+	result.subscribe = function(topic, callback) {
+		subscriptions[topic] = subscriptions[topic] || [];
+		subscriptions[topic].push(callback);
+		listen();
+		return function() {
+			var callbacks = subscriptions[topic] || [];
+			var i = callbacks.indexOf(callback);
+			if (i == -1) {
+				return;
+			}
+			callbacks.splice(i, 1);
+			if (!callbacks.length) {
+				delete subscriptions[topic];
+			}
+			listen();
+		};
+	};
 
 	result.onReady = function(callback) {
 		if (result.ready || initError) {
@@ -89,4 +1050,85 @@ var jsCode = `function rpk(url) {
 
 	return result;
 }
+
+// Encodes binary data, an ArrayBuffer or a view of one like Uint8Array, in base64.
+rpk.toBase64 = function(data) {
+	var bytes = data instanceof ArrayBuffer ? new Uint8Array(data) :
+		new Uint8Array(data.buffer, data.byteOffset, data.byteLength);
+	var chars = [];
+	for (var i = 0; i < bytes.length; i += 0x8000) {
+		chars.push(String.fromCharCode.apply(null, bytes.subarray(i, i + 0x8000)));
+	}
+	return btoa(chars.join(""));
+};
+
+// Decodes base64 text, like a Go []byte in a result, to a Uint8Array.
+rpk.fromBase64 = function(text) {
+	var chars = atob(text);
+	var bytes = new Uint8Array(chars.length);
+	for (var i = 0; i < chars.length; i++) {
+		bytes[i] = chars.charCodeAt(i);
+	}
+	return bytes;
+};
 `
+
+// jsHash is a hash of the Javascript client code, which versions it.
+var jsHash = func() string {
+	h := sha256.Sum256([]byte(jsCode))
+	return hex.EncodeToString(h[:8])
+}()
+
+// VersionedJS returns urlPath, a path of the Javascript client code, with the hash of
+// the code before its extension, like "/api/rpk.4f0a1c2b3d4e5f60.js" for "/api/rpk.js".
+// Browsers may cache the code served from versioned paths for good, since new code
+// comes with a new path:
+//
+//	http.HandleFunc(rpk.VersionedJS("/api/rpk.js"), rpk.HandleJS)
+//
+// Pages should then load the code from the versioned path.
+func VersionedJS(urlPath string) string {
+	ext := path.Ext(urlPath)
+	return strings.TrimSuffix(urlPath, ext) + "." + jsHash + ext
+}
+
+// The served Javascript client code, minified and referring to its source map, and the
+// source map. References are relative to the URL of the code, so the code is the same
+// on any path, as JSIntegrity requires.
+var jsMin, jsMap = func() (string, string) {
+	code, lines := minifyJS(jsCode)
+	return code + "//# sourceMappingURL=?map\n", string(jsSourceMap(lines, "?debug", jsCode))
+}()
+
+// JSIntegrity returns the hash of the Javascript client code that HandleJS serves, for
+// the integrity attribute of script elements (Subresource Integrity), so browsers refuse
+// code that was changed on the way:
+//
+//	<script src="/api/rpk.js" integrity="{{.Integrity}}" crossorigin="anonymous">
+func JSIntegrity() string {
+	h := sha512.Sum384([]byte(jsMin))
+	return "sha384-" + base64.StdEncoding.EncodeToString(h[:])
+}
+
+// serveJS serves the Javascript client code, with its hash as its ETag. Responses to
+// versioned paths may be cached for good, and others must be revalidated. The code is
+// minified, unless the query has "debug", and refers to its source map, which is served
+// for queries with "map".
+func serveJS(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	code, contentType, etag := jsMin, "application/javascript", jsHash
+	switch {
+	case query.Has("debug"):
+		code, etag = jsCode, jsHash+"-debug"
+	case query.Has("map"):
+		code, contentType, etag = jsMap, "application/json", jsHash+"-map"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", "\""+etag+"\"")
+	if strings.Contains(r.URL.Path, "."+jsHash+".") {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	http.ServeContent(w, r, "", time.Time{}, strings.NewReader(code))
+}
@@ -0,0 +1,88 @@
+package rpk
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleJS(t *testing.T) {
+	versioned := VersionedJS("/api/rpk.js")
+	if want := "/api/rpk." + jsHash + ".js"; versioned != want {
+		t.Fatalf("Bad versioned path: %q, expected %q.", versioned, want)
+	}
+
+	tests := []struct {
+		path, ifNoneMatch string
+		status            int
+		cache             string
+	}{
+		{"/api/rpk.js", "", 200, "no-cache"},
+		{versioned, "", 200, "public, max-age=31536000, immutable"},
+		{"/api/rpk.js", `"` + jsHash + `"`, 304, "no-cache"},
+		{"/api/rpk.js", `"old"`, 200, "no-cache"},
+		{"/api/rpk.js?debug", "", 200, "no-cache"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.path, nil)
+		if test.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", test.ifNoneMatch)
+		}
+		res := httptest.NewRecorder()
+		HandleJS(res, req)
+		if res.Code != test.status {
+			t.Fatalf("Bad status for %s: %d, expected %d.", test.path, res.Code,
+				test.status)
+		}
+		if got := res.Header().Get("Cache-Control"); got != test.cache {
+			t.Fatalf("Bad Cache-Control for %s: %q, expected %q.", test.path, got,
+				test.cache)
+		}
+		if got := res.Header().Get("ETag"); !strings.HasPrefix(got, `"`+jsHash) {
+			t.Fatalf("Bad ETag for %s: %q, expected %q.", test.path, got, jsHash)
+		}
+		if test.status == 200 && !strings.HasPrefix(res.Body.String(), "function rpk(") {
+			t.Fatalf("Bad body for %s: %.20q.", test.path, res.Body.String())
+		}
+	}
+}
+
+func TestHandleJS_variants(t *testing.T) {
+	tests := []struct {
+		query, contentType, want string
+	}{
+		{"", "application/javascript", "//# sourceMappingURL=?map\n"},
+		{"?debug", "application/javascript", "\n\t// Calls callback with the parameters"},
+		{"?map", "application/json", `"sources":["?debug"]`},
+	}
+	for _, test := range tests {
+		res := httptest.NewRecorder()
+		HandleJS(res, httptest.NewRequest("GET", "/api/rpk.js"+test.query, nil))
+		if got := res.Header().Get("Content-Type"); got != test.contentType {
+			t.Fatalf("Bad content type for %q: %q, expected %q.", test.query, got,
+				test.contentType)
+		}
+		if !strings.Contains(res.Body.String(), test.want) {
+			t.Fatalf("Bad body for %q: does not contain %q.", test.query, test.want)
+		}
+	}
+	res := httptest.NewRecorder()
+	HandleJS(res, httptest.NewRequest("GET", "/api/rpk.js", nil))
+	if strings.Contains(res.Body.String(), "\t") {
+		t.Fatalf("Default code is not minified.")
+	}
+}
+
+func TestJSIntegrity(t *testing.T) {
+	for _, path := range []string{"/api/rpk.js", VersionedJS("/js/client.js")} {
+		res := httptest.NewRecorder()
+		HandleJS(res, httptest.NewRequest("GET", path, nil))
+		h := sha512.Sum384(res.Body.Bytes())
+		want := "sha384-" + base64.StdEncoding.EncodeToString(h[:])
+		if got := JSIntegrity(); got != want {
+			t.Fatalf("Bad integrity for %s: %q, expected %q.", path, got, want)
+		}
+	}
+}
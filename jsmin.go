@@ -0,0 +1,119 @@
+package rpk
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// A jsLine locates a line of minified Javascript code in the code that it came from.
+type jsLine struct {
+	line   int // Index of the source line.
+	column int // Number of leading characters that were removed.
+}
+
+// minifyJS removes the comments, indentation and empty lines of Javascript code. Lines
+// are kept apart, so the code means the same without semicolons. Returns the minified
+// code and the source of each of its lines. Block comments and template literals are
+// not supported.
+func minifyJS(code string) (string, []jsLine) {
+	var result []string
+	var lines []jsLine
+	for i, line := range strings.Split(code, "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		trimmed = strings.TrimRight(trimmed[:jsCodeLength(trimmed)], " \t")
+		if trimmed == "" {
+			continue
+		}
+		result = append(result, trimmed)
+		lines = append(lines, jsLine{i, len(line) - len(strings.TrimLeft(line, " \t"))})
+	}
+	return strings.Join(result, "\n") + "\n", lines
+}
+
+// jsCodeLength returns the length of a line of Javascript code without its trailing
+// comment. Skips strings and regular expressions, which may contain slashes.
+func jsCodeLength(line string) int {
+	prev := byte(0) // Last non-space character outside strings.
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '"' || c == '\'':
+			i = jsSkip(line, i, c)
+		case c == '/' && i+1 < len(line) && line[i+1] == '/':
+			return i
+		case c == '/' && (prev == 0 || strings.IndexByte("(,=:[!&|?{};", prev) != -1):
+			i = jsSkip(line, i, '/')
+		}
+		if c != ' ' && c != '\t' {
+			prev = line[i]
+		}
+	}
+	return len(line)
+}
+
+// jsSkip returns the index of the end of the string or regular expression that starts at
+// index i and ends with end, or the last index if it does not end.
+func jsSkip(line string, i int, end byte) int {
+	class := false // In a character class of a regular expression.
+	for i++; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\':
+			i++
+		case end == '/' && c == '[':
+			class = true
+		case end == '/' && c == ']':
+			class = false
+		case c == end && !class:
+			return i
+		}
+	}
+	return len(line) - 1
+}
+
+// jsSourceMap returns a source map (version 3) of minified code with the given lines,
+// whose source is at the given URL, with the given content.
+func jsSourceMap(lines []jsLine, source, content string) []byte {
+	var mappings strings.Builder
+	prevLine, prevColumn := 0, 0
+	for i, l := range lines {
+		if i > 0 {
+			mappings.WriteByte(';')
+		}
+		// Generated column, source index, source line and source column, each relative
+		// to the previous segment, except for the generated column.
+		for _, n := range []int{0, 0, l.line - prevLine, l.column - prevColumn} {
+			writeVLQ(&mappings, n)
+		}
+		prevLine, prevColumn = l.line, l.column
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"version":        3,
+		"sources":        []string{source},
+		"sourcesContent": []string{content},
+		"names":          []string{},
+		"mappings":       mappings.String(),
+	})
+	return data
+}
+
+// base64Digits are the digits of base64 variable-length quantities.
+const base64Digits = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// writeVLQ writes n as a base64 variable-length quantity, as source maps encode numbers.
+func writeVLQ(b *strings.Builder, n int) {
+	v := n << 1
+	if n < 0 {
+		v = -n<<1 | 1
+	}
+	for {
+		digit := v & 31
+		v >>= 5
+		if v > 0 {
+			digit |= 32
+		}
+		b.WriteByte(base64Digits[digit])
+		if v == 0 {
+			return
+		}
+	}
+}
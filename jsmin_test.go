@@ -0,0 +1,57 @@
+package rpk
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMinifyJS(t *testing.T) {
+	code := "// Comment.\n" +
+		"function f(a) {\n" +
+		"\tvar s = \"a // b\";  // Trailing.\n" +
+		"\n" +
+		"\treturn a.replace(/\\/*$/, '//') / 2;\n" +
+		"}\n"
+	want := "function f(a) {\n" +
+		"var s = \"a // b\";\n" +
+		"return a.replace(/\\/*$/, '//') / 2;\n" +
+		"}\n"
+	got, lines := minifyJS(code)
+	if got != want {
+		t.Fatalf("Bad result: %q, expected %q.", got, want)
+	}
+	wantLines := []jsLine{{1, 0}, {2, 1}, {4, 1}, {5, 0}}
+	if len(lines) != len(wantLines) {
+		t.Fatalf("Bad lines: %v, expected %v.", lines, wantLines)
+	}
+	for i := range lines {
+		if lines[i] != wantLines[i] {
+			t.Fatalf("Bad lines: %v, expected %v.", lines, wantLines)
+		}
+	}
+}
+
+func TestWriteVLQ(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "A"}, {1, "C"}, {-1, "D"}, {15, "e"}, {16, "gB"}, {-17, "jB"}, {1000, "w+B"},
+	}
+	for _, test := range tests {
+		var b strings.Builder
+		writeVLQ(&b, test.n)
+		if b.String() != test.want {
+			t.Fatalf("Bad result for %d: %q, expected %q.", test.n, b.String(), test.want)
+		}
+	}
+}
+
+func TestJSSourceMap(t *testing.T) {
+	got := string(jsSourceMap([]jsLine{{1, 0}, {2, 1}, {4, 1}}, "a.js", "x"))
+	want := `{"mappings":"AACA;AACC;AAEA","names":[],"sources":["a.js"],` +
+		`"sourcesContent":["x"],"version":3}`
+	if got != want {
+		t.Fatalf("Bad result: %s, expected %s.", got, want)
+	}
+}
@@ -0,0 +1,201 @@
+package rpk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	jsonrpcParseError     = -32700
+	jsonrpcInvalidRequest = -32600
+	jsonrpcMethodNotFound = -32601
+	jsonrpcInvalidParams  = -32602
+	jsonrpcInternalError  = -32603
+	jsonrpcServerError    = -32000 // Errors returned by functions.
+	jsonrpcConflictError  = -32001 // ConflictErrors returned by functions.
+)
+
+// jsonrpcRequest is a JSON-RPC 2.0 request object.
+type jsonrpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"` // Nil for notifications.
+}
+
+// jsonrpcResponse is a JSON-RPC 2.0 response object.
+type jsonrpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *jsonrpcError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// jsonrpcError is a JSON-RPC 2.0 error object.
+type jsonrpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// serveJSONRPC handles a JSON-RPC 2.0 request or batch of requests.
+func (h *Handler) serveJSONRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.opts.encode.newEncoder(w).Encode(jsonrpcErrorResponse(nil, jsonrpcParseError,
+			"Error reading request: "+err.Error()))
+		return
+	}
+
+	// Batch.
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var reqs []json.RawMessage
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			h.opts.encode.newEncoder(w).Encode(jsonrpcErrorResponse(nil, jsonrpcParseError,
+				"Parse error: "+err.Error()))
+			return
+		}
+		if len(reqs) == 0 {
+			h.opts.encode.newEncoder(w).Encode(jsonrpcErrorResponse(nil, jsonrpcInvalidRequest,
+				"Invalid request: empty batch"))
+			return
+		}
+		var resps []*jsonrpcResponse
+		for _, req := range reqs {
+			if resp := h.callJSONRPC(r, req); resp != nil {
+				resps = append(resps, resp)
+			}
+		}
+		// Nothing is returned for a batch of notifications.
+		if len(resps) > 0 {
+			h.opts.encode.newEncoder(w).Encode(resps)
+		}
+		return
+	}
+
+	if resp := h.callJSONRPC(r, body); resp != nil {
+		h.opts.encode.newEncoder(w).Encode(resp)
+	}
+}
+
+// callJSONRPC handles a single JSON-RPC 2.0 request. Returns nil for notifications.
+func (h *Handler) callJSONRPC(r *http.Request, data []byte) *jsonrpcResponse {
+	var req jsonrpcRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return jsonrpcErrorResponse(nil, jsonrpcParseError, "Parse error: "+err.Error())
+		}
+		return jsonrpcErrorResponse(nil, jsonrpcInvalidRequest,
+			"Invalid request: "+err.Error())
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return jsonrpcErrorResponse(req.ID, jsonrpcInvalidRequest,
+			"Invalid request: expected jsonrpc 2.0 and a method")
+	}
+
+	result, rerr := h.runJSONRPC(r, &req)
+	if req.ID == nil {
+		return nil
+	}
+	if rerr != nil {
+		return &jsonrpcResponse{JSONRPC: "2.0", Error: rerr, ID: req.ID}
+	}
+	return &jsonrpcResponse{JSONRPC: "2.0", Result: result, ID: req.ID}
+}
+
+// runJSONRPC calls the function of a JSON-RPC 2.0 request and returns its encoded
+// result. Parameters are given either by position, as an array with at most one
+// element, or by name, as an object which is the parameter itself.
+func (h *Handler) runJSONRPC(r *http.Request, req *jsonrpcRequest) (
+	json.RawMessage, *jsonrpcError) {
+	if err := h.checkIP(r.Context(), req.Method); err != nil {
+		return nil, &jsonrpcError{jsonrpcServerError, err.Error(),
+			map[string]interface{}{"code": "permission_denied", "data": nil}}
+	}
+	// Special value - "funcs" - returns the names of registered functions.
+	if req.Method == "funcs" {
+		result, _ := h.opts.encode.marshal(h.publicNames(h.table()))
+		return result, nil
+	}
+	// Special value - "health" - returns the health report.
+	if req.Method == "health" {
+		result, _ := h.opts.encode.marshal(h.Health(r.Context()))
+		return result, nil
+	}
+
+	params := bytes.TrimSpace(req.Params)
+	var param []byte
+	switch {
+	case len(params) == 0 || bytes.Equal(params, []byte("null")):
+	case params[0] == '[':
+		var arr []json.RawMessage
+		if err := json.Unmarshal(params, &arr); err != nil {
+			return nil, &jsonrpcError{jsonrpcInvalidParams,
+				"Invalid params: " + err.Error(), nil}
+		}
+		if len(arr) > 1 {
+			return nil, &jsonrpcError{jsonrpcInvalidParams,
+				"Invalid params: expected at most 1 positional parameter", nil}
+		}
+		if len(arr) == 1 {
+			param = arr[0]
+		}
+	case params[0] == '{':
+		param = params
+	default:
+		return nil, &jsonrpcError{jsonrpcInvalidParams,
+			"Invalid params: expected an array or an object", nil}
+	}
+
+	res := h.run(r, req.Method, bytes.NewReader(param))
+	if err := res.err; err != nil {
+		code := jsonrpcServerError
+		if errors.As(err, new(*ConflictError)) {
+			code = jsonrpcConflictError
+		}
+		if cerr, ok := err.(*callError); ok {
+			switch cerr.kind {
+			case errNoSuchFunc:
+				code = jsonrpcMethodNotFound
+			case errBadParam:
+				code = jsonrpcInvalidParams
+			}
+		}
+		jerr := &jsonrpcError{Code: code, Message: err.Error()}
+		var rerr RPCError
+		if errors.As(err, &rerr) {
+			// The code of the RPCError goes with its data, as JSON-RPC codes are numbers.
+			c, data := rerr.RPCError()
+			jerr.Data = map[string]interface{}{"code": c, "data": data}
+		}
+		return nil, jerr
+	}
+	if !res.hasOut {
+		return json.RawMessage("null"), nil
+	}
+	result, err := h.opts.encode.marshal(res.encoded())
+	if err != nil {
+		return nil, &jsonrpcError{jsonrpcInternalError,
+			"Error encoding result: " + err.Error(), nil}
+	}
+	return result, nil
+}
+
+// jsonrpcErrorResponse returns a response with the given error.
+func jsonrpcErrorResponse(id json.RawMessage, code int, msg string) *jsonrpcResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &jsonrpcResponse{JSONRPC: "2.0", Error: &jsonrpcError{code, msg, nil},
+		ID: id}
+}
@@ -0,0 +1,105 @@
+package rpk
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJSONRPC(t *testing.T) {
+	handler, err := HandlerFunc(testType{}, JSONRPC())
+	if err != nil {
+		t.Fatal("Failed to create handler:", err)
+	}
+
+	jsonrpcTests := []struct {
+		req  string
+		resp string
+	}{
+		{`{"jsonrpc":"2.0","method":"Bar","params":[7],"id":1}`,
+			`{"jsonrpc":"2.0","result":"Bar 7","id":1}`},
+		{`{"jsonrpc":"2.0","method":"Fun","params":{"i":7,"s":"aaa"},"id":"a"}`,
+			`{"jsonrpc":"2.0","result":"Fun 7 aaa","id":"a"}`},
+		{`{"jsonrpc":"2.0","method":"Foo","id":2}`,
+			`{"jsonrpc":"2.0","result":null,"id":2}`},
+		{`{"jsonrpc":"2.0","method":"Foo"}`, ``},
+		{`{"jsonrpc":"2.0","method":"BarErr","params":[7],"id":3}`,
+			`{"jsonrpc":"2.0","error":{"code":-32000,"message":"Bar error 7"},"id":3}`},
+		{`{"jsonrpc":"2.0","method":"Nope","id":4}`,
+			`{"jsonrpc":"2.0","error":{"code":-32601,` +
+				`"message":"No such function 'Nope'."},"id":4}`},
+		{`{"jsonrpc":"2.0","method":"Bar","params":[1,2],"id":5}`,
+			`{"jsonrpc":"2.0","error":{"code":-32602,` +
+				`"message":"Invalid params: expected at most 1 positional parameter"},"id":5}`},
+		{`{"jsonrpc":"2.0","method":"Bar","params":["a"],"id":6}`, `"code":-32602`},
+		{`{"jsonrpc":"2.0","method":"Bar","id":7`, `"code":-32700`},
+		{`{"method":"Bar","id":8}`, `"code":-32600`},
+		{`[{"jsonrpc":"2.0","method":"Bar","params":[1],"id":1},` +
+			`{"jsonrpc":"2.0","method":"Foo"},` +
+			`{"jsonrpc":"2.0","method":"FooStr","id":2}]`,
+			`[{"jsonrpc":"2.0","result":"Bar 1","id":1},` +
+				`{"jsonrpc":"2.0","result":"Foo!","id":2}]`},
+		{`[{"jsonrpc":"2.0","method":"Foo"}]`, ``},
+		{`[]`, `"code":-32600`},
+	}
+
+	for _, test := range jsonrpcTests {
+		req, err := http.NewRequest("POST", "/api", strings.NewReader(test.req))
+		if err != nil {
+			t.Fatal("Failed to create HTTP request:", err)
+		}
+		res := &mockResponseWriter{bytes.NewBuffer(nil)}
+		handler(res, req)
+		result := strings.TrimSpace(res.buf.String())
+
+		if strings.HasPrefix(test.resp, `"`) {
+			if !strings.Contains(result, test.resp) {
+				t.Fatalf("Response to %s should contain %s, got: %s",
+					test.req, test.resp, result)
+			}
+			continue
+		}
+		if result != test.resp {
+			t.Fatalf("Bad response to %s: %s, expected %s", test.req, result, test.resp)
+		}
+	}
+}
+
+func TestJSONRPC_funcs(t *testing.T) {
+	handler, err := HandlerFunc(testType{}, JSONRPC())
+	if err != nil {
+		t.Fatal("Failed to create handler:", err)
+	}
+	req, err := http.NewRequest("POST", "/api",
+		strings.NewReader(`{"jsonrpc":"2.0","method":"funcs","id":1}`))
+	if err != nil {
+		t.Fatal("Failed to create HTTP request:", err)
+	}
+	res := &mockResponseWriter{bytes.NewBuffer(nil)}
+	handler(res, req)
+
+	var resp struct{ Result []string }
+	if err := json.Unmarshal(res.buf.Bytes(), &resp); err != nil {
+		t.Fatal("Failed to parse JSON response:", err)
+	}
+	if len(resp.Result) != len(funcNames) {
+		t.Fatalf("Bad result length: %d, expected %d.", len(resp.Result), len(funcNames))
+	}
+}
+
+func TestJSONRPC_rpcError(t *testing.T) {
+	h := New(JSONRPC())
+	h.Register("Use", func() error { return &testQuotaError{10} })
+	req := httptest.NewRequest("POST", "/api",
+		strings.NewReader(`{"jsonrpc":"2.0","method":"Use","id":1}`))
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	want := `{"jsonrpc":"2.0","error":{"code":-32000,"message":"quota of 10 exceeded",` +
+		`"data":{"code":"quota","data":{"limit":10}}},"id":1}`
+	if got := strings.TrimSpace(res.Body.String()); got != want {
+		t.Fatalf("Bad result: %s, expected %s.", got, want)
+	}
+}
@@ -0,0 +1,209 @@
+// Package kv provides a key-value API that handlers can serve as is, backed by a
+// pluggable store:
+//
+//	h := rpk.New()
+//	h.RegisterObject(kv.New(kv.NewMemoryStore()))
+//
+// Clients then call Get, Put, Delete and List. The API also serves as an example of
+// writing RPK APIs: its functions take contexts, report failures with standard errors,
+// detect conflicting changes with versions, and list items in pages.
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fluhus/rpk"
+)
+
+// An Item is a value stored under a key.
+type Item struct {
+	Key     string          `json:"key"`
+	Value   json.RawMessage `json:"value"`
+	Version string          `json:"version"` // Changes whenever the value changes.
+}
+
+// RPKVersion returns the item's version, which the handler sends as its ETag.
+func (i *Item) RPKVersion() string {
+	return i.Version
+}
+
+// A Store stores items. Implementations should be safe for concurrent use.
+type Store interface {
+	// Get returns the item with the given key, or an error that wraps rpk.ErrNotFound
+	// if there is none.
+	Get(ctx context.Context, key string) (*Item, error)
+
+	// Put sets the value of a key, and returns the item with its new version. If
+	// version is not empty, the item should have that version, or Put returns an
+	// *rpk.ConflictError. The version of a key that does not exist is "0".
+	Put(ctx context.Context, key string, value json.RawMessage, version string) (
+		*Item, error)
+
+	// Delete removes the item with the given key, if it has the given version, or any
+	// version if it is empty. Deleting a key that does not exist is not an error.
+	Delete(ctx context.Context, key, version string) error
+
+	// List returns up to limit items whose keys start with prefix and come after the
+	// given key, ordered by key.
+	List(ctx context.Context, prefix, after string, limit int) ([]*Item, error)
+}
+
+// An API serves the items of a store.
+type API struct {
+	store Store
+}
+
+// New returns an API that serves the items of the given store.
+func New(store Store) *API {
+	return &API{store}
+}
+
+// Get returns the item with the given key.
+func (a *API) Get(ctx context.Context, key string) (*Item, error) {
+	if key == "" {
+		return nil, fmt.Errorf("key is empty: %w", rpk.ErrInvalidArgument)
+	}
+	return a.store.Get(ctx, key)
+}
+
+// A PutParam sets the value of a key.
+type PutParam struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Put sets the value of a key, and returns the item with its new version. If the call
+// expects a version, as given by rpk.ExpectedVersion, the item should have it.
+func (a *API) Put(ctx context.Context, p PutParam) (*Item, error) {
+	if p.Key == "" {
+		return nil, fmt.Errorf("key is empty: %w", rpk.ErrInvalidArgument)
+	}
+	if len(p.Value) == 0 {
+		p.Value = json.RawMessage("null")
+	}
+	version, _ := rpk.ExpectedVersion(ctx)
+	return a.store.Put(ctx, p.Key, p.Value, version)
+}
+
+// Delete removes the item with the given key. If the call expects a version, the item
+// should have it.
+func (a *API) Delete(ctx context.Context, key string) error {
+	if key == "" {
+		return fmt.Errorf("key is empty: %w", rpk.ErrInvalidArgument)
+	}
+	version, _ := rpk.ExpectedVersion(ctx)
+	return a.store.Delete(ctx, key, version)
+}
+
+// A ListParam asks for a page of the items whose keys start with a prefix.
+type ListParam struct {
+	rpk.PageRequest
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// List returns a page of the items whose keys start with the given prefix, ordered by
+// key, with 100 items by default and up to 1000.
+func (a *API) List(ctx context.Context, p ListParam) (*rpk.Page[*Item], error) {
+	size := p.Size(100, 1000)
+	// One more item tells whether there is a next page.
+	items, err := a.store.List(ctx, p.Prefix, p.Cursor, size+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &rpk.Page[*Item]{Items: items}
+	if len(items) > size {
+		page.Items = items[:size]
+		page.Next = items[size-1].Key
+	}
+	return page, nil
+}
+
+// A MemoryStore is a Store that keeps items in memory. The zero value is an empty
+// store.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	items map[string]*Item
+	last  uint64 // Last version given, so that versions are never reused.
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(ctx context.Context, key string) (*Item, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	item, ok := s.items[key]
+	if !ok {
+		return nil, fmt.Errorf("key %q: %w", key, rpk.ErrNotFound)
+	}
+	return item, nil
+}
+
+// Put implements Store.
+func (s *MemoryStore) Put(ctx context.Context, key string, value json.RawMessage,
+	version string) (*Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current := s.version(key)
+	if version != "" && version != current {
+		return nil, &rpk.ConflictError{Current: current}
+	}
+	s.last++
+	item := &Item{key, append(json.RawMessage(nil), value...),
+		strconv.FormatUint(s.last, 10)}
+	if s.items == nil {
+		s.items = map[string]*Item{}
+	}
+	s.items[key] = item
+	return item, nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(ctx context.Context, key, version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current := s.version(key); version != "" && version != current {
+		return &rpk.ConflictError{Current: current}
+	}
+	delete(s.items, key)
+	return nil
+}
+
+// version returns the version of a key, "0" if it does not exist.
+func (s *MemoryStore) version(key string) string {
+	if item, ok := s.items[key]; ok {
+		return item.Version
+	}
+	return "0"
+}
+
+// List implements Store.
+func (s *MemoryStore) List(ctx context.Context, prefix, after string, limit int) (
+	[]*Item, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var keys []string
+	for key := range s.items {
+		if strings.HasPrefix(key, prefix) && key > after {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	items := make([]*Item, len(keys))
+	for i, key := range keys {
+		items[i] = s.items[key]
+	}
+	return items, nil
+}
@@ -0,0 +1,80 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fluhus/rpk"
+	"github.com/fluhus/rpk/rpktest"
+)
+
+func TestAPI(t *testing.T) {
+	s := rpktest.NewServer(New(NewMemoryStore()))
+	defer s.Close()
+	ctx := context.Background()
+
+	item := rpktest.Call[*Item](t, s, "Put", PutParam{"a", json.RawMessage(`{"x":1}`)})
+	if item.Key != "a" || string(item.Value) != `{"x":1}` || item.Version == "" {
+		t.Fatalf("Bad result of Put: %+v.", item)
+	}
+	got := rpktest.Call[*Item](t, s, "Get", "a")
+	if got.Version != item.Version || string(got.Value) != `{"x":1}` {
+		t.Fatalf("Bad result of Get: %+v, expected %+v.", got, item)
+	}
+
+	// Changes with an old version conflict.
+	err := s.Client.Call(rpk.WithVersion(ctx, item.Version), "Put",
+		PutParam{"a", json.RawMessage(`2`)}, nil)
+	if err != nil {
+		t.Fatalf("Put with the current version failed: %v", err)
+	}
+	err = s.Client.Call(rpk.WithVersion(ctx, item.Version), "Delete", "a", nil)
+	var rerr *rpk.RemoteError
+	if !errors.As(err, &rerr) || !rerr.Conflict {
+		t.Fatalf("Bad error of Delete with an old version: %v, expected a conflict.", err)
+	}
+	if err := s.Client.Call(ctx, "Delete", "a", nil); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := s.Client.Call(ctx, "Get", "a", nil); !errors.Is(err, rpk.ErrNotFound) {
+		t.Fatalf("Bad error of Get after Delete: %v, expected not found.", err)
+	}
+	if err := s.Client.Call(ctx, "Get", "", nil); !errors.Is(err, rpk.ErrInvalidArgument) {
+		t.Fatalf("Bad error of Get with no key: %v, expected invalid argument.", err)
+	}
+}
+
+func TestAPI_List(t *testing.T) {
+	api := New(&MemoryStore{})
+	ctx := context.Background()
+	for i := range 5 {
+		api.Put(ctx, PutParam{fmt.Sprint("user/", i), json.RawMessage(fmt.Sprint(i))})
+	}
+	api.Put(ctx, PutParam{"other", nil})
+
+	var keys []string
+	p := ListParam{rpk.PageRequest{Limit: 2}, "user/"}
+	for pages := 0; ; pages++ {
+		if pages == 10 {
+			t.Fatalf("Too many pages.")
+		}
+		page, err := api.List(ctx, p)
+		if err != nil {
+			t.Fatalf("List(%+v) failed: %v", p, err)
+		}
+		for _, item := range page.Items {
+			keys = append(keys, item.Key)
+		}
+		if page.Next == "" {
+			break
+		}
+		p.Cursor = page.Next
+	}
+	want := fmt.Sprint([]string{"user/0", "user/1", "user/2", "user/3", "user/4"})
+	if fmt.Sprint(keys) != want {
+		t.Fatalf("Bad keys: %v, expected %v.", keys, want)
+	}
+}
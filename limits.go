@@ -0,0 +1,104 @@
+package rpk
+
+import (
+	"fmt"
+	"io"
+)
+
+// DecodeLimits are limits on the parameters that a handler decodes, which protect it
+// from payloads that exhaust its stack or memory. Zero fields mean no limit.
+type DecodeLimits struct {
+	MaxDepth  int // Nesting depth of arrays and objects.
+	MaxString int // Length of strings and object keys, in encoded bytes.
+	MaxArray  int // Number of elements in arrays.
+}
+
+// Limits makes the handler reject parameters that exceed the given limits. They are
+// checked as parameters are read, before they are decoded, so exceeding payloads are
+// not read any further.
+func Limits(l DecodeLimits) Option {
+	return func(o *options) {
+		o.limits = &l
+	}
+}
+
+// reader returns a reader of param that fails once it exceeds the limits, or param if
+// l is nil.
+func (l *DecodeLimits) reader(param io.Reader) io.Reader {
+	if l == nil {
+		return param
+	}
+	return &limitReader{r: param, l: l}
+}
+
+// limitReader scans the JSON that it reads, and fails once it exceeds its limits.
+type limitReader struct {
+	r       io.Reader
+	l       *DecodeLimits
+	err     error
+	inStr   bool   // Whether in a string.
+	escaped bool   // Whether the last character in a string was a backslash.
+	strLen  int    // Length of the current string.
+	stack   []byte // Open arrays and objects.
+	counts  []int  // Number of commas in each open array.
+}
+
+func (r *limitReader) Read(b []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.r.Read(b)
+	for _, c := range b[:n] {
+		if r.err = r.scan(c); r.err != nil {
+			return 0, r.err
+		}
+	}
+	return n, err
+}
+
+// scan checks the next byte.
+func (r *limitReader) scan(c byte) error {
+	l := r.l
+	if r.inStr {
+		switch {
+		case r.escaped:
+			r.escaped = false
+		case c == '\\':
+			r.escaped = true
+		case c == '"':
+			r.inStr = false
+			return nil
+		}
+		r.strLen++
+		if l.MaxString > 0 && r.strLen > l.MaxString {
+			return fmt.Errorf("string longer than %d bytes", l.MaxString)
+		}
+		return nil
+	}
+	switch c {
+	case '"':
+		r.inStr = true
+		r.strLen = 0
+	case '[', '{':
+		r.stack = append(r.stack, c)
+		r.counts = append(r.counts, 0)
+		if l.MaxDepth > 0 && len(r.stack) > l.MaxDepth {
+			return fmt.Errorf("nesting deeper than %d", l.MaxDepth)
+		}
+	case ']', '}':
+		if len(r.stack) > 0 {
+			r.stack = r.stack[:len(r.stack)-1]
+			r.counts = r.counts[:len(r.counts)-1]
+		}
+	case ',':
+		i := len(r.stack) - 1
+		if i < 0 || r.stack[i] != '[' {
+			break
+		}
+		r.counts[i]++
+		if l.MaxArray > 0 && r.counts[i] >= l.MaxArray {
+			return fmt.Errorf("array longer than %d elements", l.MaxArray)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package rpk
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	h := New(Limits(DecodeLimits{MaxDepth: 3, MaxString: 5, MaxArray: 3}))
+	h.Register("Any", func(v interface{}) int { return 1 })
+	h.Register("Raw", func(v Raw) int { return len(v) })
+
+	tests := []struct {
+		funcName string
+		param    string
+		want     string // Error, or empty for none.
+	}{
+		{"Any", `[[[1]]]`, ""},
+		{"Any", `[[[[1]]]]`, "nesting deeper than 3"},
+		{"Any", `{"a":{"b":{"c":{}}}}`, "nesting deeper than 3"},
+		{"Any", `"abcde"`, ""},
+		{"Any", `"a\"cdef"`, "string longer than 5 bytes"},
+		{"Any", `{"abcdef":1}`, "string longer than 5 bytes"},
+		{"Any", `[1,2,3]`, ""},
+		{"Any", `[1,2,3,4]`, "array longer than 3 elements"},
+		{"Any", `[[1,2],[3,4],{"a":1,"b":2,"c":3,"d":4}]`, ""},
+		{"Any", `["a,b,c"]`, ""},
+		{"Raw", `[1,2,3,4]`, "array longer than 3 elements"},
+	}
+	for _, test := range tests {
+		got := string(h.CallRaw(test.funcName, []byte(test.param)))
+		if test.want == "" && isJSONError(got) ||
+			test.want != "" && !strings.Contains(got, test.want) {
+			t.Fatalf("Bad result for %s: %q, expected error %q.", test.param, got,
+				test.want)
+		}
+	}
+}
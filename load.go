@@ -0,0 +1,127 @@
+package rpk
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// LoadShedding makes the handler reject calls of the named functions, or of all
+// functions if none are named, while it is overloaded: while more than maxInFlight calls
+// of any function run, or while the 99th percentile latency of calls in the last few
+// seconds is above maxLatency. Zero thresholds are not checked. Rejected calls fail
+// early, without being decoded, with an error that wraps ErrUnavailable, which clients
+// may retry later. Naming the functions of low priority keeps the server responsive to
+// the others under overload. With Prioritize, the thresholds are halved for Background
+// functions, and doubled for Interactive ones, so lower priorities are shed first.
+// Handler.LoadStats reports the load.
+func LoadShedding(maxInFlight int, maxLatency time.Duration, names ...string) Option {
+	return func(o *options) {
+		o.shedding = &sheddingOptions{maxInFlight, maxLatency, nameSet(names)}
+	}
+}
+
+// LoadStats describes the load of a handler.
+type LoadStats struct {
+	InFlight int     `json:"inFlight"` // Calls in progress.
+	P99      float64 `json:"p99"`      // Latency of recent calls, in milliseconds.
+	Shed     uint64  `json:"shed"`     // Calls rejected since the handler was created.
+}
+
+// LoadStats returns the load of the handler, with the LoadShedding option.
+func (h *Handler) LoadStats() *LoadStats {
+	if h.opts.shedding == nil {
+		return nil
+	}
+	m := &h.load
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return &LoadStats{m.inFlight, float64(m.p99()) / float64(time.Millisecond), m.shed}
+}
+
+// sheddingOptions are the options of LoadShedding.
+type sheddingOptions struct {
+	maxInFlight int
+	maxLatency  time.Duration
+	names       map[string]bool // Nil means all.
+}
+
+const (
+	loadWindow  = 10 * time.Second // Age of latencies that load shedding considers.
+	loadSamples = 1000             // Maximal number of latencies that it keeps.
+	loadRefresh = time.Second / 10 // How often it computes the percentile.
+)
+
+// loadMeter measures the load of a handler.
+type loadMeter struct {
+	mu       sync.Mutex
+	inFlight int
+	samples  []loadSample // Ring of recent calls.
+	next     int          // Next index in samples.
+	cached   time.Duration
+	computed time.Time // When cached was computed.
+	shed     uint64
+}
+
+// loadSample is the latency of a call.
+type loadSample struct {
+	end     time.Time
+	latency time.Duration
+}
+
+// p99 returns the 99th percentile latency of recent calls, recomputing it if it is
+// older than loadRefresh. Must be called with mu held.
+func (m *loadMeter) p99() time.Duration {
+	now := time.Now()
+	if now.Sub(m.computed) < loadRefresh {
+		return m.cached
+	}
+	var latencies []time.Duration
+	for _, s := range m.samples {
+		if now.Sub(s.end) <= loadWindow {
+			latencies = append(latencies, s.latency)
+		}
+	}
+	m.cached, m.computed = 0, now
+	if len(latencies) > 0 {
+		slices.Sort(latencies)
+		m.cached = latencies[min(len(latencies)*99/100, len(latencies)-1)]
+	}
+	return m.cached
+}
+
+// enterLoad checks the load before a call of the named function. Returns an error if
+// the call should be shed, and otherwise a function to call when it ends.
+func (h *Handler) enterLoad(funcName string) (func(), error) {
+	o := h.opts.shedding
+	if o == nil || h.table()[funcName] == nil {
+		return nil, nil
+	}
+	m := &h.load
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if o.names == nil || o.names[funcName] {
+		f := sheddingFactor(h.opts.priorities[funcName])
+		if o.maxInFlight > 0 && float64(m.inFlight) >= float64(o.maxInFlight)*f ||
+			o.maxLatency > 0 && float64(m.p99()) > float64(o.maxLatency)*f {
+			m.shed++
+			return nil, fmt.Errorf("Server is overloaded, try again later: %w",
+				ErrUnavailable)
+		}
+	}
+	m.inFlight++
+	start := time.Now()
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.inFlight--
+		s := loadSample{time.Now(), time.Since(start)}
+		if len(m.samples) < loadSamples {
+			m.samples = append(m.samples, s)
+			return
+		}
+		m.samples[m.next] = s
+		m.next = (m.next + 1) % loadSamples
+	}, nil
+}
@@ -0,0 +1,60 @@
+package rpk
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadShedding(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	h := New(LoadShedding(1, 0, "Report"))
+	h.Register("Slow", func() {
+		close(started)
+		<-release
+	})
+	h.Register("Report", func() int { return 1 })
+	h.Register("Ping", func() int { return 2 })
+
+	if got := callAPI(h, "Report", ""); got != "1" {
+		t.Fatalf("Bad result of Report: %q, expected %q.", got, "1")
+	}
+	done := make(chan string)
+	go func() { done <- callAPI(h, "Slow", "") }()
+	<-started
+	want := `{"error":"Server is overloaded, try again later: unavailable",` +
+		`"code":"unavailable"}`
+	if got := callAPI(h, "Report", ""); got != want {
+		t.Fatalf("Bad result under load: %q, expected %q.", got, want)
+	}
+	if got := callAPI(h, "Ping", ""); got != "2" {
+		t.Fatalf("Bad result of Ping: %q, expected %q.", got, "2")
+	}
+	if got := *h.LoadStats(); got.InFlight != 1 || got.Shed != 1 {
+		t.Fatalf("Bad load stats: %+v, expected 1 in flight and 1 shed.", got)
+	}
+	close(release)
+	<-done
+	if got := callAPI(h, "Report", ""); got != "1" {
+		t.Fatalf("Bad result of Report: %q, expected %q.", got, "1")
+	}
+}
+
+func TestLoadShedding_latency(t *testing.T) {
+	h := New(LoadShedding(0, 10*time.Millisecond))
+	h.Register("Sleep", func(ms int) {
+		time.Sleep(time.Duration(ms) * time.Millisecond)
+	})
+
+	if got := callAPI(h, "Sleep", "20"); got != "" {
+		t.Fatalf("Bad result of Sleep: %q, expected %q.", got, "")
+	}
+	time.Sleep(loadRefresh)
+	if got := callAPI(h, "Sleep", "0"); !strings.Contains(got, "overloaded") {
+		t.Fatalf("Bad result with high latency: %q, expected an overload error.", got)
+	}
+	if got := h.LoadStats().P99; got < 20 {
+		t.Fatalf("Bad p99: %v, expected at least 20.", got)
+	}
+}
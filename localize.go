@@ -0,0 +1,122 @@
+package rpk
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A LocalizedError is an error for end users, whose message can be translated to their
+// language by its key and arguments. LocalizedErrors are sent to clients in production
+// mode, like errors wrapped by Public.
+type LocalizedError struct {
+	Key    string        // Identifies the message, like "user.notFound".
+	Format string        // The message, formatted with Args like fmt.Sprintf.
+	Args   []interface{} // Of the message.
+}
+
+// Localized returns a LocalizedError with the given key, whose message is format with
+// args when not translated.
+func Localized(key, format string, args ...interface{}) error {
+	return &LocalizedError{key, format, args}
+}
+
+func (e *LocalizedError) Error() string {
+	return fmt.Sprintf(e.Format, e.Args...)
+}
+
+// A Translator returns the message of a key with its arguments in the first language
+// that it can, of languages like "fr-CA", in order of preference. Returns false if it
+// has no translation.
+type Translator func(langs []string, key string, args []interface{}) (string, bool)
+
+// Translate makes the handler translate the messages of errors to the languages that
+// clients accept, by their Accept-Language header. Errors that have a LocalizedError in
+// their chain are replaced by the translation of its key. The handler's own errors that
+// end users may see have these keys, with their arguments:
+//
+//	rpk.internalError  Errors hidden by HideErrors, with the error ID.
+//	rpk.busy           Calls rejected by Workers, with no arguments.
+//
+// Errors without a translation are sent as they are.
+func Translate(t Translator) Option {
+	return func(o *options) {
+		o.translate = t
+	}
+}
+
+// callErrorKeys are the message keys of the handler's errors, by kind.
+var callErrorKeys = map[errKind]string{
+	errHidden: "rpk.internalError",
+	errBusy:   "rpk.busy",
+}
+
+// translatedError is an error whose message is translated.
+type translatedError struct {
+	msg string
+	err error
+}
+
+func (e *translatedError) Error() string {
+	return e.msg
+}
+
+func (e *translatedError) Unwrap() error {
+	return e.err
+}
+
+// translate translates the error of a call, if it can.
+func (h *Handler) translate(r *http.Request, res *callResult) {
+	if res.err == nil {
+		return
+	}
+	var key string
+	var args []interface{}
+	var lerr *LocalizedError
+	if errors.As(res.err, &lerr) {
+		key, args = lerr.Key, lerr.Args
+	} else if cerr, ok := res.err.(*callError); ok && callErrorKeys[cerr.kind] != "" {
+		key, args = callErrorKeys[cerr.kind], cerr.args
+	} else {
+		return
+	}
+	if msg, ok := h.opts.translate(acceptedLanguages(r), key, args); ok {
+		res.err = &translatedError{msg, res.err}
+	}
+}
+
+// acceptedLanguages returns the languages in a request's Accept-Language header, in
+// order of preference.
+func acceptedLanguages(r *http.Request) []string {
+	type lang struct {
+		name string
+		q    float64
+	}
+	var langs []lang
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.TrimSpace(name)
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if name == "" || name == "*" || q <= 0 {
+			continue
+		}
+		langs = append(langs, lang{name, q})
+	}
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+	result := make([]string, len(langs))
+	for i, l := range langs {
+		result[i] = l.name
+	}
+	return result
+}
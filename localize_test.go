@@ -0,0 +1,70 @@
+package rpk
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAcceptedLanguages(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{}},
+		{"fr", []string{"fr"}},
+		{"da, en-gb;q=0.8, en;q=0.7", []string{"da", "en-gb", "en"}},
+		{"en;q=0.5, de, *;q=0.1, he;q=0", []string{"de", "en"}},
+		{"en;q=x, fr", []string{"fr"}},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Language", test.header)
+		if got := acceptedLanguages(req); !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("Bad result for %q: %q, expected %q.", test.header, got, test.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	messages := map[string]string{
+		"fr/user.notFound":     "Utilisateur %v introuvable.",
+		"fr/rpk.internalError": "Erreur interne, ID %s.",
+	}
+	translate := func(langs []string, key string, args []interface{}) (string, bool) {
+		for _, lang := range langs {
+			if format, ok := messages[lang+"/"+key]; ok {
+				return fmt.Sprintf(format, args...), true
+			}
+		}
+		return "", false
+	}
+	h := New(Translate(translate), HideErrors(nil))
+	h.Register("Get", func(id int) error {
+		if id == 0 {
+			return errors.New("database is down")
+		}
+		return fmt.Errorf("getting: %w", Localized("user.notFound", "User %v not found.", id))
+	})
+
+	tests := []struct {
+		lang, param, want string
+	}{
+		{"fr", "7", `{"error":"Utilisateur 7 introuvable."}`},
+		{"de, fr;q=0.5", "7", `{"error":"Utilisateur 7 introuvable."}`},
+		{"de", "7", `{"error":"getting: User 7 not found."}`},
+		{"", "7", `{"error":"getting: User 7 not found."}`},
+		{"fr", "0", `{"error":"Erreur interne, ID `},
+		{"de", "0", `{"error":"Internal error, ID `},
+	}
+	for _, test := range tests {
+		req := apiRequest("Get", test.param)
+		req.Header.Set("Accept-Language", test.lang)
+		if got := serve(h, req); !strings.HasPrefix(got, test.want) {
+			t.Fatalf("Bad result for %q: %s, expected %s.", test.lang, got, test.want)
+		}
+	}
+}
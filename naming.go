@@ -0,0 +1,124 @@
+package rpk
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// FieldNaming makes the handler name struct fields on the wire by converting their Go
+// names with convert, such as CamelCase or SnakeCase, in both parameters and outputs.
+// Fields with a name in their json tag keep it. Types with custom JSON marshaling are
+// not affected.
+func FieldNaming(convert func(name string) string) Option {
+	return func(o *options) {
+		o.codecsOf().naming = convert
+	}
+}
+
+// JSNames makes the Javascript client expose the handler's functions under names
+// converted from their Go names with convert, such as CamelCase, so that GetUser is
+// called as api.getUser, following Javascript conventions. Each part of the names of
+// functions of nested objects is converted, so "Users.GetProfile" becomes
+// "users.getProfile". Names that would collide with members of the client, like wait
+// and ready, or with the names of other functions, like those of ID and Id, are kept as
+// they are. The client gets the names as a mapping from Go names to Javascript names,
+// with the names of functions, and calls functions by their Go names, so other clients
+// are not affected.
+func JSNames(convert func(name string) string) Option {
+	return func(o *options) {
+		o.jsNames = convert
+	}
+}
+
+// jsNameMap returns the Javascript names of the given functions, that differ from their
+// Go names, by Go name. Returns nil without the JSNames option.
+func (h *Handler) jsNameMap(names []string) map[string]string {
+	if h.opts.jsNames == nil {
+		return nil
+	}
+	converted := map[string]string{}
+	taken := map[string]int{} // Number of functions that may get each name.
+	for _, name := range names {
+		taken[name]++
+		parts := strings.Split(name, ".")
+		for i, p := range parts {
+			parts[i] = h.opts.jsNames(p)
+		}
+		if js := strings.Join(parts, "."); js != name {
+			converted[name] = js
+			taken[js]++
+		}
+	}
+	result := map[string]string{}
+	for name, js := range converted {
+		if taken[js] == 1 && !jsMembers[strings.Split(js, ".")[0]] {
+			result[name] = js
+		}
+	}
+	return result
+}
+
+// jsMembers are the names of the members of the Javascript client, which functions
+// cannot take.
+var jsMembers = map[string]bool{"features": true, "flags": true, "invalidate": true,
+	"onReady": true, "protocol": true, "ready": true, "replay": true, "setAuth": true,
+	"subscribe": true, "transaction": true, "upload": true, "use": true,
+	"version": true, "wait": true}
+
+// serveFuncs writes the names of the public functions. Clients that negotiate the
+// jsnames feature get an object with the names and their Javascript names, and those
+// that negotiate the int64string feature get one with the paths of 64-bit integers in
+// the functions' results. Others get an array of the names.
+func (h *Handler) serveFuncs(w http.ResponseWriter, r *http.Request) {
+	names := h.publicNames(h.table())
+	features := strings.Split(strings.ReplaceAll(r.Header.Get(FeaturesHeader), " ", ""),
+		",")
+	result := map[string]interface{}{"funcs": names}
+	if h.opts.jsNames != nil && slices.Contains(features, "jsnames") {
+		result["jsNames"] = h.jsNameMap(names)
+	}
+	if paths := h.int64Paths(names); paths != nil &&
+		slices.Contains(features, "int64string") {
+		result["int64Paths"] = paths
+	}
+	if len(result) == 1 {
+		h.opts.encode.newEncoder(w).Encode(names)
+		return
+	}
+	h.opts.encode.newEncoder(w).Encode(result)
+}
+
+// CamelCase converts a Go name to camelCase, for FieldNaming. Leading initialisms are
+// lowercased as a whole, so "ID" becomes "id", and "HTTPServer" becomes "httpServer".
+func CamelCase(name string) string {
+	r := []rune(name)
+	n := 0 // Length of the leading upper case run.
+	for n < len(r) && unicode.IsUpper(r[n]) {
+		n++
+	}
+	// Keep the last letter of an initialism that starts the next word.
+	if n > 1 && n < len(r) && unicode.IsLower(r[n]) {
+		n--
+	}
+	for i := 0; i < n; i++ {
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
+
+// SnakeCase converts a Go name to snake_case, for FieldNaming. Initialisms are kept
+// together, so "UserID" becomes "user_id", and "HTTPServer" becomes "http_server".
+func SnakeCase(name string) string {
+	r := []rune(name)
+	var b strings.Builder
+	for i, c := range r {
+		if i > 0 && unicode.IsUpper(c) && (!unicode.IsUpper(r[i-1]) ||
+			i+1 < len(r) && unicode.IsLower(r[i+1])) && r[i-1] != '_' {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(c))
+	}
+	return b.String()
+}
@@ -0,0 +1,106 @@
+package rpk
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCamelCase(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"Name", "name"}, {"UserName", "userName"}, {"ID", "id"}, {"UserID", "userID"},
+		{"HTTPServer", "httpServer"}, {"X", "x"}, {"Field2", "field2"},
+	}
+	for _, test := range tests {
+		if got := CamelCase(test.name); got != test.want {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"Name", "name"}, {"UserName", "user_name"}, {"ID", "id"}, {"UserID", "user_id"},
+		{"HTTPServer", "http_server"}, {"X", "x"}, {"Field2", "field2"},
+		{"Snake_Case", "snake_case"},
+	}
+	for _, test := range tests {
+		if got := SnakeCase(test.name); got != test.want {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.name, got, test.want)
+		}
+	}
+}
+
+func TestFieldNaming(t *testing.T) {
+	type inner struct {
+		CreatedBy string
+	}
+	type user struct {
+		UserID   int
+		FullName string `json:"name"`
+		Meta     inner
+	}
+	h := New(FieldNaming(SnakeCase))
+	h.Register("Echo", func(u user) user { return u })
+
+	param := `{"user_id":1,"name":"Bob","meta":{"created_by":"Alice"}}`
+	if got := callAPI(h, "Echo", param); got != param {
+		t.Fatalf("Bad result: %q, expected %q.", got, param)
+	}
+}
+
+func TestJSNames(t *testing.T) {
+	h := New(JSNames(CamelCase))
+	h.Register("GetUser", func() {})
+	h.Register("ping", func() {})
+	funcs := func(features string) string {
+		req := apiRequest("funcs", "")
+		if features != "" {
+			req.Header.Set(ProtocolHeader, "1")
+			req.Header.Set(FeaturesHeader, features)
+		}
+		return serve(h, req)
+	}
+
+	tests := []struct{ features, want string }{
+		{"", `["GetUser","ping"]`},
+		{"events,stream", `["GetUser","ping"]`},
+		{"events,jsnames", `{"funcs":["GetUser","ping"],"jsNames":{"GetUser":"getUser"}}`},
+	}
+	for _, test := range tests {
+		if got := funcs(test.features); got != test.want {
+			t.Fatalf("Bad result for %q: %q, expected %q.", test.features, got, test.want)
+		}
+	}
+	want := `"jsNames":{"GetUser":"getUser"}`
+	if got := string(h.Bootstrap("rpk", "")); !strings.Contains(got, want) {
+		t.Fatalf("Bad bootstrap: %s, expected it to contain %s.", got, want)
+	}
+}
+
+func TestJSNames_collisions(t *testing.T) {
+	h := New(JSNames(CamelCase))
+	for _, name := range []string{"ID", "Id", "Wait", "Ready", "Upload.File",
+		"getName", "GetName", "SetName", "Users.Wait"} {
+		h.Register(name, func() {})
+	}
+	got := h.jsNameMap(h.publicNames(h.table()))
+	want := map[string]string{"SetName": "setName", "Users.Wait": "users.wait"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Bad names: %v, expected %v.", got, want)
+	}
+}
+
+func TestJSMembers(t *testing.T) {
+	// Every member that the client sets should be in jsMembers.
+	members := regexp.MustCompile(`result\.(\w+) =`).FindAllStringSubmatch(jsCode, -1)
+	if len(members) == 0 {
+		t.Fatal("Found no members of the client.")
+	}
+	for _, m := range members {
+		if !jsMembers[m[1]] {
+			t.Fatalf("Member %q of the client is not in jsMembers.", m[1])
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package rpk
+
+import "encoding/json"
+
+// An Optional is a parameter field that tells whether the client sent it, so that
+// functions that update things can tell a field that is set to its zero value from a
+// field that is absent:
+//
+//	type UserUpdate struct {
+//		ID    int
+//		Name  rpk.Optional[string]
+//		Email rpk.Optional[string]
+//	}
+//
+// A field that is sent as null is set, to the zero value. In outputs, unset fields are
+// encoded as null, or omitted if tagged with omitzero. Handlers encode and decode the
+// values of Optionals like any other values, with their codecs, FieldNaming, Strict and
+// the other options that change encodings.
+type Optional[T any] struct {
+	Value T
+	Set   bool
+}
+
+// Some returns a set Optional with value v.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{v, true}
+}
+
+// Get returns the value and whether it is set.
+func (o Optional[T]) Get() (T, bool) {
+	return o.Value, o.Set
+}
+
+// IsZero returns whether the value is unset, for omitzero.
+func (o Optional[T]) IsZero() bool {
+	return !o.Set
+}
+
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.Set {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.Value)
+}
+
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
+}
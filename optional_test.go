@@ -0,0 +1,87 @@
+package rpk
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestOptional(t *testing.T) {
+	type update struct {
+		Name  Optional[string]
+		Count Optional[int] `json:",omitzero"`
+	}
+	h := New()
+	h.Register("Update", func(u update) string {
+		name, ok := u.Name.Get()
+		return fmt.Sprintf("%q %v %d %v", name, ok, u.Count.Value, u.Count.Set)
+	})
+
+	tests := []struct {
+		param  string
+		result string
+	}{
+		{`{}`, `"\"\" false 0 false"`},
+		{`{"Name":"","Count":0}`, `"\"\" true 0 true"`},
+		{`{"Name":null,"Count":3}`, `"\"\" true 3 true"`},
+		{`{"Name":"bob"}`, `"\"bob\" true 0 false"`},
+	}
+	for _, test := range tests {
+		if result := callAPI(h, "Update", test.param); result != test.result {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.param, result,
+				test.result)
+		}
+	}
+
+	data, err := json.Marshal(update{Name: Some("bob")})
+	if err != nil {
+		t.Fatal("Failed to encode:", err)
+	}
+	if want := `{"Name":"bob"}`; string(data) != want {
+		t.Fatalf("Bad encoding: %s, expected %s.", data, want)
+	}
+}
+
+func TestOptional_fieldNaming(t *testing.T) {
+	type user struct {
+		FullName Optional[string] `json:",omitzero"`
+		Age      Optional[int]
+	}
+	h := New(FieldNaming(SnakeCase))
+	h.Register("Echo", func(u user) user { return u })
+
+	if result, want := callAPI(h, "Echo", `{"age":0}`), `{"age":0}`; result != want {
+		t.Fatalf("Bad result: %q, expected %q.", result, want)
+	}
+}
+
+func TestOptional_options(t *testing.T) {
+	type inner struct {
+		FullName string
+	}
+	type update struct {
+		ID    Optional[int64]
+		At    Optional[time.Time] `json:",omitzero"`
+		Inner Optional[inner]
+	}
+	h := New(Int64AsString(), TimeFormat("2006-01-02"), FieldNaming(SnakeCase), Strict())
+	h.Register("Echo", func(u update) update { return u })
+
+	tests := []struct {
+		param  string
+		result string
+	}{
+		{`{"id":"7","at":"2024-05-06","inner":{"full_name":"bob"}}`,
+			`{"id":"7","at":"2024-05-06","inner":{"full_name":"bob"}}`},
+		{`{"inner":null}`, `{"id":null,"inner":{"full_name":""}}`},
+		{`{"inner":{"full_name":"bob","age":3}}`, `{"error":"Error decoding JSON: ` +
+			`field inner: json: unknown field \"age\""}`},
+	}
+	for _, test := range tests {
+		if result := callAPI(h, "Echo", test.param); result != test.result {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.param, result,
+				test.result)
+		}
+	}
+}
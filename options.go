@@ -0,0 +1,356 @@
+package rpk
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// An Option configures a handler.
+type Option func(*options)
+
+// options holds the configuration of a handler.
+type options struct {
+	decoder     DecoderFunc
+	strict      bool
+	limits      *DecodeLimits
+	skipInvalid bool
+	warn        func(err *MethodError)
+	pathRouting bool
+	pathPrefix  string
+	jsonrpc     bool
+	safe        map[string]bool
+	deprecated  map[string]*Deprecation
+	internal    map[string]func(r *http.Request) bool
+	stats       bool
+	breaker     *breakerOptions
+	shedding    *sheddingOptions
+	priorities  map[string]Priority
+	jsNames     func(name string) string
+	uploads     *uploadOptions
+
+	healthChecks []healthCheck
+	idempotency  IdempotencyStore
+	idemScope    func(r *http.Request) string
+	hideErrors   bool
+	logError     func(id string, err error)
+	translate    Translator
+	encode       *encodeOptions
+	unions       map[reflect.Type]*union // By interface type.
+	codecs       *codecSet
+	nested       bool
+	exclude      map[string]bool
+	include      map[string]bool // Nil means all.
+	noPromoted   bool
+	onSkip       func(err *MethodError)
+	providers    map[reflect.Type]*provider
+	pool         *pool
+	bulkheads    map[string]*pool // By bulkhead name.
+	bulkheadOf   map[string]*pool // By function name.
+	async        map[string]bool
+	jobTTL       time.Duration
+	tenants      func(r *http.Request) (string, error)
+	tenantRate   float64
+	tenantBurst  int
+	examples     int
+	replayKey    func(r *http.Request) ([]byte, error)
+	replayWindow time.Duration
+	replayFuncs  map[string]bool // Nil means all.
+
+	trustedProxies []netip.Prefix
+	ipRules        []ipRule
+
+	authorizeTopic func(r *http.Request, topic string) error
+	connectionUser func(r *http.Request) string
+	onConnect      func(c Connection)
+	onDisconnect   func(c Connection)
+	pubsub         PubSub
+}
+
+// newOptions returns the default options, modified by opts.
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.replayKey != nil && o.jsonrpc {
+		panic("rpk: ReplayProtection is not supported with JSONRPC")
+	}
+	if o.decoder == nil {
+		o.decoder = newJSONDecoder
+		if o.strict {
+			o.decoder = newStrictJSONDecoder
+		}
+	}
+	return o
+}
+
+// ParamDecoder makes the handler decode input parameters using decoders created by f,
+// instead of the default JSON decoder. Parameters with data after them are rejected, so
+// decoders should return io.EOF once their input is used up, like json.Decoder.
+func ParamDecoder(f DecoderFunc) Option {
+	return func(o *options) {
+		o.decoder = f
+	}
+}
+
+// Strict makes the handler reject parameters with object fields that do not match the
+// fields of their types, with an error that names the unexpected field, instead of
+// silently ignoring them. This surfaces typos in clients early. Decoders given to
+// ParamDecoder are not affected.
+func Strict() Option {
+	return func(o *options) {
+		o.strict = true
+		o.codecsOf().strict = true
+	}
+}
+
+// SkipInvalid makes the handler skip methods that do not match the requirements of RPK,
+// instead of failing. If warn is not nil, it is called with each skipped method, unless
+// the handler has OnSkip, which then reports them instead. New code should use OnSkip,
+// which also reports methods that the filters skip.
+func SkipInvalid(warn func(err *MethodError)) Option {
+	return func(o *options) {
+		o.skipInvalid = true
+		o.warn = warn
+	}
+}
+
+// PathRouting makes the handler expose each function at its own path, as
+// POST <prefix><FunctionName>, with the encoded parameter as the request body. This
+// plays nicer with reverse proxies, access logs and other per-path HTTP tooling. The
+// prefix is stripped from the request's URL path, so for example with the prefix
+// "/api/", the function Half is at "/api/Half".
+//
+// The Javascript client should then be created with the pathRouting option.
+func PathRouting(prefix string) Option {
+	return func(o *options) {
+		o.pathRouting = true
+		o.pathPrefix = prefix
+	}
+}
+
+// JSONRPC makes the handler speak JSON-RPC 2.0 instead of the RPK protocol, so that
+// existing JSON-RPC clients can call it. Requests are POSTed as JSON, and may be
+// batched or sent as notifications. A function's parameter is given either by
+// position, as an array with at most one element, or by name, as an object which is the
+// parameter itself. Errors returned by functions have code -32000, or -32001 for
+// ConflictErrors.
+//
+// The Javascript client should then be created with the jsonrpc option. Handlers with
+// this option cannot have ReplayProtection.
+func JSONRPC() Option {
+	return func(o *options) {
+		o.jsonrpc = true
+	}
+}
+
+// Safe marks the named functions as safe, meaning that they are read-only and
+// idempotent. Safe functions can also be called with GET requests, with the parameter in
+// the URL query, which enables browser and CDN caching and simple debugging with curl:
+//
+//	GET /api?func=GetUser&param=7
+//
+// Other functions can only be called with POST requests.
+func Safe(names ...string) Option {
+	return func(o *options) {
+		if o.safe == nil {
+			o.safe = map[string]bool{}
+		}
+		for _, name := range names {
+			o.safe[name] = true
+		}
+	}
+}
+
+// Nested makes the handler also expose the exported methods of the exported struct
+// fields of registered objects, recursively, named by their fields. For example, with
+//
+//	type API struct {
+//		Users *UserService
+//	}
+//
+// the method UserService.Create is exposed as "Users.Create", and the Javascript client
+// calls it as api.Users.Create. This lets large APIs be organized as composed structs.
+// Nil fields, fields with custom JSON marshaling, and fields tagged with `rpk:"-"` are
+// skipped.
+func Nested() Option {
+	return func(o *options) {
+		o.nested = true
+	}
+}
+
+// Exclude makes the handler skip the named methods when registering objects, for example
+// "String" or "Close", which should not be called over the network. Methods of nested
+// fields are named like "Users.Delete". Functions registered with Register are not
+// affected.
+func Exclude(names ...string) Option {
+	return func(o *options) {
+		if o.exclude == nil {
+			o.exclude = map[string]bool{}
+		}
+		for _, name := range names {
+			o.exclude[name] = true
+		}
+	}
+}
+
+// Include makes the handler register only the named methods of objects, skipping all
+// others. Can be given several times. Methods of nested fields are named like
+// "Users.Create". Functions registered with Register are not affected.
+func Include(names ...string) Option {
+	return func(o *options) {
+		if o.include == nil {
+			o.include = map[string]bool{}
+		}
+		for _, name := range names {
+			o.include[name] = true
+		}
+	}
+}
+
+// NoPromoted makes the handler skip methods that registered objects get from their
+// embedded fields, such as those of an embedded sync.Mutex or http.Client. Methods that
+// an object declares itself with the same name as one of an embedded field are skipped
+// too.
+//
+// Promoted methods are exposed by default. To skip those of a single embedded field,
+// tag it with `rpk:"-"`. If two embedded fields have a method with the same name, and
+// the object does not declare it, registration fails, since Go cannot tell which one to
+// call.
+func NoPromoted() Option {
+	return func(o *options) {
+		o.noPromoted = true
+	}
+}
+
+// Reasons for skipping methods, reported to OnSkip.
+var (
+	ErrExcluded    = errors.New("excluded")
+	ErrNotIncluded = errors.New("not included")
+	ErrPromoted    = errors.New("promoted from an embedded field")
+)
+
+// OnSkip makes the handler call report with each method that it skips when registering
+// objects, and the reason: ErrExcluded, ErrNotIncluded or ErrPromoted for methods
+// that the filters skip, or what is wrong with methods that SkipInvalid skips. This
+// helps noticing methods that silently disappear from an API. Note that unexported
+// methods are invisible to reflection, so a method whose name was mistakenly
+// lowercased cannot be reported. The warn callback of SkipInvalid is not called with it.
+func OnSkip(report func(err *MethodError)) Option {
+	return func(o *options) {
+		o.onSkip = report
+	}
+}
+
+// skip reports a skipped method to the OnSkip callback, if any.
+func (o *options) skip(err *MethodError) {
+	if o.onSkip != nil {
+		o.onSkip(err)
+	}
+}
+
+// skipInvalidMethod reports a method that SkipInvalid skips, like skip, or to the warn
+// callback of SkipInvalid without OnSkip.
+func (o *options) skipInvalidMethod(err *MethodError) {
+	if o.onSkip == nil && o.warn != nil {
+		o.warn(err)
+		return
+	}
+	o.skip(err)
+}
+
+// filter returns why the named method of type t should not be registered by the
+// options' filters, or nil if it should.
+func (o *options) filter(t reflect.Type, method, name string) error {
+	switch {
+	case o.exclude[name]:
+		return ErrExcluded
+	case o.include != nil && !o.include[name]:
+		return ErrNotIncluded
+	}
+	for _, f := range promotedFrom(t, method) {
+		if o.noPromoted || f.Tag.Get("rpk") == "-" {
+			return ErrPromoted
+		}
+	}
+	return nil
+}
+
+// promotedFrom returns the embedded fields of type t that have the named method.
+func promotedFrom(t reflect.Type, method string) []reflect.StructField {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var result []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.Anonymous {
+			continue
+		}
+		_, ok := f.Type.MethodByName(method)
+		if !ok {
+			_, ok = reflect.PtrTo(f.Type).MethodByName(method)
+		}
+		if ok {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// ambiguousMethods returns errors for the exported methods that two or more embedded
+// fields of type t have, and that t therefore lacks, since Go cannot tell which one to
+// promote. Methods that the options filter out are ignored.
+func (o *options) ambiguousMethods(t reflect.Type, prefix string) MethodErrors {
+	st := t
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return nil
+	}
+
+	// Embedded field names, by method name.
+	fields := map[string][]string{}
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		if !f.Anonymous {
+			continue
+		}
+		methods := map[string]bool{}
+		for _, ft := range []reflect.Type{f.Type, reflect.PtrTo(f.Type)} {
+			for j := 0; j < ft.NumMethod(); j++ {
+				methods[ft.Method(j).Name] = true
+			}
+		}
+		for m := range methods {
+			fields[m] = append(fields[m], f.Name)
+		}
+	}
+
+	var errs MethodErrors
+	for m, fs := range fields {
+		if len(fs) < 2 || o.filter(t, m, prefix+m) != nil {
+			continue
+		}
+		if _, ok := t.MethodByName(m); ok {
+			continue
+		}
+		errs = append(errs, &MethodError{prefix + m, fmt.Errorf(
+			"promoted from embedded fields %s, which is ambiguous; declare it on %v "+
+				"to choose", strings.Join(fs, " and "), st)})
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Method < errs[j].Method
+	})
+	return errs
+}
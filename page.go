@@ -0,0 +1,36 @@
+package rpk
+
+// A PageRequest asks for a page of a list. Functions that list things take it as their
+// parameter, or embed it in their parameter along with filters, and return a Page:
+//
+//	type ListUsersParams struct {
+//		rpk.PageRequest
+//		Role string
+//	}
+//
+//	func (a API) ListUsers(p ListUsersParams) (rpk.Page[User], error)
+//
+// The Javascript client iterates over all the pages with api.ListUsers.pages(params).
+type PageRequest struct {
+	Cursor string `json:"cursor,omitempty"` // Where the page starts, empty for the first.
+	Limit  int    `json:"limit,omitempty"`  // Maximal number of items, 0 for default.
+}
+
+// Size returns the number of items to put in the requested page: its limit, or def if
+// it has none, and no more than max.
+func (r PageRequest) Size(def, max int) int {
+	n := r.Limit
+	if n <= 0 {
+		n = def
+	}
+	if n > max {
+		n = max
+	}
+	return n
+}
+
+// A Page is a part of a list.
+type Page[T any] struct {
+	Items []T    `json:"items"`
+	Next  string `json:"next,omitempty"` // Cursor of the next page, empty for the last.
+}
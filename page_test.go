@@ -0,0 +1,63 @@
+package rpk
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestPage(t *testing.T) {
+	type listParams struct {
+		PageRequest
+		Odd bool `json:"odd"`
+	}
+	h := New()
+	h.Register("List", func(p listParams) Page[int] {
+		start, _ := strconv.Atoi(p.Cursor)
+		var page Page[int]
+		for i := start; i < 10 && len(page.Items) < p.Size(3, 4); i++ {
+			if !p.Odd || i%2 == 1 {
+				page.Items = append(page.Items, i)
+			}
+			if i < 9 {
+				page.Next = strconv.Itoa(i + 1)
+			} else {
+				page.Next = ""
+			}
+		}
+		return page
+	})
+	server := httptest.NewServer(h)
+	defer server.Close()
+	c := NewClient(server.URL, nil)
+
+	tests := []struct {
+		param listParams
+		want  [][]int
+	}{
+		{listParams{}, [][]int{{0, 1, 2}, {3, 4, 5}, {6, 7, 8}, {9}}},
+		{listParams{PageRequest{Limit: 10}, false}, [][]int{{0, 1, 2, 3}, {4, 5, 6, 7},
+			{8, 9}}},
+		{listParams{PageRequest{Limit: 2}, true}, [][]int{{1, 3}, {5, 7}, {9}}},
+	}
+	for _, test := range tests {
+		var got [][]int
+		param := test.param
+		for {
+			var page Page[int]
+			if err := c.Call(context.Background(), "List", param, &page); err != nil {
+				t.Fatal("Failed to call List:", err)
+			}
+			got = append(got, page.Items)
+			if page.Next == "" {
+				break
+			}
+			param.Cursor = page.Next
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("Bad pages for %+v: %v, expected %v.", test.param, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package rpk
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// HandlePlayground returns an http.Handler that serves a page for trying out the
+// functions of h. The page lists the functions, renders forms for their parameters
+// from their schemas, calls or validates them, and shows their outputs and, with the
+// CaptureExamples option, examples of their calls. Calls from the page are sent to the
+// playground's own URL and handled by h, so it can be mounted anywhere:
+//
+//	http.Handle("/api", h)
+//	http.Handle("/api/playground", rpk.HandlePlayground(h))
+//
+// The playground lets its visitors call all of h's functions, so it should be served
+// only to those who may call them anyway, for example behind the same authentication.
+func HandlePlayground(h *Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("func") {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+		snapshot := h.Snapshot()
+		examples := map[string][]Example{}
+		for _, f := range snapshot.Funcs {
+			if e := h.Examples(f.Name); len(e) > 0 {
+				examples[f.Name] = e
+			}
+		}
+		// Marshal escapes <, > and &, so the data cannot end its element.
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		edata, err := json.Marshal(examples)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write([]byte(strings.NewReplacer("{{snapshot}}", string(data),
+			"{{examples}}", string(edata)).Replace(playgroundHTML)))
+	})
+}
+
+// playgroundHTML is the playground page, in which {{snapshot}} and {{examples}} are
+// replaced with the handler's snapshot and examples.
+const playgroundHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>rpk playground</title>
+<style>
+body {font-family: sans-serif; margin: 0; display: flex; height: 100vh;}
+#funcs {width: 240px; overflow: auto; margin: 0; padding: 8px; list-style: none;
+  border-right: 1px solid #ccc;}
+#funcs li {padding: 4px 8px; cursor: pointer; border-radius: 4px;}
+#funcs li:hover, #funcs li.selected {background: #e8eefc;}
+#funcs li.deprecated {text-decoration: line-through; color: #888;}
+main {flex: 1; padding: 16px; overflow: auto;}
+fieldset {border: 1px solid #ddd; margin: 4px 0;}
+label {display: block; margin: 4px 0;}
+textarea {width: 100%; font-family: monospace;}
+pre {background: #f6f6f6; padding: 8px; white-space: pre-wrap;}
+.error {color: #b00;}
+</style>
+</head>
+<body>
+<ul id="funcs"></ul>
+<main id="main"><p>Select a function.</p></main>
+<script type="application/json" id="rpk-snapshot">{{snapshot}}</script>
+<script type="application/json" id="rpk-examples">{{examples}}</script>
+<script>
+(function() {
+  var snapshot = JSON.parse(document.getElementById("rpk-snapshot").textContent);
+  var examples = JSON.parse(document.getElementById("rpk-examples").textContent);
+  var list = document.getElementById("funcs");
+  var main = document.getElementById("main");
+
+  // el creates an element with the given properties and children.
+  function el(tag, props, children) {
+    var e = document.createElement(tag);
+    for (var k in props || {}) {
+      e[k] = props[k];
+    }
+    (children || []).forEach(function(c) {
+      e.appendChild(typeof c == "string" ? document.createTextNode(c) : c);
+    });
+    return e;
+  }
+
+  // resolve returns the schema that s refers to, if it is a reference.
+  function resolve(s, defs) {
+    for (var i = 0; s && s.$ref && i < 100; i++) {
+      s = defs[s.$ref.replace("#/$defs/", "")];
+    }
+    return s || {};
+  }
+
+  // input returns a form element for values of schema s, with a value function that
+  // returns its value. Calls onchange when the value changes.
+  function input(s, defs, depth, onchange) {
+    s = resolve(s, defs);
+    var e;
+    if (s.enum) {
+      e = el("select", {onchange: onchange}, s.enum.map(function(v) {
+        return el("option", {value: JSON.stringify(v)}, [JSON.stringify(v)]);
+      }));
+      e.value_ = function() { return JSON.parse(e.value); };
+      return e;
+    }
+    switch (s.type) {
+    case "boolean":
+      e = el("input", {type: "checkbox", onchange: onchange});
+      e.value_ = function() { return e.checked; };
+      return e;
+    case "integer":
+    case "number":
+      e = el("input", {type: "number", step: s.type == "integer" ? "1" : "any",
+          value: "0", oninput: onchange});
+      e.value_ = function() { return Number(e.value); };
+      return e;
+    case "string":
+      e = el("input", {type: "text", oninput: onchange,
+          placeholder: s.format == "date-time" ? "2006-01-02T15:04:05Z" :
+              s.contentEncoding == "base64" ? "Base64" : ""});
+      if (s.format == "date-time") {
+        e.value = "0001-01-01T00:00:00Z";
+      }
+      e.value_ = function() { return e.value; };
+      return e;
+    case "object":
+      if (s.properties && depth < 5) {
+        var fields = Object.keys(s.properties).sort().map(function(k) {
+          return [k, input(s.properties[k], defs, depth + 1, onchange)];
+        });
+        e = el("fieldset", {}, fields.map(function(f) {
+          return el("label", {}, [f[0] + " ", f[1]]);
+        }));
+        e.value_ = function() {
+          var v = {};
+          fields.forEach(function(f) { v[f[0]] = f[1].value_(); });
+          return v;
+        };
+        return e;
+      }
+    }
+    // Arrays, maps and anything else are edited as JSON.
+    e = el("textarea", {rows: 3, placeholder: "JSON", oninput: onchange});
+    e.value_ = function() { return e.value.trim() ? JSON.parse(e.value) : null; };
+    return e;
+  }
+
+  // isError returns whether data is an error returned by a function.
+  function isError(data) {
+    if (!data || typeof data != "object" || typeof data.error != "string") {
+      return false;
+    }
+    return Object.keys(data).every(function(k) {
+      return ["error", "conflict", "version", "code", "data"].indexOf(k) != -1;
+    });
+  }
+
+  // show shows the page of function f.
+  function show(f) {
+    main.textContent = "";
+    main.appendChild(el("h2", {}, [f.name]));
+    if (f.deprecated) {
+      main.appendChild(el("p", {className: "error"},
+          ["Deprecated: " + (f.deprecated.message || "")]));
+    }
+    var text = null;
+    if (f.param) {
+      var form;
+      var update = function() {
+        try {
+          text.value = JSON.stringify(form.value_(), null, 2);
+        } catch (e) {
+          // Invalid JSON in a field, keep the last parameter.
+        }
+      };
+      form = input(f.param, f.param.$defs || {}, 0, update);
+      text = el("textarea", {rows: 8});
+      main.appendChild(el("h3", {}, ["Parameter"]));
+      main.appendChild(form);
+      main.appendChild(el("p", {}, ["Sent as:"]));
+      main.appendChild(text);
+      update();
+    }
+    if (f.result) {
+      main.appendChild(el("details", {}, [el("summary", {}, ["Result schema"]),
+          el("pre", {}, [JSON.stringify(f.result, null, 2)])]));
+    }
+    var output = el("pre");
+    var button = el("button", {onclick: function() {
+      call(f.name, text && text.value, output, false);
+    }}, ["Call"]);
+    var validate = el("button", {onclick: function() {
+      call(f.name, text && text.value, output, true);
+    }}, ["Validate"]);
+    main.appendChild(el("p", {}, [button, " ", validate]));
+    main.appendChild(output);
+
+    var list = examples[f.name] || [];
+    if (list.length) {
+      main.appendChild(el("h3", {}, ["Examples"]));
+    }
+    list.forEach(function(e, i) {
+      var param = e.param === undefined ? null : JSON.stringify(e.param, null, 2);
+      var children = [el("summary", {}, ["Example " + (i + 1)])];
+      if (param != null) {
+        children.push(el("p", {}, ["Parameter:"]), el("pre", {}, [param]));
+        children.push(el("button", {onclick: function() {
+          text.value = param;
+        }}, ["Use"]));
+      }
+      if (e.result !== undefined) {
+        children.push(el("p", {}, ["Result:"]),
+            el("pre", {}, [JSON.stringify(e.result, null, 2)]));
+      }
+      main.appendChild(el("details", {}, children));
+    });
+  }
+
+  // call calls the named function with param, JSON text or null, and shows its
+  // output. If validateOnly is true, the parameter is only validated.
+  function call(name, param, output, validateOnly) {
+    var start = Date.now();
+    var headers = {"Content-Type": "application/json"};
+    if (validateOnly) {
+      headers["Rpk-Validate-Only"] = "true";
+    }
+    output.className = "";
+    output.textContent = validateOnly ? "Validating..." : "Calling...";
+    fetch(location.pathname + "?func=" + encodeURIComponent(name), {
+      method: "POST",
+      headers: headers,
+      body: param == null ? "" : param
+    }).then(function(res) {
+      return res.text().then(function(body) {
+        var ms = Date.now() - start;
+        var data;
+        try {
+          data = body.trim() ? JSON.parse(body) : undefined;
+        } catch (e) {
+          data = body;
+        }
+        output.className = res.status != 200 || isError(data) ? "error" : "";
+        output.textContent = "Status " + res.status + ", " + ms + " ms\n\n" +
+            (data === undefined ? (validateOnly ? "(valid)" : "(no output)") :
+                typeof data == "string" ? data : JSON.stringify(data, null, 2));
+      });
+    }).catch(function(e) {
+      output.className = "error";
+      output.textContent = String(e);
+    });
+  }
+
+  snapshot.funcs.forEach(function(f) {
+    var item = el("li", {className: f.deprecated ? "deprecated" : "",
+        onclick: function() {
+          Array.prototype.forEach.call(list.children, function(c) {
+            c.classList.remove("selected");
+          });
+          item.classList.add("selected");
+          show(f);
+        }}, [f.name]);
+    list.appendChild(item);
+  });
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,55 @@
+package rpk
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlePlayground(t *testing.T) {
+	type point struct{ X, Y int }
+	h := New()
+	h.Register("Half", func(i int) int { return i / 2 })
+	h.Register("Move", func(p point) point { return point{p.X + 1, p.Y} })
+	p := HandlePlayground(h)
+
+	req := httptest.NewRequest("GET", "/api/playground", nil)
+	res := httptest.NewRecorder()
+	p.ServeHTTP(res, req)
+	if got := res.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Fatalf("Bad content type: %q, expected HTML.", got)
+	}
+	for _, want := range []string{
+		`{"name":"Half","param":{"type":"integer"},"result":{"type":"integer"}}`,
+		`"properties":{"X":{"type":"integer"},"Y":{"type":"integer"}}`,
+	} {
+		if !strings.Contains(res.Body.String(), want) {
+			t.Fatalf("Page does not contain %q.", want)
+		}
+	}
+	if strings.Contains(res.Body.String(), "{{snapshot}}") {
+		t.Fatal("Page contains the snapshot placeholder.")
+	}
+
+	tests := []struct{ name, param, want string }{
+		{"Half", "7", "3"},
+		{"Move", `{"X":1,"Y":2}`, `{"X":2,"Y":2}`},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/api/playground?func="+test.name,
+			strings.NewReader(test.param))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		p.ServeHTTP(res, req)
+		if got := strings.TrimSpace(res.Body.String()); got != test.want {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.name, got, test.want)
+		}
+	}
+
+	req = httptest.NewRequest("POST", "/api/playground", nil)
+	res = httptest.NewRecorder()
+	p.ServeHTTP(res, req)
+	if res.Code != 405 {
+		t.Fatalf("Bad status for POST without a function: %d, expected 405.", res.Code)
+	}
+}
@@ -0,0 +1,219 @@
+package rpk
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// An Overflow is a policy for calls that arrive when the queue of a worker pool is full.
+type Overflow int
+
+const (
+	// Block makes calls wait for room in the queue, until their context is done.
+	Block Overflow = iota
+
+	// Reject makes calls fail immediately with a "busy" error.
+	Reject
+)
+
+// Workers makes the handler run calls on a pool of n workers, instead of on the
+// goroutines of their HTTP requests. Calls wait in a queue of the given size until a
+// worker is free, and overflow, either Block or Reject, says what happens to calls that
+// arrive when the queue is full. This bounds the number of calls that run at once, for
+// functions that are CPU-heavy. Queued calls of functions with higher priorities, set by
+// Prioritize, run first. Handler.QueueStats reports the state of the pool.
+func Workers(n, queueSize int, overflow Overflow) Option {
+	return func(o *options) {
+		o.pool = newPool(n, queueSize, overflow)
+	}
+}
+
+// Bulkhead makes the handler run calls of the named functions on a pool of their own,
+// like that of Workers, so that slow functions, like ones that generate reports, cannot
+// take the workers of latency-sensitive ones, and functions of other bulkheads or of
+// the Workers pool are not affected by them. Name identifies the bulkhead in
+// Handler.BulkheadStats. Panics if a function is in more than one bulkhead.
+func Bulkhead(name string, n, queueSize int, overflow Overflow, funcs ...string) Option {
+	return func(o *options) {
+		p := newPool(n, queueSize, overflow)
+		if o.bulkheads == nil {
+			o.bulkheads = map[string]*pool{}
+			o.bulkheadOf = map[string]*pool{}
+		}
+		o.bulkheads[name] = p
+		for _, f := range funcs {
+			if o.bulkheadOf[f] != nil {
+				panic("rpk: function '" + f + "' is in more than one bulkhead")
+			}
+			o.bulkheadOf[f] = p
+		}
+	}
+}
+
+// QueueStats describes the state of a handler's worker pool.
+type QueueStats struct {
+	Queued   int    // Calls waiting for a worker.
+	Running  int    // Calls being run by workers.
+	Rejected uint64 // Calls rejected since the handler was created.
+}
+
+// QueueStats returns the state of the handler's worker pool, or zeros if it has none.
+func (h *Handler) QueueStats() QueueStats {
+	return h.opts.pool.stats()
+}
+
+// BulkheadStats returns the state of the pool of each bulkhead, by name.
+func (h *Handler) BulkheadStats() map[string]QueueStats {
+	if h.opts.bulkheads == nil {
+		return nil
+	}
+	result := make(map[string]QueueStats, len(h.opts.bulkheads))
+	for name, p := range h.opts.bulkheads {
+		result[name] = p.stats()
+	}
+	return result
+}
+
+// stats returns the state of the pool, or zeros if it is nil.
+func (p *pool) stats() QueueStats {
+	if p == nil {
+		return QueueStats{}
+	}
+	p.mu.Lock()
+	queued := len(p.queue)
+	p.mu.Unlock()
+	return QueueStats{
+		Queued:   queued,
+		Running:  int(atomic.LoadInt64(&p.running)),
+		Rejected: atomic.LoadUint64(&p.rejected),
+	}
+}
+
+// pool is a pool of workers that run calls.
+type pool struct {
+	n        int
+	overflow Overflow
+	slots    chan struct{} // Held by calls that run or wait, n plus the queue size.
+	ready    chan struct{} // Has an element for each job in queue.
+	mu       sync.Mutex
+	queue    []*job // Waiting jobs, by descending priority, guarded by mu.
+	start    sync.Once
+	running  int64
+	rejected uint64
+}
+
+// newPool returns a pool of n workers with a queue of the given size.
+func newPool(n, queueSize int, overflow Overflow) *pool {
+	return &pool{n: n, overflow: overflow, slots: make(chan struct{}, n+queueSize),
+		ready: make(chan struct{}, n+queueSize)}
+}
+
+// push adds a job to the queue, after the jobs of its priority and higher ones.
+func (p *pool) push(j *job) {
+	p.mu.Lock()
+	i := len(p.queue)
+	for i > 0 && p.queue[i-1].priority < j.priority {
+		i--
+	}
+	p.queue = slices.Insert(p.queue, i, j)
+	p.mu.Unlock()
+	p.ready <- struct{}{}
+}
+
+// pop removes the first job in the queue.
+func (p *pool) pop() *job {
+	<-p.ready
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	j := p.queue[0]
+	p.queue = p.queue[1:]
+	return j
+}
+
+// job is a call waiting for a worker.
+type job struct {
+	r        *http.Request
+	funcName string
+	param    io.Reader
+	priority Priority
+	done     chan jobResult
+}
+
+// jobResult is the outcome of a job.
+type jobResult struct {
+	res      callResult
+	panicked bool
+	p        interface{} // Panic value.
+}
+
+// runPooled calls a function like runHooked, on a worker if the handler has a pool for
+// it, either of its bulkhead or of Workers.
+func (h *Handler) runPooled(r *http.Request, funcName string, param io.Reader) callResult {
+	p := h.opts.bulkheadOf[funcName]
+	if p == nil {
+		p = h.opts.pool
+	}
+	if p == nil {
+		return h.runHooked(r, funcName, param)
+	}
+	p.start.Do(func() {
+		for i := 0; i < p.n; i++ {
+			go h.work(p)
+		}
+	})
+
+	if p.overflow == Reject {
+		select {
+		case p.slots <- struct{}{}:
+		default:
+			atomic.AddUint64(&p.rejected, 1)
+			return callResult{err: newCallError(errBusy,
+				"Server is busy, try again later.")}
+		}
+	} else {
+		select {
+		case p.slots <- struct{}{}:
+		case <-r.Context().Done():
+			return callResult{err: r.Context().Err()}
+		}
+	}
+	j := &job{r, funcName, param, h.opts.priorities[funcName], make(chan jobResult, 1)}
+	p.push(j)
+	// Panics are passed on to the request's goroutine, as if the call ran there.
+	jr := <-j.done
+	if jr.panicked {
+		panic(jr.p)
+	}
+	return jr.res
+}
+
+// work runs queued calls of a pool.
+func (h *Handler) work(p *pool) {
+	for {
+		j := p.pop()
+		// Calls whose clients gave up while waiting are not run.
+		if err := j.r.Context().Err(); err != nil {
+			<-p.slots
+			j.done <- jobResult{res: callResult{err: err}}
+			continue
+		}
+		atomic.AddInt64(&p.running, 1)
+		jr := h.runJob(j)
+		atomic.AddInt64(&p.running, -1)
+		<-p.slots
+		j.done <- jr
+	}
+}
+
+// runJob runs a queued call, recovering from panics.
+func (h *Handler) runJob(j *job) (jr jobResult) {
+	defer func() {
+		if p := recover(); p != nil {
+			jr = jobResult{panicked: true, p: p}
+		}
+	}()
+	return jobResult{res: h.runHooked(j.r, j.funcName, j.param)}
+}
@@ -0,0 +1,130 @@
+package rpk
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkers(t *testing.T) {
+	var running, maxRunning int64
+	h := New(Workers(2, 10, Block))
+	err := h.Register("Work", func() int {
+		n := atomic.AddInt64(&running, 1)
+		for {
+			m := atomic.LoadInt64(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt64(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt64(&running, -1)
+		return 1
+	})
+	if err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if result := callAPI(h, "Work", ""); result != "1" {
+				t.Errorf("Bad result: %q, expected %q.", result, "1")
+			}
+		}()
+	}
+	wg.Wait()
+	if maxRunning > 2 {
+		t.Fatalf("%d calls ran at once, expected at most 2.", maxRunning)
+	}
+}
+
+func TestWorkers_reject(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	h := New(Workers(1, 1, Reject))
+	err := h.Register("Wait", func() {
+		started <- struct{}{}
+		<-release
+	})
+	if err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+
+	// One call runs and one waits in the queue, so the third is rejected.
+	results := make(chan string, 2)
+	go func() { results <- callAPI(h, "Wait", "") }()
+	<-started
+	go func() { results <- callAPI(h, "Wait", "") }()
+	for h.QueueStats().Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if result := callAPI(h, "Wait", ""); !strings.Contains(result, "busy") {
+		t.Fatalf("Bad result for a full queue: %q, expected a busy error.", result)
+	}
+	if stats := h.QueueStats(); stats != (QueueStats{1, 1, 1}) {
+		t.Fatalf("QueueStats()=%+v, want %+v", stats, QueueStats{1, 1, 1})
+	}
+	close(release)
+	<-started
+	for i := 0; i < 2; i++ {
+		if result := <-results; result != "" {
+			t.Fatalf("Bad result: %q, expected an empty one.", result)
+		}
+	}
+}
+
+func TestWorkers_panic(t *testing.T) {
+	h := New(Workers(1, 1, Block), HideErrors(nil))
+	if err := h.Register("Panic", func() { panic("oops") }); err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+	if result := callAPI(h, "Panic", ""); !strings.Contains(result, "Internal error") {
+		t.Fatalf("Bad result: %q, expected a hidden error.", result)
+	}
+}
+
+func TestBulkhead(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	h := New(Workers(1, 1, Reject), Bulkhead("reports", 1, 1, Reject, "Report"))
+	h.Register("Report", func() {
+		started <- struct{}{}
+		<-release
+	})
+	h.Register("Ping", func() int { return 1 })
+
+	// The bulkhead is full, but calls of other functions still run.
+	results := make(chan string, 2)
+	go func() { results <- callAPI(h, "Report", "") }()
+	<-started
+	go func() { results <- callAPI(h, "Report", "") }()
+	for h.BulkheadStats()["reports"].Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if got := callAPI(h, "Report", ""); !strings.Contains(got, "busy") {
+		t.Fatalf("Bad result for a full bulkhead: %q, expected a busy error.", got)
+	}
+	for range 3 {
+		if got := callAPI(h, "Ping", ""); got != "1" {
+			t.Fatalf("Bad result of Ping: %q, expected %q.", got, "1")
+		}
+	}
+	stats := h.BulkheadStats()
+	if want := (QueueStats{1, 1, 1}); stats["reports"] != want {
+		t.Fatalf("BulkheadStats()=%+v, want %+v", stats, want)
+	}
+	if want := (QueueStats{0, 0, 0}); h.QueueStats() != want {
+		t.Fatalf("QueueStats()=%+v, want %+v", h.QueueStats(), want)
+	}
+	close(release)
+	for range 2 {
+		if got := <-results; got != "" {
+			t.Fatalf("Bad result of Report: %q, expected %q.", got, "")
+		}
+	}
+}
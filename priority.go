@@ -0,0 +1,47 @@
+package rpk
+
+// A Priority is the priority class of functions, set by Prioritize.
+type Priority int
+
+const (
+	// Background is the priority of functions whose calls can wait, like ones that
+	// generate reports or sync data.
+	Background Priority = -1
+
+	// Normal is the priority of functions that are not prioritized.
+	Normal Priority = 0
+
+	// Interactive is the priority of functions that users wait for, like ones that
+	// load pages.
+	Interactive Priority = 1
+)
+
+// Prioritize sets the priority of the named functions. Queued calls of functions with
+// higher priorities run first on the pools of Workers and Bulkhead, and LoadShedding
+// sheds calls of functions with lower priorities first. Panics if p is not one of
+// Background, Normal and Interactive.
+func Prioritize(p Priority, names ...string) Option {
+	if p < Background || p > Interactive {
+		panic("rpk: bad priority")
+	}
+	return func(o *options) {
+		if o.priorities == nil {
+			o.priorities = map[string]Priority{}
+		}
+		for _, name := range names {
+			o.priorities[name] = p
+		}
+	}
+}
+
+// sheddingFactor returns the factor of the thresholds of LoadShedding for functions of
+// priority p.
+func sheddingFactor(p Priority) float64 {
+	switch p {
+	case Background:
+		return 0.5
+	case Interactive:
+		return 2
+	}
+	return 1
+}
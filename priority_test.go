@@ -0,0 +1,88 @@
+package rpk
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPrioritize_workers(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	h := New(Workers(1, 10, Block), Prioritize(Background, "Report"),
+		Prioritize(Interactive, "Page"))
+	h.Register("Hold", func() {
+		close(started)
+		<-release
+	})
+	for _, name := range []string{"Report", "Sync", "Page"} {
+		h.Register(name, func() {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+		})
+	}
+	var wg sync.WaitGroup
+	call := func(name string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			callAPI(h, name, "")
+		}()
+	}
+
+	// Calls are queued while the worker is held.
+	call("Hold")
+	<-started
+	for i, name := range []string{"Report", "Sync", "Page"} {
+		call(name)
+		for h.QueueStats().Queued != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	close(release)
+	wg.Wait()
+	if got := strings.Join(order, ","); got != "Page,Sync,Report" {
+		t.Fatalf("Bad order of calls: %q, expected %q.", got, "Page,Sync,Report")
+	}
+}
+
+func TestPrioritize_shedding(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	h := New(LoadShedding(2, 0), Prioritize(Background, "Report"),
+		Prioritize(Interactive, "Page"))
+	h.Register("Hold", func() {
+		started <- struct{}{}
+		<-release
+	})
+	for _, name := range []string{"Report", "Sync", "Page"} {
+		h.Register(name, func() int { return 1 })
+	}
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			callAPI(h, "Hold", "")
+		}()
+		<-started
+	}
+	// Thresholds are 1 for Report, 2 for Sync and 4 for Page.
+	tests := []struct {
+		name string
+		shed bool
+	}{{"Report", true}, {"Sync", true}, {"Page", false}}
+	for _, test := range tests {
+		got := callAPI(h, test.name, "")
+		if shed := strings.Contains(got, "overloaded"); shed != test.shed {
+			t.Fatalf("Bad result for %s: %q, expected shed=%v.", test.name, got, test.shed)
+		}
+	}
+	close(release)
+	wg.Wait()
+}
@@ -0,0 +1,103 @@
+package rpk
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProtocolVersion is the version of the protocol between handlers and clients. Clients
+// send the versions that they speak in the ProtocolHeader of their first call, and
+// handlers answer with the version that both speak. Clients that send no version, like
+// scripts that were cached before versions were introduced, speak version 1.
+const ProtocolVersion = 1
+
+// ProtocolHeader is the request and response header of the protocol version.
+const ProtocolHeader = "Rpk-Protocol"
+
+// FeaturesHeader is the request and response header of the features that a client and a
+// handler support, separated by commas. The handler answers with the features that both
+// support, or with all of its features if the client sends none. The features are:
+//
+//	batch        Batches of calls, with JSONRPC.
+//	events       Broadcast messages.
+//	gzip         Compressed streams.
+//	idempotency  Idempotency keys.
+//	int64string  64-bit integers encoded as strings, with Int64AsString.
+//	jobs         Background jobs, with Async.
+//	jsnames      Javascript names of functions, with JSNames.
+//	paths        Function names in URL paths, with PathRouting.
+//	stream       Newline-delimited JSON streams.
+//	transactions Groups of calls, with SetTxWrapper.
+//	uploads      Chunked uploads, with Uploads.
+//	versions     Resource versions.
+const FeaturesHeader = "Rpk-Features"
+
+// features returns the features that the handler supports, sorted.
+func (h *Handler) features() []string {
+	result := []string{"events", "gzip", "stream", "versions"}
+	if h.opts.jsonrpc {
+		result = append(result, "batch")
+	}
+	if h.opts.idempotency != nil {
+		result = append(result, "idempotency")
+	}
+	if h.opts.codecs != nil && h.opts.codecs.kinds[reflect.Int64] != nil {
+		result = append(result, "int64string")
+	}
+	if len(h.opts.async) > 0 {
+		result = append(result, "jobs")
+	}
+	if h.opts.jsNames != nil {
+		result = append(result, "jsnames")
+	}
+	if h.opts.pathRouting {
+		result = append(result, "paths")
+	}
+	if h.tx != nil {
+		result = append(result, "transactions")
+	}
+	if h.opts.uploads != nil {
+		result = append(result, "uploads")
+	}
+	sort.Strings(result)
+	return result
+}
+
+// negotiate answers a request that asks for a protocol version with the version and
+// the features that the handler and the client both support.
+func (h *Handler) negotiate(w http.ResponseWriter, r *http.Request) {
+	v := r.Header.Get(ProtocolHeader)
+	if v == "" {
+		return
+	}
+	version, features := h.negotiated(v, r.Header.Get(FeaturesHeader))
+	w.Header().Set(ProtocolHeader, strconv.Itoa(version))
+	w.Header().Set(FeaturesHeader, strings.Join(features, ","))
+}
+
+// negotiated returns the protocol version and the features that the handler and a
+// client both support, given the version and the features that the client sent.
+func (h *Handler) negotiated(v, f string) (int, []string) {
+	version, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || version < 1 || version > ProtocolVersion {
+		version = ProtocolVersion
+	}
+	features := h.features()
+	if f != "" {
+		wanted := map[string]bool{}
+		for _, name := range strings.Split(f, ",") {
+			wanted[strings.TrimSpace(name)] = true
+		}
+		var both []string
+		for _, name := range features {
+			if wanted[name] {
+				both = append(both, name)
+			}
+		}
+		features = both
+	}
+	return version, features
+}
@@ -0,0 +1,40 @@
+package rpk
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_negotiate(t *testing.T) {
+	h := New(Async(time.Minute, "Job"), Int64AsString())
+	tests := []struct {
+		version, features         string
+		wantVersion, wantFeatures string
+	}{
+		{"", "", "", ""},
+		{"1", "", "1", "events,gzip,int64string,jobs,stream,versions"},
+		{"1", "jobs, stream,future", "1", "jobs,stream"},
+		{"7", "paths", "1", ""},
+		{"x", "", "1", "events,gzip,int64string,jobs,stream,versions"},
+	}
+	for _, test := range tests {
+		req := apiRequest("funcs", "")
+		if test.version != "" {
+			req.Header.Set(ProtocolHeader, test.version)
+		}
+		if test.features != "" {
+			req.Header.Set(FeaturesHeader, test.features)
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		if got := res.Header().Get(ProtocolHeader); got != test.wantVersion {
+			t.Fatalf("Bad version for %q: %q, expected %q.", test.version, got,
+				test.wantVersion)
+		}
+		if got := res.Header().Get(FeaturesHeader); got != test.wantFeatures {
+			t.Fatalf("Bad features for %q: %q, expected %q.", test.features, got,
+				test.wantFeatures)
+		}
+	}
+}
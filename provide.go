@@ -0,0 +1,84 @@
+package rpk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// Provide adds a provider of values that are injected into functions as extra
+// parameters, by type. F should be a function that takes an *http.Request and
+// returns a value, optionally followed by an error, such as:
+//
+//	h.Provide(func(r *http.Request) (*UserSession, error) { ... })
+//
+// Functions can then take a *UserSession besides their input, in any order after the
+// optional context, and get the value that the provider derives from the call's
+// request:
+//
+//	func (a API) Profile(s *UserSession, id int) (*Profile, error)
+//
+// If the provider returns an error, the function is not called, and the error is
+// returned to the client. Providers should be added before registering the functions
+// that use them, and their types then stop being valid input types.
+func (h *Handler) Provide(f interface{}) error {
+	value := reflect.ValueOf(f)
+	if value.Kind() != reflect.Func || value.Type().NumIn() != 1 ||
+		value.Type().In(0) != reflect.TypeOf((*http.Request)(nil)) {
+		return fmt.Errorf("provider should be a function that takes an *http.Request, "+
+			"got %T", f)
+	}
+	typ := value.Type()
+	if typ.NumOut() < 1 || typ.NumOut() > 2 || isError(typ.Out(0)) ||
+		typ.NumOut() == 2 && !isError(typ.Out(1)) {
+		return fmt.Errorf("provider should return a value and optionally an error, "+
+			"got %v", typ)
+	}
+	if isContext(typ.Out(0)) {
+		return fmt.Errorf("provider cannot provide a context.Context")
+	}
+	if h.opts.providers == nil {
+		h.opts.providers = map[reflect.Type]*provider{}
+	}
+	h.opts.providers[typ.Out(0)] = &provider{value, typ.NumOut() == 2}
+	return nil
+}
+
+// provider creates values to inject into functions.
+type provider struct {
+	fn     reflect.Value
+	hasErr bool
+}
+
+// provide returns a value for the call with the given context.
+func (p *provider) provide(ctx context.Context) (reflect.Value, error) {
+	r, _ := ctx.Value(requestKey{}).(*http.Request)
+	if r == nil {
+		return reflect.Value{}, fmt.Errorf("cannot inject %v without a request",
+			p.fn.Type().Out(0))
+	}
+	out := p.fn.Call([]reflect.Value{reflect.ValueOf(r)})
+	if p.hasErr && !out[1].IsNil() {
+		return reflect.Value{}, out[1].Interface().(error)
+	}
+	return out[0], nil
+}
+
+// requestKey is the context key of the request of a call, for providers.
+type requestKey struct{}
+
+// callContext returns the context for calling functions for request r.
+func (h *Handler) callContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if len(h.opts.providers) > 0 {
+		ctx = context.WithValue(ctx, requestKey{}, r)
+	}
+	if v := ifMatch(r); v != "" {
+		ctx = context.WithValue(ctx, requestVersionKey{}, v)
+	}
+	if validateOnly(r) {
+		ctx = WithValidateOnly(ctx)
+	}
+	return ctx
+}
@@ -0,0 +1,81 @@
+package rpk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+type provideSession struct {
+	User string
+}
+
+type provideAPI struct{}
+
+func (provideAPI) Whoami(s *provideSession) string {
+	return s.User
+}
+
+func (provideAPI) Greet(ctx context.Context, greeting string, s *provideSession) string {
+	return greeting + " " + s.User
+}
+
+func TestProvide(t *testing.T) {
+	h := New()
+	err := h.Provide(func(r *http.Request) (*provideSession, error) {
+		user := r.Header.Get("X-User")
+		if user == "" {
+			return nil, errors.New("not logged in")
+		}
+		return &provideSession{user}, nil
+	})
+	if err != nil {
+		t.Fatal("Failed to add provider:", err)
+	}
+	if err := h.RegisterObject(provideAPI{}); err != nil {
+		t.Fatal("Failed to register object:", err)
+	}
+
+	tests := []struct {
+		funcName string
+		param    string
+		user     string
+		result   string
+	}{
+		{"Whoami", "", "amy", `"amy"`},
+		{"Greet", `"hi"`, "amy", `"hi amy"`},
+		{"Whoami", "", "", `{"error":"not logged in"}`},
+	}
+	for _, test := range tests {
+		req := apiRequest(test.funcName, test.param)
+		if test.user != "" {
+			req.Header.Set("X-User", test.user)
+		}
+		if result := serve(h, req); result != test.result {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.funcName, result,
+				test.result)
+		}
+	}
+}
+
+func TestProvide_bad(t *testing.T) {
+	h := New()
+	for _, f := range []interface{}{
+		nil,
+		1,
+		func() int { return 0 },
+		func(r *http.Request) {},
+		func(r *http.Request) (int, int) { return 0, 0 },
+		func(r *http.Request) context.Context { return nil },
+	} {
+		if err := h.Provide(f); err == nil {
+			t.Fatalf("Provide(%T) succeeded, expected an error.", f)
+		}
+	}
+
+	// Without a provider, two inputs are invalid.
+	if err := h.RegisterObject(provideAPI{}); err == nil {
+		t.Fatal("RegisterObject succeeded without a provider.")
+	}
+}
@@ -0,0 +1,65 @@
+package rpk
+
+import (
+	"fmt"
+	"sync"
+)
+
+// A PubSub carries broadcast messages between the instances of a server, so that clients
+// get the messages of all instances, whichever one they are connected to. The package
+// rpk/redis implements it with Redis.
+type PubSub interface {
+	// Publish sends an encoded message to all the instances, including this one.
+	Publish(topic string, message []byte) error
+
+	// Subscribe makes the PubSub call receive with each message that it gets, from
+	// any instance. It is called once, when a handler is created.
+	Subscribe(receive func(topic string, message []byte))
+}
+
+// PubSubBackend makes the handler send broadcast messages through b, so that several
+// instances of a server behind a load balancer can broadcast to all their clients.
+// Without it, messages go only to the clients of the instance that broadcasts them.
+func PubSubBackend(b PubSub) Option {
+	return func(o *options) {
+		o.pubsub = b
+	}
+}
+
+// A MemoryPubSub is a PubSub that carries messages between handlers in the same
+// process. The zero value is ready to use.
+type MemoryPubSub struct {
+	mu        sync.Mutex
+	receivers []func(topic string, message []byte)
+}
+
+// Publish calls the receivers of all subscribed handlers.
+func (m *MemoryPubSub) Publish(topic string, message []byte) error {
+	m.mu.Lock()
+	receivers := m.receivers
+	m.mu.Unlock()
+	for _, receive := range receivers {
+		receive(topic, message)
+	}
+	return nil
+}
+
+// Subscribe adds a receiver.
+func (m *MemoryPubSub) Subscribe(receive func(topic string, message []byte)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.receivers = append(m.receivers[:len(m.receivers):len(m.receivers)], receive)
+}
+
+// publish sends an encoded broadcast message through the handler's backend, or to its
+// own subscribers if it has none.
+func (h *Handler) publish(topic string, data []byte) error {
+	if h.opts.pubsub == nil {
+		h.subs.publish(topic, data)
+		return nil
+	}
+	if err := h.opts.pubsub.Publish(topic, data); err != nil {
+		return fmt.Errorf("rpk: error publishing to topic '%s': %v", topic, err)
+	}
+	return nil
+}
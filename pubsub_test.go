@@ -0,0 +1,36 @@
+package rpk
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPubSubBackend(t *testing.T) {
+	var bus MemoryPubSub
+	h1 := New(PubSubBackend(&bus))
+	h2 := New(PubSubBackend(&bus))
+	server := httptest.NewServer(h2)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "?func=events&topics=a")
+	if err != nil {
+		t.Fatal("Failed to subscribe:", err)
+	}
+	defer res.Body.Close()
+	r := bufio.NewReader(res.Body)
+	r.ReadString('\n')
+	r.ReadString('\n')
+
+	// Broadcast by the handler that the client is not connected to.
+	if err := h1.Broadcast("a", 1); err != nil {
+		t.Fatal("Failed to broadcast:", err)
+	}
+	want := `data: {"topic":"a","payload":1}`
+	line, _ := r.ReadString('\n')
+	if line = strings.TrimSpace(line); line != want {
+		t.Fatalf("Bad message: %q, expected %q.", line, want)
+	}
+}
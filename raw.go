@@ -0,0 +1,41 @@
+package rpk
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+)
+
+// CallRaw calls the named function with an encoded parameter, and returns the encoded
+// response, as the handler would send it over HTTP, but without going through HTTP. An
+// empty param means none. Hooks, providers and other options apply as usual, with a
+// request that has no headers. This is the entry point for fuzzing the decoding and
+// calling of functions.
+func (h *Handler) CallRaw(name string, param []byte) []byte {
+	r, _ := http.NewRequestWithContext(context.Background(), "POST", "/?func="+name,
+		bytes.NewReader(param))
+	w := &memResponseWriter{header: http.Header{}}
+	res := h.run(r, name, bytes.NewReader(param))
+	if res.streamed(r) {
+		h.writeStream(w, r, &res)
+	} else {
+		res.write(&w.buf, h.opts.encode)
+	}
+	return w.buf.Bytes()
+}
+
+// memResponseWriter is a ResponseWriter that keeps the response body in memory.
+type memResponseWriter struct {
+	header http.Header
+	buf    bytes.Buffer
+}
+
+func (w *memResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *memResponseWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+func (w *memResponseWriter) WriteHeader(status int) {}
@@ -0,0 +1,80 @@
+package rpk
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fuzzThing struct {
+	Name   string
+	Count  int64
+	Ratio  float64
+	Tags   []string
+	Attrs  map[string]*fuzzThing
+	When   time.Time
+	Big    *big.Int
+	Nested [2]struct{ A, B uint8 }
+	Color  testColor
+}
+
+// fuzzHandler returns a handler with functions of various parameter types.
+func fuzzHandler(opts ...Option) *Handler {
+	h := New(opts...)
+	h.Register("Int", func(i int) int { return i })
+	h.Register("Uint8", func(i uint8) uint8 { return i })
+	h.Register("String", func(s string) string { return s })
+	h.Register("Thing", func(t *fuzzThing) *fuzzThing { return t })
+	h.Register("Any", func(v interface{}) interface{} { return v })
+	h.Register("Raw", func(r Raw) int { return len(r) })
+	h.Register("None", func() {})
+	return h
+}
+
+func TestCallRaw(t *testing.T) {
+	h := fuzzHandler()
+	tests := []struct {
+		name  string
+		param string
+		want  string
+	}{
+		{"Int", "3", "3\n"},
+		{"String", `"a"`, "\"a\"\n"},
+		{"None", "", ""},
+		{"Nope", "", "{\"error\":\"No such function 'Nope'.\"}\n"},
+	}
+	for _, test := range tests {
+		if got := h.CallRaw(test.name, []byte(test.param)); string(got) != test.want {
+			t.Fatalf("Bad result for %s(%s): %q, expected %q.", test.name, test.param,
+				got, test.want)
+		}
+	}
+}
+
+// checkResponse fails if a response is not empty or a JSON value.
+func checkResponse(t *testing.T, name string, param, response []byte) {
+	if len(response) > 0 && !json.Valid(response) {
+		t.Fatalf("Bad response for %s(%q): %q", name, param, response)
+	}
+}
+
+func FuzzCallRaw(f *testing.F) {
+	for _, seed := range []string{"1", `"a"`, "null", "[1,2]", `{"Name":"a","Count":1}`,
+		`{"Attrs":{"a":{"Tags":["x"]}}}`, "1e999", "-0", "99999999999999999999",
+		strings.Repeat("[", 1000), `{"When":"2020-01-01T00:00:00Z","Big":123}`,
+		`{"Nested":[{"A":300}]}`, `{"Color":"pink"}`, "\"\\ud800\""} {
+		f.Add(seed)
+	}
+	handlers := []*Handler{fuzzHandler(), fuzzHandler(Strict(), Int64AsString(),
+		TimeFormat(time.DateOnly), FieldNaming(SnakeCase))}
+	names := []string{"Int", "Uint8", "String", "Thing", "Any", "Raw", "None"}
+	f.Fuzz(func(t *testing.T, param string) {
+		for _, h := range handlers {
+			for _, name := range names {
+				checkResponse(t, name, []byte(param), h.CallRaw(name, []byte(param)))
+			}
+		}
+	})
+}
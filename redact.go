@@ -0,0 +1,158 @@
+package rpk
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Redact returns a copy of v in which struct fields tagged with `rpk:"redact"` are set to
+// their zero values, at any depth. Values with no such fields are returned as they are.
+// Only the parts of v that lead to redacted fields are copied, so the rest may be shared
+// with v.
+//
+// Tag fields that hold sensitive values, such as passwords and tokens. The handler keeps
+// their values out of error messages, and redacts the parameters that hooks see, so that
+// they do not end up in logs and audit entries.
+//
+//	type Login struct {
+//		User     string
+//		Password string `rpk:"redact"`
+//	}
+func Redact(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	val := reflect.ValueOf(v)
+	if !hasRedacted(val.Type()) {
+		return v
+	}
+	return redactValue(val).Interface()
+}
+
+// redactValue returns a copy of v in which redacted fields are zeroed.
+func redactValue(v reflect.Value) reflect.Value {
+	t := v.Type()
+	if !hasRedacted(t) {
+		return v
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(t.Elem())
+		c.Elem().Set(redactValue(v.Elem()))
+		return c
+	case reflect.Struct:
+		c := reflect.New(t).Elem()
+		c.Set(v)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			if isRedacted(f) {
+				c.Field(i).Set(reflect.Zero(f.Type))
+			} else {
+				c.Field(i).Set(redactValue(v.Field(i)))
+			}
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(t, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(redactValue(v.Index(i)))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(t).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(redactValue(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(t, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), redactValue(iter.Value()))
+		}
+		return c
+	}
+	return v
+}
+
+// isRedacted returns whether a struct field is tagged for redaction.
+func isRedacted(f reflect.StructField) bool {
+	for _, opt := range strings.Split(f.Tag.Get("rpk"), ",") {
+		if opt == "redact" {
+			return true
+		}
+	}
+	return false
+}
+
+// redactedTypes caches whether types have redacted fields.
+var redactedTypes sync.Map // reflect.Type -> bool
+
+// hasRedacted returns whether values of type t may contain redacted fields. Fields of
+// interface types are not looked into.
+func hasRedacted(t reflect.Type) bool {
+	if r, ok := redactedTypes.Load(t); ok {
+		return r.(bool)
+	}
+	r := findRedacted(t, map[reflect.Type]bool{})
+	redactedTypes.Store(t, r)
+	return r
+}
+
+// findRedacted implements hasRedacted. Seen holds the types that are already being
+// looked into, for recursive types.
+func findRedacted(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return findRedacted(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			if isRedacted(f) || findRedacted(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeErrorMessage returns the message of an error of decoding into v. If v has
+// redacted fields, the message leaves out anything that may echo the input.
+func decodeErrorMessage(err error, v interface{}) string {
+	if v == nil || !hasRedacted(reflect.TypeOf(v)) {
+		return err.Error()
+	}
+	switch err := err.(type) {
+	case *json.UnmarshalTypeError:
+		if err.Field != "" {
+			return fmt.Sprintf("bad value for field %s of type %v (details redacted)",
+				err.Field, err.Type)
+		}
+		return fmt.Sprintf("bad value for type %v (details redacted)", err.Type)
+	case *json.SyntaxError:
+		return fmt.Sprintf("syntax error at offset %d (details redacted)", err.Offset)
+	}
+	return "malformed parameter (details redacted)"
+}
@@ -0,0 +1,77 @@
+package rpk
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type redactLogin struct {
+	User     string
+	Password string `rpk:"redact"`
+	Count    int
+}
+
+type redactNested struct {
+	Logins []redactLogin
+	ByName map[string]*redactLogin
+	Next   *redactNested
+}
+
+func TestRedact(t *testing.T) {
+	login := &redactLogin{"a", "secret", 1}
+	got := Redact(login).(*redactLogin)
+	if want := (&redactLogin{"a", "", 1}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Redact(%v)=%v, want %v", login, got, want)
+	}
+	if login.Password != "secret" {
+		t.Fatalf("Redact modified its input: %v", login)
+	}
+
+	nested := redactNested{
+		Logins: []redactLogin{{"a", "x", 1}},
+		ByName: map[string]*redactLogin{"b": {"b", "y", 2}},
+		Next:   &redactNested{Logins: []redactLogin{{"c", "z", 3}}},
+	}
+	gotNested := Redact(nested).(redactNested)
+	wantNested := redactNested{
+		Logins: []redactLogin{{"a", "", 1}},
+		ByName: map[string]*redactLogin{"b": {"b", "", 2}},
+		Next:   &redactNested{Logins: []redactLogin{{"c", "", 3}}},
+	}
+	if !reflect.DeepEqual(gotNested, wantNested) {
+		t.Fatalf("Redact(%v)=%v, want %v", nested, gotNested, wantNested)
+	}
+
+	plain := &struct{ A string }{"a"}
+	if got := Redact(plain); got != plain {
+		t.Fatalf("Redact(%v)=%v, want the same pointer", plain, got)
+	}
+}
+
+func TestRedact_decodeError(t *testing.T) {
+	h := New()
+	if err := h.Register("Login", func(l *redactLogin) {}); err != nil {
+		t.Fatal("Failed to register function:", err)
+	}
+	var hooked interface{}
+	h.After("Login", func(c *Call) {
+		hooked = c.Param
+	})
+
+	for _, param := range []string{
+		`{"User":"a","Password":1234567}`,
+		`{"User":"a","Password":"1234567"`,
+		`{"User":"a","Password":"1234567","Count":"1234567"}`,
+	} {
+		if result := callAPI(h, "Login", param); !strings.Contains(result, "error") ||
+			strings.Contains(result, "1234567") {
+			t.Fatalf("Bad result for %s: %q, expected a redacted error.", param, result)
+		}
+	}
+
+	callAPI(h, "Login", `{"User":"a","Password":"secret"}`)
+	if want := (&redactLogin{User: "a"}); !reflect.DeepEqual(hooked, want) {
+		t.Fatalf("Hook got %v, want %v", hooked, want)
+	}
+}
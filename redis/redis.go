@@ -0,0 +1,337 @@
+// Package redis implements rpk.PubSub with Redis, so that several instances of a server
+// can broadcast messages to all of their clients.
+//
+//	ps := redis.New("localhost:6379", redis.Prefix("myapp:"))
+//	h := rpk.New(rpk.PubSubBackend(ps))
+//
+// Messages are published to Redis channels named by the prefix and their topic, and each
+// instance receives them with a pattern subscription to the prefix.
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A PubSub sends broadcast messages through a Redis server. It implements rpk.PubSub.
+type PubSub struct {
+	addr     string
+	password string
+	prefix   string
+	onError  func(err error)
+	timeout  time.Duration
+
+	mu     sync.Mutex     // Guards the connections.
+	conn   *conn          // Idle, for publishing, nil if there is none.
+	busy   map[*conn]bool // Publishing.
+	sub    *conn          // For receiving, nil if not connected.
+	closed chan struct{}  // Closed by Close.
+}
+
+// An Option configures a PubSub.
+type Option func(*PubSub)
+
+// Password makes the PubSub authenticate with the given password.
+func Password(password string) Option {
+	return func(p *PubSub) {
+		p.password = password
+	}
+}
+
+// Prefix sets the prefix of the names of the channels that the PubSub uses, so that
+// several applications can share a Redis server. The default is "rpk:".
+func Prefix(prefix string) Option {
+	return func(p *PubSub) {
+		p.prefix = prefix
+	}
+}
+
+// OnError makes the PubSub call report with errors of its subscription, after which
+// it reconnects. Messages published while it is disconnected are lost.
+func OnError(report func(err error)) Option {
+	return func(p *PubSub) {
+		p.onError = report
+	}
+}
+
+// Timeout sets how long the PubSub waits for the server to connect and to answer
+// commands, after which their connections are dropped. The default is 10 seconds.
+func Timeout(timeout time.Duration) Option {
+	return func(p *PubSub) {
+		p.timeout = timeout
+	}
+}
+
+// New returns a PubSub that uses the Redis server at addr. It connects when first used.
+func New(addr string, opts ...Option) *PubSub {
+	p := &PubSub{addr: addr, prefix: "rpk:", timeout: 10 * time.Second,
+		busy: map[*conn]bool{}, closed: make(chan struct{})}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Publish publishes a message to the channel of topic. Concurrent messages are
+// published on connections of their own, so a slow server does not hold up the others.
+func (p *PubSub) Publish(topic string, message []byte) error {
+	c, err := p.take()
+	if err != nil {
+		return err
+	}
+	c.SetDeadline(time.Now().Add(p.timeout))
+	_, err = c.do("PUBLISH", []byte(p.prefix+topic), message)
+	// Connections that failed are dropped, and reconnected next time.
+	p.put(c, err == nil || errors.As(err, new(redisError)))
+	return err
+}
+
+// take returns the idle connection for publishing, or a new one if there is none.
+func (p *PubSub) take() (*conn, error) {
+	p.mu.Lock()
+	if p.isClosed() {
+		p.mu.Unlock()
+		return nil, errClosed
+	}
+	c := p.conn
+	p.conn = nil
+	p.mu.Unlock()
+	if c == nil {
+		var err error
+		if c, err = p.dial(); err != nil {
+			return nil, err
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.isClosed() {
+		c.Close()
+		return nil, errClosed
+	}
+	p.busy[c] = true
+	return c, nil
+}
+
+// put returns a connection that was used for publishing, which is kept for the next
+// message if ok, and there is no other idle one.
+func (p *PubSub) put(c *conn, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.busy, c)
+	if ok && p.conn == nil && !p.isClosed() {
+		p.conn = c
+	} else {
+		c.Close()
+	}
+}
+
+// isClosed returns whether Close was called.
+func (p *PubSub) isClosed() bool {
+	select {
+	case <-p.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// errClosed is the error of publishing on a closed PubSub.
+var errClosed = errors.New("redis: closed")
+
+// Subscribe starts receiving messages, in the background, and calls receive with each,
+// until the PubSub is closed.
+func (p *PubSub) Subscribe(receive func(topic string, message []byte)) {
+	go func() {
+		backoff := 100 * time.Millisecond
+		for {
+			start := time.Now()
+			err := p.receive(receive)
+			select {
+			case <-p.closed:
+				return
+			default:
+			}
+			if p.onError != nil {
+				p.onError(err)
+			}
+			if time.Since(start) > time.Minute {
+				backoff = 100 * time.Millisecond
+			}
+			select {
+			case <-p.closed:
+				return
+			case <-time.After(backoff):
+			}
+			if backoff < 10*time.Second {
+				backoff *= 2
+			}
+		}
+	}()
+}
+
+// Close closes the PubSub's connections and stops its subscription. Messages that are
+// being published fail.
+func (p *PubSub) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.isClosed() {
+		return nil
+	}
+	close(p.closed)
+	for _, c := range []*conn{p.conn, p.sub} {
+		if c != nil {
+			c.Close()
+		}
+	}
+	for c := range p.busy {
+		c.Close()
+	}
+	return nil
+}
+
+// receive subscribes to the prefix's channels and calls f with each message, until the
+// connection fails.
+func (p *PubSub) receive(f func(topic string, message []byte)) error {
+	c, err := p.dial()
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	if p.isClosed() {
+		p.mu.Unlock()
+		c.Close()
+		return nil
+	}
+	p.sub = c
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.sub = nil
+		p.mu.Unlock()
+		c.Close()
+	}()
+	// Messages may take any time to come, so only sending is timed.
+	c.SetWriteDeadline(time.Now().Add(p.timeout))
+	if err := c.send("PSUBSCRIBE", []byte(p.prefix+"*")); err != nil {
+		return err
+	}
+	for {
+		v, err := c.read()
+		if err != nil {
+			return err
+		}
+		// Messages are ["pmessage", pattern, channel, data].
+		msg, ok := v.([]interface{})
+		if !ok || len(msg) != 4 {
+			continue
+		}
+		kind, _ := msg[0].([]byte)
+		channel, _ := msg[2].([]byte)
+		data, _ := msg[3].([]byte)
+		if string(kind) != "pmessage" || len(channel) < len(p.prefix) {
+			continue
+		}
+		f(string(channel[len(p.prefix):]), data)
+	}
+}
+
+// dial connects to the server and authenticates. The connection has no deadlines.
+func (p *PubSub) dial() (*conn, error) {
+	nc, err := net.DialTimeout("tcp", p.addr, p.timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &conn{nc, bufio.NewReader(nc)}
+	if p.password != "" {
+		c.SetDeadline(time.Now().Add(p.timeout))
+		if _, err := c.do("AUTH", []byte(p.password)); err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.SetDeadline(time.Time{})
+	}
+	return c, nil
+}
+
+// A conn is a connection to a Redis server.
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// A redisError is an error reply of the server.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// do sends a command and returns its reply.
+func (c *conn) do(cmd string, args ...[]byte) (interface{}, error) {
+	if err := c.send(cmd, args...); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// send sends a command.
+func (c *conn) send(cmd string, args ...[]byte) error {
+	buf := []byte(fmt.Sprintf("*%d\r\n$%d\r\n%s\r\n", len(args)+1, len(cmd), cmd))
+	for _, arg := range args {
+		buf = append(buf, fmt.Sprintf("$%d\r\n", len(arg))...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	_, err := c.Write(buf)
+	return err
+}
+
+// read reads a reply: a string or bulk string as []byte, an integer as int64, an array
+// as []interface{}, or an error reply as a redisError.
+func (c *conn) read() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: bad reply line: %q", line)
+	}
+	kind, line := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return []byte(line), nil
+	case '-':
+		return nil, redisError(line)
+	case ':':
+		return strconv.ParseInt(line, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < 0 {
+			return nil, err // Nil bulk string.
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < 0 {
+			return nil, err // Nil array.
+		}
+		result := make([]interface{}, n)
+		for i := range result {
+			if result[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("redis: bad reply type: %q", kind)
+}
@@ -0,0 +1,176 @@
+package redis
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fluhus/rpk"
+)
+
+// fakeServer is a Redis server that supports AUTH, PUBLISH and PSUBSCRIBE with a
+// trailing "*".
+type fakeServer struct {
+	l    net.Listener
+	mu   sync.Mutex
+	subs map[*conn]string // Patterns by subscriber.
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Failed to listen:", err)
+	}
+	s := &fakeServer{l: l, subs: map[*conn]string{}}
+	go func() {
+		for {
+			nc, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(&conn{nc, bufio.NewReader(nc)})
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) serve(c *conn) {
+	defer c.Close()
+	for {
+		v, err := c.read()
+		if err != nil {
+			return
+		}
+		args := v.([]interface{})
+		switch string(args[0].([]byte)) {
+		case "AUTH":
+			if string(args[1].([]byte)) != "secret" {
+				c.Write([]byte("-WRONGPASS invalid password\r\n"))
+			} else {
+				c.Write([]byte("+OK\r\n"))
+			}
+		case "PSUBSCRIBE":
+			pattern := string(args[1].([]byte))
+			s.mu.Lock()
+			s.subs[c] = pattern
+			c.send("psubscribe", []byte(pattern), []byte("1"))
+			s.mu.Unlock()
+		case "PUBLISH":
+			channel, data := args[1].([]byte), args[2].([]byte)
+			s.mu.Lock()
+			n := 0
+			for sub, pattern := range s.subs {
+				if strings.HasPrefix(string(channel), strings.TrimSuffix(pattern, "*")) {
+					sub.send("pmessage", []byte(pattern), channel, data)
+					n++
+				}
+			}
+			s.mu.Unlock()
+			c.Write([]byte(":" + strconv.Itoa(n) + "\r\n"))
+		}
+	}
+}
+
+func (s *fakeServer) subscribers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subs)
+}
+
+func TestPubSub(t *testing.T) {
+	s := newFakeServer(t)
+	defer s.l.Close()
+
+	type message struct{ topic, data string }
+	got := make(chan message, 10)
+	p := New(s.l.Addr().String(), Password("secret"), Prefix("app:"))
+	defer p.Close()
+	p.Subscribe(func(topic string, data []byte) {
+		got <- message{topic, string(data)}
+	})
+	for s.subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := p.Publish("news", []byte(`{"a":1}`)); err != nil {
+		t.Fatal("Failed to publish:", err)
+	}
+	want := message{"news", `{"a":1}`}
+	if m := <-got; m != want {
+		t.Fatalf("Bad message: %+v, expected %+v.", m, want)
+	}
+
+	bad := New(s.l.Addr().String(), Password("wrong"))
+	defer bad.Close()
+	if err := bad.Publish("news", nil); err == nil ||
+		err.Error() != "redis: WRONGPASS invalid password" {
+		t.Fatalf("Bad error for wrong password: %v, expected %q.", err,
+			"redis: WRONGPASS invalid password")
+	}
+}
+
+func TestPubSub_hung(t *testing.T) {
+	// A server that never answers.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Failed to listen:", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			nc, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer nc.Close()
+		}
+	}()
+
+	p := New(l.Addr().String(), Timeout(50*time.Millisecond))
+	defer p.Close()
+	start := time.Now()
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() { errs <- p.Publish("news", nil) }()
+	}
+	for range 2 {
+		if err := <-errs; err == nil {
+			t.Fatal("Publishing to a hung server succeeded.")
+		}
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Publishing to a hung server took %v, expected about 50ms.", d)
+	}
+
+	// Close does not wait for messages that are being published.
+	p = New(l.Addr().String(), Timeout(time.Hour))
+	go func() { errs <- p.Publish("news", nil) }()
+	for {
+		p.mu.Lock()
+		n := len(p.busy)
+		p.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	p.Close()
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Fatal("Publishing on a closed PubSub succeeded.")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Publish did not return after Close.")
+	}
+	if err := p.Publish("news", nil); err != errClosed {
+		t.Fatalf("Bad error after Close: %v, expected %v.", err, errClosed)
+	}
+}
+
+// Check that PubSub implements rpk.PubSub.
+var _ rpk.PubSub = &PubSub{}
@@ -0,0 +1,212 @@
+package rpk
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Request headers of signed calls. Clients send a random nonce, the time of the call
+// in milliseconds since the Unix epoch, and a hex HMAC-SHA256 signature, keyed by the
+// shared secret, of the function name, the nonce, the timestamp and the encoded
+// parameter, separated by newlines. Clients with several keys, like servers that call
+// other servers, also send the ID of the key that they sign with.
+const (
+	NonceHeader     = "Rpk-Nonce"
+	TimestampHeader = "Rpk-Timestamp"
+	SignatureHeader = "Rpk-Signature"
+	KeyIDHeader     = "Rpk-Key-Id"
+)
+
+// ReplayProtection makes the handler reject calls of the named functions, or of all
+// functions and transactions if none are named, that are not signed, or that repeat the
+// nonce of an earlier call. Transactions that call named functions must be signed
+// themselves. key returns the secret that the call should be signed with, which may be
+// shared with the client or made for its session. Calls whose timestamps are more than
+// window away from the server's time are rejected, so nonces are only remembered for
+// that long. This protects functions that perform financial or destructive actions from
+// captured requests being sent again. Rejected calls get an
+// ErrPermissionDenied.
+//
+// Nonces are kept in memory, so each server of a deployment rejects the replays that
+// it sees. The Go client signs calls with Client.SignCalls, and the rpk command with
+// its -key-file flag. Panics if the handler has the JSONRPC option, whose calls cannot
+// be signed.
+func ReplayProtection(key func(r *http.Request) ([]byte, error), window time.Duration,
+	names ...string) Option {
+	return func(o *options) {
+		o.replayKey = key
+		o.replayWindow = window
+		if len(names) > 0 {
+			o.replayFuncs = map[string]bool{}
+			for _, name := range names {
+				o.replayFuncs[name] = true
+			}
+		}
+	}
+}
+
+// SigningKeys returns a key function for ReplayProtection, that looks up keys by the
+// IDs that clients send in KeyIDHeader. This lets machine clients, that cannot use
+// cookies or tokens, authenticate with keys of their own:
+//
+//	rpk.ReplayProtection(rpk.SigningKeys(func(id string) ([]byte, error) {
+//	  key, ok := keys[id]
+//	  if !ok {
+//	    return nil, rpk.ErrPermissionDenied
+//	  }
+//	  return key, nil
+//	}), time.Minute)
+//
+// The functions find the ID of the key that signed their call with SignerKeyID.
+func SigningKeys(lookup func(id string) ([]byte, error)) func(r *http.Request) (
+	[]byte, error) {
+	return func(r *http.Request) ([]byte, error) {
+		id := r.Header.Get(KeyIDHeader)
+		if id == "" {
+			return nil, fmt.Errorf("Call has no key ID: %w", ErrPermissionDenied)
+		}
+		return lookup(id)
+	}
+}
+
+// SignerKeyID returns the ID of the key that signed the call with ctx, as sent in
+// KeyIDHeader, for handlers with the ReplayProtection option. Returns an empty string
+// if the call is not signed, or was signed without a key ID.
+func SignerKeyID(ctx context.Context) string {
+	id, _ := ctx.Value(signerKey{}).(string)
+	return id
+}
+
+// signerKey is the context key of the ID of the key that signed a call.
+type signerKey struct{}
+
+// signCall returns the signature of a call with the given key.
+func signCall(key []byte, funcName, nonce, timestamp string, param []byte) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n", funcName, nonce, timestamp)
+	mac.Write(param)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkReplay checks the signature and nonce of a call, if the handler protects its
+// function. Returns the request with the signer's key ID in its context, and a reader of
+// the parameter in place of param, which it reads.
+func (h *Handler) checkReplay(r *http.Request, funcName string, param io.Reader) (
+	*http.Request, io.Reader, error) {
+	// Transactions are the only special function that modifies data.
+	if h.opts.replayKey == nil || reservedNames[funcName] && funcName != "transaction" {
+		return r, param, nil
+	}
+	if h.opts.replayFuncs != nil && !h.opts.replayFuncs[funcName] {
+		if funcName != "transaction" {
+			return r, param, nil
+		}
+		// The calls of a transaction are not checked on their own, so transactions that
+		// call protected functions must be signed, which covers all their calls.
+		data, err := io.ReadAll(param)
+		if err != nil {
+			return nil, nil, newCallError(errBadParam, "Error reading parameter: %v", err)
+		}
+		param = bytes.NewReader(data)
+		if !h.callsProtected(data) {
+			return r, param, nil
+		}
+	}
+	nonce, timestamp := r.Header.Get(NonceHeader), r.Header.Get(TimestampHeader)
+	sig := r.Header.Get(SignatureHeader)
+	if nonce == "" || timestamp == "" || sig == "" {
+		return nil, nil, fmt.Errorf("Call is not signed: %w", ErrPermissionDenied)
+	}
+	ms, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Bad timestamp: %w", ErrPermissionDenied)
+	}
+	t := time.UnixMilli(ms)
+	if d := time.Since(t); d > h.opts.replayWindow || d < -h.opts.replayWindow {
+		return nil, nil, fmt.Errorf("Call is expired: %w", ErrPermissionDenied)
+	}
+	key, err := h.opts.replayKey(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := io.ReadAll(param)
+	if err != nil {
+		return nil, nil, newCallError(errBadParam, "Error reading parameter: %v", err)
+	}
+	want := signCall(key, funcName, nonce, timestamp, data)
+	if !hmac.Equal([]byte(sig), []byte(want)) {
+		return nil, nil, fmt.Errorf("Bad signature: %w", ErrPermissionDenied)
+	}
+	if !h.nonces.use(nonce, h.opts.replayWindow) {
+		return nil, nil, fmt.Errorf("Call is replayed: %w", ErrPermissionDenied)
+	}
+	if id := r.Header.Get(KeyIDHeader); id != "" {
+		r = r.WithContext(context.WithValue(r.Context(), signerKey{}, id))
+	}
+	return r, bytes.NewReader(data), nil
+}
+
+// callsProtected returns whether the encoded calls of a transaction include a call of
+// a function that ReplayProtection names. Returns false if they cannot be decoded, in
+// which case the transaction fails anyway.
+func (h *Handler) callsProtected(param []byte) bool {
+	var calls []txCall
+	if err := newJSONDecoder(bytes.NewReader(param)).Decode(&calls); err != nil {
+		return false
+	}
+	for _, c := range calls {
+		if h.opts.replayFuncs[c.Func] {
+			return true
+		}
+	}
+	return false
+}
+
+// nonceSet holds the nonces of signed calls, until their calls expire.
+type nonceSet struct {
+	mu    sync.Mutex
+	m     map[string]time.Time // Expiration times, by nonce.
+	queue []usedNonce          // By expiration time.
+}
+
+// usedNonce is a nonce in the queue of a nonceSet.
+type usedNonce struct {
+	nonce   string
+	expires time.Time
+}
+
+// use adds a nonce that expires after the given duration, and returns false if it is
+// already there. Calls are accepted for a window on both sides of the server's time, so
+// nonces are kept for twice the window, which keeps them in order of expiration, and
+// expired ones are dropped from the front of the queue.
+func (s *nonceSet) use(nonce string, window time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for len(s.queue) > 0 && now.After(s.queue[0].expires) {
+		n := s.queue[0]
+		s.queue = s.queue[1:]
+		if s.m[n.nonce].Equal(n.expires) {
+			delete(s.m, n.nonce)
+		}
+	}
+	if _, ok := s.m[nonce]; ok {
+		return false
+	}
+	if s.m == nil {
+		s.m = map[string]time.Time{}
+	}
+	expires := now.Add(2 * window)
+	s.m[nonce] = expires
+	s.queue = append(s.queue, usedNonce{nonce, expires})
+	return true
+}
@@ -0,0 +1,154 @@
+package rpk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestReplayProtection(t *testing.T) {
+	key := []byte("secret")
+	h := New(ReplayProtection(func(r *http.Request) ([]byte, error) {
+		return key, nil
+	}, time.Minute, "Pay"))
+	paid := 0
+	h.Register("Pay", func(amount int) int {
+		paid += amount
+		return paid
+	})
+	h.Register("Balance", func() int { return paid })
+
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).UnixMilli(), 10)
+	tests := []struct {
+		funcName, param, nonce, timestamp, sig string
+		result                                 string
+	}{
+		{"Pay", "5", "a", now, signCall(key, "Pay", "a", now, []byte("5")), "5"},
+		{"Pay", "5", "a", now, signCall(key, "Pay", "a", now, []byte("5")),
+			`{"error":"Call is replayed: permission denied","code":"permission_denied"}`},
+		{"Pay", "50", "b", now, signCall(key, "Pay", "b", now, []byte("5")),
+			`{"error":"Bad signature: permission denied","code":"permission_denied"}`},
+		{"Pay", "5", "c", old, signCall(key, "Pay", "c", old, []byte("5")),
+			`{"error":"Call is expired: permission denied","code":"permission_denied"}`},
+		{"Pay", "5", "", "", "", `{"error":"Call is not signed: permission denied",` +
+			`"code":"permission_denied"}`},
+		{"Pay", "2", "d", now, signCall(key, "Pay", "d", now, []byte("2")), "7"},
+		{"Balance", "", "", "", "", "7"},
+	}
+	for _, test := range tests {
+		req := apiRequest(test.funcName, test.param)
+		if test.nonce != "" {
+			req.Header.Set(NonceHeader, test.nonce)
+			req.Header.Set(TimestampHeader, test.timestamp)
+			req.Header.Set(SignatureHeader, test.sig)
+		}
+		if result := serve(h, req); result != test.result {
+			t.Fatalf("Bad result for %s(%s): %q, expected %q.", test.funcName,
+				test.param, result, test.result)
+		}
+	}
+}
+
+func TestReplayProtection_client(t *testing.T) {
+	h := New(ReplayProtection(func(r *http.Request) ([]byte, error) {
+		return []byte("secret"), nil
+	}, time.Minute))
+	h.Register("Double", func(i int) int { return i * 2 })
+	server := httptest.NewServer(h)
+	defer server.Close()
+	c := NewClient(server.URL, nil)
+
+	var result int
+	err := c.Call(context.Background(), "Double", 3, &result)
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("Bad error of an unsigned call: %v, expected %v.", err,
+			ErrPermissionDenied)
+	}
+	c.SignCalls("", []byte("secret"))
+	for range 2 {
+		if err := c.Call(context.Background(), "Double", 3, &result); err != nil {
+			t.Fatalf("Signed call failed: %v.", err)
+		}
+		if result != 6 {
+			t.Fatalf("Bad result: %d, expected 6.", result)
+		}
+	}
+}
+
+func TestReplayProtection_transaction(t *testing.T) {
+	key := []byte("secret")
+	h := New(ReplayProtection(func(r *http.Request) ([]byte, error) {
+		return key, nil
+	}, time.Minute, "Pay"))
+	h.SetTxWrapper(func(ctx context.Context, fn func(context.Context) error) error {
+		return fn(ctx)
+	})
+	paid := 0
+	h.Register("Pay", func(amount int) int {
+		paid += amount
+		return paid
+	})
+	h.Register("Balance", func() int { return paid })
+
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	pay := `[{"func":"Balance"},{"func":"Pay","param":5}]`
+	tests := []struct {
+		param, nonce, sig string
+		result            string
+	}{
+		{pay, "", "", `{"error":"Call is not signed: permission denied",` +
+			`"code":"permission_denied"}`},
+		{pay, "a", signCall(key, "transaction", "a", now, []byte(pay)), "[0,5]"},
+		{pay, "a", signCall(key, "transaction", "a", now, []byte(pay)),
+			`{"error":"Call is replayed: permission denied","code":"permission_denied"}`},
+		{`[{"func":"Balance"}]`, "", "", "[5]"},
+	}
+	for _, test := range tests {
+		req := apiRequest("transaction", test.param)
+		if test.nonce != "" {
+			req.Header.Set(NonceHeader, test.nonce)
+			req.Header.Set(TimestampHeader, now)
+			req.Header.Set(SignatureHeader, test.sig)
+		}
+		if result := serve(h, req); result != test.result {
+			t.Fatalf("Bad result for transaction(%s): %q, expected %q.", test.param,
+				result, test.result)
+		}
+	}
+	if paid != 5 {
+		t.Fatalf("Bad amount paid: %d, expected 5.", paid)
+	}
+}
+
+func TestReplayProtection_jsonrpc(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected a panic for ReplayProtection with JSONRPC.")
+		}
+	}()
+	New(JSONRPC(), ReplayProtection(func(r *http.Request) ([]byte, error) {
+		return []byte("secret"), nil
+	}, time.Minute))
+}
+
+func TestNonceSet(t *testing.T) {
+	var s nonceSet
+	if !s.use("a", time.Millisecond) || s.use("a", time.Millisecond) {
+		t.Fatal("Expected the first use of a nonce to be accepted, and the second not.")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if !s.use("b", time.Millisecond) {
+		t.Fatal("Expected a new nonce to be accepted.")
+	}
+	if len(s.m) != 1 || len(s.queue) != 1 {
+		t.Fatalf("Bad nonces: %v, expected only b.", s.m)
+	}
+	if !s.use("a", time.Millisecond) {
+		t.Fatal("Expected an expired nonce to be accepted again.")
+	}
+}
@@ -1,144 +1,767 @@
 // Package rpk provides simple RPC between Javascript and Go.
 // The package converts objects to RPC handlers that call their exported methods.
 //
-// Server code example
+// # Server code example
 //
 // The server defines the exported RPC interface through the methods of a type.
 //
-//  type myAPI struct{}
+//	type myAPI struct{}
 //
-//  func (myAPI) Half(i int) int {
-//    return i / 2
-//  }
+//	func (myAPI) Half(i int) int {
+//	  return i / 2
+//	}
 //
-//  func main() {
-//    http.HandleFunc("/api/rpk.js", rpk.HandleJS)  // Serves client code.
-//    handler, _ := rpk.HandlerFunc(myAPI{})
-//    http.HandleFunc("/api", handler)
-//    http.ListenAndServe(":8080", nil)
-//  }
+//	func main() {
+//	  http.HandleFunc("/api/rpk.js", rpk.HandleJS)  // Serves client code.
+//	  handler, _ := rpk.HandlerFunc(myAPI{})
+//	  http.HandleFunc("/api", handler)
+//	  http.ListenAndServe(":8080", nil)
+//	}
 //
-// Client code example
+// Standalone functions and closures can also be registered explicitly, under any name,
+// alongside the methods of objects.
+//
+//	h := rpk.New()
+//	h.Register("Half", func(ctx context.Context, i int) int {
+//	  return i / 2
+//	})
+//	h.RegisterObject(myAPI{})
+//	http.Handle("/api", h)
+//
+// # Client code example
 //
 // The client needs to fetch the complementary Javascript code.
 //
-//  <script type="text/javascript" src="/api/rpk.js"></script>
-//  <script type="text/javascript">
+//	<script type="text/javascript" src="/api/rpk.js"></script>
+//	<script type="text/javascript">
 //
-//  let api = rpk("/api")
-//  api.onReady(function(error) {...});
+//	let api = rpk("/api")
+//	api.onReady(function(error) {...});
 //
-//  // ... After ready ...
-//  api.Half(10, function(result, error) {
-//    if (error) {
-//      console.error("error=" + error);
-//    } else {
-//      console.log("10/2=" + result);
-//    }
-//  });
+//	// ... After ready ...
+//	api.Half(10, function(result, error) {
+//	  if (error) {
+//	    console.error("error=" + error);
+//	  } else {
+//	    console.log("10/2=" + result);
+//	  }
+//	});
 //
-//  </script>
+//	</script>
 //
-// Restrictions on RPC methods
+// # Restrictions on RPC methods
 //
 // The methods of an RPC object must:
-// (1) have at most 1 input argument, which should be JSON encodable
+// (1) have at most 1 input argument, which should be JSON encodable, optionally preceded
+// by a context.Context
 // (2) have at most 2 outputs: 1 optional value of any JSON encodable type, and an optional
 // error. If using 2 outputs, the error should come second.
 //
+// So the supported shapes are, with or without an input argument:
+//
+//	func (a API) F([ctx context.Context,] [param T])
+//	func (a API) F([ctx context.Context,] [param T]) V
+//	func (a API) F([ctx context.Context,] [param T]) error
+//	func (a API) F([ctx context.Context,] [param T]) (V, error)
+//
+// The context is that of the HTTP request. Functions may also take parameters that
+// are injected by type, from providers added with Handler.Provide.
+//
+// Large outputs may be streamed, by returning an io.Reader of newline-delimited JSON
+// values, or an iterator of type iter.Seq[V] or iter.Seq2[V, error]. The handler writes
+// the values as they come, compressed with gzip if the client accepts it. Clients may
+// also get slices as newline-delimited JSON, one element per line, by accepting
+// NDJSONType. Streamed outputs are not supported with the JSONRPC and Async options, or
+// with idempotency keys.
+//
 // Unexported methods are ignored and do not have any restriction.
 //
-// Javascript API
+// # Javascript API
 //
 // The Javascript code exposes a single function.
-//  rpk(/*string*/ url)
+//
+//	rpk(/*string or array*/ url, /*optional object*/ options)
+//
 // Returns an RPK object, which will have the exported methods of the Go object that
-// handles that URL.
+// handles that URL. The URL may be relative to the page, like "api" or "../api", or of
+// another origin, like "https://api.example.com:8443/v1/rpc". Query parameters in it,
+// like "/api?tenant=acme", are sent with every call. On https pages, http URLs are
+// called over https, as browsers block calls to them.
+//
+// The URL may also be an array of the URLs of several endpoints of the handler, like
+// those of several regions, for failover. Calls go to the first endpoint, until one
+// fails to reach it, or a proxy answers it with 502, 503 or 504, as in rolling deploys.
+// Calls then go to the next endpoint, and the health of the first one is checked
+// periodically, until calls can go to it again. Calls that got 503 were not passed on by
+// the proxy, and are sent to the next endpoint, until each was tried once. Other failed
+// calls may have run, so they fail, unless they have an Idempotency-Key header, like
+// one set by an interceptor, in which case they are sent again like those that got 503.
+// Endpoints should then share an IdempotencyStore. Available options:
+//
+//	pathRouting
+//
+// Boolean. Call functions at url/FuncName, for handlers created with the PathRouting
+// option. The URL may end with a slash or not.
+//
+//	concurrency
+//
+// Number. The maximal number of calls in flight at once. Other calls wait, and are sent
+// as calls end, so pages that make many calls at once do not take all of the browser's
+// connections to the server. Event streams are not counted.
+//
+//	priorities
+//
+// Object. Maps names of functions to numbers, 0 by default. With the concurrency option,
+// waiting calls of functions with higher numbers are sent first, like those that users
+// wait for.
+//
+//	failback
+//
+// Number. Milliseconds between checks of the health of the first endpoint, while calls
+// go to another one (default 30000).
+//
+//	jsonrpc
+//
+// Boolean. Speak JSON-RPC 2.0, for handlers created with the JSONRPC option.
+//
+//	get
+//
+// Array of strings. Names of functions to call with GET requests, for functions marked
+// with the Safe option.
+//
+//	timeout
+//
+// Number. Milliseconds to wait for each call before failing it. The timeout is sent to
+// the server, which applies it to the call's context.
+//
+//	dates
+//
+// Boolean. Convert times in results to Date objects. Times should be in RFC 3339, as
+// the handler encodes them by default. Date objects in parameters are encoded in RFC
+// 3339 regardless.
+//
+//	cache
+//
+// Object. Maps names of functions to milliseconds for which their results are cached,
+// by parameter. Calls with a cached result call back without calling the server. Use
+// this for read-only functions that are called often. Errors are not cached.
+//
+//	dedupe
+//
+// Boolean. Make identical calls, to the same function with the same parameter, share a
+// single request while one is in progress, and call back all of their callbacks with
+// its result. Useful when several components of a page fetch the same data at once.
+//
+//	offline
+//
+// Array of strings. Names of functions, typically ones that change data, whose calls are
+// queued when they fail to reach the server, and replayed in order when it is reachable
+// again: when the browser goes online, when the page is reloaded, or on replay. The
+// callbacks of queued calls get an error with a true queued property. The queue is
+// kept in local storage, where available.
+//
+//	onReplay
+//
+// Function(name, param, data, error). Called with the result of each replayed call, for
+// example to handle conflicts with changes that others made in the meantime.
+//
+//	bigints
+//
+// Boolean. Convert the int64 and uint64 values in results, which handlers created with
+// the Int64AsString option encode as strings, to BigInt values, where supported. Other
+// strings are left as they are, and so are values in fields of interface types, in
+// recursive types below their first level, and in broadcast messages, transactions and
+// job results. BigInt values in parameters are encoded as strings regardless.
+//
+//	bootstrap
+//
+// String. The ID of the element that BootstrapScript or the handler's Bootstrap method
+// returns. If the page has it, the object takes its configuration from it: the URL, if
+// url is empty, a CSRF token, which is sent with every call, feature flags and the
+// deployment's version. If it has the handler's functions, the object is ready as soon
+// as it is created, without calling the handler for them.
+//
+//	rpkObject.ready
 //
-//  rpkObject.ready
 // Boolean. Indicates whether this RPK object is ready to be called.
 //
-//  rpkObject.onReady( callback(error) )
+//	rpkObject.flags
+//	rpkObject.version
+//
+// The feature flags, an object which is empty if there are none, and the version of the
+// deployment, from the element of the bootstrap option.
+//
+//	rpkObject.protocol
+//	rpkObject.features
+//
+// The protocol version and the list of features that the handler and the client both
+// support, as described by ProtocolVersion and FeaturesHeader, once ready.
+//
+//	rpkObject.onReady( callback(error) )
+//
 // Adds a listener that will be called when myRpkObject finishes initializing.
 // If successful, error will be null. Else, error will be a string describing
 // the problem. Several listeners can be added. They will be called by order of
 // adding.
 //
-//  rpkObject.FuncName(param, callback(data, error))
+//	rpkObject.wait(job, callback(data, error), interval, onProgress(percent, message))
+//
+// Waits for a background job of a function marked with the Async option, polling its
+// status every interval milliseconds (default 1000). Job is the ID that the function
+// returned. Callback is called like that of a regular function, with the job's result.
+// The optional onProgress is called with each progress that the job reports with
+// Progress.
+//
+//	rpkObject.transaction(calls, callback(data, error))
+//
+// Calls a group of functions in a transaction of the handler's SetTxWrapper, so they
+// succeed or fail together. Calls is an array of objects with func, the name of a
+// function, and param, which is omitted if the function takes no input. On success,
+// data is an array of the calls' results.
+//
+//	rpkObject.upload(name, file, callback(id, error), uploadOptions)
+//
+// Uploads a File or Blob in chunks to a handler with the Uploads option, then calls
+// back with the ID of the upload, to pass to the named function, which reads it with
+// OpenUpload. The chunks are admitted like calls of that function.
+// Calls that fail to reach the server are retried, waiting longer after each, and the
+// upload resumes from the last chunk that the server received. Returns an object with
+// pause() and resume() methods, and the upload's id once it started. Resume also
+// restarts an upload that failed. Not supported with the jsonrpc option. The optional
+// uploadOptions are:
+//
+//	chunkSize: Bytes in each chunk (default 1048576).
+//	onProgress: Function(sent, total), called after each chunk.
+//	retries: Retries of chunks that fail to reach the server (default 5).
+//	id: ID of an earlier upload of the same file to resume, like after a page reload.
+//
+//	rpkObject.replay()
+//
+// Replays the calls queued by the offline option, until one fails to reach the server.
+//
+//	rpkObject.subscribe(topic, callback(payload))
+//
+// Calls callback with the payload of each message that the handler broadcasts to topic
+// with Broadcast. Returns a function that cancels the subscription. All subscriptions
+// share a single stream of server-sent events.
+//
+//	rpkObject.use(interceptor(call, next))
+//
+// Adds an interceptor of calls, which runs before each request, in the order that
+// interceptors were added. Call has the name of the function, its param, an object of
+// request headers, and the callback(data, error, status) of the call, where status is
+// the status of the response, or 0 if there is none. The interceptor may change them,
+// for example to add an authorization header, or wrap the callback to inspect
+// responses, and then passes the call on with next(call). It may also call the
+// callback itself instead, or call next again, to retry.
+//
+//	api.use(function(call, next) {
+//	  call.headers["Authorization"] = "Bearer " + token;
+//	  next(call);
+//	});
+//
+//	rpkObject.setAuth({getToken, refreshToken})
+//
+// Attaches a token to each call, in a bearer Authorization header, from getToken(),
+// which returns the token or a promise of it. When the server rejects a token with
+// status 401, refreshToken(), which may return a promise, gets a new token for getToken
+// to return, and the call is retried once. Calls that fail meanwhile share a single
+// refresh, and new calls wait for it. Calls fail with the original error if the
+// refresh fails.
+//
+//	rpkObject.invalidate(name)
+//
+// Drops the cached results of the named function, for example after calling a function
+// that changes them. Drops all cached results if name is omitted.
+//
+//	rpkObject.FuncName.pages(param, pageOptions)
+//
+// Returns an async iterator over the pages of a function that returns a Page, starting
+// with param, which holds the function's PageRequest fields, except cursor, and its
+// other fields. Iteration stops at the last page, or with an exception on error. If the
+// optional pageOptions has a true prefetch, the next page is fetched in the background
+// as soon as a page is returned, so infinite-scroll pages show it without waiting. An
+// error of fetching it is thrown when it is asked for.
+//
+//	for await (let page of api.ListUsers.pages({limit: 50}, {prefetch: true})) {
+//	  console.log(page.items);
+//	}
+//
+//	rpkObject.FuncName.stream(param, onItem(item), callback(data, error))
+//
+// Calls a Go method that streams its output, or returns a list, and calls onItem with
+// each of its values as it arrives, then calls back with null data. Param should be omitted if the method
+// expects no input. Regular calls of such methods call back with an array of all the
+// values.
+//
+//	rpkObject.FuncName(param, callback(data, error), callOptions)
+//
 // Calls a Go method.
 // Param should be of the type expected by the Go method. If the Go method expects
 // no input, then param should be omitted. On success, error will be null and data
 // will contain the output (if any). On error, error will be a string describing
-// the problem.
+// the problem. Call options are optional, and may have ifMatch, the version of the
+// resource that the call expects, as returned by ExpectedVersion, and validateOnly, which
+// makes the call only validate param, as with ValidateOnlyHeader. If the function
+// returns a ConflictError, error will have a true conflict property and the current
+// version in its version property. If it returns an RPCError, error will have its code
+// and data in its code and data properties. Calling a function that is marked Deprecated logs a
+// warning to the console, once per function. Binary data in parameters, like
+// ArrayBuffer and Uint8Array values, is encoded in base64, as Go decodes []byte.
+//
+//	rpk.toBase64(data)
+//	rpk.fromBase64(text)
+//
+// Convert binary data to base64 text and back to a Uint8Array, for example for []byte
+// fields in results.
 package rpk
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"reflect"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 )
 
-// TODO(amit): Test with bad types.
 // TODO(amit): Consider a better name for HandleJS.
 
 // funcs represents a set of callable functions, that communicates in JSON.
 // Maps from function name to the reflection of that function.
-type funcs map[string]reflect.Value
+type funcs map[string]*methodInfo
+
+// methodInfo holds the reflection of a function, along with metadata that is derived
+// once at registration instead of on every call.
+type methodInfo struct {
+	value  reflect.Value
+	recv   []reflect.Value // Receiver arguments, preallocated for calling value.
+	hasCtx bool            // Whether the function takes a context first.
+	in     reflect.Type    // Input type, nil if the function takes no input.
+	inPtr  bool            // Whether the input is a pointer.
+	numOut int             // Number of outputs.
+	valOut int             // Position of the value output, -1 if none.
+	errOut int             // Position of the error output, -1 if none.
+	hasIn  bool            // Whether the function takes an input.
+	hasOut bool            // Whether the function has a value output.
+	stream bool            // Whether the value output is streamed rather than encoded.
+	raw    bool            // Whether the input is a Raw, which is not decoded.
+	enums  bool            // Whether the input may contain values of enum types.
+	inU    *union          // Union of the input type, nil if none.
+	outU   *union          // Union of the output type, nil if none.
+	inC    *codecSet       // Codecs for the input, nil if it has no values with codecs.
+	outC   *codecSet       // Codecs for the output, nil if it has no values with codecs.
+	source string          // What the function was registered from, for error messages.
+
+	// Parameters after the context. Nil for the decoded input, or the provider of an
+	// injected parameter.
+	args []*provider
+
+	// Statically dispatched function, replaces value if not nil.
+	static func(ctx context.Context, dec Decoder) (interface{}, error)
+}
+
+// newMethodInfo returns the metadata of function fn, whose signature typ should already
+// have passed checkInputs and checkOutputs. For methods, fn is the method's function
+// rather than a bound method value, which saves allocating the receiver on every call.
+// Then recv holds the receiver and typ excludes it.
+func newMethodInfo(typ reflect.Type, fn reflect.Value, recv []reflect.Value,
+	o *options) *methodInfo {
+	m := &methodInfo{value: fn, recv: recv, numOut: typ.NumOut(), valOut: -1, errOut: -1}
+	first := 0
+	if typ.NumIn() > 0 && isContext(typ.In(0)) {
+		m.hasCtx = true
+		first = 1
+	}
+	for i := first; i < typ.NumIn(); i++ {
+		if p := o.providers[typ.In(i)]; p != nil {
+			m.args = append(m.args, p)
+			continue
+		}
+		m.args = append(m.args, nil)
+		m.in = typ.In(i)
+		m.inPtr = m.in.Kind() == reflect.Ptr
+		m.hasIn = true
+		m.raw = m.in == rawType || m.inPtr && m.in.Elem() == rawType
+	}
+	for i := 0; i < m.numOut; i++ {
+		if i == m.numOut-1 && isError(typ.Out(i)) {
+			m.errOut = i
+		} else {
+			m.valOut = i
+			m.hasOut = true
+		}
+	}
+	m.configure(o)
+	return m
+}
+
+// configure sets how the function's input and output are decoded and encoded, by the
+// unions and codecs of the options.
+func (m *methodInfo) configure(o *options) {
+	if m.hasIn {
+		in := m.in
+		if m.inPtr {
+			in = in.Elem()
+		}
+		m.inU = o.unions[in]
+		m.inC = o.codecs.forType(in)
+		m.enums = hasEnums(in)
+	}
+	if m.hasOut {
+		out := m.value.Type().Out(m.valOut)
+		if m.stream = isStream(out); m.stream {
+			return
+		}
+		m.outU = o.unions[out]
+		m.outC = o.codecs.forType(out)
+	}
+}
+
+// invoke calls the function with ctx if it takes one, decoding its input using dec if it
+// takes one. Returns the function's value output (nil if none) and error output.
+func (m *methodInfo) invoke(ctx context.Context, dec Decoder) (interface{}, error) {
+	if m.static != nil {
+		return m.static(ctx, dec)
+	}
+
+	in := m.recv
+	if m.hasCtx || len(m.args) > 0 {
+		args := argsPool.Get().(*[]reflect.Value)
+		*args = append((*args)[:0], m.recv...)
+		if m.hasCtx {
+			*args = append(*args, reflect.ValueOf(ctx))
+		}
+		for _, p := range m.args {
+			if p != nil {
+				v, err := p.provide(ctx)
+				if err != nil {
+					argsPool.Put(args)
+					return nil, err
+				}
+				*args = append(*args, v)
+				continue
+			}
+
+			// Pointers are decoded into through a pointer to them, so that null
+			// gives nil.
+			v := reflect.New(m.in)
+			if err := dec.Decode(v.Interface()); err != nil {
+				argsPool.Put(args)
+				return nil, err
+			}
+			*args = append(*args, v.Elem())
+		}
+		in = *args
+		defer func() {
+			for i := range *args {
+				(*args)[i] = reflect.Value{}
+			}
+			argsPool.Put(args)
+		}()
+	}
+
+	out := m.value.Call(in)
+
+	var val interface{}
+	var err error
+	if m.valOut != -1 {
+		val = out[m.valOut].Interface()
+	}
+	if m.errOut != -1 && !out[m.errOut].IsNil() {
+		err = out[m.errOut].Interface().(error)
+	}
+	return val, err
+}
 
 // newFuncs creates a funcs instance from the methods of the given interface.
-// Returns an error if a method does not match the requirements (see package description).
-func newFuncs(a interface{}) (funcs, error) {
+// Returns a MethodErrors listing every method that does not match the requirements (see
+// package description), unless the options say to skip them.
+func newFuncs(a interface{}, o *options) (funcs, error) {
+	// Generated dispatch tables replace reflection.
+	if d, ok := a.(Dispatcher); ok && !o.nested {
+		return dispatchFuncs(d, o, ""), nil
+	}
+	if o.nested {
+		return nestedFuncs(reflect.ValueOf(a), o, "", map[reflect.Type]bool{})
+	}
+	return methodFuncs(reflect.ValueOf(a), o, "")
+}
+
+// dispatchFuncs creates a funcs instance from the dispatch table of d, with names that
+// start with prefix, filtered like methods are. Functions whose values the options
+// convert, like with unions, codecs and enums, or that take injected parameters or
+// stream their outputs, are called by reflection, since their generated code does not
+// do it.
+func dispatchFuncs(d Dispatcher, o *options, prefix string) funcs {
+	value := reflect.ValueOf(d)
+	result := funcs{}
+	for method, f := range d.RPKDispatch() {
+		name := prefix + method
+		if err := o.filter(value.Type(), method, name); err != nil {
+			o.skip(&MethodError{name, err})
+			continue
+		}
+		mt, ok := value.Type().MethodByName(method)
+		if !ok {
+			result[name] = &methodInfo{valOut: -1, errOut: -1, hasIn: f.HasInput,
+				hasOut: f.HasOutput, static: f.Call, source: fmt.Sprint(value.Type())}
+			continue
+		}
+		m := newMethodInfo(value.MethodByName(method).Type(), mt.Func,
+			[]reflect.Value{value}, o)
+		m.source = fmt.Sprint(value.Type())
+		if !m.converted() {
+			m.static = f.Call
+		}
+		result[name] = m
+	}
+	return result
+}
+
+// converted returns whether the function's values are converted by the options, or it
+// takes injected parameters or streams its output.
+func (m *methodInfo) converted() bool {
+	return m.inU != nil || m.outU != nil || m.inC != nil || m.outC != nil || m.enums ||
+		m.stream || slices.ContainsFunc(m.args, func(p *provider) bool { return p != nil })
+}
+
+// nestedFuncs creates a funcs instance from the exported methods of the given value,
+// and from those of its exported struct fields, recursively. Methods of fields are
+// named with the field names, like "Users.Create". Values that are Dispatchers are
+// called through their dispatch tables. Seen holds the types of the values that contain
+// value, to stop at recursive types.
+func nestedFuncs(value reflect.Value, o *options, prefix string,
+	seen map[reflect.Type]bool) (funcs, error) {
+	var result funcs
+	var err error
+	if d, ok := value.Interface().(Dispatcher); ok {
+		result = dispatchFuncs(d, o, prefix)
+	} else {
+		result, err = methodFuncs(value, o, prefix)
+	}
+	errs, _ := err.(MethodErrors)
+	if err != nil && errs == nil {
+		return nil, err
+	}
+
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return result, nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct || seen[value.Type()] {
+		return result, nil
+	}
+	seen[value.Type()] = true
+	defer delete(seen, value.Type())
+
+	for i := 0; i < value.NumField(); i++ {
+		f := value.Type().Field(i)
+		// Methods of embedded fields are already promoted. Fields with custom
+		// marshaling, like time.Time, hold data rather than services.
+		if f.PkgPath != "" || f.Anonymous || f.Tag.Get("rpk") == "-" ||
+			isMarshaler(f.Type) {
+			continue
+		}
+		fv := value.Field(i)
+		switch fv.Kind() {
+		case reflect.Ptr, reflect.Interface:
+			if fv.IsNil() || seen[fv.Elem().Type()] {
+				continue
+			}
+		case reflect.Struct:
+			if seen[fv.Type()] {
+				continue
+			}
+			if fv.CanAddr() {
+				fv = fv.Addr()
+			}
+		}
+		sub, err := nestedFuncs(fv, o, prefix+f.Name+".", seen)
+		if err != nil {
+			errs = append(errs, err.(MethodErrors)...)
+			continue
+		}
+		if result == nil {
+			continue // Errors were found, so nothing is returned.
+		}
+		for name, m := range sub {
+			result[name] = m
+		}
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+	return result, nil
+}
+
+// methodFuncs creates a funcs instance from the exported methods of the given value,
+// with names that start with prefix. If value is of an interface type, only the
+// interface's methods are taken.
+func methodFuncs(value reflect.Value, o *options, prefix string) (funcs, error) {
 	result := funcs{}
-	value := reflect.ValueOf(a)
 	n := value.NumMethod()
+	var errs MethodErrors
 
 	// Go over functions.
 	for i := 0; i < n; i++ {
-		method := value.Method(i)
-		name := reflect.TypeOf(a).Method(i).Name
-		typ := method.Type()
+		method := value.Type().Method(i)
+		name := prefix + method.Name
+		typ := value.Method(i).Type()
 
 		// Check if exported.
-		if name[:1] == strings.ToLower(name[:1]) {
+		if method.Name[:1] == strings.ToLower(method.Name[:1]) {
+			continue
+		}
+		if err := o.filter(value.Type(), method.Name, name); err != nil {
+			o.skip(&MethodError{name, err})
 			continue
 		}
 
 		// Check that function matches the requirements.
-		if err := checkInputs(typ); err != nil {
-			return nil, fmt.Errorf("Function '%s': %v", name, err)
+		err := checkInputs(typ, o)
+		if err == nil {
+			err = checkOutputs(typ)
 		}
-		if err := checkOutputs(typ); err != nil {
-			return nil, fmt.Errorf("Function '%s': %v", name, err)
+		if err != nil {
+			merr := &MethodError{name, err}
+			if o.skipInvalid {
+				o.skipInvalidMethod(merr)
+			} else {
+				errs = append(errs, merr)
+			}
+			continue
+		}
+
+		// Passed. Register function. Interface methods have no function to call
+		// directly, so they are called through the bound method value.
+		if value.Kind() == reflect.Interface {
+			result[name] = newMethodInfo(typ, value.Method(i), nil, o)
+		} else {
+			result[name] = newMethodInfo(typ, method.Func, []reflect.Value{value}, o)
 		}
+		result[name].source = fmt.Sprint(value.Type())
+	}
 
-		// Passed. Register function.
-		result[name] = method
+	// Methods that embedded fields collide on are missing from the method set.
+	if value.Kind() != reflect.Interface {
+		for _, merr := range o.ambiguousMethods(value.Type(), prefix) {
+			if o.skipInvalid {
+				o.skipInvalidMethod(merr)
+			} else {
+				errs = append(errs, merr)
+			}
+		}
 	}
 
+	if errs != nil {
+		return nil, errs
+	}
 	return result, nil
 }
 
-// checkInputs checks if a function's input argument match the requirements of RPK.
-func checkInputs(f reflect.Type) error {
-	// Must have at most 1 input argument.
-	if f.NumIn() > 1 {
-		return fmt.Errorf("Must have 0 or 1 inputs. It has %d. %v %v",
-			f.NumIn(), f.In(0), f.In(1))
+// names returns the sorted names of the functions.
+func (fs funcs) names() []string {
+	names := make([]string, 0, len(fs))
+	for name := range fs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// A MethodError describes a method that does not match the requirements of RPK.
+type MethodError struct {
+	Method string // Name of the offending method.
+	Err    error  // What is wrong with it.
+}
+
+func (e *MethodError) Error() string {
+	return fmt.Sprintf("Function '%s': %v", e.Method, e.Err)
+}
+
+func (e *MethodError) Unwrap() error {
+	return e.Err
+}
+
+// MethodErrors lists all the methods of a type that do not match the requirements of
+// RPK, so they can be fixed in one go.
+type MethodErrors []*MethodError
+
+func (e MethodErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// checkInputs checks if a function's input arguments match the requirements of RPK.
+// Inputs of types that the options have providers for are injected, and do not count.
+// Positions in error messages count from 1, not including the receiver.
+func checkInputs(f reflect.Type, o *options) error {
+	if f.IsVariadic() {
+		return fmt.Errorf("variadic functions are not supported")
+	}
+	// May start with a context.
+	first := 0
+	if f.NumIn() > 0 && isContext(f.In(0)) {
+		first = 1
+	}
+	var ins []int // Positions of inputs that are not injected.
+	for i := first; i < f.NumIn(); i++ {
+		if o.providers[f.In(i)] == nil {
+			ins = append(ins, i)
+		}
+	}
+	// Must have at most 1 input argument besides the context.
+	if len(ins) > 1 {
+		return fmt.Errorf("input %d (%v): expected at most 1 input after an optional "+
+			"context.Context, found %d", ins[1]+1, f.In(ins[1]), len(ins))
+	}
+	if len(ins) == 1 {
+		in := f.In(ins[0])
+		if isContext(in) {
+			return fmt.Errorf("input %d (%v): context.Context should come first",
+				ins[0]+1, in)
+		}
+		if !isJSONType(in) {
+			return fmt.Errorf("input %d (%v): type cannot be decoded from JSON",
+				ins[0]+1, in)
+		}
 	}
 	return nil
 }
 
 // checkOutputs checks if a function's outputs match the requirements of RPK.
+// Positions in error messages count from 1.
 func checkOutputs(f reflect.Type) error {
 	// Must have at most 2 outputs.
 	if f.NumOut() > 2 {
-		return fmt.Errorf("More than 2 outputs: %d", f.NumOut())
+		return fmt.Errorf("expected at most 2 outputs (value, error), found %d",
+			f.NumOut())
 	}
 	// If 2 outputs, then the second must be an error.
 	if f.NumOut() == 2 && !isError(f.Out(1)) {
-		return fmt.Errorf("second output should be an error, but found %v", f.Out(1))
+		return fmt.Errorf("output 2 (%v): should be an error", f.Out(1))
+	}
+	if f.NumOut() == 2 && isError(f.Out(0)) {
+		return fmt.Errorf("output 1 (%v): should be a value, the error should come last",
+			f.Out(0))
+	}
+	// The value must be encodable, or streamed.
+	if f.NumOut() > 0 && !isError(f.Out(0)) && !isStream(f.Out(0)) &&
+		!isEncodable(f.Out(0)) {
+		return fmt.Errorf("output 1 (%v): type cannot be encoded to JSON", f.Out(0))
 	}
 	return nil
 }
@@ -149,106 +772,330 @@ func isError(t reflect.Type) bool {
 	return t == reflect.TypeOf(perr).Elem()
 }
 
-// call calls a function with the given JSON encoded parameter.
-// Functions with no parameters should get an empty string.
-// On error, returns a JSON object with an error field.
-func (fs funcs) call(funcName string, param string) string {
+// isContext checks if the given type is context.Context.
+func isContext(t reflect.Type) bool {
+	var pctx *context.Context
+	return t == reflect.TypeOf(pctx).Elem()
+}
+
+// isJSONType checks if the given type can be encoded to and decoded from JSON. Types
+// with custom marshaling are assumed to handle it.
+func isJSONType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128,
+		reflect.UnsafePointer:
+		return false
+	}
+	return true
+}
+
+// call calls a function with ctx and the encoded parameter read from param, decoded by a
+// decoder that newDecoder creates. Functions with no parameters should get an empty
+// reader. Writes the JSON encoded result to w. On error, writes a JSON object with an
+// error field.
+func (fs funcs) call(ctx context.Context, w io.Writer, funcName string, param io.Reader,
+	newDecoder DecoderFunc) {
+	res := fs.run(ctx, funcName, param, newDecoder)
+	res.write(w, nil)
+}
+
+// callResult is the outcome of calling a function.
+type callResult struct {
+	param  interface{} // Pointer to the decoded input, nil if none.
+	val    interface{} // Value output, nil if none.
+	hasOut bool        // Whether the function has a value output.
+	stream bool        // Whether the value output is streamed rather than encoded.
+	outU   *union      // Union of the value output's type, nil if none.
+	outC   *codecSet   // Codecs for the value output, nil if none apply.
+
+	// Whether the parameter was only validated, and the function was not called.
+	validated bool
+
+	// Fields of the value output to encode, nil for all.
+	fields fieldMask
+
+	// Error returned by the function, or a *callError for errors of the call itself.
+	err error
+}
+
+// write writes the result to w, encoded by enc. On error, writes a JSON object with an
+// error field.
+func (res *callResult) write(w io.Writer, enc *encodeOptions) {
+	if res.err != nil {
+		writeCallError(w, res.err)
+		return
+	}
+	if res.hasOut && !enc.omits(res.val) {
+		cw := &countWriter{w: w}
+		err := enc.newEncoder(cw).Encode(res.encoded())
+		// The encoder writes nothing if encoding fails, so an error can still be
+		// reported. Once writing had started, the client is likely gone.
+		if err != nil && cw.n == 0 {
+			writeError(w, "Error encoding result: %v", err)
+		}
+	}
+}
+
+// encoded returns the value output as it should be encoded.
+func (res *callResult) encoded() interface{} {
+	v := res.val
+	if res.outU != nil {
+		v = res.outU.wrap(res.val)
+	} else if res.outC != nil {
+		v = codecValue{res.val, res.outC}
+	}
+	if res.fields != nil {
+		return res.fields.pruned(v)
+	}
+	return v
+}
+
+// run calls a function like call does, but returns its outcome instead of writing it.
+func (fs funcs) run(ctx context.Context, funcName string, param io.Reader,
+	newDecoder DecoderFunc) callResult {
 	// Get function.
 	f, ok := fs[funcName]
 	if !ok {
-		return jsonError("No such function '%s'.", funcName)
+		return callResult{err: newCallError(errNoSuchFunc,
+			"No such function '%s'.", funcName)}
 	}
 
-	typ := f.Type()
-	var out []reflect.Value
+	// Check if a parameter was given, without consuming it.
+	br := readerPool.Get().(*bufio.Reader)
+	br.Reset(param)
+	defer func() {
+		br.Reset(nil)
+		readerPool.Put(br)
+	}()
+	_, err := br.Peek(1)
+	if err != nil && err != io.EOF {
+		var cerr *callError
+		if errors.As(err, &cerr) {
+			return callResult{err: cerr}
+		}
+		return callResult{err: newCallError(errBadParam, "Error reading parameter: %v", err)}
+	}
+	hasParam := err == nil
 
-	// If function has an input argument.
-	if typ.NumIn() == 1 {
-		// Extract input parameter.
-		inType := typ.In(0)
-		in := reflect.New(inType)
-		err := json.Unmarshal([]byte(param), in.Interface())
-		if err != nil {
-			return jsonError("Error decoding JSON: %v", err)
+	var dec *recordingDecoder
+	if f.raw {
+		dec = &recordingDecoder{Decoder: rawDecoder{br}}
+	} else if f.hasIn {
+		d := newDecoder(br)
+		dec = &recordingDecoder{Decoder: d, end: d}
+		if f.inU != nil {
+			dec.Decoder = unionDecoder{d, f.inU}
+		} else if f.inC != nil {
+			dec.Decoder = codecDecoder{d, f.inC}
 		}
+	} else if hasParam {
+		// Argument not expected.
+		return callResult{err: newCallError(errBadParam,
+			"Function '%s' does not accept parameters.", funcName)}
+	}
 
-		// Call method.
-		out = f.Call([]reflect.Value{in.Elem()})
+	if dec != nil && f.enums {
+		dec.Decoder = enumDecoder{dec.Decoder}
+	}
 
-	} else {
-		// Argument not expected.
-		if param != "" {
-			return jsonError("Function '%s' does not accept parameters.", funcName)
+	validate := ValidateOnly(ctx)
+	if validate {
+		if dec == nil {
+			return callResult{validated: true}
 		}
-		out = f.Call(nil)
+		dec.validateOnly = true
 	}
 
-	// Sort out outputs.
-	var outVal, outErr reflect.Value
-	if len(out) == 2 {
-		outVal, outErr = out[0], out[1]
-	} else if len(out) == 1 {
-		if isError(out[0].Type()) {
-			outErr = out[0]
-		} else {
-			outVal = out[0]
+	// Call method.
+	var in Decoder
+	if dec != nil {
+		in = dec
+	}
+	val, err := f.invoke(ctx, in)
+	if dec != nil && dec.err != nil {
+		var cerr *callError
+		if errors.As(dec.err, &cerr) {
+			return callResult{err: cerr}
 		}
+		return callResult{err: newCallError(errBadParam, "Error decoding JSON: %s",
+			decodeErrorMessage(dec.err, dec.v))}
+	}
+	if dec != nil && dec.invalid != nil {
+		return callResult{param: dec.v, err: dec.invalid}
+	}
+	if validate && err == errValidated {
+		return callResult{param: dec.v, validated: true}
+	}
+	res := callResult{val: val, hasOut: f.hasOut, stream: f.stream, outU: f.outU,
+		outC: f.outC, err: err}
+	if dec != nil {
+		res.param = dec.v
 	}
+	return res
+}
+
+// errKind classifies errors of calls.
+type errKind int
+
+const (
+	errNoSuchFunc errKind = iota // The called function does not exist.
+	errBadParam                  // The parameter could not be read or decoded.
+	errHidden                    // The details of the error are hidden from the client.
+	errBusy                      // The worker pool's queue is full.
+)
+
+// callError is an error of a call itself, rather than one returned by the function.
+type callError struct {
+	kind errKind
+	msg  string
+	args []interface{} // Of the message, for translations.
+}
+
+// newCallError returns a callError with a message that evaluates to the given format.
+func newCallError(kind errKind, s string, a ...interface{}) *callError {
+	return &callError{kind, fmt.Sprintf(s, a...), a}
+}
 
-	if outErr.IsValid() && !outErr.IsNil() {
-		return jsonError("%v", outErr.Interface())
+func (e *callError) Error() string {
+	return e.msg
+}
+
+// readerPool holds buffered readers for peeking at parameters, to save their allocation
+// on every call.
+var readerPool = sync.Pool{
+	New: func() interface{} {
+		return bufio.NewReader(nil)
+	},
+}
+
+// argsPool holds argument slices for calling functions with an input.
+var argsPool = sync.Pool{
+	New: func() interface{} {
+		s := make([]reflect.Value, 0, 2)
+		return &s
+	},
+}
+
+// recordingDecoder remembers the error of its underlying decoder, so that decoding
+// failures can be told apart from errors returned by functions. It also remembers the
+// decoded value, and validates it if it is a Validator.
+type recordingDecoder struct {
+	Decoder
+	v       interface{}
+	err     error
+	invalid error   // Returned by the decoded value's RPKValidate.
+	end     Decoder // Should find nothing after the value, nil if not checked.
+
+	// Whether the call only validates, so decoding returns errValidated when done.
+	validateOnly bool
+}
+
+func (d *recordingDecoder) Decode(v interface{}) error {
+	d.v = v
+	d.err = d.Decoder.Decode(v)
+	if d.err != nil {
+		return d.err
 	}
-	if outVal.IsValid() {
-		result, err := json.Marshal(outVal.Interface())
-		if err != nil {
-			return jsonError("Error encoding result: %v", err)
+	if d.end != nil && d.end.Decode(&json.RawMessage{}) != io.EOF {
+		d.err = errTrailingData
+		return d.err
+	}
+	// Pointer parameters are decoded through pointers to them, and are recorded as they
+	// are, or as nil for null.
+	if p := reflect.ValueOf(v); p.Kind() == reflect.Ptr && p.Elem().Kind() == reflect.Ptr {
+		d.v = nil
+		if !p.Elem().IsNil() {
+			d.v = p.Elem().Interface()
+		}
+	}
+	if val, ok := d.v.(Validator); ok {
+		if d.invalid = val.RPKValidate(); d.invalid != nil {
+			return d.invalid
 		}
-		return string(result)
 	}
-	return ""
+	if d.validateOnly {
+		return errValidated
+	}
+	return nil
 }
 
-// jsonError generates a JSON string with an error field, which evaluates to the given
+// errTrailingData is the error of parameters that are followed by more data.
+var errTrailingData = errors.New("unexpected data after the parameter")
+
+// writeError writes a JSON object with an error field, which evaluates to the given
 // format.
-func jsonError(s string, a ...interface{}) string {
-	result, _ := json.Marshal(map[string]string{"error": fmt.Sprintf(s, a...)})
-	return string(result)
-}
-
-// HandlerFunc returns a handler function that calls a's exported methods. Access this handler
-// using the Javascript code served by HandleJS. Returns an error if a's methods do not match
-// the requirements - see package description.
-func HandlerFunc(a interface{}) (http.HandlerFunc, error) {
-	// The "Content-Type" header field should read "application/x-www-form-urlencoded".
-	// The content should be "func=FunctionName&param=JsonEncodedParam".
-	f, err := newFuncs(a)
-	if err != nil {
-		return nil, err
-	}
+func writeError(w io.Writer, s string, a ...interface{}) {
+	json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf(s, a...)})
+}
 
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		// TODO(amit): Verify that request is POST.
-		funcName := r.FormValue("func")
+// countWriter counts the bytes written through it.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
 
-		// Special value - "funcs" - returns the names of registered functions.
-		if funcName == "funcs" {
-			names := make([]string, 0, len(f))
-			for name := range f {
-				names = append(names, name)
-			}
-			json.NewEncoder(w).Encode(names)
-			return
-		}
+func (c *countWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// A Decoder decodes input parameters from a stream. *json.Decoder implements this
+// interface.
+type Decoder interface {
+	Decode(v interface{}) error
+}
+
+// A DecoderFunc creates a Decoder that reads from r.
+type DecoderFunc func(r io.Reader) Decoder
+
+// newJSONDecoder is the default DecoderFunc.
+func newJSONDecoder(r io.Reader) Decoder {
+	return json.NewDecoder(r)
+}
+
+// newStrictJSONDecoder is the default DecoderFunc of strict handlers.
+func newStrictJSONDecoder(r io.Reader) Decoder {
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+	return d
+}
 
-		param := r.FormValue("param")
-		result := f.call(funcName, param)
-		w.Write([]byte(result))
-	}, nil
+// Raw is an encoded parameter. Functions that take a Raw (or a json.RawMessage, which is
+// the same) get the parameter's bytes untouched, regardless of the handler's decoder,
+// and can decode them later. This is useful for proxying calls, and for payloads whose
+// type is only known after looking into them. A missing parameter gives an empty Raw.
+type Raw = json.RawMessage
+
+// rawType is the reflected type of Raw.
+var rawType = reflect.TypeOf(Raw(nil))
+
+// rawDecoder reads the bytes of a parameter into a *Raw or a **Raw, without decoding
+// them.
+type rawDecoder struct {
+	r io.Reader
+}
+
+func (d rawDecoder) Decode(v interface{}) error {
+	data, err := io.ReadAll(d.r)
+	if err != nil {
+		return err
+	}
+	if p, ok := v.(**Raw); ok {
+		*p = new(Raw)
+		v = *p
+	}
+	*v.(*Raw) = data
+	return nil
 }
 
-// HandleJS returns an http.HandlerFunc for serving the Javascript client code.
+// HandleJS returns an http.HandlerFunc for serving the Javascript client code. The code
+// is served with its hash as its ETag, so browsers revalidate it cheaply, and may be
+// cached for good when served from a path returned by VersionedJS. The code is minified,
+// with a source map for debuggers. Add "?debug" to the URL for the readable code.
 func HandleJS(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/javascript")
-	w.Write([]byte(jsCode))
+	serveJS(w, r)
 }
@@ -0,0 +1,175 @@
+package rpktest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A recording is a recorded call and its response, as a line in a recording file.
+type recording struct {
+	Func     string `json:"func"`
+	Param    string `json:"param,omitempty"`
+	Status   int    `json:"status"`
+	Response string `json:"response"`
+}
+
+// Record returns a handler that serves calls with h, and writes each call and its
+// response to w, as a line of JSON. Replay serves the recorded responses.
+func Record(h http.Handler, w io.Writer) http.Handler {
+	var mu sync.Mutex
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		name, param, err := readCall(r)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tee := &teeWriter{ResponseWriter: rw, status: http.StatusOK}
+		h.ServeHTTP(tee, r)
+		line, _ := json.Marshal(recording{name, param, tee.status, tee.buf.String()})
+		mu.Lock()
+		w.Write(append(line, '\n'))
+		mu.Unlock()
+	})
+}
+
+// Replay returns a handler that serves the responses recorded by Record to the given
+// file, so that clients can run against a deterministic mock backend. Calls get the
+// response of the first recording of the same function with the same parameter, or
+// else the first recording of the same function. The special function "funcs" lists
+// the recorded functions, if it was not recorded itself.
+func Replay(file string) (http.Handler, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewReplayHandler(f)
+}
+
+// NewReplayHandler returns a handler like Replay does, with recordings read from r.
+func NewReplayHandler(r io.Reader) (http.Handler, error) {
+	exact := map[[2]string]*recording{} // By function and parameter.
+	byFunc := map[string]*recording{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<26)
+	for i := 1; sc.Scan(); i++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		rec := &recording{}
+		if err := json.Unmarshal(sc.Bytes(), rec); err != nil {
+			return nil, fmt.Errorf("rpktest: line %d: %v", i, err)
+		}
+		key := [2]string{rec.Func, compact(rec.Param)}
+		if exact[key] == nil {
+			exact[key] = rec
+		}
+		if byFunc[rec.Func] == nil {
+			byFunc[rec.Func] = rec
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("rpktest: %v", err)
+	}
+	if byFunc["funcs"] == nil {
+		var names []string
+		for name := range byFunc {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		data, _ := json.Marshal(names)
+		byFunc["funcs"] = &recording{Func: "funcs", Status: http.StatusOK,
+			Response: string(data) + "\n"}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		name, param, err := readCall(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		rec := exact[[2]string{name, compact(param)}]
+		if rec == nil {
+			rec = byFunc[name]
+		}
+		if rec == nil {
+			json.NewEncoder(w).Encode(map[string]string{
+				"error": fmt.Sprintf("No recording of function '%s'.", name)})
+			return
+		}
+		w.WriteHeader(rec.Status)
+		io.WriteString(w, rec.Response)
+	}), nil
+}
+
+// readCall returns the name of the function that r calls, and its encoded parameter,
+// leaving r's body to be read again. The name is in the "func" value, or else the last
+// element of the URL path, for handlers with path routing.
+func readCall(r *http.Request) (string, string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", "", fmt.Errorf("Error reading request: %v", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	values := r.URL.Query()
+	typ, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	param := values.Get("param")
+	if typ == "application/x-www-form-urlencoded" {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "", "", fmt.Errorf("Error parsing form: %v", err)
+		}
+		for k, v := range form {
+			values[k] = append(values[k], v...)
+		}
+		param = values.Get("param")
+	} else if r.Method == "POST" {
+		param = string(body)
+	}
+	name := values.Get("func")
+	if name == "" {
+		name = path.Base(r.URL.Path)
+	}
+	return name, strings.TrimSpace(param), nil
+}
+
+// compact returns the compact form of an encoded parameter, so that parameters that
+// differ only in white space match.
+func compact(param string) string {
+	buf := bytes.NewBuffer(nil)
+	if json.Compact(buf, []byte(param)) != nil {
+		return param
+	}
+	return buf.String()
+}
+
+// teeWriter is a ResponseWriter that keeps a copy of the response.
+type teeWriter struct {
+	http.ResponseWriter
+	status int
+	buf    bytes.Buffer
+}
+
+func (w *teeWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *teeWriter) Write(b []byte) (int, error) {
+	w.buf.Write(b)
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,47 @@
+package rpktest
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fluhus/rpk"
+)
+
+func TestRecordReplay(t *testing.T) {
+	h := rpk.New()
+	h.RegisterObject(testAPI{})
+	buf := bytes.NewBuffer(nil)
+	rec := httptest.NewServer(Record(h, buf))
+	c := rpk.NewClient(rec.URL, nil)
+	var half int
+	c.Call(t.Context(), "Half", 10, &half)
+	c.Call(t.Context(), "Half", 20, &half)
+	c.Call(t.Context(), "Fail", 1, nil)
+	rec.Close()
+
+	replay, err := NewReplayHandler(buf)
+	if err != nil {
+		t.Fatal("Failed to read recordings:", err)
+	}
+	s := &Server{Server: httptest.NewServer(replay)}
+	s.Client = rpk.NewClient(s.URL, nil)
+	defer s.Close()
+
+	tests := []struct {
+		param interface{}
+		want  int
+	}{{20, 10}, {10, 5}, {11, 5}}
+	for _, test := range tests {
+		if got := Call[int](t, s, "Half", test.param); got != test.want {
+			t.Fatalf("Bad result for Half(%v): %d, expected %d.", test.param, got,
+				test.want)
+		}
+	}
+	CallError(t, s, "Fail", 2, "failed with 1")
+	CallError(t, s, "Nope", nil, "No recording of function 'Nope'.")
+	if got := Call[[]string](t, s, "funcs", nil); strings.Join(got, ",") != "Fail,Half" {
+		t.Fatalf("Bad funcs: %v, expected [Fail Half].", got)
+	}
+}
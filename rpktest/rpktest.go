@@ -0,0 +1,89 @@
+// Package rpktest provides utilities for testing RPK APIs, through real HTTP calls.
+//
+//	func TestHalf(t *testing.T) {
+//		s := rpktest.NewServer(myAPI{})
+//		defer s.Close()
+//
+//		if got := rpktest.Call[int](t, s, "Half", 10); got != 5 {
+//			t.Fatalf("Half(10)=%d, expected 5", got)
+//		}
+//		rpktest.CallError(t, s, "Half", "ten", "cannot unmarshal")
+//	}
+//
+// Record and Replay let frontend developers run against a mock backend, which serves
+// responses that were recorded from the real one.
+package rpktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fluhus/rpk"
+)
+
+// A Server is an HTTP server that serves an RPK handler, for tests.
+type Server struct {
+	*httptest.Server
+	Handler *rpk.Handler
+	Client  *rpk.Client // Calls the server.
+}
+
+// NewServer starts and returns a server that exposes api's exported methods, with
+// handler options opts. The caller should call Close when finished, to shut it down.
+// Panics if api's methods do not match the requirements of RPK.
+func NewServer(api interface{}, opts ...rpk.Option) *Server {
+	h := rpk.New(opts...)
+	if err := h.RegisterObject(api); err != nil {
+		panic(fmt.Sprintf("rpktest: %v", err))
+	}
+	return NewHandlerServer(h)
+}
+
+// NewHandlerServer starts and returns a server that serves h. The caller should call
+// Close when finished, to shut it down.
+func NewHandlerServer(h *rpk.Handler) *Server {
+	s := httptest.NewServer(h)
+	return &Server{s, h, rpk.NewClient(s.URL, s.Client())}
+}
+
+// Call calls the named function with param, and returns its output. A nil param means
+// that the function takes no input. Fails the test if the call fails.
+func Call[T any](t testing.TB, s *Server, name string, param interface{}) T {
+	t.Helper()
+	var result T
+	if err := s.Client.Call(context.Background(), name, param, &result); err != nil {
+		t.Fatalf("Call %s(%v) failed: %v", name, param, err)
+	}
+	return result
+}
+
+// CallError calls the named function with param, and returns the error that it
+// returns. A nil param means that the function takes no input. Fails the test if the
+// call succeeds, or if the error's message does not contain want.
+func CallError(t testing.TB, s *Server, name string, param interface{},
+	want string) *rpk.RemoteError {
+	t.Helper()
+	err := s.Client.Call(context.Background(), name, param, nil)
+	if err == nil {
+		t.Fatalf("Call %s(%v) succeeded, expected an error.", name, param)
+	}
+	return AssertError(t, err, want)
+}
+
+// AssertError fails the test if err is not a *rpk.RemoteError, or if its message does
+// not contain want. Returns the remote error.
+func AssertError(t testing.TB, err error, want string) *rpk.RemoteError {
+	t.Helper()
+	var rerr *rpk.RemoteError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("Error %q is not a remote error.", err)
+	}
+	if !strings.Contains(rerr.Message, want) {
+		t.Fatalf("Bad error: %q, expected it to contain %q.", rerr.Message, want)
+	}
+	return rerr
+}
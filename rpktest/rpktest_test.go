@@ -0,0 +1,70 @@
+package rpktest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type testAPI struct{}
+
+func (testAPI) Half(i int) int { return i / 2 }
+
+func (testAPI) Fail(i int) error { return fmt.Errorf("failed with %d", i) }
+
+// fakeT records the failures of tests.
+type fakeT struct {
+	testing.TB
+	failed string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Fatalf(format string, args ...interface{}) {
+	t.failed = fmt.Sprintf(format, args...)
+	panic(t)
+}
+
+// failure runs f with a fakeT, and returns its failure message.
+func failure(f func(t *fakeT)) (msg string) {
+	t := &fakeT{}
+	defer func() {
+		if r := recover(); r != nil && r != t {
+			panic(r)
+		}
+		msg = t.failed
+	}()
+	f(t)
+	return
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer(testAPI{})
+	defer s.Close()
+
+	if got := Call[int](t, s, "Half", 10); got != 5 {
+		t.Fatalf("Bad result: %d, expected %d.", got, 5)
+	}
+	if err := CallError(t, s, "Fail", 3, "with 3"); err.Message != "failed with 3" {
+		t.Fatalf("Bad error: %q, expected %q.", err.Message, "failed with 3")
+	}
+
+	tests := []struct {
+		f    func(t *fakeT)
+		want string
+	}{
+		{func(t *fakeT) { Call[int](t, s, "Fail", 1) },
+			"Call Fail(1) failed: failed with 1"},
+		{func(t *fakeT) { CallError(t, s, "Half", 1, "") },
+			"Call Half(1) succeeded, expected an error."},
+		{func(t *fakeT) { CallError(t, s, "Fail", 1, "with 2") },
+			`Bad error: "failed with 1", expected it to contain "with 2".`},
+		{func(t *fakeT) { AssertError(t, errors.New("local"), "") },
+			`Error "local" is not a remote error.`},
+	}
+	for i, test := range tests {
+		if got := failure(test.f); got != test.want {
+			t.Fatalf("Bad failure #%d: %q, expected %q.", i, got, test.want)
+		}
+	}
+}
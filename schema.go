@@ -0,0 +1,186 @@
+package rpk
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// A JSONSchema describes the JSON encoding of a Go type, by JSON Schema (2020-12).
+type JSONSchema struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	ContentEncoding      string                 `json:"contentEncoding,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	Defs                 map[string]*JSONSchema `json:"$defs,omitempty"`
+}
+
+// An Enum is a type whose values are limited to a known set, which RPKEnum returns.
+// Parameters with other values are rejected, except for zero values, which stand for
+// absent fields, and schemas list the set. Typically implemented by named string or
+// integer types with constants. RPKEnum is called on a new zero value.
+type Enum interface {
+	RPKEnum() []interface{}
+}
+
+// SchemaFor returns the schema of the JSON encoding of values of type t. Named struct
+// types are described in the schema's definitions, and referred to by name, which
+// supports recursive types. Types with custom JSON marshaling are described as any
+// value, except for types that marshal to text, which are strings, and enums.
+func SchemaFor(t reflect.Type) *JSONSchema {
+	return schemaFor(t, nil)
+}
+
+// schemaFor returns the schema of type t, with fields named by cs.
+func schemaFor(t reflect.Type, cs *codecSet) *JSONSchema {
+	s := &schemaBuilder{defs: map[string]*JSONSchema{}, names: map[reflect.Type]string{},
+		cs: cs}
+	result := s.schema(t)
+	if len(s.defs) > 0 {
+		if result.Ref != "" {
+			result = &JSONSchema{Ref: result.Ref}
+		}
+		result.Defs = s.defs
+	}
+	return result
+}
+
+// schemaBuilder builds a schema with definitions.
+type schemaBuilder struct {
+	defs  map[string]*JSONSchema  // By name.
+	names map[reflect.Type]string // Definition names of struct types.
+	cs    *codecSet               // Names fields, nil for their default names.
+}
+
+// enumType is the type of Enum.
+var enumType = reflect.TypeOf((*Enum)(nil)).Elem()
+
+// schema returns the schema of type t.
+func (s *schemaBuilder) schema(t reflect.Type) *JSONSchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t.Implements(enumType) || reflect.PtrTo(t).Implements(enumType):
+		return enumSchema(t)
+	case t == timeType:
+		return &JSONSchema{Type: "string", Format: "date-time"}
+	case t == durationType:
+		return &JSONSchema{Type: "integer"}
+	case isOptional(t):
+		return s.schema(t.Field(0).Type)
+	case t.Implements(textMarshalerType) ||
+		reflect.PtrTo(t).Implements(textMarshalerType):
+		return &JSONSchema{Type: "string"}
+	case isMarshaler(t):
+		return &JSONSchema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &JSONSchema{Type: "string", ContentEncoding: "base64"}
+		}
+		result := &JSONSchema{Type: "array", Items: s.schema(t.Elem())}
+		if t.Kind() == reflect.Array {
+			n := t.Len()
+			result.MinItems, result.MaxItems = &n, &n
+		}
+		return result
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Struct:
+		return s.structSchema(t)
+	}
+	return &JSONSchema{} // Interfaces, and anything else.
+}
+
+// structSchema returns the schema of struct type t. Named types are defined once, and
+// referred to.
+func (s *schemaBuilder) structSchema(t reflect.Type) *JSONSchema {
+	if t.Name() == "" {
+		return s.properties(t)
+	}
+	if name, ok := s.names[t]; ok {
+		return &JSONSchema{Ref: "#/$defs/" + name}
+	}
+	name := t.Name()
+	if _, ok := s.defs[name]; ok {
+		name = defName(t.PkgPath() + "." + t.Name())
+	}
+	s.names[t] = name
+	s.defs[name] = nil // Reserve the name, for recursive types.
+	s.defs[name] = s.properties(t)
+	return &JSONSchema{Ref: "#/$defs/" + name}
+}
+
+// properties returns the schema of struct type t, with its fields.
+func (s *schemaBuilder) properties(t reflect.Type) *JSONSchema {
+	result := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{}}
+	for _, f := range jsonFields(t) {
+		result.Properties[s.cs.fieldName(f)] = s.schema(t.FieldByIndex(f.index).Type)
+	}
+	return result
+}
+
+// badDefChars matches characters that do not belong in definition names.
+var badDefChars = regexp.MustCompile(`[^\w.-]+`)
+
+// defName returns a definition name for a type name.
+func defName(name string) string {
+	return strings.Trim(badDefChars.ReplaceAllString(name, "_"), "_")
+}
+
+// enumSchema returns the schema of enum type t.
+func enumSchema(t reflect.Type) *JSONSchema {
+	values := reflect.New(t).Interface().(Enum).RPKEnum()
+	result := &JSONSchema{}
+	for _, v := range values {
+		// List the values as they are encoded.
+		data, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+		var e interface{}
+		json.Unmarshal(data, &e)
+		result.Enum = append(result.Enum, e)
+		switch e.(type) {
+		case string:
+			result.Type = "string"
+		case float64:
+			if result.Type != "number" {
+				result.Type = "integer"
+			}
+			if e != float64(int64(e.(float64))) {
+				result.Type = "number"
+			}
+		}
+	}
+	return result
+}
+
+// isOptional returns whether t is an Optional type.
+func isOptional(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.PkgPath() == optionalType.PkgPath() &&
+		strings.HasPrefix(t.Name(), "Optional[")
+}
+
+// optionalType is an instance of Optional, for its package path.
+var optionalType = reflect.TypeOf(Optional[int]{})
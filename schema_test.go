@@ -0,0 +1,56 @@
+package rpk
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testColor string
+
+func (testColor) RPKEnum() []interface{} {
+	return []interface{}{testColor("red"), testColor("green")}
+}
+
+type testNode struct {
+	Name     string      `json:"name"`
+	Children []*testNode `json:"children,omitempty"`
+	Color    testColor   `json:"color"`
+	Weights  map[string]float64
+	Created  time.Time
+	Data     []byte
+	Pair     [2]int
+	Nick     Optional[string]
+	Any      interface{}
+	hidden   int
+}
+
+func TestSchemaFor(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{1, `{"type":"integer"}`},
+		{"a", `{"type":"string"}`},
+		{[]*bool{}, `{"type":"array","items":{"type":"boolean"}}`},
+		{struct{ A uint }{}, `{"type":"object","properties":{"A":{"type":"integer"}}}`},
+		{testNode{}, `{"$ref":"#/$defs/testNode","$defs":{"testNode":{"type":"object",` +
+			`"properties":{"Any":{},"Created":{"type":"string","format":"date-time"},` +
+			`"Data":{"type":"string","contentEncoding":"base64"},"Nick":{"type":` +
+			`"string"},"Pair":{"type":"array","items":{"type":"integer"},"minItems":2,` +
+			`"maxItems":2},"Weights":{"type":"object","additionalProperties":{"type":` +
+			`"number"}},"children":{"type":"array","items":{"$ref":"#/$defs/testNode"}}` +
+			`,"color":{"type":"string","enum":["red","green"]},"name":{"type":` +
+			`"string"}}}}}`},
+	}
+	for _, test := range tests {
+		data, err := json.Marshal(SchemaFor(reflect.TypeOf(test.value)))
+		if err != nil {
+			t.Fatalf("Failed to encode schema of %T: %v", test.value, err)
+		}
+		if string(data) != test.want {
+			t.Fatalf("Bad schema for %T: %s, expected %s.", test.value, data, test.want)
+		}
+	}
+}
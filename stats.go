@@ -0,0 +1,174 @@
+package rpk
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// CollectStats makes the handler count the calls of each function, and measure their
+// latencies, for Handler.FuncStats and HandleAdmin.
+func CollectStats() Option {
+	return func(o *options) {
+		o.stats = true
+	}
+}
+
+// FuncStats describes the calls of a function.
+type FuncStats struct {
+	Calls    uint64  `json:"calls"`    // Calls that finished, including failed ones.
+	Errors   uint64  `json:"errors"`   // Calls that failed.
+	InFlight int     `json:"inFlight"` // Calls in progress.
+	Streams  int     `json:"streams"`  // Streamed outputs being written.
+	ErrRate  float64 `json:"errRate"`  // Errors divided by calls, 0 if none.
+
+	// State of the function's circuit, with the CircuitBreaker option.
+	Circuit string `json:"circuit,omitempty"`
+
+	// Latencies of recent calls, including waiting for a worker with the Workers
+	// option, in milliseconds.
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+}
+
+// FuncStats returns the statistics of calls of each function, by name, with the
+// CollectStats option.
+func (h *Handler) FuncStats() map[string]FuncStats {
+	if h.stats == nil {
+		return nil
+	}
+	circuits := h.CircuitStates()
+	h.stats.mu.Lock()
+	defer h.stats.mu.Unlock()
+	result := make(map[string]FuncStats, len(h.stats.m))
+	for name, s := range h.stats.m {
+		st := s.stats
+		if st.Calls > 0 {
+			st.ErrRate = float64(st.Errors) / float64(st.Calls)
+		}
+		if len(s.latencies) > 0 {
+			sorted := slices.Clone(s.latencies)
+			slices.Sort(sorted)
+			st.P50 = percentile(sorted, 0.5)
+			st.P95 = percentile(sorted, 0.95)
+		}
+		if b := h.opts.breaker; b != nil && (b.names == nil || b.names[name]) {
+			st.Circuit = CircuitClosed
+			if c, ok := circuits[name]; ok {
+				st.Circuit = c
+			}
+		}
+		result[name] = st
+	}
+	return result
+}
+
+// percentile returns the p percentile of sorted latencies, in milliseconds.
+func percentile(sorted []time.Duration, p float64) float64 {
+	i := min(int(float64(len(sorted))*p), len(sorted)-1)
+	return float64(sorted[i]) / float64(time.Millisecond)
+}
+
+// Disable makes calls of the named function fail with an error that wraps
+// ErrUnavailable, without calling it, for example to turn off a misbehaving feature
+// while the server runs. Enable undoes it.
+func (h *Handler) Disable(name string) {
+	h.fmu.Lock()
+	defer h.fmu.Unlock()
+	if h.disabled == nil {
+		h.disabled = map[string]bool{}
+	}
+	h.disabled[name] = true
+}
+
+// Enable makes calls of the named function, disabled by Disable, call it again.
+func (h *Handler) Enable(name string) {
+	h.fmu.Lock()
+	defer h.fmu.Unlock()
+	delete(h.disabled, name)
+}
+
+// isDisabled returns whether the named function is disabled.
+func (h *Handler) isDisabled(name string) bool {
+	h.fmu.RLock()
+	defer h.fmu.RUnlock()
+	return h.disabled[name]
+}
+
+// checkDisabled returns an error if the named function is disabled.
+func (h *Handler) checkDisabled(name string) error {
+	if h.isDisabled(name) {
+		return fmt.Errorf("Function '%s' is disabled: %w", name, ErrUnavailable)
+	}
+	return nil
+}
+
+// maxLatencies is the number of recent latencies that are kept for each function.
+const maxLatencies = 1000
+
+// statSet holds the statistics of the CollectStats option.
+type statSet struct {
+	mu sync.Mutex
+	m  map[string]*funcState // By function name.
+}
+
+// funcState holds the statistics of a function.
+type funcState struct {
+	stats     FuncStats
+	latencies []time.Duration // Recent, cyclic once full.
+	next      int             // Where the next latency goes once full.
+}
+
+// get returns the state of the named function, creating it if needed. The caller
+// should hold mu.
+func (s *statSet) get(name string) *funcState {
+	if s.m == nil {
+		s.m = map[string]*funcState{}
+	}
+	f := s.m[name]
+	if f == nil {
+		f = &funcState{}
+		s.m[name] = f
+	}
+	return f
+}
+
+// start counts a call of the named function as in flight, and returns a function that
+// counts it as finished with the given error.
+func (s *statSet) start(name string) func(err error) {
+	t := time.Now()
+	s.mu.Lock()
+	s.get(name).stats.InFlight++
+	s.mu.Unlock()
+	return func(err error) {
+		d := time.Since(t)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		f := s.get(name)
+		f.stats.InFlight--
+		f.stats.Calls++
+		if err != nil {
+			f.stats.Errors++
+		}
+		if len(f.latencies) < maxLatencies {
+			f.latencies = append(f.latencies, d)
+		} else {
+			f.latencies[f.next] = d
+			f.next = (f.next + 1) % maxLatencies
+		}
+	}
+}
+
+// stream counts a streamed output of the named function as being written, and returns
+// a function that counts it as done.
+func (s *statSet) stream(name string) func() {
+	s.mu.Lock()
+	s.get(name).stats.Streams++
+	s.mu.Unlock()
+	return func() {
+		s.mu.Lock()
+		s.get(name).stats.Streams--
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,165 @@
+package rpk
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// readerType is the type of io.Reader.
+var readerType = reflect.TypeOf((*io.Reader)(nil)).Elem()
+
+// isStream returns whether functions stream outputs of type t, rather than encode them.
+// Streamed outputs are io.Readers of newline-delimited JSON values, and iterators of
+// values, of types iter.Seq[V] and iter.Seq2[V, error].
+func isStream(t reflect.Type) bool {
+	if t.Implements(readerType) {
+		return true
+	}
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 0 {
+		return false
+	}
+	yield := t.In(0)
+	if yield.Kind() != reflect.Func || yield.NumOut() != 1 ||
+		yield.Out(0).Kind() != reflect.Bool {
+		return false
+	}
+	switch yield.NumIn() {
+	case 1:
+		return isEncodable(yield.In(0))
+	case 2:
+		return isEncodable(yield.In(0)) && isError(yield.In(1))
+	}
+	return false
+}
+
+// NDJSONType is the media type of newline-delimited JSON, in which the handler writes
+// streamed outputs. Clients that send it in the Accept header get list outputs, which
+// are slices and arrays, in it too, one element per line. That lets clients like curl
+// and jq, and the stream method of the Javascript client, consume large lists as they
+// arrive.
+const NDJSONType = "application/x-ndjson"
+
+// acceptsNDJSON returns whether the client accepts list outputs as newline-delimited
+// JSON.
+func acceptsNDJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), NDJSONType)
+}
+
+// isList returns whether outputs of type t can be written as newline-delimited JSON
+// values, one per element. Byte slices are excluded, since they are encoded as strings.
+func isList(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return t.Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
+
+// streamed returns whether a result should be written as newline-delimited JSON to the
+// client that sent r.
+func (res *callResult) streamed(r *http.Request) bool {
+	if res.err != nil {
+		return false
+	}
+	if res.stream {
+		return true
+	}
+	return res.hasOut && res.val != nil && isList(reflect.TypeOf(res.val)) &&
+		acceptsNDJSON(r)
+}
+
+// writeStream writes a streamed output as newline-delimited JSON, compressed with gzip
+// if the client accepts it. The output is flushed as it is written, so the client gets
+// values as they come. Errors while streaming are written as a last line with an error
+// field.
+func (h *Handler) writeStream(w http.ResponseWriter, r *http.Request, res *callResult) {
+	w.Header().Set("Content-Type", NDJSONType)
+	var out io.Writer = w
+	var gz *gzip.Writer
+	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz = gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	}
+	flush := func() {
+		if gz != nil {
+			gz.Flush()
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	fail := func(err error) {
+		if h.opts.hideErrors {
+			err = h.hideError(err)
+		}
+		writeCallError(out, err)
+	}
+
+	if v := reflect.ValueOf(res.val); !v.IsValid() || v.Kind() == reflect.Func && v.IsNil() {
+		return
+	}
+	if rd, ok := res.val.(io.Reader); ok {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := rd.Read(buf)
+			out.Write(buf[:n])
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				fail(err)
+				break
+			}
+			flush()
+		}
+		if c, ok := rd.(io.Closer); ok {
+			c.Close()
+		}
+		return
+	}
+
+	enc := h.opts.encode.newEncoder(out)
+	enc.SetIndent("", "") // Values must be on a single line.
+
+	// Lists are flushed only as buffers fill, since their values are all ready.
+	if v := reflect.ValueOf(res.val); isList(v.Type()) {
+		for i := 0; i < v.Len() && r.Context().Err() == nil; i++ {
+			item := v.Index(i).Interface()
+			if res.outC != nil {
+				item = codecValue{item, res.outC}
+			}
+			if res.fields != nil {
+				item = res.fields.pruned(item)
+			}
+			if err := enc.Encode(item); err != nil {
+				fail(err)
+				return
+			}
+		}
+		return
+	}
+
+	yield := reflect.MakeFunc(reflect.ValueOf(res.val).Type().In(0),
+		func(args []reflect.Value) []reflect.Value {
+			if len(args) == 2 && !args[1].IsNil() {
+				fail(args[1].Interface().(error))
+				return []reflect.Value{reflect.ValueOf(false)}
+			}
+			item := args[0].Interface()
+			if res.fields != nil {
+				item = res.fields.pruned(item)
+			}
+			if err := enc.Encode(item); err != nil {
+				fail(err)
+				return []reflect.Value{reflect.ValueOf(false)}
+			}
+			flush()
+			return []reflect.Value{reflect.ValueOf(r.Context().Err() == nil)}
+		})
+	reflect.ValueOf(res.val).Call([]reflect.Value{yield})
+}
@@ -0,0 +1,106 @@
+package rpk
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"iter"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	h := New()
+	funcs := map[string]interface{}{
+		"Reader": func() io.Reader {
+			return strings.NewReader("1\n2\n")
+		},
+		"Seq": func(n int) iter.Seq[int] {
+			return func(yield func(int) bool) {
+				for i := 0; i < n && yield(i); i++ {
+				}
+			}
+		},
+		"Seq2": func() iter.Seq2[string, error] {
+			return func(yield func(string, error) bool) {
+				_ = yield("a", nil) && yield("", errors.New("oops")) && yield("b", nil)
+			}
+		},
+		"Nil": func() iter.Seq[int] { return nil },
+	}
+	for name, f := range funcs {
+		if err := h.Register(name, f); err != nil {
+			t.Fatal("Failed to register function:", err)
+		}
+	}
+
+	tests := []struct {
+		funcName string
+		param    string
+		want     string
+	}{
+		{"Reader", "", "1\n2\n"},
+		{"Seq", "3", "0\n1\n2\n"},
+		{"Seq2", "", "\"a\"\n{\"error\":\"oops\"}\n"},
+		{"Nil", "", ""},
+	}
+	for _, gz := range []bool{false, true} {
+		for _, test := range tests {
+			req := apiRequest(test.funcName, test.param)
+			if gz {
+				req.Header.Set("Accept-Encoding", "gzip")
+			}
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+			if typ := res.Header().Get("Content-Type"); typ != NDJSONType {
+				t.Fatalf("Bad content type for %s: %q, expected %q.", test.funcName, typ,
+					NDJSONType)
+			}
+			var body io.Reader = res.Body
+			if gz {
+				zr, err := gzip.NewReader(body)
+				if err != nil {
+					t.Fatalf("Failed to decompress %s: %v", test.funcName, err)
+				}
+				body = zr
+			}
+			got, _ := io.ReadAll(body)
+			if string(got) != test.want {
+				t.Fatalf("Bad result for %s: %q, expected %q.", test.funcName, got,
+					test.want)
+			}
+		}
+	}
+}
+
+func TestStream_list(t *testing.T) {
+	h := New()
+	h.Register("List", func() []string { return []string{"a", "b"} })
+	h.Register("Bytes", func() []byte { return []byte("ab") })
+
+	tests := []struct {
+		funcName string
+		accept   string
+		typ      string
+		want     string
+	}{
+		{"List", "", "application/json", "[\"a\",\"b\"]\n"},
+		{"List", NDJSONType, NDJSONType, "\"a\"\n\"b\"\n"},
+		{"Bytes", NDJSONType, "application/json", "\"YWI=\"\n"},
+	}
+	for _, test := range tests {
+		req := apiRequest(test.funcName, "")
+		req.Header.Set("Accept", test.accept)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		if typ := res.Header().Get("Content-Type"); typ != test.typ {
+			t.Fatalf("Bad content type for %s: %q, expected %q.", test.funcName, typ,
+				test.typ)
+		}
+		if got := res.Body.String(); got != test.want {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.funcName, got,
+				test.want)
+		}
+	}
+}
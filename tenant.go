@@ -0,0 +1,131 @@
+package rpk
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Tenants makes the handler serve several tenants, like the customers of a SaaS
+// backend. Resolve returns the ID of the tenant of a request, for example by its host, a
+// header or its token. If it returns an error, the function is not called, and the error
+// is returned to the client. Functions get the ID with TenantOf, and hooks in their
+// Call. Handler.TenantStats counts the calls of each tenant.
+func Tenants(resolve func(r *http.Request) (string, error)) Option {
+	return func(o *options) {
+		o.tenants = resolve
+	}
+}
+
+// TenantRateLimit limits the rate of calls of each tenant, with the Tenants option, to
+// perSecond calls per second on average, with bursts of up to burst calls. Calls beyond
+// the limit fail with an error that wraps ErrUnavailable, without calling the function.
+func TenantRateLimit(perSecond float64, burst int) Option {
+	return func(o *options) {
+		o.tenantRate, o.tenantBurst = perSecond, burst
+	}
+}
+
+// tenantKey is the context key of the tenant of a call.
+type tenantKey struct{}
+
+// TenantOf returns the ID of the tenant of the call with the given context, or "" if
+// the handler does not have the Tenants option.
+func TenantOf(ctx context.Context) string {
+	t, _ := ctx.Value(tenantKey{}).(string)
+	return t
+}
+
+// TenantStats counts the calls of a tenant.
+type TenantStats struct {
+	Calls   uint64 // Calls that were made, including failed and limited ones.
+	Errors  uint64 // Calls that failed.
+	Limited uint64 // Calls that were rejected by the rate limit.
+}
+
+// TenantStats returns the counts of calls of each tenant, by ID, for example for
+// metrics with tenant labels. Only the 10,000 tenants that called last are kept, so IDs
+// that clients make up cannot grow the handler's memory without bound: the stats and
+// the rate limit of the tenant that has not called for the longest are dropped when a
+// new one calls.
+func (h *Handler) TenantStats() map[string]TenantStats {
+	h.tenants.mu.Lock()
+	defer h.tenants.mu.Unlock()
+	result := make(map[string]TenantStats, len(h.tenants.m))
+	for id, t := range h.tenants.m {
+		result[id] = t.stats
+	}
+	return result
+}
+
+// maxTenants is the number of tenants whose state is kept.
+const maxTenants = 10000
+
+// tenantSet holds the state of the tenants of a handler.
+type tenantSet struct {
+	mu     sync.Mutex
+	m      map[string]*tenantState // By ID.
+	recent list.List               // Of IDs, the tenant that called last first.
+}
+
+// tenantState is the state of a tenant.
+type tenantState struct {
+	stats  TenantStats
+	tokens float64       // Of the rate limit's bucket.
+	last   time.Time     // When tokens was updated.
+	elem   *list.Element // Of the tenant in recent.
+}
+
+// tenant resolves the tenant of a request, and returns the request with the tenant in
+// its context. Returns an error if it cannot be resolved, or is over its rate limit.
+func (h *Handler) tenant(r *http.Request) (*http.Request, string, error) {
+	id, err := h.opts.tenants(r)
+	if err != nil {
+		return r, "", err
+	}
+	s := &h.tenants
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m == nil {
+		s.m = map[string]*tenantState{}
+	}
+	t := s.m[id]
+	if t == nil {
+		if len(s.m) >= maxTenants {
+			oldest := s.recent.Back()
+			delete(s.m, s.recent.Remove(oldest).(string))
+		}
+		t = &tenantState{tokens: float64(h.opts.tenantBurst), last: time.Now()}
+		t.elem = s.recent.PushFront(id)
+		s.m[id] = t
+	} else {
+		s.recent.MoveToFront(t.elem)
+	}
+	t.stats.Calls++
+	if h.opts.tenantRate > 0 {
+		now := time.Now()
+		t.tokens += now.Sub(t.last).Seconds() * h.opts.tenantRate
+		t.tokens = min(t.tokens, float64(h.opts.tenantBurst))
+		t.last = now
+		if t.tokens < 1 {
+			t.stats.Limited++
+			t.stats.Errors++
+			return r, id, fmt.Errorf("rate limit of tenant %q exceeded: %w", id,
+				ErrUnavailable)
+		}
+		t.tokens--
+	}
+	return r.WithContext(context.WithValue(r.Context(), tenantKey{}, id)), id, nil
+}
+
+// countError counts a failed call of a tenant, unless it has been dropped since.
+func (h *Handler) countError(id string) {
+	h.tenants.mu.Lock()
+	if t := h.tenants.m[id]; t != nil {
+		t.stats.Errors++
+	}
+	h.tenants.mu.Unlock()
+}
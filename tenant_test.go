@@ -0,0 +1,95 @@
+package rpk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestTenants(t *testing.T) {
+	resolve := func(r *http.Request) (string, error) {
+		tenant := r.Header.Get("X-Tenant")
+		if tenant == "" {
+			return "", errors.New("no tenant")
+		}
+		return tenant, nil
+	}
+	h := New(Tenants(resolve), TenantRateLimit(0.001, 2))
+	h.Register("Whoami", func(ctx context.Context) (string, error) {
+		if TenantOf(ctx) == "bad" {
+			return "", errors.New("failed")
+		}
+		return TenantOf(ctx), nil
+	})
+	var hookTenant string
+	h.After("*", func(c *Call) { hookTenant = c.Tenant })
+
+	tests := []struct {
+		tenant, want string
+	}{
+		{"a", `"a"`},
+		{"b", `"b"`},
+		{"a", `"a"`},
+		{"a", `{"error":"rate limit of tenant \"a\" exceeded: unavailable",` +
+			`"code":"unavailable"}`},
+		{"bad", `{"error":"failed"}`},
+		{"", `{"error":"no tenant"}`},
+	}
+	for _, test := range tests {
+		req := apiRequest("Whoami", "")
+		req.Header.Set("X-Tenant", test.tenant)
+		if got := serve(h, req); got != test.want {
+			t.Fatalf("Bad result for %q: %s, expected %s.", test.tenant, got, test.want)
+		}
+	}
+	if hookTenant != "a" {
+		t.Fatalf("Bad tenant in hook: %q, expected %q.", hookTenant, "a")
+	}
+
+	stats := h.TenantStats()
+	want := map[string]TenantStats{
+		"a":   {Calls: 3, Errors: 1, Limited: 1},
+		"b":   {Calls: 1},
+		"bad": {Calls: 1, Errors: 1},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("Bad stats: %v, expected %v.", stats, want)
+	}
+	for id, s := range want {
+		if stats[id] != s {
+			t.Fatalf("Bad stats of %q: %+v, expected %+v.", id, stats[id], s)
+		}
+	}
+}
+
+func TestTenants_bounded(t *testing.T) {
+	h := New(Tenants(func(r *http.Request) (string, error) {
+		return r.Header.Get("X-Tenant"), nil
+	}))
+	h.Register("Ping", func() {})
+	call := func(tenant string) {
+		req := apiRequest("Ping", "")
+		req.Header.Set("X-Tenant", tenant)
+		serve(h, req)
+	}
+	call("first")
+	call("kept")
+	for i := 0; i < maxTenants-1; i++ {
+		call(fmt.Sprint("made-up-", i))
+		if i == maxTenants/2 {
+			call("kept")
+		}
+	}
+	stats := h.TenantStats()
+	if len(stats) != maxTenants {
+		t.Fatalf("Bad number of tenants: %d, expected %d.", len(stats), maxTenants)
+	}
+	if _, ok := stats["first"]; ok {
+		t.Fatalf("Tenant %q was kept, expected it to be dropped.", "first")
+	}
+	if got := stats["kept"].Calls; got != 2 {
+		t.Fatalf("Bad calls of %q: %d, expected 2.", "kept", got)
+	}
+}
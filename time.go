@@ -0,0 +1,111 @@
+package rpk
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Formats for TimeFormat and DurationFormat, besides time layouts.
+const (
+	// UnixMillis encodes times as numbers of milliseconds since the Unix epoch.
+	UnixMillis = "unixmillis"
+
+	// DurationMillis encodes durations as numbers of milliseconds.
+	DurationMillis = "millis"
+
+	// DurationString encodes durations as strings like "1h5m0.5s", as returned by
+	// time.Duration.String.
+	DurationString = "string"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// TimeFormat makes the handler encode time.Time values in parameters and results with
+// the given layout, as in time.Format, or as UnixMillis. The default is RFC 3339 with
+// nanoseconds, as encoding/json does. Decoding also accepts RFC 3339 strings, which
+// is how Javascript encodes Date objects.
+func TimeFormat(layout string) Option {
+	return func(o *options) {
+		o.codecsOf().m[timeType] = &codec{
+			encode: func(v reflect.Value) (interface{}, error) {
+				t := v.Interface().(time.Time)
+				if layout == UnixMillis {
+					return t.UnixMilli(), nil
+				}
+				return t.Format(layout), nil
+			},
+			decode: func(data []byte, v reflect.Value) error {
+				t, err := decodeTime(data, layout)
+				if err != nil {
+					return err
+				}
+				v.Set(reflect.ValueOf(t))
+				return nil
+			},
+		}
+	}
+}
+
+// decodeTime decodes a time in the given layout, or in RFC 3339.
+func decodeTime(data []byte, layout string) (time.Time, error) {
+	var ms int64
+	if layout == UnixMillis && json.Unmarshal(data, &ms) == nil {
+		return time.UnixMilli(ms), nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return time.Time{}, fmt.Errorf("expected a time, got %s", data)
+	}
+	if layout != UnixMillis {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("bad time %q", s)
+	}
+	return t, nil
+}
+
+// DurationFormat makes the handler encode time.Duration values in parameters and
+// results as DurationMillis or DurationString, instead of numbers of nanoseconds.
+// Decoding accepts both numbers of milliseconds and duration strings.
+func DurationFormat(format string) Option {
+	return func(o *options) {
+		o.codecsOf().m[durationType] = &codec{
+			encode: func(v reflect.Value) (interface{}, error) {
+				d := time.Duration(v.Int())
+				switch format {
+				case DurationMillis:
+					return d.Milliseconds(), nil
+				case DurationString:
+					return d.String(), nil
+				}
+				return nil, fmt.Errorf("unknown duration format: %q", format)
+			},
+			decode: func(data []byte, v reflect.Value) error {
+				var ms float64
+				if json.Unmarshal(data, &ms) == nil {
+					v.SetInt(int64(ms * float64(time.Millisecond)))
+					return nil
+				}
+				var s string
+				if err := json.Unmarshal(data, &s); err != nil {
+					return fmt.Errorf("expected a duration, got %s", data)
+				}
+				d, err := time.ParseDuration(s)
+				if err != nil {
+					return fmt.Errorf("bad duration %q", s)
+				}
+				v.SetInt(int64(d))
+				return nil
+			},
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package rpk
+
+import (
+	"testing"
+	"time"
+)
+
+type timeEvent struct {
+	Name  string
+	At    time.Time     `json:"at"`
+	Took  time.Duration `json:"took,omitempty"`
+	Later []*time.Time  `json:"later,omitempty"`
+}
+
+func TestTimeFormat(t *testing.T) {
+	tests := []struct {
+		opts   []Option
+		param  string
+		result string
+	}{
+		{nil, `{"Name":"a","at":"2020-01-02T03:04:05Z","took":1000}`,
+			`{"Name":"a","at":"2020-01-02T03:04:05Z","took":1000}`},
+		{[]Option{TimeFormat(UnixMillis)}, `{"Name":"a","at":1577934245000}`,
+			`{"Name":"a","at":1577934245000}`},
+		{[]Option{TimeFormat(UnixMillis)}, `{"Name":"a","at":"2020-01-02T03:04:05.000Z"}`,
+			`{"Name":"a","at":1577934245000}`},
+		{[]Option{TimeFormat(time.DateOnly)}, `{"name":"a","at":"2020-01-02"}`,
+			`{"Name":"a","at":"2020-01-02"}`},
+		{[]Option{DurationFormat(DurationString)}, `{"Name":"a","took":"1.5s"}`,
+			`{"Name":"a","at":"0001-01-01T00:00:00Z","took":"1.5s"}`},
+		{[]Option{DurationFormat(DurationMillis)}, `{"Name":"a","took":1500}`,
+			`{"Name":"a","at":"0001-01-01T00:00:00Z","took":1500}`},
+		{[]Option{TimeFormat(UnixMillis)},
+			`{"Name":"a","at":0,"later":[1577934245000,null]}`,
+			`{"Name":"a","at":0,"later":[1577934245000,null]}`},
+	}
+	for _, test := range tests {
+		h := New(test.opts...)
+		err := h.Register("Echo", func(e timeEvent) timeEvent { return e })
+		if err != nil {
+			t.Fatal("Failed to register function:", err)
+		}
+		if result := callAPI(h, "Echo", test.param); result != test.result {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.param, result,
+				test.result)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package rpk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// SetTxWrapper enables transactions, in which clients send a group of calls that
+// succeed or fail together, like the steps of a multi-step form. The built-in function
+// "transaction" takes a list of TxCalls, and calls them in order inside wrap, with the
+// context that wrap passes to fn, so that functions can find the transaction in it.
+// The first error stops the calls, and is returned to wrap, which should then roll the
+// transaction back, and otherwise commit it. Returns a list of the calls' results, or
+// the error, prefixed with the position of the failed call, counting from 1.
+//
+//	h.SetTxWrapper(func(ctx context.Context, fn func(context.Context) error) error {
+//	  tx, err := db.BeginTx(ctx, nil)
+//	  if err != nil {
+//	    return err
+//	  }
+//	  if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+//	    tx.Rollback()
+//	    return err
+//	  }
+//	  return tx.Commit()
+//	})
+//
+// Each call runs with its function's hooks. Functions that stream their outputs cannot
+// be called in transactions. The wrapper should be set before the handler starts
+// serving.
+func (h *Handler) SetTxWrapper(wrap func(ctx context.Context,
+	fn func(ctx context.Context) error) error) {
+	h.tx = wrap
+}
+
+// A TxCall is a call in a transaction.
+type TxCall struct {
+	Func  string      `json:"func"`            // The name of the called function.
+	Param interface{} `json:"param,omitempty"` // Omitted if the function takes no input.
+}
+
+// txCall is a TxCall as it is decoded by the handler.
+type txCall struct {
+	Func  string          `json:"func"`
+	Param json.RawMessage `json:"param"`
+}
+
+// runTx runs the calls of a transaction, read from param, and returns their results.
+func (h *Handler) runTx(r *http.Request, param io.Reader) callResult {
+	var calls []txCall
+	if err := newJSONDecoder(param).Decode(&calls); err != nil {
+		return callResult{err: newCallError(errBadParam, "Error decoding JSON: %v", err)}
+	}
+	for i, c := range calls {
+		if m := h.table()[c.Func]; m != nil && m.stream {
+			return callResult{err: newCallError(errBadParam,
+				"Call %d: function '%s' streams its output, and cannot be called in a "+
+					"transaction.", i+1, c.Func)}
+		}
+	}
+
+	results := make([]json.RawMessage, len(calls))
+	var failed error // Of a call, rather than of the wrapper.
+	err := h.tx(r.Context(), func(ctx context.Context) error {
+		r := r.WithContext(ctx)
+		for i, c := range calls {
+			err := h.checkIP(ctx, c.Func)
+			if err == nil {
+				err = h.admit(r, c.Func)
+			}
+			if err != nil {
+				failed = fmt.Errorf("Call %d (%s): %w", i+1, c.Func, err)
+				return failed
+			}
+			res := h.runHidden(r, c.Func, bytes.NewReader(c.Param))
+			if res.err != nil {
+				failed = fmt.Errorf("Call %d (%s): %w", i+1, c.Func, res.err)
+				return failed
+			}
+			results[i] = json.RawMessage("null")
+			if !res.hasOut || res.validated {
+				continue
+			}
+			data, err := h.opts.encode.marshal(res.encoded())
+			if err != nil {
+				failed = fmt.Errorf("Call %d (%s): error encoding result: %v", i+1,
+					c.Func, err)
+				return failed
+			}
+			results[i] = data
+		}
+		return nil
+	})
+	if err != nil {
+		if err != failed && h.opts.hideErrors {
+			err = h.hideError(err)
+		}
+		return callResult{err: err}
+	}
+	return callResult{val: results, hasOut: true}
+}
@@ -0,0 +1,100 @@
+package rpk
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+type testTxKey struct{}
+
+func TestTransaction(t *testing.T) {
+	balance := map[string]int{"a": 10, "b": 0}
+	var committed, rolledBack int
+	h := New()
+	h.SetTxWrapper(func(ctx context.Context, fn func(context.Context) error) error {
+		// Changes are made to a copy, which replaces the balances on commit.
+		tx := map[string]int{}
+		for k, v := range balance {
+			tx[k] = v
+		}
+		if err := fn(context.WithValue(ctx, testTxKey{}, tx)); err != nil {
+			rolledBack++
+			return err
+		}
+		balance = tx
+		committed++
+		return nil
+	})
+	type move struct {
+		Name   string
+		Amount int
+	}
+	h.Register("Add", func(ctx context.Context, m move) (int, error) {
+		tx, _ := ctx.Value(testTxKey{}).(map[string]int)
+		if tx == nil {
+			return 0, errors.New("not in a transaction")
+		}
+		if tx[m.Name]+m.Amount < 0 {
+			return 0, ErrInvalidArgument
+		}
+		tx[m.Name] += m.Amount
+		return tx[m.Name], nil
+	})
+	h.Register("Ping", func() {})
+
+	tests := []struct{ param, result string }{
+		{`[{"func":"Add","param":{"Name":"a","Amount":-3}},` +
+			`{"func":"Add","param":{"Name":"b","Amount":3}},{"func":"Ping"}]`,
+			`[7,3,null]`},
+		{`[{"func":"Add","param":{"Name":"a","Amount":-5}},` +
+			`{"func":"Add","param":{"Name":"b","Amount":-5}}]`,
+			`{"error":"Call 2 (Add): invalid argument","code":"invalid_argument"}`},
+		{`[{"func":"Add","param":{"Name":"a","Amount":-1}},{"func":"Nope"}]`,
+			`{"error":"Call 2 (Nope): No such function 'Nope'."}`},
+		{`{"func":"Ping"}`, `{"error":"Error decoding JSON: json: cannot unmarshal ` +
+			`object into Go value of type []rpk.txCall"}`},
+	}
+	for _, test := range tests {
+		if result := callAPI(h, "transaction", test.param); result != test.result {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.param, result,
+				test.result)
+		}
+	}
+	if balance["a"] != 7 || balance["b"] != 3 {
+		t.Fatalf("Bad balances: %v, expected a=7 and b=3.", balance)
+	}
+	if committed != 1 || rolledBack != 2 {
+		t.Fatalf("Bad number of commits and rollbacks: %d and %d, expected 1 and 2.",
+			committed, rolledBack)
+	}
+
+	req := httptest.NewRequest("GET", "/api?func=transaction&param=[]", nil)
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	if res.Code != 405 {
+		t.Fatalf("Bad status for GET: %d, expected 405.", res.Code)
+	}
+}
+
+func TestTransaction_client(t *testing.T) {
+	h := New()
+	h.SetTxWrapper(func(ctx context.Context, fn func(context.Context) error) error {
+		return fn(ctx)
+	})
+	h.Register("Double", func(i int) int { return i * 2 })
+	server := httptest.NewServer(h)
+	defer server.Close()
+	c := NewClient(server.URL, nil)
+
+	var result []int
+	err := c.Call(context.Background(), "transaction",
+		[]TxCall{{Func: "Double", Param: 2}, {Func: "Double", Param: 5}}, &result)
+	if err != nil {
+		t.Fatalf("Transaction failed: %v.", err)
+	}
+	if len(result) != 2 || result[0] != 4 || result[1] != 10 {
+		t.Fatalf("Bad result: %v, expected [4 10].", result)
+	}
+}
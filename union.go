@@ -0,0 +1,167 @@
+package rpk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Union makes an interface type a tagged union, so that functions can take and return
+// one of several concrete types through it. The discriminator field of encoded values
+// names their concrete type, as a key of variants. Iface should be a nil pointer to the
+// interface type, and each variant should be a value of a type that implements it.
+// Panics if the types do not match. For example:
+//
+//	rpk.Union((*Shape)(nil), "kind", map[string]interface{}{
+//		"circle": Circle{},
+//		"square": &Square{},
+//	})
+//
+// A parameter {"kind":"circle","radius":1} is then decoded as a Circle. Results get the
+// discriminator field if they do not already have it. Unions apply to parameters and
+// results of the interface type itself, not to fields and elements of that type.
+func Union(iface interface{}, field string, variants map[string]interface{}) Option {
+	ptr := reflect.TypeOf(iface)
+	if ptr == nil || ptr.Kind() != reflect.Ptr || ptr.Elem().Kind() != reflect.Interface {
+		panic(fmt.Sprintf("rpk: union type should be a pointer to an interface, got %T",
+			iface))
+	}
+	u := &union{
+		iface:    ptr.Elem(),
+		field:    field,
+		variants: map[string]reflect.Type{},
+		kinds:    map[reflect.Type]string{},
+	}
+	for kind, v := range variants {
+		t := reflect.TypeOf(v)
+		if t == nil || !t.Implements(u.iface) {
+			panic(fmt.Sprintf("rpk: union variant %q (%T) does not implement %v",
+				kind, v, u.iface))
+		}
+		u.variants[kind] = t
+		u.kinds[t] = kind
+	}
+	return func(o *options) {
+		if o.unions == nil {
+			o.unions = map[reflect.Type]*union{}
+		}
+		o.unions[u.iface] = u
+	}
+}
+
+// union is a tagged union of the concrete types of an interface.
+type union struct {
+	iface    reflect.Type
+	field    string                  // Discriminator field.
+	variants map[string]reflect.Type // By discriminator value.
+	kinds    map[reflect.Type]string // Discriminator values, by type.
+}
+
+// names returns the sorted discriminator values of u.
+func (u *union) names() []string {
+	var result []string
+	for kind := range u.variants {
+		result = append(result, kind)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// decode decodes data into v, a pointer to the union's interface, as the variant that
+// data names.
+func (u *union) decode(data []byte, v interface{}) error {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	var kind string
+	if raw, ok := obj[u.field]; !ok || json.Unmarshal(raw, &kind) != nil {
+		return fmt.Errorf("expected a string field %q, with one of: %v", u.field,
+			u.names())
+	}
+	t, ok := u.variants[kind]
+	if !ok {
+		return fmt.Errorf("unknown %s %q, expected one of: %v", u.field, kind, u.names())
+	}
+
+	// Pointer variants are decoded into directly.
+	var val reflect.Value
+	if t.Kind() == reflect.Ptr {
+		val = reflect.New(t.Elem())
+	} else {
+		val = reflect.New(t)
+	}
+	if err := json.Unmarshal(data, val.Interface()); err != nil {
+		return err
+	}
+	if t.Kind() != reflect.Ptr {
+		val = val.Elem()
+	}
+	reflect.ValueOf(v).Elem().Set(val)
+	return nil
+}
+
+// unionDecoder decodes parameters of a union type, reading them with its underlying
+// decoder.
+type unionDecoder struct {
+	Decoder
+	u *union
+}
+
+func (d unionDecoder) Decode(v interface{}) error {
+	var raw Raw
+	if err := d.Decoder.Decode(&raw); err != nil {
+		return err
+	}
+	// Pointers to the interface are given a new value to point to.
+	val := reflect.ValueOf(v)
+	if val.Elem().Kind() == reflect.Ptr {
+		val.Elem().Set(reflect.New(d.u.iface))
+		v = val.Elem().Interface()
+	}
+	return d.u.decode(raw, v)
+}
+
+// unionValue is a result of a union type, which is encoded with its discriminator.
+type unionValue struct {
+	v     interface{}
+	field string
+	kind  string
+}
+
+// wrap returns v as a unionValue, or v itself if it is not one of the union's variants.
+func (u *union) wrap(v interface{}) interface{} {
+	kind, ok := u.kinds[reflect.TypeOf(v)]
+	if !ok {
+		return v
+	}
+	return unionValue{v, u.field, kind}
+}
+
+func (v unionValue) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(v.v)
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(data, &obj) != nil {
+		return data, nil // Not an object.
+	}
+	if _, ok := obj[v.field]; ok {
+		return data, nil
+	}
+	field, _ := json.Marshal(v.field)
+	kind, _ := json.Marshal(v.kind)
+	buf := bytes.NewBuffer(nil)
+	buf.WriteByte('{')
+	buf.Write(field)
+	buf.WriteByte(':')
+	buf.Write(kind)
+	if len(obj) > 0 {
+		buf.WriteByte(',')
+	}
+	buf.Write(data[1:])
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,88 @@
+package rpk
+
+import (
+	"testing"
+)
+
+type unionShape interface {
+	Area() float64
+}
+
+type unionCircle struct {
+	Radius float64 `json:"radius"`
+}
+
+func (c unionCircle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+type unionSquare struct {
+	Kind string  `json:"kind"`
+	Side float64 `json:"side"`
+}
+
+func (s *unionSquare) Area() float64 { return s.Side * s.Side }
+
+func TestUnion(t *testing.T) {
+	h := New(Union((*unionShape)(nil), "kind", map[string]interface{}{
+		"circle": unionCircle{},
+		"square": &unionSquare{},
+	}))
+	funcs := map[string]interface{}{
+		"Area":    func(s unionShape) float64 { return s.Area() },
+		"AreaPtr": func(s *unionShape) float64 { return (*s).Area() },
+		"Grow": func(s unionShape) unionShape {
+			switch s := s.(type) {
+			case unionCircle:
+				return unionCircle{s.Radius * 2}
+			case *unionSquare:
+				return &unionSquare{s.Kind, s.Side * 2}
+			}
+			return nil
+		},
+	}
+	for name, f := range funcs {
+		if err := h.Register(name, f); err != nil {
+			t.Fatal("Failed to register function:", err)
+		}
+	}
+
+	tests := []struct {
+		funcName string
+		param    string
+		result   string
+	}{
+		{"Area", `{"kind":"circle","radius":2}`, "12"},
+		{"Area", `{"kind":"square","side":3}`, "9"},
+		{"AreaPtr", `{"kind":"square","side":3}`, "9"},
+		{"Grow", `{"kind":"circle","radius":1}`, `{"kind":"circle","radius":2}`},
+		{"Grow", `{"kind":"square","side":1}`, `{"kind":"square","side":2}`},
+		{"Area", `{"kind":"triangle"}`, "{\"error\":\"Error decoding JSON: " +
+			"unknown kind \\\"triangle\\\", expected one of: [circle square]\"}"},
+		{"Area", `{"radius":2}`, "{\"error\":\"Error decoding JSON: " +
+			"expected a string field \\\"kind\\\", with one of: [circle square]\"}"},
+	}
+	for _, test := range tests {
+		if result := callAPI(h, test.funcName, test.param); result != test.result {
+			t.Fatalf("Bad result for %s(%s): %q, expected %q.", test.funcName,
+				test.param, result, test.result)
+		}
+	}
+}
+
+func TestUnion_panic(t *testing.T) {
+	for _, f := range []func(){
+		func() { Union(unionCircle{}, "kind", nil) },
+		func() { Union((*unionShape)(nil), "kind", map[string]interface{}{"a": 1}) },
+		func() {
+			Union((*unionShape)(nil), "kind", map[string]interface{}{"a": unionSquare{}})
+		},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("Union did not panic.")
+				}
+			}()
+			f()
+		}()
+	}
+}
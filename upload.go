@@ -0,0 +1,283 @@
+package rpk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Request headers of the built-in "upload" function, which receives files in chunks,
+// so that uploads of large files can resume where they stopped. Clients start an upload
+// by calling it with the upload's length in bytes, and get its ID. They then send the
+// chunks in order, each as the body of a call with the upload's ID and the offset of
+// the chunk, and with the content type "application/octet-stream". A call with only
+// the upload's ID gets the number of bytes received so far, from which the upload
+// resumes. Each call returns an UploadInfo. Once the upload is complete, clients pass
+// its ID to a function, which reads it with OpenUpload.
+//
+// Each call names that function in UploadFuncHeader, and is admitted like a call of it:
+// its IP rules, Internal policy, tenants, rate limits, load shedding, circuit breaker,
+// replay protection and hooks apply, and it is counted in its stats. Hooks see the
+// call with a nil Param.
+const (
+	UploadIDHeader     = "Rpk-Upload-Id"
+	UploadOffsetHeader = "Rpk-Upload-Offset"
+	UploadLengthHeader = "Rpk-Upload-Length"
+	UploadFuncHeader   = "Rpk-Upload-Func"
+)
+
+// UploadInfo describes an upload.
+type UploadInfo struct {
+	ID     string `json:"id"`
+	Offset int64  `json:"offset"` // Bytes received so far.
+	Size   int64  `json:"size"`   // Length of the upload.
+}
+
+// An UploadStore keeps the chunks of uploads. Implementations should be safe for
+// concurrent use, and shared by the servers of a deployment, so uploads can resume on
+// any of them.
+type UploadStore interface {
+	// Create starts an upload of the given size, and returns it with its new ID.
+	Create(ctx context.Context, size int64) (*UploadInfo, error)
+
+	// Append adds a chunk to an upload, and returns the upload. Offset should be the
+	// number of bytes received so far, and the chunk should not make the upload longer
+	// than its size, or Append returns an error that wraps ErrInvalidArgument. Returns
+	// an error that wraps ErrNotFound if there is no such upload.
+	Append(ctx context.Context, id string, offset int64, chunk io.Reader) (
+		*UploadInfo, error)
+
+	// Info returns an upload, or an error that wraps ErrNotFound if there is none.
+	Info(ctx context.Context, id string) (*UploadInfo, error)
+
+	// Open returns a reader of the bytes of an upload.
+	Open(ctx context.Context, id string) (io.ReadCloser, error)
+
+	// Delete removes an upload. Deleting an upload that does not exist is not an error.
+	Delete(ctx context.Context, id string) error
+}
+
+// Uploads makes the handler receive uploads in chunks into store, with the built-in
+// "upload" function. Chunks are of at most maxChunk bytes, and uploads of at most
+// maxSize bytes, where zero means no limit. The Javascript client uploads files with
+// rpkObject.upload.
+func Uploads(store UploadStore, maxChunk int, maxSize int64) Option {
+	return func(o *options) {
+		o.uploads = &uploadOptions{store, maxChunk, maxSize}
+	}
+}
+
+// OpenUpload returns a reader of the complete upload with the given ID, for functions
+// that get the IDs of uploads of a handler with the Uploads option. Returns an error
+// that wraps ErrInvalidArgument if the upload is incomplete.
+func OpenUpload(ctx context.Context, id string) (io.ReadCloser, error) {
+	store, err := uploadStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info, err := store.Info(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if info.Offset != info.Size {
+		return nil, fmt.Errorf("Upload '%s' is incomplete: %w", id, ErrInvalidArgument)
+	}
+	return store.Open(ctx, id)
+}
+
+// DeleteUpload removes the upload with the given ID, for functions that are done with
+// it.
+func DeleteUpload(ctx context.Context, id string) error {
+	store, err := uploadStore(ctx)
+	if err != nil {
+		return err
+	}
+	return store.Delete(ctx, id)
+}
+
+// uploadsKey is the context key of the upload store of a call.
+type uploadsKey struct{}
+
+// uploadStore returns the upload store of the call with ctx.
+func uploadStore(ctx context.Context) (UploadStore, error) {
+	store, _ := ctx.Value(uploadsKey{}).(UploadStore)
+	if store == nil {
+		return nil, errors.New("rpk: handler has no Uploads option")
+	}
+	return store, nil
+}
+
+// uploadOptions are the options of Uploads.
+type uploadOptions struct {
+	store    UploadStore
+	maxChunk int
+	maxSize  int64
+}
+
+// uploadCallKey is the context key of the response writer of a call of the "upload"
+// function.
+type uploadCallKey struct{}
+
+// uploadWriter returns the response writer of the request if it is of the "upload"
+// function, or nil if it is not.
+func uploadWriter(r *http.Request) http.ResponseWriter {
+	w, _ := r.Context().Value(uploadCallKey{}).(http.ResponseWriter)
+	return w
+}
+
+// routeUpload returns a call of the "upload" function as a call of the function that
+// the upload is for, with a reader of its chunk. Returns false if the request is bad,
+// after writing an error.
+func (h *Handler) routeUpload(w http.ResponseWriter, r *http.Request) (
+	*http.Request, string, io.Reader, bool) {
+	if h.opts.uploads == nil {
+		writeCallError(w, newCallError(errNoSuchFunc, "No such function '%s'.", "upload"))
+		return nil, "", nil, false
+	}
+	name := r.Header.Get(UploadFuncHeader)
+	if h.table()[name] == nil {
+		writeCallError(w, newCallError(errNoSuchFunc,
+			"No such function '%s' to upload for.", name))
+		return nil, "", nil, false
+	}
+	r = r.WithContext(context.WithValue(r.Context(), uploadCallKey{}, w))
+	return r, name, r.Body, true
+}
+
+// upload creates, appends to or describes an upload, as a call of the "upload" function
+// asks, with the given chunk.
+func (h *Handler) upload(w http.ResponseWriter, r *http.Request, chunk io.Reader) (
+	*UploadInfo, error) {
+	u := h.opts.uploads
+	ctx := r.Context()
+	id := r.Header.Get(UploadIDHeader)
+	if id == "" {
+		size, err := strconv.ParseInt(r.Header.Get(UploadLengthHeader), 10, 64)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("Bad upload length: %w", ErrInvalidArgument)
+		}
+		if u.maxSize > 0 && size > u.maxSize {
+			return nil, fmt.Errorf("Upload is longer than %d bytes: %w", u.maxSize,
+				ErrInvalidArgument)
+		}
+		return u.store.Create(ctx, size)
+	}
+	if r.Header.Get(UploadOffsetHeader) == "" {
+		return u.store.Info(ctx, id)
+	}
+	offset, err := strconv.ParseInt(r.Header.Get(UploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		return nil, fmt.Errorf("Bad upload offset: %w", ErrInvalidArgument)
+	}
+	if u.maxChunk > 0 {
+		chunk = http.MaxBytesReader(w, io.NopCloser(chunk), int64(u.maxChunk))
+	}
+	return u.store.Append(ctx, id, offset, chunk)
+}
+
+// NewMemoryUploadStore returns an in-memory store that keeps uploads for the given
+// duration since their last chunk.
+func NewMemoryUploadStore(ttl time.Duration) UploadStore {
+	return &memoryUploadStore{ttl: ttl, m: map[string]*storedUpload{}}
+}
+
+// memoryUploadStore is an UploadStore that keeps uploads in memory.
+type memoryUploadStore struct {
+	ttl time.Duration
+	mu  sync.Mutex
+	m   map[string]*storedUpload
+}
+
+// storedUpload is an upload kept in a memoryUploadStore.
+type storedUpload struct {
+	data    []byte
+	size    int64
+	expires time.Time
+}
+
+func (s *memoryUploadStore) Create(ctx context.Context, size int64) (*UploadInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop expired uploads.
+	now := time.Now()
+	for k, u := range s.m {
+		if now.After(u.expires) {
+			delete(s.m, k)
+		}
+	}
+	id := newID() + newID()
+	s.m[id] = &storedUpload{size: size, expires: now.Add(s.ttl)}
+	return &UploadInfo{id, 0, size}, nil
+}
+
+func (s *memoryUploadStore) Append(ctx context.Context, id string, offset int64,
+	chunk io.Reader) (*UploadInfo, error) {
+	u, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	// The chunk is read before locking, and up to one byte past the upload's size.
+	data, err := io.ReadAll(io.LimitReader(chunk, u.size-offset+1))
+	if err != nil {
+		return nil, fmt.Errorf("Error reading chunk: %v: %w", err, ErrInvalidArgument)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if int64(len(u.data)) != offset {
+		return nil, fmt.Errorf("Offset %d is not the upload's offset %d: %w", offset,
+			len(u.data), ErrInvalidArgument)
+	}
+	if offset+int64(len(data)) > u.size {
+		return nil, fmt.Errorf("Chunk is past the upload's size %d: %w", u.size,
+			ErrInvalidArgument)
+	}
+	u.data = append(u.data, data...)
+	u.expires = time.Now().Add(s.ttl)
+	return &UploadInfo{id, int64(len(u.data)), u.size}, nil
+}
+
+func (s *memoryUploadStore) Info(ctx context.Context, id string) (*UploadInfo, error) {
+	u, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &UploadInfo{id, int64(len(u.data)), u.size}, nil
+}
+
+func (s *memoryUploadStore) Open(ctx context.Context, id string) (io.ReadCloser, error) {
+	u, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return io.NopCloser(bytes.NewReader(u.data)), nil
+}
+
+func (s *memoryUploadStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, id)
+	return nil
+}
+
+// get returns the upload with the given ID, if it has not expired.
+func (s *memoryUploadStore) get(id string) (*storedUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.m[id]
+	if !ok || time.Now().After(u.expires) {
+		return nil, fmt.Errorf("Upload '%s': %w", id, ErrNotFound)
+	}
+	return u, nil
+}
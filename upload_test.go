@@ -0,0 +1,134 @@
+package rpk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUploads(t *testing.T) {
+	h := New(Uploads(NewMemoryUploadStore(time.Hour), 4, 20))
+	h.Register("Save", func(ctx context.Context, id string) (string, error) {
+		r, err := OpenUpload(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return "", err
+		}
+		return string(data), DeleteUpload(ctx, id)
+	})
+	upload := func(id string, offset int, length int, chunk string) *UploadInfo {
+		req := httptest.NewRequest("POST", "/api?func=upload", strings.NewReader(chunk))
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.Header.Set(UploadFuncHeader, "Save")
+		if id != "" {
+			req.Header.Set(UploadIDHeader, id)
+		}
+		if offset >= 0 {
+			req.Header.Set(UploadOffsetHeader, strconv.Itoa(offset))
+		}
+		if length >= 0 {
+			req.Header.Set(UploadLengthHeader, strconv.Itoa(length))
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		if bytes.Contains(res.Body.Bytes(), []byte(`"error"`)) {
+			return nil
+		}
+		info := &UploadInfo{}
+		if err := newJSONDecoder(res.Body).Decode(info); err != nil {
+			t.Fatalf("Failed to decode %q: %v", res.Body.String(), err)
+		}
+		return info
+	}
+
+	if info := upload("", -1, 21, ""); info != nil {
+		t.Fatalf("Upload longer than the maximum was created: %+v", info)
+	}
+	info := upload("", -1, 10, "")
+	if info == nil || info.Offset != 0 || info.Size != 10 {
+		t.Fatalf("Bad new upload: %+v, expected offset 0 and size 10.", info)
+	}
+	id := info.ID
+	if got := upload(id, 0, -1, "abcd"); got == nil || got.Offset != 4 {
+		t.Fatalf("Bad upload after the first chunk: %+v, expected offset 4.", got)
+	}
+	if got := upload(id, 0, -1, "abcd"); got != nil {
+		t.Fatalf("Chunk with a bad offset was accepted: %+v", got)
+	}
+	if got := upload(id, 4, -1, "efghi"); got != nil {
+		t.Fatalf("Chunk longer than the maximum was accepted: %+v", got)
+	}
+	want := `{"error":"Upload '` + id + `' is incomplete: invalid argument",` +
+		`"code":"invalid_argument"}`
+	if got := callAPI(h, "Save", strconv.Quote(id)); got != want {
+		t.Fatalf("Bad result of an incomplete upload: %q, expected %q.", got, want)
+	}
+
+	// Resumes from the offset that the handler reports.
+	if got := upload(id, -1, -1, ""); got == nil || got.Offset != 4 {
+		t.Fatalf("Bad upload status: %+v, expected offset 4.", got)
+	}
+	upload(id, 4, -1, "efgh")
+	if got := upload(id, 8, -1, "ijk"); got != nil {
+		t.Fatalf("Chunk past the upload's size was accepted: %+v", got)
+	}
+	if got := upload(id, 8, -1, "ij"); got == nil || got.Offset != 10 {
+		t.Fatalf("Bad upload after the last chunk: %+v, expected offset 10.", got)
+	}
+	if got := callAPI(h, "Save", strconv.Quote(id)); got != `"abcdefghij"` {
+		t.Fatalf("Bad result of Save: %q, expected %q.", got, `"abcdefghij"`)
+	}
+	if got := upload(id, -1, -1, ""); got != nil {
+		t.Fatalf("Deleted upload was found: %+v", got)
+	}
+}
+
+func TestUploads_admission(t *testing.T) {
+	h := New(Uploads(NewMemoryUploadStore(time.Hour), 0, 0),
+		Tenants(func(r *http.Request) (string, error) {
+			if tenant := r.Header.Get("Tenant"); tenant != "" {
+				return tenant, nil
+			}
+			return "", errors.New("no tenant")
+		}), TenantRateLimit(0.001, 1))
+	h.Register("Save", func(id string) {})
+	upload := func(funcName, tenant string) string {
+		req := httptest.NewRequest("POST", "/api?func=upload", nil)
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.Header.Set(UploadFuncHeader, funcName)
+		req.Header.Set(UploadLengthHeader, "10")
+		if tenant != "" {
+			req.Header.Set("Tenant", tenant)
+		}
+		return serve(h, req)
+	}
+
+	tests := []struct {
+		funcName string
+		tenant   string
+		want     string
+	}{
+		{"Save", "", `no tenant`},
+		{"Nothing", "a", `No such function 'Nothing' to upload for.`},
+		{"Save", "a", `"size":10`},
+		{"Save", "a", `rate limit of tenant \"a\" exceeded`},
+		{"Save", "b", `"size":10`},
+	}
+	for _, test := range tests {
+		if got := upload(test.funcName, test.tenant); !strings.Contains(got, test.want) {
+			t.Fatalf("Bad result for %s of tenant %q: %q, expected %q.",
+				test.funcName, test.tenant, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package rpk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// ValidateOnlyHeader is the request header with which clients ask to only validate a
+// call's parameter. When it is "true", the handler decodes and validates the parameter
+// and runs the before hooks, but does not call the function, so forms can be checked
+// on the server before they are submitted. A valid call returns no output, and an
+// invalid one returns the error that the call would have.
+const ValidateOnlyHeader = "Rpk-Validate-Only"
+
+// A Validator is a parameter type that checks its own values after they are decoded.
+// If RPKValidate returns an error, the function is not called, and the error is
+// returned to the client, so it can be an RPCError like ErrInvalidArgument.
+type Validator interface {
+	RPKValidate() error
+}
+
+// ValidateOnly returns whether the call with ctx only validates its parameter, as
+// asked in the ValidateOnlyHeader.
+func ValidateOnly(ctx context.Context) bool {
+	v, _ := ctx.Value(validateOnlyKey{}).(bool)
+	return v
+}
+
+// WithValidateOnly returns a copy of ctx with which calls of the Go client only
+// validate their parameters.
+func WithValidateOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, validateOnlyKey{}, true)
+}
+
+// validateOnlyKey is the context key of whether a call only validates its parameter.
+type validateOnlyKey struct{}
+
+// validateOnly returns whether the request asks to only validate its parameter.
+func validateOnly(r *http.Request) bool {
+	v, _ := strconv.ParseBool(r.Header.Get(ValidateOnlyHeader))
+	return v
+}
+
+// errValidated is returned by decoders of validate-only calls after a successful
+// validation, so that the function is not called.
+var errValidated = errors.New("validated")
@@ -0,0 +1,120 @@
+package rpk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testSignup struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func (s testSignup) RPKValidate() error {
+	if s.Name == "" {
+		return fmt.Errorf("name is required: %w", ErrInvalidArgument)
+	}
+	return nil
+}
+
+func TestValidateOnly(t *testing.T) {
+	calls := 0
+	var hooked []bool
+	h := New()
+	h.Register("Signup", func(s testSignup) string {
+		calls++
+		return "hello " + s.Name
+	})
+	h.Register("Ping", func() string {
+		calls++
+		return "pong"
+	})
+	h.Before("*", func(c *Call) error {
+		hooked = append(hooked, c.ValidateOnly)
+		return nil
+	})
+
+	tests := []struct {
+		funcName string
+		param    string
+		validate bool
+		result   string
+	}{
+		{"Signup", `{"name":"a","age":3}`, true, ""},
+		{"Signup", `{"age":3}`, true,
+			`{"error":"name is required: invalid argument","code":"invalid_argument"}`},
+		{"Signup", `{"name":"a","age":"x"}`, true, `{"error":"Error decoding JSON: json: ` +
+			`cannot unmarshal string into Go struct field testSignup.age of type int"}`},
+		{"Ping", "", true, ""},
+		{"Signup", `{"age":3}`, false,
+			`{"error":"name is required: invalid argument","code":"invalid_argument"}`},
+		{"Signup", `{"name":"a"}`, false, `"hello a"`},
+	}
+	for _, test := range tests {
+		req := apiRequest(test.funcName, test.param)
+		if test.validate {
+			req.Header.Set(ValidateOnlyHeader, "true")
+		}
+		if result := serve(h, req); result != test.result {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.funcName, result,
+				test.result)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("Bad number of calls: %d, expected 1.", calls)
+	}
+	if len(hooked) != len(tests) || !hooked[0] || hooked[len(hooked)-1] {
+		t.Fatalf("Bad validate-only flags in hooks: %v.", hooked)
+	}
+}
+
+func TestValidateOnly_client(t *testing.T) {
+	calls := 0
+	h := New()
+	h.Register("Signup", func(s testSignup) string {
+		calls++
+		return "hello " + s.Name
+	})
+	server := httptest.NewServer(h)
+	defer server.Close()
+	c := NewClient(server.URL, nil)
+	ctx := WithValidateOnly(context.Background())
+
+	result := "unset"
+	if err := c.Call(ctx, "Signup", testSignup{Name: "a"}, &result); err != nil {
+		t.Fatalf("Validation failed: %v.", err)
+	}
+	if result != "unset" || calls != 0 {
+		t.Fatalf("Bad result of validation: %q with %d calls, expected none.", result,
+			calls)
+	}
+	err := c.Call(ctx, "Signup", testSignup{}, &result)
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Bad error: %v, expected %v.", err, ErrInvalidArgument)
+	}
+}
+
+func TestValidateOnly_static(t *testing.T) {
+	o := newOptions(nil)
+	f, err := newFuncs(staticType{}, o)
+	if err != nil {
+		t.Fatal("Failed to create funcs:", err)
+	}
+	ctx := WithValidateOnly(context.Background())
+	for _, test := range []struct{ funcName, param, err string }{
+		{"Bar", "3", ""},
+		{"Bar", `"x"`, "Error decoding JSON"},
+		{"FooErr", "", ""},
+	} {
+		res := f.run(ctx, test.funcName, strings.NewReader(test.param), o.decoder)
+		if !res.validated && test.err == "" || res.err != nil &&
+			!strings.Contains(res.err.Error(), test.err) {
+			t.Fatalf("Bad validation of %s(%s): %v, expected %q.", test.funcName,
+				test.param, res.err, test.err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package rpk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// A Versioned result carries the version of the resource it describes, which the
+// handler sends in the ETag header. Clients send the version back in the If-Match header
+// of calls that modify the resource, so that the functions can detect changes made by
+// others in between, and return a ConflictError.
+type Versioned interface {
+	RPKVersion() string
+}
+
+// A ConflictError reports that a call expected a version of a resource that is not
+// the current one. Clients can tell it apart from other errors: the Go client returns
+// it as a RemoteError with Conflict set, and the Javascript client gives an error with
+// a conflict property.
+type ConflictError struct {
+	Current string // The current version of the resource.
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("Version conflict, the current version is %q.", e.Current)
+}
+
+// ExpectedVersion returns the version that the call with ctx expects the resource it
+// modifies to have, as sent in the If-Match header. Returns false if the call does not
+// expect a version. Calls of the Go client with ctx do not send it on, unless
+// WithVersion says so.
+func ExpectedVersion(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(requestVersionKey{}).(string)
+	return v, ok
+}
+
+// WithVersion returns a copy of ctx with which calls of the Go client send v as the
+// expected version, for functions that modify versioned resources.
+func WithVersion(ctx context.Context, v string) context.Context {
+	return context.WithValue(ctx, versionKey{}, v)
+}
+
+// versionKey is the context key of the version that calls of the Go client expect.
+type versionKey struct{}
+
+// clientVersion returns the version that calls of the Go client with ctx expect, and
+// whether they expect one.
+func clientVersion(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(versionKey{}).(string)
+	return v, ok
+}
+
+// requestVersionKey is the context key of the version that a call expects.
+type requestVersionKey struct{}
+
+// ifMatch returns the version in the request's If-Match header, without the quotes of
+// an entity tag.
+func ifMatch(r *http.Request) string {
+	v := strings.TrimPrefix(r.Header.Get("If-Match"), "W/")
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		v = v[1 : len(v)-1]
+	}
+	return v
+}
+
+// setETag sets the ETag header of a response to the version of its result, if it is
+// versioned.
+func setETag(w http.ResponseWriter, res *callResult) {
+	if v, ok := res.val.(Versioned); ok && res.err == nil {
+		w.Header().Set("ETag", `"`+v.RPKVersion()+`"`)
+	}
+}
+
+// writeCallError writes an error returned by a function. ConflictErrors also carry the
+// current version, and RPCErrors their code and data.
+func writeCallError(w io.Writer, err error) {
+	var cerr *ConflictError
+	var rerr RPCError
+	switch {
+	case errors.As(err, &cerr):
+		json.NewEncoder(w).Encode(struct {
+			Error    string `json:"error"`
+			Conflict bool   `json:"conflict"`
+			Version  string `json:"version"`
+		}{err.Error(), true, cerr.Current})
+	case errors.As(err, &rerr):
+		code, data := rerr.RPCError()
+		json.NewEncoder(w).Encode(struct {
+			Error string      `json:"error"`
+			Code  string      `json:"code"`
+			Data  interface{} `json:"data,omitempty"`
+		}{err.Error(), code, data})
+	default:
+		writeError(w, "%v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package rpk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testDoc struct {
+	Text    string `json:"text"`
+	Version string `json:"version"`
+}
+
+func (d testDoc) RPKVersion() string { return d.Version }
+
+func TestVersions(t *testing.T) {
+	doc := testDoc{"hello", "v1"}
+	h := New()
+	h.Register("Get", func() testDoc { return doc })
+	h.Register("Set", func(ctx context.Context, text string) (testDoc, error) {
+		if v, ok := ExpectedVersion(ctx); ok && v != doc.Version {
+			return testDoc{}, &ConflictError{doc.Version}
+		}
+		doc = testDoc{text, doc.Version + "+"}
+		return doc, nil
+	})
+
+	tests := []struct {
+		funcName string
+		param    string
+		ifMatch  string
+		etag     string
+		result   string
+	}{
+		{"Get", "", "", `"v1"`, `{"text":"hello","version":"v1"}`},
+		{"Set", `"a"`, `"v1"`, `"v1+"`, `{"text":"a","version":"v1+"}`},
+		{"Set", `"b"`, `"v1"`, "", `{"error":"Version conflict, the current version ` +
+			`is \"v1+\".","conflict":true,"version":"v1+"}`},
+		{"Set", `"c"`, "", `"v1++"`, `{"text":"c","version":"v1++"}`},
+	}
+	for _, test := range tests {
+		req := apiRequest(test.funcName, test.param)
+		if test.ifMatch != "" {
+			req.Header.Set("If-Match", test.ifMatch)
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		if result := strings.TrimSpace(res.Body.String()); result != test.result {
+			t.Fatalf("Bad result for %s: %q, expected %q.", test.funcName, result,
+				test.result)
+		}
+		if etag := res.Header().Get("ETag"); etag != test.etag {
+			t.Fatalf("Bad ETag for %s: %q, expected %q.", test.funcName, etag, test.etag)
+		}
+	}
+}
+
+func TestVersions_client(t *testing.T) {
+	h := New(HideErrors(nil))
+	h.Register("Set", func(ctx context.Context) error {
+		v, _ := ExpectedVersion(ctx)
+		if v != "v2" {
+			return &ConflictError{"v2"}
+		}
+		return errors.New("other")
+	})
+	server := httptest.NewServer(h)
+	defer server.Close()
+	c := NewClient(server.URL, nil)
+
+	err := c.Call(WithVersion(context.Background(), "v1"), "Set", nil, nil)
+	var rerr *RemoteError
+	if !errors.As(err, &rerr) || !rerr.Conflict || rerr.Version != "v2" {
+		t.Fatalf("Bad error: %#v, expected a conflict with version %q.", err, "v2")
+	}
+	err = c.Call(WithVersion(context.Background(), "v2"), "Set", nil, nil)
+	if !errors.As(err, &rerr) || rerr.Conflict {
+		t.Fatalf("Bad error: %#v, expected a non-conflict remote error.", err)
+	}
+
+	// The version that a call expects is not sent with the calls that it makes.
+	var sent []string
+	h.Register("Get", func(ctx context.Context) {
+		v, ok := ExpectedVersion(ctx)
+		sent = append(sent, fmt.Sprintf("%s %v", v, ok))
+	})
+	front := New()
+	front.Register("Call", func(ctx context.Context) error {
+		if err := c.Call(ctx, "Get", nil, nil); err != nil {
+			return err
+		}
+		return c.Call(WithVersion(ctx, "v3"), "Get", nil, nil)
+	})
+	req := apiRequest("Call", "")
+	req.Header.Set("If-Match", `"v2"`)
+	if got := serve(front, req); got != "" || fmt.Sprint(sent) != "[ false v3 true]" {
+		t.Fatalf("Bad result: %q with versions %q, expected none and v3.", got, sent)
+	}
+}